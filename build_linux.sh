@@ -0,0 +1,2 @@
+#!/usr/bin/env bash
+CGO_ENABLED=1 go build -tags sqlite_fts5 -o tagvault main.go
@@ -9,6 +9,7 @@ require (
 	github.com/chai2010/webp v1.1.1
 	github.com/dsnet/compress v0.0.1
 	github.com/gen2brain/avif v0.3.2
+	github.com/gen2brain/heic v0.4.5
 	github.com/gen2brain/svg v0.1.0
 	github.com/grafana/pyroscope-go v1.2.0
 	github.com/mattn/go-sqlite3 v1.14.23
@@ -26,7 +27,7 @@ require (
 	fyne.io/systray v1.11.0 // indirect
 	github.com/BurntSushi/toml v1.4.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/ebitengine/purego v0.7.1 // indirect
+	github.com/ebitengine/purego v0.8.3 // indirect
 	github.com/fredbi/uri v1.1.0 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/fyne-io/gl-js v0.0.0-20230506162202-1fdaa286a934 // indirect
@@ -46,7 +47,7 @@ require (
 	github.com/rymdport/portal v0.3.0 // indirect
 	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c // indirect
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef // indirect
-	github.com/tetratelabs/wazero v1.7.3 // indirect
+	github.com/tetratelabs/wazero v1.9.0 // indirect
 	github.com/yuin/goldmark v1.7.4 // indirect
 	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/mobile v0.0.0-20240909163608-642950227fb3 // indirect
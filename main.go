@@ -3,43 +3,49 @@ package main
 import (
 	"bytes"
 	"database/sql"
+	"errors"
 	"fmt"
 	"image"
-	"image/gif"
+	"image/color"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"main/pkg/apptheme"
+	"main/pkg/colorutils"
 	"main/pkg/components/buttons"
+	"main/pkg/components/layouts"
 	"main/pkg/database"
+	"main/pkg/filepreview"
 	"main/pkg/fileutils"
+	"main/pkg/geo"
 	"main/pkg/icon"
+	"main/pkg/imagecodec"
+	"main/pkg/imagetransform"
 	"main/pkg/logger"
 	"main/pkg/options"
 	"main/pkg/profiling"
+	"main/pkg/resourcecache"
 	"main/pkg/tagwindow"
+	"main/pkg/thumbcache"
 	"main/pkg/utilwindows"
+	"main/pkg/video"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/gen2brain/avif"
-	"github.com/gen2brain/svg"
-
-	// "github.com/jdeng/goheif"
-	"github.com/xfmoulet/qoi"
-	"golang.org/x/image/bmp"
 	"golang.org/x/image/draw"
-	"golang.org/x/image/tiff"
-	"golang.org/x/image/webp"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	// fyneGif "fyne.io/x/fyne/widget"
@@ -47,17 +53,35 @@ import (
 )
 
 var (
-	resourceCache sync.Map
-	appOptions    = new(options.Options).InitDefault()
-	optionsExist  = false
-	appLogger     = logger.InitLogger()
-	page          = 0
-	selectedFiles = map[string]bool{}
-	home, _       = os.UserHomeDir()
-	prevoiusImage = ""
-	orderBy       = ""
+	resourceCache  = resourcecache.New(resourceCacheSize)
+	appOptions     = new(options.Options).InitDefault()
+	optionsExist   = false
+	appLogger      = logger.InitLogger()
+	pageMu         sync.Mutex      // guards pageCursor and pageGeneration
+	pageCursor     database.Cursor // where the last loaded page of images ended
+	pageGeneration int             // counts the times the grid was replaced, pages loaded for an older grid are dropped
+	loadingPage    atomic.Bool     // true while a page of images is being loaded
+	pagedGrid      atomic.Bool     // true while the grid shows the library page by page instead of a list of results
+	lastPage       atomic.Bool     // true once every image has been loaded
+	selectedFiles  = map[string]bool{}
+	home, _        = os.UserHomeDir()
+	prevoiusImage  = ""
+	orderBy        = ""
+	thumbnails     sync.Map                       // image path -> buttons.Markable showing it in the grid, cleared with the grid
+	tilePaths      sync.Map                       // tile of the grid -> image path it shows
+	gridContent    *fyne.Container                // holds the grids of the images shown, one for every loaded page
+	setMarks       func(marks database.FileMarks) // updates the marks controls in the sidebar
+	showImages     func(imagePaths []string)      // replaces the grid with the images, they aren't paged
+	reloadImages   func()                         // clears the grid and loads it again from the first page
+	search         func(query string)             // puts the query in the search bar and runs it
 )
 
+// Most bytes of image resources kept in memory, about 10000 thumbnails at the default size
+const resourceCacheSize = 256 << 20
+
+// Height the rows of the justified grid are scaled from
+const justifiedRowHeight = 180
+
 func main() {
 	db := database.Init()
 	defer db.Close()
@@ -84,8 +108,6 @@ func main() {
 		if err != nil {
 			appLogger.Fatalln("Failed to save Options: ", err)
 		}
-
-		database.AddImageTypeTags(db)
 	} else {
 		appLogger.Println("Loading options")
 		appOptions, err = options.LoadOptionsFromDB(db)
@@ -103,6 +125,11 @@ func main() {
 		appLogger.Println("VACUUM Executed Successfully")
 	}
 
+	// adds the tags of file types supported since the library was made
+	if err := database.AddImageTypeTags(db); err != nil {
+		appLogger.Println("Failed to add image type tags: ", err)
+	}
+
 	if appOptions.Profiling {
 		profiling.SetupProfiling()
 	}
@@ -118,11 +145,22 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		database.DiscoverImages(db, appOptions.ExcludedDirs)
+		database.DiscoverImages(db, appOptions.ExcludedDirs, appOptions.MediaTypes)
 	}()
 
 	wg.Wait()
 
+	// hashes new images in the background for the duplicate finder
+	go func() {
+		if _, err := database.HashImages(db); err != nil {
+			appLogger.Println("Failed to hash images: ", err)
+		}
+	}()
+
+	// makes the thumbnails missing from the disk cache so the grid doesn't have to
+	go pregenerateThumbnails(db)
+	go logResourceCacheStats()
+
 	// ---------- CLAUDE LAYOUT START
 
 	content := container.NewVBox()
@@ -140,11 +178,98 @@ func main() {
 	form.SetPlaceHolder("Enter a Tag to Search by")
 
 	imageContent := content
+	gridContent = imageContent
+
+	// ends the load of a page, unless the grid was replaced while it loaded and a new load started
+	finishPage := func(generation int) bool {
+		pageMu.Lock()
+		defer pageMu.Unlock()
+		if generation != pageGeneration {
+			return false
+		}
+		loadingPage.Store(false)
+		return true
+	}
+
+	// loads the next page of images from the database and appends it to the grid
+	var loadNextPage func()
+	loadNextPage = func() {
+		if loadingPage.Swap(true) {
+			return
+		}
+		pageMu.Lock()
+		generation, cursor := pageGeneration, pageCursor
+		pageMu.Unlock()
+
+		nextImages, nextCursor, err := database.GetImagesFromDatabase(db, orderBy, appOptions.SortDesc, cursor, appOptions.ImageNumber)
+		if err != nil {
+			appLogger.Println("Failed to load more images on scroll: ", err)
+			finishPage(generation)
+			return
+		}
+
+		// the page is added under the lock so a reset can't clear the grid in between
+		pageMu.Lock()
+		defer pageMu.Unlock()
+		if generation != pageGeneration {
+			appLogger.Println("Dropped page of images loaded before the grid was reset")
+			return
+		}
+		pageCursor = nextCursor
+		if len(nextImages) < int(appOptions.ImageNumber) {
+			lastPage.Store(true)
+		}
+		appLogger.Println("Loaded page of ", len(nextImages), " images")
+
+		if len(nextImages) == 0 {
+			loadingPage.Store(false)
+			return
+		}
+
+		displayImages := createDisplayImagesFunctionFromDb(db, w, sidebar, sidebarScroll, split, a, imageContent, nextImages, func() {
+			if !finishPage(generation) {
+				return
+			}
+			// keep loading until the grid is taller than the window
+			if !lastPage.Load() && imageContent.MinSize().Height < scroll.Size().Height {
+				loadNextPage()
+			}
+		})
+		displayImages("")
+		imageContent.Refresh()
+	}
+
+	// clears the grid and starts loading images from the first page
+	resetPages := func() {
+		pageMu.Lock()
+		pageGeneration++
+		imageContent.RemoveAll()
+		tilePaths.Clear()
+		thumbnails.Clear()
+		pageCursor = database.Cursor{}
+		pagedGrid.Store(true)
+		lastPage.Store(false)
+		// a page still loading for the old grid is dropped, so it doesn't hold up the first page
+		loadingPage.Store(false)
+		pageMu.Unlock()
+		loadNextPage()
+	}
+	reloadImages = func() {
+		if !appOptions.FirstBoot {
+			resetPages()
+		}
+	}
 
 	form.OnSubmitted = func(s string) {
-		imagePaths, err := database.GetImagePathsByTag(db, "%"+s+"%")
+		if s == "" && !appOptions.FirstBoot {
+			resetPages()
+			return
+		}
+		// search results are not paged so stop loading pages on scroll
+		lastPage.Store(true)
+		imagePaths, err := database.SearchImages(db, s)
 		if err != nil {
-			fmt.Print("searchImagesByTag")
+			fmt.Print("searchImages")
 			dialog.ShowError(err, w)
 			return
 		}
@@ -163,7 +288,7 @@ func main() {
 	// }
 
 	settingsButton := widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
-		utilwindows.ShowSettingsWindow(a, w, db, appOptions)
+		utilwindows.ShowSettingsWindow(a, w, db, appOptions, reloadImages)
 	})
 
 	filterOptions := []string{"Name", "Date Added"}
@@ -173,12 +298,29 @@ func main() {
 		utilwindows.ShowSelectWindow(a, filterOptions, "Filter Options", func(selected string) {
 			// return
 			orderBy = selected
+			if !appOptions.FirstBoot {
+				resetPages()
+			}
 		})
 	})
 	filterButton.Icon = loadFilterButton
 	// test := orderBy
 
-	optContainer := container.NewGridWithColumns(2, filterButton, settingsButton)
+	// finds the images with a dominant color picked with the sliders
+	colorSearchButton := widget.NewButtonWithIcon("", theme.ColorPaletteIcon(), func() {
+		utilwindows.ShowColorSearchWindow(a, appOptions, func(hex string) {
+			search("color:" + hex)
+		})
+	})
+
+	// shows the images of the grid or of a saved search one by one, full screen
+	slideshowButton := widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() {
+		utilwindows.ShowSlideshowWindow(a, db, appOptions, form.Text, resultPaths(db), func(path string) (image.Image, error) {
+			return decodeViewerImage(db, path, 0)
+		})
+	})
+
+	optContainer := container.NewGridWithColumns(4, slideshowButton, colorSearchButton, filterButton, settingsButton)
 	controls := container.NewBorder(nil, nil, nil, optContainer, form)
 
 	// Create main container with tabs above controls
@@ -208,36 +350,86 @@ func main() {
 	)
 	tabs.SetTabLocation(container.TabLocationTop)
 
-	appLogger.Printf("Page: %d, ImageNumber: %d", page, appOptions.ImageNumber)
+	search = func(query string) {
+		form.SetText(query)
+		form.OnSubmitted(query)
+	}
+	showImages = func(imagePaths []string) {
+		lastPage.Store(true)
+		updateContentWithSearchResults(imageContent, imagePaths, db, w, sidebar, sidebarScroll, split, a)
+	}
+
+	// selecting a year, month or day in the timeline shows its images in the grid
+	timelineTab := container.NewTabItem("Timeline", createTimeline(db, func(field database.DateField, period string) {
+		dates, err := database.ParseDateRange(period)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		imagePaths, err := database.GetImagesByDate(db, field, dates)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		// the same images can be found again with the date filter in the search bar
+		if field == database.CaptureDate {
+			form.SetText("taken:" + period)
+		} else {
+			form.SetText("added:" + period)
+		}
+		lastPage.Store(true)
+		tabs.Select(mainTab)
+		updateContentWithSearchResults(imageContent, imagePaths, db, w, sidebar, sidebarScroll, split, a)
+	}))
+	tabs.Append(timelineTab)
+
+	// selecting a place shows the images taken there in the grid
+	placesTab := container.NewTabItem("Places", createPlaces(db, func(cell geo.Cell, cellSize float64) {
+		imagePaths, err := database.GetImagesInCell(db, cell, cellSize)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		form.SetText("")
+		lastPage.Store(true)
+		tabs.Select(mainTab)
+		updateContentWithSearchResults(imageContent, imagePaths, db, w, sidebar, sidebarScroll, split, a)
+	}))
+	tabs.Append(placesTab)
+
+	// deleting duplicates reloads the grid so it doesn't show the removed files
+	duplicatesTab := container.NewTabItem("Duplicates", createDuplicates(db, w, reloadImages))
+	tabs.Append(duplicatesTab)
+
+	appLogger.Printf("ImageNumber: %d", appOptions.ImageNumber)
 	if appOptions.FirstBoot {
 		appLogger.Println("This is first boot")
 		displayImages := createDisplayImagesFunction(db, w, sidebar, sidebarScroll, split, a, imageContent)
 		displayImages(home + "/Pictures")
 	} else {
-		dbImages, err := database.GetImagesFromDatabase(db, page, appOptions.ImageNumber)
-		if err != nil {
-			appLogger.Fatal(err)
-		}
-		displayImages := createDisplayImagesFunctionFromDb(db, w, sidebar, sidebarScroll, split, a, imageContent, dbImages)
-		displayImages("")
+		pagedGrid.Store(true)
+		loadNextPage()
 	}
 
 	scroll.OnScrolled = func(pos fyne.Position) {
-		// appLogger.Println("Scrolled: ", pos.Y)
-		if scroll.Offset.Y == 148+(float32(page)*648) && !appOptions.FirstBoot {
-			page += 1
-			appLogger.Println("Scrolled to bottom. Current page: ", page)
-			appLogger.Println("Skip images: ", page*int(appOptions.ImageNumber))
-			nextImages, err := database.GetImagesFromDatabase(db, page*int(appOptions.ImageNumber), appOptions.ImageNumber)
-			if err != nil {
-				appLogger.Fatal("Failed to load more images on scroll: ", err)
-			}
-			displayImages := createDisplayImagesFunctionFromDb(db, w, sidebar, sidebarScroll, split, a, imageContent, nextImages)
-			displayImages("")
-			imageContent.Refresh()
+		if appOptions.FirstBoot || lastPage.Load() || loadingPage.Load() {
+			return
+		}
+		// load the next page when less than one window height of images is left below the view
+		remaining := imageContent.MinSize().Height - (pos.Y + scroll.Size().Height)
+		if remaining < scroll.Size().Height {
+			loadNextPage()
 		}
 	}
 
+	w.Canvas().SetOnTypedRune(func(r rune) {
+		// only when the sidebar shows an image and no text field has focus
+		if prevoiusImage == "" || !sidebarScroll.Visible() {
+			return
+		}
+		markShortcut(db, prevoiusImage, r)
+	})
+
 	// ---------- CLAUDE LAYOUT END
 
 	appLogger.Println("Remember to delete fyne folder from `.config/fyne` folder")
@@ -267,7 +459,7 @@ func createDisplayImagesFunction(db *sql.DB, w fyne.Window, sidebar *fyne.Contai
 		}
 
 		// make a grid to display images
-		imageContainer := container.NewAdaptiveGrid(5) // default value 4
+		imageContainer := newImageGrid()
 		// create a loading bar & start it
 		loadingIndicator := widget.NewProgressBarInfinite()
 		loadingIndicator.Start()
@@ -283,9 +475,9 @@ func createDisplayImagesFunction(db *sql.DB, w fyne.Window, sidebar *fyne.Contai
 		// loop through images
 		for _, file := range files {
 			// check if it's an image
-			if !file.IsDir() && fileutils.IsImageFileMap(file.Name()) {
-				// get full image path
-				imgPath := filepath.Join(dir, file.Name())
+			// get full image path
+			imgPath := filepath.Join(dir, file.Name())
+			if !file.IsDir() && fileutils.HasMediaType(imgPath, appOptions.MediaTypes) {
 				wg.Add(1)
 				go func(path string) {
 					defer wg.Done()
@@ -314,10 +506,11 @@ func createDisplayImagesFunction(db *sql.DB, w fyne.Window, sidebar *fyne.Contai
 	}
 }
 
-func createDisplayImagesFunctionFromDb(db *sql.DB, w fyne.Window, sidebar *fyne.Container, sidebarScroll *container.Scroll, split *container.Split, a fyne.App, mainContainer *fyne.Container, files []string) func(string) {
+// onLoaded is called once every image of the page has been added, it can be nil
+func createDisplayImagesFunctionFromDb(db *sql.DB, w fyne.Window, sidebar *fyne.Container, sidebarScroll *container.Scroll, split *container.Split, a fyne.App, mainContainer *fyne.Container, files []string, onLoaded func()) func(string) {
 	return func(dir string) {
 		// make a grid to display images
-		imageContainer := container.NewAdaptiveGrid(5) // default value 4
+		imageContainer := newImageGrid()
 		// create a loading bar & start it
 		loadingIndicator := widget.NewProgressBarInfinite()
 		loadingIndicator.Start()
@@ -353,6 +546,10 @@ func createDisplayImagesFunctionFromDb(db *sql.DB, w fyne.Window, sidebar *fyne.
 			content.Remove(loadingIndicator)
 			// refresh the container that contains images
 			canvas.Refresh(content)
+
+			if onLoaded != nil {
+				onLoaded()
+			}
 		}()
 	}
 }
@@ -361,143 +558,149 @@ func displayImage(db *sql.DB, w fyne.Window, path string, imageContainer *fyne.C
 	// create a placeholder image
 	placeholderResource := fyne.NewStaticResource("placeholder", []byte{})
 
-	if filepath.Ext(path) == ".gif" {
-		gifPath, _ := storage.ParseURI("file://" + path)
-		gifButton := buttons.NewGifButton(gifPath)
-		gifButton.StartAnimation()
-
-		resourceChan := make(chan fyne.Resource, 1)
-
-		// claude ai solution to load images in bg
-		go func() {
-			// load the image as a fyne resource
-			resource, err := loadImageResourceThumbnailEfficient(path)
-			if err != nil {
-				appLogger.Printf("No resource image empty %s: %v", path, err)
-				resourceChan <- placeholderResource
-				return
-			}
-
-			// set the image button image to the resource
-			resourceChan <- resource
-		}()
-
-		resource := <-resourceChan
-
-		gifButton.SetOnTapped(func() {
-			appLogger.Println("TAPPED GIF")
-			updateSidebar(db, w, path, resource, sidebar, sidebarScroll, split, a, imageContainer)
-		})
-
-		gifButton.SetOnLongTap(func() {
-			// If image is not already selected and selectedFiles is 0 or bigger than 0
-			if len(selectedFiles) >= 0 && !selectedFiles[path] {
-				selectedFiles[path] = true
-				appLogger.Println("Added new file: ", path)
-				// gifButton.Image.Translucency = 0.7
-				gifButton.Selected = true
-				canvas.Refresh(gifButton)
-				// If image is already selected and selectedFiles is 0 or bigger than 0
-			} else if len(selectedFiles) >= 0 && selectedFiles[path] {
-				appLogger.Println("Removed file: ", path)
-				delete(selectedFiles, path)
-				// gifButton.Image.Translucency = 0
-				gifButton.Selected = false
-				canvas.Refresh(gifButton)
-			}
-			appLogger.Println("Selected files: ", selectedFiles)
+	format, _ := imagecodec.DetectFile(path)
+	imgButton := buttons.NewImageButton(placeholderResource)
+	// GIFs and animated WebP and PNG files play over their thumbnail
+	if imagecodec.IsAnimated(path) {
+		imgButton = buttons.NewAnimatedButton(placeholderResource, animationPlayback(), func() ([]image.Image, []time.Duration, error) {
+			return loadAnimationFrames(db, path)
 		})
+		imgButton.SetFrameRate(float64(appOptions.AnimationFrameRate))
+	}
+	isVideo := format != nil && format.Video
+	if isVideo {
+		imgButton.SetVideo(videoDuration(db, path))
+	}
+	// documents show their type and name, their previews don't tell them apart
+	mediaType := fileutils.MediaType(path)
+	isDocument := !isVideo && mediaType != "" && mediaType != fileutils.ImageMedia
+	if isDocument {
+		imgButton.SetBadge(mediaIcon(mediaType), truncateFilename(filepath.Base(path), 14, true))
+	}
 
-		gifButton.SetOnRightClick(func() {
-			utilwindows.ShowRightClickMenu(w, selectedFiles, a)
-		})
+	resourceChan := make(chan fyne.Resource, 1)
 
-		imageContainer.Add(container.NewPadded(gifButton))
-		// appLogger.Println("Skipping GIF")
-	} else {
-		imgButton := buttons.NewImageButton(placeholderResource)
+	// claude ai solution to load images in bg
+	go func() {
+		// load the image as a fyne resource
+		resource, err := loadImageResourceThumbnailEfficient(db, path)
+		if err != nil && isVideo {
+			// most video codecs can't be decoded, those videos get an icon
+			imgButton.Image.Resource = theme.MediaVideoIcon()
+			canvas.Refresh(imgButton)
+			resourceChan <- theme.MediaVideoIcon()
+			return
+		}
+		if err != nil && isDocument {
+			// files without a preview get the icon of their type
+			imgButton.Image.Resource = mediaIcon(mediaType)
+			canvas.Refresh(imgButton)
+			resourceChan <- mediaIcon(mediaType)
+			return
+		}
+		if err != nil {
+			appLogger.Printf("No resource image empty %s: %v", path, err)
+			resourceChan <- placeholderResource
+			canvas.Refresh(imgButton)
+			return
+		}
 
-		resourceChan := make(chan fyne.Resource, 1)
+		// set the image button image to the resource
+		imgButton.SetResource(resource)
+		imgButton.Image.Translucency = 0
+		// imgButton.image.Refresh()
+		canvas.Refresh(imgButton)
+		resourceChan <- resource
+	}()
 
-		// claude ai solution to load images in bg
-		go func() {
-			// load the image as a fyne resource
-			resource, err := loadImageResourceThumbnailEfficient(path)
-			if err != nil {
-				appLogger.Printf("No resource image empty %s: %v", path, err)
-				resourceChan <- placeholderResource
-				canvas.Refresh(imgButton)
-				return
-			}
+	resource := <-resourceChan
+	imgButton.SetOnTapped(func() {
+		updateSidebar(db, w, path, resource, sidebar, sidebarScroll, split, a, imageContainer)
+	})
+	// imgButton.OnTapped = func() {
+	// 	// updates the sidebar
+	// 	updateSidebar(db, w, path, resource, sidebar, sidebarScroll, split, a, imageContainer)
+	// }
 
-			// set the image button image to the resource
-			imgButton.Image.Resource = resource
+	imgButton.SetOnLongTap(func() {
+		// If image is not already selected and selectedFiles is 0 or bigger than 0
+		if len(selectedFiles) >= 0 && !selectedFiles[path] {
+			selectedFiles[path] = true
+			appLogger.Println("Added new file: ", path)
+			imgButton.Image.Translucency = 0.7
+			imgButton.Selected = true
+			canvas.Refresh(imgButton)
+			// If image is already selected and selectedFiles is 0 or bigger than 0
+		} else if len(selectedFiles) >= 0 && selectedFiles[path] {
+			appLogger.Println("Removed file: ", path)
+			delete(selectedFiles, path)
 			imgButton.Image.Translucency = 0
-			// imgButton.image.Refresh()
+			imgButton.Selected = false
 			canvas.Refresh(imgButton)
-			resourceChan <- resource
-		}()
+		}
+		appLogger.Println("Selected files: ", selectedFiles)
+	})
+	// imgButton.OnLongTap = func() {
+	// 	// If image is not already selected and selectedFiles is 0 or bigger than 0
+	// 	if len(selectedFiles) >= 0 && !selectedFiles[path] {
+	// 		selectedFiles[path] = true
+	// 		appLogger.Println("Added new file: ", path)
+	// 		imgButton.Image.Translucency = 0.7
+	// 		imgButton.Selected = true
+	// 		canvas.Refresh(imgButton)
+	// 		// If image is already selected and selectedFiles is 0 or bigger than 0
+	// 	} else if len(selectedFiles) >= 0 && selectedFiles[path] {
+	// 		appLogger.Println("Removed file: ", path)
+	// 		delete(selectedFiles, path)
+	// 		imgButton.Image.Translucency = 0
+	// 		imgButton.Selected = false
+	// 		canvas.Refresh(imgButton)
+	// 	}
+	// 	appLogger.Println("Selected files: ", selectedFiles)
+	// }
 
-		resource := <-resourceChan
-		imgButton.SetOnTapped(func() {
-			updateSidebar(db, w, path, resource, sidebar, sidebarScroll, split, a, imageContainer)
-		})
-		// imgButton.OnTapped = func() {
-		// 	// updates the sidebar
-		// 	updateSidebar(db, w, path, resource, sidebar, sidebarScroll, split, a, imageContainer)
-		// }
+	imgButton.SetOnRightClick(func() {
+		appLogger.Println("Add functionality to open menu to add to archive and compress")
+		utilwindows.ShowRightClickMenu(w, db, selectedFiles, a)
+	})
+	// imgButton.OnRightClick = func() {
+	// 	appLogger.Println("Add functionality to open menu to add to archive and compress")
+	// 	utilwindows.ShowRightClickMenu(w, db, selectedFiles, a)
+	// }
 
-		imgButton.SetOnLongTap(func() {
-			// If image is not already selected and selectedFiles is 0 or bigger than 0
-			if len(selectedFiles) >= 0 && !selectedFiles[path] {
-				selectedFiles[path] = true
-				appLogger.Println("Added new file: ", path)
-				imgButton.Image.Translucency = 0.7
-				imgButton.Selected = true
-				canvas.Refresh(imgButton)
-				// If image is already selected and selectedFiles is 0 or bigger than 0
-			} else if len(selectedFiles) >= 0 && selectedFiles[path] {
-				appLogger.Println("Removed file: ", path)
-				delete(selectedFiles, path)
-				imgButton.Image.Translucency = 0
-				imgButton.Selected = false
-				canvas.Refresh(imgButton)
-			}
-			appLogger.Println("Selected files: ", selectedFiles)
-		})
-		// imgButton.OnLongTap = func() {
-		// 	// If image is not already selected and selectedFiles is 0 or bigger than 0
-		// 	if len(selectedFiles) >= 0 && !selectedFiles[path] {
-		// 		selectedFiles[path] = true
-		// 		appLogger.Println("Added new file: ", path)
-		// 		imgButton.Image.Translucency = 0.7
-		// 		imgButton.Selected = true
-		// 		canvas.Refresh(imgButton)
-		// 		// If image is already selected and selectedFiles is 0 or bigger than 0
-		// 	} else if len(selectedFiles) >= 0 && selectedFiles[path] {
-		// 		appLogger.Println("Removed file: ", path)
-		// 		delete(selectedFiles, path)
-		// 		imgButton.Image.Translucency = 0
-		// 		imgButton.Selected = false
-		// 		canvas.Refresh(imgButton)
-		// 	}
-		// 	appLogger.Println("Selected files: ", selectedFiles)
-		// }
+	thumbnails.Store(path, imgButton)
+	refreshMarks(db, path)
 
-		imgButton.SetOnRightClick(func() {
-			appLogger.Println("Add functionality to open menu to add to archive and compress")
-			utilwindows.ShowRightClickMenu(w, selectedFiles, a)
-		})
-		// imgButton.OnRightClick = func() {
-		// 	appLogger.Println("Add functionality to open menu to add to archive and compress")
-		// 	utilwindows.ShowRightClickMenu(w, selectedFiles, a)
-		// }
+	// make a parent container to hold the image button, it fills the whole cell
+	// so the rows of the justified layout can size it
+	imageTile := container.NewPadded(imgButton)
+	setTileAspect(imageContainer, imageTile, resource)
+	tilePaths.Store(imageTile, path)
+	imageContainer.Add(imageTile)
+	// appLogger.Println("Showing ", len(imageContainer.Objects), " images")
+}
 
-		// make a parent container to hold the image button and label
-		imageTile := container.NewVBox(container.NewPadded(imgButton))
-		imageContainer.Add(imageTile)
+// Returns the container the thumbnails are shown in, laid out as set in the options
+func newImageGrid() *fyne.Container {
+	if appOptions.GridLayout == options.JustifiedLayout {
+		return layouts.NewJustifiedContainer(justifiedRowHeight)
 	}
-	// appLogger.Println("Showing ", len(imageContainer.Objects), " images")
+	return container.NewAdaptiveGrid(5) // default value 4
+}
+
+// Gives a tile of the justified layout the aspect ratio of its thumbnail, other
+// layouts don't need it. Only fitted thumbnails have the aspect of their image, the
+// justified layout gets them (see options.FitThumbnails) and other tiles stay square
+func setTileAspect(imageContainer *fyne.Container, tile fyne.CanvasObject, resource fyne.Resource) {
+	justified, ok := imageContainer.Layout.(*layouts.Justified)
+	if !ok || resource == nil || !appOptions.FitThumbnails() {
+		return
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(resource.Content()))
+	if err != nil || config.Height == 0 {
+		return
+	}
+	justified.SetAspect(tile, float32(config.Width)/float32(config.Height))
 }
 
 func CreateDisplayDirContentsContainer(dirFiles []string, w fyne.Window, a fyne.App) *fyne.Container {
@@ -506,7 +709,6 @@ func CreateDisplayDirContentsContainer(dirFiles []string, w fyne.Window, a fyne.
 
 	scrollContainer := container.NewVScroll(fileContainer)
 
-	fileIcon := theme.FileIcon()
 	folderIcon := theme.FolderIcon()
 
 	for _, v := range dirFiles {
@@ -552,7 +754,8 @@ func CreateDisplayDirContentsContainer(dirFiles []string, w fyne.Window, a fyne.
 			fileContainer.Add(icon)
 		} else {
 			// this will run if current item is not a dir
-			icon := buttons.NewFileButton(&fileIcon, truncateDirname(filepath.Base(v), 10))
+			typeIcon := mediaIcon(fileutils.MediaType(filepath.Join(home, v)))
+			icon := buttons.NewFileButton(&typeIcon, truncateDirname(filepath.Base(v), 10))
 
 			icon.SetOnRightClick(func() {
 				utilwindows.ShowFileRightClickMenu(w, selectedFiles, a)
@@ -613,7 +816,6 @@ func SetDisplayDirNewContent(content *fyne.Container, files []string, currentDir
 		dirContent = files
 	}
 
-	fileIcon := theme.FileIcon()
 	folderIcon := theme.FolderIcon()
 
 	for _, v := range dirContent {
@@ -632,7 +834,8 @@ func SetDisplayDirNewContent(content *fyne.Container, files []string, currentDir
 			// 	SetDisplayDirNewContent(content, nil, newDir)
 			// })
 		} else {
-			icon = buttons.NewFileButton(&fileIcon, truncateFilename(filepath.Base(v), 10, true))
+			typeIcon := mediaIcon(fileutils.MediaType(filepath.Join(currentDir, v)))
+			icon = buttons.NewFileButton(&typeIcon, truncateFilename(filepath.Base(v), 10, true))
 
 			// icon = widget.NewButtonWithIcon(truncateFilename(v, 10, true), theme.FileIcon(), nil)
 		}
@@ -660,10 +863,46 @@ func updateSidebar(db *sql.DB, w fyne.Window, path string, resource fyne.Resourc
 	fullLabel.Wrapping = fyne.TextWrapWord
 	dateAdded := widget.NewLabel("Date Added: " + database.GetDate(db, path))
 	dateAdded.Wrapping = fyne.TextWrapWord
-	ext := filepath.Ext(path)
-	fileType := widget.NewLabel("Type: " + strings.ToUpper(ext[1:]))
-	fileType.Wrapping = fyne.TextWrapWord
 	imageId := database.GetImageId(db, path)
+	fileType := createFileTypeInfo(db, w, imageId, path)
+
+	// Editable title, description and notes of the image
+	notes, err := database.GetFileNotes(db, imageId)
+	if err != nil {
+		appLogger.Println("Error getting notes:", err)
+	}
+	titleEntry := widget.NewEntry()
+	titleEntry.SetPlaceHolder("Title")
+	titleEntry.SetText(notes.Title)
+	descriptionEntry := widget.NewMultiLineEntry()
+	descriptionEntry.SetPlaceHolder("Description")
+	descriptionEntry.Wrapping = fyne.TextWrapWord
+	descriptionEntry.SetMinRowsVisible(2)
+	descriptionEntry.SetText(notes.Description)
+	notesEntry := widget.NewMultiLineEntry()
+	notesEntry.SetPlaceHolder("Notes")
+	notesEntry.Wrapping = fyne.TextWrapWord
+	notesEntry.SetMinRowsVisible(4)
+	notesEntry.SetText(notes.Notes)
+	notesForm := &widget.Form{
+		Items: []*widget.FormItem{
+			{Text: "Title", Widget: titleEntry},
+			{Text: "Description", Widget: descriptionEntry},
+			{Text: "Notes", Widget: notesEntry},
+		},
+		SubmitText: "Save",
+		OnSubmit: func() {
+			err := database.SetFileNotes(db, imageId, database.FileNotes{
+				Title:       strings.TrimSpace(titleEntry.Text),
+				Description: strings.TrimSpace(descriptionEntry.Text),
+				Notes:       strings.TrimSpace(notesEntry.Text),
+			})
+			if err != nil {
+				dialog.ShowError(err, w)
+			}
+		},
+	}
+
 	tagDisplay := tagwindow.CreateTagDisplay(db, imageId, appLogger, sidebar, w)
 	addTagButton := widget.NewButton("+", func() {
 		tagwindow.ShowTagWindow(a, w, db, imageId, tagDisplay)
@@ -672,29 +911,77 @@ func updateSidebar(db *sql.DB, w fyne.Window, path string, resource fyne.Resourc
 		tagwindow.ShowCreateTagWindow(a, w, db, appOptions, false, "", 0)
 	})
 
-	// Create fullscreen button
+	// Create fullscreen button, the viewer goes through the files of the current results
 	fullscreenButton := widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), func() {
-		// Create new image for fullscreen view
-		// fullscreenImg := canvas.NewImageFromResource(resource)
-		fullscreenImg := canvas.NewImageFromFile(path)
-		fullscreenImg.FillMode = canvas.ImageFillContain
-		fullscreenImg.SetMinSize(fyne.NewSize(600, 400)) // Set a reasonable default size
+		paths := resultPaths(db)
+		index := slices.Index(paths, path)
+		if index < 0 {
+			paths, index = []string{path}, 0
+		}
+		utilwindows.ShowViewerWindow(a, paths, index, func(path string, frame int) (image.Image, error) {
+			return decodeViewerImage(db, path, frame)
+		}, func(path string) int {
+			if fileutils.MediaType(path) != fileutils.ImageMedia {
+				return 1
+			}
+			return imagecodec.ImageCount(path)
+		})
+	})
+	fullscreenButton.Importance = widget.LowImportance
+	// videos are played in the default player of the system and documents are
+	// opened in their default app
+	if mediaType := fileutils.MediaType(path); mediaType != fileutils.ImageMedia {
+		fullscreenButton.SetIcon(theme.MediaPlayIcon())
+		if mediaType != fileutils.VideoMedia {
+			fullscreenButton.SetIcon(mediaIcon(mediaType))
+		}
+		fullscreenButton.OnTapped = func() {
+			if err := a.OpenURL(&url.URL{Scheme: "file", Path: path}); err != nil {
+				dialog.ShowError(err, w)
+			}
+		}
+	}
 
-		// Create container for the image
-		content := container.NewStack(fullscreenImg)
+	// shows the images that look like this one in the grid, the closest first
+	similarButton := widget.NewButtonWithIcon("Similar", theme.SearchIcon(), func() {
+		similar, err := database.GetSimilarImages(db, imageId, path, int(appOptions.ImageNumber)*5)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if len(similar) == 0 {
+			dialog.ShowInformation("Similar Images", "No similar images were found", w)
+			return
+		}
+		showImages(similar)
+	})
 
-		// Show the dialog
-		dialog.ShowCustom("View Image", "Close", content, w)
+	// rotate, flip, crop and resize without changing the file, only images can be edited
+	editButton := widget.NewButtonWithIcon("Edit", theme.DocumentCreateIcon(), func() {
+		utilwindows.ShowEditWindow(a, w, db, path, func() {
+			resourceCache.RemovePath(path)
+			reloadImages()
+		}, func(newPath string) {
+			reloadImages()
+		})
 	})
-	fullscreenButton.Importance = widget.LowImportance
 
 	// Create button container with right alignment
 	// buttonContainer := container.NewHBox(layout.NewSpacer(), fullscreenButton)
 
 	sidebar.Add(container.NewStack(paddedImg, fullscreenButton))
 	sidebar.Add(container.NewGridWithRows(3, dateAdded, fullLabel, fileType))
+	sidebar.Add(createExifInfo(db, imageId, path))
+	sidebar.Add(createPaletteSwatches(db, imageId))
+	sidebar.Add(createMarksControls(db, w, path))
 	sidebar.Add(tagDisplay)
 	sidebar.Add(container.NewPadded(container.NewGridWithColumns(2, addTagButton, createTagButton)))
+	if fileutils.MediaType(path) == fileutils.ImageMedia {
+		sidebar.Add(container.NewPadded(container.NewGridWithColumns(2, editButton, similarButton)))
+	} else {
+		sidebar.Add(container.NewPadded(similarButton))
+	}
+	sidebar.Add(container.NewPadded(notesForm))
 	// sidebar.Add(buttonContainer) // Add the fullscreen button container
 
 	// Show sidebar if hidden else show
@@ -712,6 +999,539 @@ func updateSidebar(db *sql.DB, w fyne.Window, path string, resource fyne.Resourc
 	// sidebar.Refresh()
 }
 
+// Creates the timeline tree that groups images by year, month and day with their counts,
+// onSelect gets the period that was picked like 2021, 2021-07 or 2021-07-04
+func createTimeline(db *sql.DB, onSelect func(field database.DateField, period string)) fyne.CanvasObject {
+	field := database.CaptureDate
+	periods := map[string][]string{} // loaded children of each node, the tree asks for them often
+	counts := map[string]int{}
+
+	tree := widget.NewTree(
+		func(uid widget.TreeNodeID) []widget.TreeNodeID {
+			if children, ok := periods[uid]; ok {
+				return children
+			}
+			timeline, err := database.GetTimeline(db, field, uid)
+			if err != nil {
+				appLogger.Println("Error getting timeline:", err)
+			}
+			children := make([]string, 0, len(timeline))
+			for _, period := range timeline {
+				children = append(children, period.Key)
+				counts[period.Key] = period.Count
+			}
+			periods[uid] = children
+			return children
+		},
+		func(uid widget.TreeNodeID) bool {
+			// years and months open up, days show their images
+			return len(uid) <= len("2006-01")
+		},
+		func(branch bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(uid widget.TreeNodeID, branch bool, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(fmt.Sprintf("%s (%d)", periodLabel(uid), counts[uid]))
+		},
+	)
+	tree.OnSelected = func(uid widget.TreeNodeID) {
+		onSelect(field, uid)
+	}
+
+	reload := func() {
+		clear(periods)
+		clear(counts)
+		tree.UnselectAll()
+		tree.CloseAllBranches()
+		tree.Refresh()
+	}
+	fieldSelect := widget.NewRadioGroup([]string{"Capture Date", "Date Added"}, func(selected string) {
+		field = database.CaptureDate
+		if selected == "Date Added" {
+			field = database.DateAdded
+		}
+		reload()
+	})
+	fieldSelect.Horizontal = true
+	fieldSelect.Required = true
+	fieldSelect.SetSelected("Capture Date")
+	refreshButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), reload)
+
+	return container.NewBorder(container.NewBorder(nil, nil, nil, refreshButton, fieldSelect), nil, nil, nil, tree)
+}
+
+// Grid sizes in degrees the places can be grouped by
+var placeGridSizes = map[string]float64{
+	"City":    0.1,
+	"Region":  1,
+	"Country": 5,
+}
+
+// Creates the list of places images were taken at, grouped into cells of a coordinate grid,
+// onSelect gets the cell that was picked
+func createPlaces(db *sql.DB, onSelect func(cell geo.Cell, cellSize float64)) fyne.CanvasObject {
+	cellSize := placeGridSizes["City"]
+	var clusters []database.LocationCluster
+
+	list := widget.NewList(
+		func() int {
+			return len(clusters)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			c := clusters[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s (%d)", c.Place, c.Count))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		onSelect(clusters[id].Cell, cellSize)
+	}
+
+	reload := func() {
+		var err error
+		clusters, err = database.GetLocationClusters(db, cellSize)
+		if err != nil {
+			appLogger.Println("Error getting places:", err)
+		}
+		list.UnselectAll()
+		list.Refresh()
+	}
+	sizeSelect := widget.NewRadioGroup([]string{"City", "Region", "Country"}, func(selected string) {
+		cellSize = placeGridSizes[selected]
+		reload()
+	})
+	sizeSelect.Horizontal = true
+	sizeSelect.Required = true
+	sizeSelect.SetSelected("City")
+	refreshButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), reload)
+
+	return container.NewBorder(container.NewBorder(nil, nil, nil, refreshButton, sizeSelect), nil, nil, nil, list)
+}
+
+// Creates the duplicate finder that lists groups of copies and similar looking images,
+// the images of a group are shown side by side to keep, delete or merge the tags of.
+// onDeleted is called after files were deleted
+func createDuplicates(db *sql.DB, w fyne.Window, onDeleted func()) fyne.CanvasObject {
+	var groups []database.DuplicateGroup
+	includeSimilar := true
+	selected := -1
+	comparison := container.NewHBox()
+	var mergeButton *widget.Button
+
+	list := widget.NewList(
+		func() int {
+			return len(groups)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			group := groups[id]
+			kind := "Similar"
+			if group.Exact {
+				kind = "Copies"
+			}
+			item.(*widget.Label).SetText(fmt.Sprintf("%s: %s (%d)", kind, filepath.Base(group.Files[0].Path), len(group.Files)))
+		},
+	)
+
+	reload := func() {
+		maxDistance := -1
+		if includeSimilar {
+			maxDistance = database.SimilarDistance
+		}
+		var err error
+		groups, err = database.GetDuplicateGroups(db, maxDistance)
+		if err != nil {
+			appLogger.Println("Error getting duplicates:", err)
+		}
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+		comparison.RemoveAll()
+		mergeButton.Disable()
+	}
+
+	// removes files from the disk and the library
+	deleteFiles := func(files []database.DuplicateFile) {
+		for _, f := range files {
+			if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+				dialog.ShowError(err, w)
+				break
+			}
+			if err := database.DeleteFile(db, f.Id); err != nil {
+				dialog.ShowError(err, w)
+				break
+			}
+			resourceCache.RemovePath(f.Path)
+			appLogger.Println("Deleted duplicate ", f.Path)
+		}
+		reload()
+		onDeleted()
+	}
+
+	fileIds := func(files []database.DuplicateFile) []int {
+		ids := make([]int, len(files))
+		for i, f := range files {
+			ids[i] = f.Id
+		}
+		return ids
+	}
+
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		group := groups[id]
+		comparison.RemoveAll()
+		for _, f := range group.Files {
+			var others []database.DuplicateFile
+			for _, other := range group.Files {
+				if other.Id != f.Id {
+					others = append(others, other)
+				}
+			}
+
+			keepButton := widget.NewButtonWithIcon("Keep", theme.ConfirmIcon(), func() {
+				message := fmt.Sprintf("Delete the other %d files from the disk? Their tags are added to this one first.", len(others))
+				dialog.ShowConfirm("Keep Image", message, func(keep bool) {
+					if !keep {
+						return
+					}
+					if err := database.MergeTags(db, fileIds(group.Files)); err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+					deleteFiles(others)
+				}, w)
+			})
+			deleteButton := widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Delete Image", "Delete "+filepath.Base(f.Path)+" from the disk?", func(remove bool) {
+					if remove {
+						deleteFiles([]database.DuplicateFile{f})
+					}
+				}, w)
+			})
+
+			// similar images can still differ, so they are only deleted one at a time
+			actions := container.NewGridWithColumns(2, keepButton, deleteButton)
+			if !group.Exact {
+				actions = container.NewGridWithColumns(1, deleteButton)
+			}
+			comparison.Add(container.NewVBox(
+				duplicatePreview(f.Path),
+				createDuplicateInfo(db, f, group),
+				actions,
+			))
+		}
+		mergeButton.Enable()
+	}
+
+	mergeButton = widget.NewButton("Merge Tags", func() {
+		if selected < 0 || selected >= len(groups) {
+			return
+		}
+		if err := database.MergeTags(db, fileIds(groups[selected].Files)); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("Merge Tags", "Every image in the group has the same tags now", w)
+	})
+
+	// images added since startup get hashed first
+	var scanButton *widget.Button
+	scanButton = widget.NewButtonWithIcon("Find Duplicates", theme.SearchIcon(), func() {
+		scanButton.Disable()
+		go func() {
+			defer scanButton.Enable()
+			if _, err := database.HashImages(db); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			reload()
+		}()
+	})
+	// the groups are only looked for once asked, comparing every image takes a moment
+	similarCheck := widget.NewCheck("Include similar images", nil)
+	similarCheck.SetChecked(includeSimilar)
+	similarCheck.OnChanged = func(checked bool) {
+		includeSimilar = checked
+		reload()
+	}
+	mergeButton.Disable()
+
+	controls := container.NewHBox(scanButton, similarCheck, mergeButton)
+	split := container.NewHSplit(list, container.NewScroll(comparison))
+	split.Offset = 0.25
+	return container.NewBorder(controls, nil, nil, nil, split)
+}
+
+// Shows an image of a duplicate group scaled to fit next to the others, upright
+// by its EXIF orientation. Videos show their poster and documents their preview
+func duplicatePreview(path string) fyne.CanvasObject {
+	img := canvas.NewImageFromResource(mediaIcon(fileutils.MediaType(path)))
+	if preview, _, err := decodePreview(path); err == nil {
+		img = canvas.NewImageFromImage(preview)
+	}
+	img.FillMode = canvas.ImageFillContain
+	img.SetMinSize(fyne.NewSize(300, 300))
+	return img
+}
+
+// Creates the labels that tell the images of a duplicate group apart
+func createDuplicateInfo(db *sql.DB, f database.DuplicateFile, group database.DuplicateGroup) *fyne.Container {
+	info := container.NewVBox(
+		widget.NewLabel(filepath.Base(f.Path)),
+		widget.NewLabel(strings.Replace(filepath.Dir(f.Path), home, "~", 1)),
+	)
+	if stat, err := os.Stat(f.Path); err == nil {
+		info.Add(widget.NewLabel(fileutils.FormatSize(stat.Size())))
+	}
+	if meta, err := database.GetFileMetadata(db, f.Id, f.Path); err == nil && meta.Dimensions() != "" {
+		info.Add(widget.NewLabel(meta.Dimensions()))
+	}
+	// similar groups can hold copies too
+	if !group.Exact {
+		for _, other := range group.Files {
+			if other.Id != f.Id && other.MD5 == f.MD5 {
+				info.Add(widget.NewLabel("Same content as " + filepath.Base(other.Path)))
+				break
+			}
+		}
+	}
+	return info
+}
+
+// Formats a timeline period like 2021, July 2021 or Sun 04 July 2021
+func periodLabel(period string) string {
+	if date, err := time.Parse("2006-01-02", period); err == nil {
+		return date.Format("Mon 02 January 2006")
+	}
+	if date, err := time.Parse("2006-01", period); err == nil {
+		return date.Format("January 2006")
+	}
+	return period
+}
+
+// Shows the format of an image, images with an extension of another format are
+// flagged and can be renamed to the right one
+func createFileTypeInfo(db *sql.DB, w fyne.Window, imageId int, path string) fyne.CanvasObject {
+	format, mismatch := database.CheckExtension(path)
+	typeName := strings.ToUpper(strings.TrimPrefix(filepath.Ext(path), "."))
+	if format != nil {
+		typeName = format.Name
+	}
+	fileType := widget.NewLabel("Type: " + typeName)
+	fileType.Wrapping = fyne.TextWrapWord
+	if !mismatch {
+		return fileType
+	}
+
+	fileType.SetText(fmt.Sprintf("Type: %s, saved as %s", format.Name, filepath.Ext(path)))
+	fileType.Importance = widget.WarningImportance
+	fixButton := widget.NewButtonWithIcon("Fix Extension", theme.WarningIcon(), func() {
+		file := database.ExtensionMismatch{Id: imageId, Path: path, Format: format}
+		message := fmt.Sprintf("Rename %s to %s?", filepath.Base(path), filepath.Base(file.FixedPath()))
+		dialog.ShowConfirm("Fix Extension", message, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if _, err := database.FixExtension(db, file); err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			resourceCache.RemovePath(path)
+			reloadImages()
+		}, w)
+	})
+	return container.NewBorder(nil, nil, nil, fixButton, fileType)
+}
+
+// Creates a swatch for every dominant color of the image, tapping one searches for the color
+func createPaletteSwatches(db *sql.DB, imageId int) *fyne.Container {
+	swatches := container.NewHBox()
+	palette, err := database.GetFilePalette(db, imageId)
+	if err != nil {
+		appLogger.Println("Error getting palette:", err)
+		return swatches
+	}
+
+	for _, swatch := range palette {
+		hex := swatch.Hex()
+		rect := canvas.NewRectangle(color.NRGBA{swatch.Red, swatch.Green, swatch.Blue, 255})
+		rect.SetMinSize(fyne.NewSize(30, 30))
+		rect.CornerRadius = 5
+		button := widget.NewButton("", func() {
+			search("color:" + hex)
+		})
+		button.Importance = widget.LowImportance
+		swatches.Add(container.NewStack(rect, button))
+	}
+	return swatches
+}
+
+// Creates a label for every EXIF field picked in the options that the image has,
+// videos show their length, size and codecs instead
+func createExifInfo(db *sql.DB, imageId int, path string) *fyne.Container {
+	info := container.NewVBox()
+	meta, err := database.GetFileMetadata(db, imageId, path)
+	if err != nil {
+		appLogger.Println("Error getting metadata:", err)
+		return info
+	}
+
+	// documents have no metadata of their own, their size is shown instead
+	if mediaType := fileutils.MediaType(path); mediaType != fileutils.ImageMedia && mediaType != fileutils.VideoMedia {
+		if stat, err := os.Stat(path); err == nil {
+			info.Add(widget.NewLabel("Size: " + fileutils.FormatSize(stat.Size())))
+		}
+		return info
+	}
+
+	fields := appOptions.ExifFields
+	if meta.Duration > 0 || meta.VideoCodec != "" {
+		fields = []string{"Duration", "Dimensions", "Codecs", "CaptureDate"}
+	}
+	for _, field := range fields {
+		value := meta.Field(field)
+		// the place is named from the bundled cities, not stored in the file
+		if field == "Location" && meta.HasGPS {
+			value = geo.PlaceName(meta.Latitude, meta.Longitude)
+		}
+		if value == "" {
+			continue
+		}
+		label := widget.NewLabel(field + ": " + value)
+		label.Wrapping = fyne.TextWrapWord
+		info.Add(label)
+	}
+	return info
+}
+
+// Creates the rating stars, favorite checkbox and color label select shown in the sidebar
+func createMarksControls(db *sql.DB, w fyne.Window, path string) *fyne.Container {
+	current, err := database.GetFileMarks(db, path)
+	if err != nil {
+		appLogger.Println("Error getting marks:", err)
+	}
+
+	starResource := fyne.NewStaticResource("star", icon.StarIcon)
+	stars := container.NewHBox()
+	starButtons := make([]*widget.Button, 5)
+	for i := range starButtons {
+		rating := i + 1
+		starButtons[i] = widget.NewButtonWithIcon("", starResource, func() {
+			// tapping the current rating again clears it
+			newRating := rating
+			if current.Rating == rating {
+				newRating = 0
+			}
+			if err := database.SetRating(db, path, newRating); err != nil {
+				dialog.ShowError(err, w)
+			}
+			refreshMarks(db, path)
+		})
+		stars.Add(starButtons[i])
+	}
+
+	favoriteCheck := widget.NewCheck("Favorite", func(favorite bool) {
+		if favorite == current.Favorite {
+			return
+		}
+		if err := database.SetFavorite(db, path, favorite); err != nil {
+			dialog.ShowError(err, w)
+		}
+		refreshMarks(db, path)
+	})
+
+	labelSelect := widget.NewSelect(append([]string{"none"}, colorutils.LabelNames...), func(label string) {
+		if label == "none" {
+			label = ""
+		}
+		if label == current.Label {
+			return
+		}
+		if err := database.SetLabel(db, path, label); err != nil {
+			dialog.ShowError(err, w)
+		}
+		refreshMarks(db, path)
+	})
+
+	setMarks = func(marks database.FileMarks) {
+		current = marks
+		for i, button := range starButtons {
+			if i < marks.Rating {
+				button.Importance = widget.HighImportance
+			} else {
+				button.Importance = widget.LowImportance
+			}
+			button.Refresh()
+		}
+		favoriteCheck.SetChecked(marks.Favorite)
+		if marks.Label == "" {
+			labelSelect.SetSelected("none")
+		} else {
+			labelSelect.SetSelected(marks.Label)
+		}
+	}
+	setMarks(current)
+
+	return container.NewVBox(stars, container.NewGridWithColumns(2, favoriteCheck, labelSelect))
+}
+
+// Shows the rating, favorite flag and color label of the image on its thumbnail and in the sidebar
+func refreshMarks(db *sql.DB, path string) {
+	marks, err := database.GetFileMarks(db, path)
+	if err != nil {
+		appLogger.Println("Error getting marks:", err)
+		return
+	}
+
+	if thumbnail, ok := thumbnails.Load(path); ok {
+		thumbnail.(buttons.Markable).SetMarks(marks.Rating, marks.Favorite, labelColor(marks.Label))
+	}
+	if setMarks != nil && prevoiusImage == path {
+		setMarks(marks)
+	}
+}
+
+// Returns the color of a color label or nil if the file has no label
+func labelColor(label string) color.Color {
+	hex, ok := colorutils.LabelColors[label]
+	if !ok {
+		return nil
+	}
+	c, _ := colorutils.HexToColor(hex)
+	return c
+}
+
+// Keyboard shortcuts for the image shown in the sidebar: 0-5 set the rating, f toggles favorite,
+// 6-9 set the red, yellow, green or blue label and - removes the label
+func markShortcut(db *sql.DB, path string, r rune) {
+	var err error
+	switch {
+	case r >= '0' && r <= '5':
+		err = database.SetRating(db, path, int(r-'0'))
+	case r == 'f' || r == 'F':
+		var marks database.FileMarks
+		marks, err = database.GetFileMarks(db, path)
+		if err == nil {
+			err = database.SetFavorite(db, path, !marks.Favorite)
+		}
+	case r >= '6' && r <= '9':
+		err = database.SetLabel(db, path, colorutils.LabelNames[r-'6'])
+	case r == '-':
+		err = database.SetLabel(db, path, "")
+	default:
+		return
+	}
+	if err != nil {
+		appLogger.Println("Error setting marks:", err)
+		return
+	}
+	refreshMarks(db, path)
+}
+
 func truncateFilename(filename string, maxLength int, showExt bool) string {
 	// if hidden file don't truncate it
 	if string(filename[0]) == "." {
@@ -750,157 +1570,114 @@ func truncateDirname(dirname string, maxLength int) string {
 	return dirname
 }
 
-// Optimized function to load image resources
-// Use this for thumbnails only or add a thumbnail bool
-func loadImageResourceEfficient(path string) (fyne.Resource, error) {
-	if cachedResource, ok := resourceCache.Load(path); ok {
-		return cachedResource.(fyne.Resource), nil
+func loadImageResourceThumbnailEfficient(db *sql.DB, path string) (fyne.Resource, error) {
+	key := resourcecache.Key{Path: path, Variant: resourcecache.Thumbnail, Size: appOptions.ThumbnailSize}
+	if appOptions.FitThumbnails() {
+		key.Variant = resourcecache.Scaled
 	}
-
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+	if cachedResource, ok := resourceCache.Get(key); ok {
+		return cachedResource, nil
 	}
-	defer file.Close()
-
-	// Decode the image
-	var img image.Image
 
-	switch filepath.Ext(path) {
-	case ".jpg", ".jpeg":
-		img, _, err = image.Decode(file)
-	case ".png":
-		img, _, err = image.Decode(file)
-	case ".bmp":
-		img, err = png.Decode(file)
-	case ".webp":
-		img, err = webp.Decode(file)
-	// case ".heic":
-	// 	img, err = goheif.Decode(file)
-	case ".avif":
-		img, err = avif.Decode(file)
-	case ".qoi":
-		img, err = qoi.Decode(file)
-	case ".tiff", ".tif":
-		img, err = tiff.Decode(file)
-	case ".svg":
-		img, err = svg.Decode(file)
-	default:
-		return nil, fmt.Errorf("unsupported image format")
-	}
+	thumbnail, err := loadThumbnail(db, path)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate the thumbnail dimensions while maintaining aspect ratio
-	bounds := img.Bounds()
-	ratio := float64(bounds.Dx()) / float64(bounds.Dy())
-	var thumbWidth, thumbHeight int
-	if ratio > 1 {
-		thumbWidth = appOptions.ThumbnailSize
-		thumbHeight = int(float64(appOptions.ThumbnailSize) / ratio)
-	} else {
-		thumbHeight = appOptions.ThumbnailSize
-		thumbWidth = int(float64(appOptions.ThumbnailSize) * ratio)
-	}
+	// Create a new static resource with the thumbnail image
+	resource := fyne.NewStaticResource(filepath.Base(path), thumbnail)
 
-	// Create a new image with the thumbnail dimensions
-	thumbImg := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	// Store in cache
+	resourceCache.Put(key, resource)
 
-	// Resize the image
-	draw.ApproxBiLinear.Scale(thumbImg, thumbImg.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return resource, nil
+}
 
-	// Encode the resized image
-	var buf bytes.Buffer
-	switch filepath.Ext(path) {
-	case ".jpg", ".jpeg":
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".png":
-		err = png.Encode(&buf, thumbImg)
-	case ".bmp":
-		// err = bmp.Encode(&buf, thumbImg)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".webp":
-		// err = chaiWebp.Encode(&buf, thumbImg, &chaiWebp.Options{Lossless: true})
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".heic":
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".avif":
-		// avif.Encode(&buf, thumbImg, avif.Options{Quality: 85})
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-		// os.Exit(1)
-	case ".qoi":
-		// err = qoi.Encode(&buf, thumbImg)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".tiff", ".tif":
-		// err = tiff.Encode(&buf, thumbImg, &tiff.Options{Compression: tiff.Deflate})
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".svg":
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
+// Returns the encoded thumbnail of an image from the disk cache, it is made and
+// cached the first time. Images that aren't in the library aren't cached
+func loadThumbnail(db *sql.DB, path string) ([]byte, error) {
+	hash, hashErr := database.GetFileMD5(db, path)
+	variant := thumbnailVariant(db, path)
+	if hashErr == nil {
+		if thumbnail, ok := thumbcache.Load(hash, appOptions.ThumbnailSize, variant); ok {
+			return thumbnail, nil
+		}
 	}
+
+	thumbnail, err := makeThumbnail(db, path)
 	if err != nil {
 		return nil, err
 	}
-
-	// Create a new static resource with the thumbnail image
-	resource := fyne.NewStaticResource(filepath.Base(path), buf.Bytes())
-
-	// Store in cache
-	resourceCache.Store(path, resource)
-
-	return resource, nil
+	if hashErr == nil {
+		if err := thumbcache.Store(hash, appOptions.ThumbnailSize, variant, thumbnail); err != nil {
+			appLogger.Println("Failed to cache thumbnail: ", err)
+		}
+	}
+	return thumbnail, nil
 }
 
-func loadImageResourceThumbnailEfficient(path string) (fyne.Resource, error) {
-	if cachedResource, ok := resourceCache.Load(path); ok {
-		return cachedResource.(fyne.Resource), nil
+// Returns the variant of the cached thumbnail of a file, fitted and edited images
+// get their own thumbnails so the others stay cached for when they are used again
+func thumbnailVariant(db *sql.DB, path string) string {
+	var parts []string
+	if appOptions.FitThumbnails() {
+		parts = append(parts, "fit")
+	}
+	if edit, err := database.GetFileEdit(db, database.GetImageId(db, path)); err == nil && !edit.IsZero() {
+		parts = append(parts, edit.Key())
 	}
+	return strings.Join(parts, "_")
+}
 
-	file, err := os.Open(path)
+// Decodes an image, crops the center square and encodes it at the thumbnail size.
+// With fitted thumbnails the whole image is scaled to fit the thumbnail size instead
+func makeThumbnail(db *sql.DB, path string) ([]byte, error) {
+	// Decode the image
+	img, format, err := decodePreview(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	// Decode the image
-	var img image.Image
-	// test:= image.Decode()
-	switch filepath.Ext(path) {
-	case ".jpg", ".jpeg":
-		img, _, err = image.Decode(file)
-	case ".png":
-		img, _, err = image.Decode(file)
-	case ".gif":
-		img, err = gif.Decode(file)
-	case ".bmp":
-		img, err = bmp.Decode(file)
-	case ".webp":
-		img, err = webp.Decode(file)
-	// case ".heic":
-	// 	img, err = goheif.Decode(file)
-	case ".avif":
-		img, err = avif.Decode(file)
-	case ".qoi":
-		img, err = qoi.Decode(file)
-	case ".tiff", ".tif":
-		img, err = tiff.Decode(file)
-	case ".svg":
-		img, err = svg.Decode(file)
-	default:
-		return nil, fmt.Errorf("unsupported image format")
+	// the palette is taken while the image is decoded anyway
+	imageId := database.GetImageId(db, path)
+	if imageId != 0 && !database.HasPalette(db, imageId) {
+		if err := database.SaveImagePalette(db, imageId, img); err != nil {
+			appLogger.Println("Failed to save palette: ", err)
+		}
 	}
+	img = applyEdit(db, imageId, path, img)
+
+	// Encode the resized image
+	var buf bytes.Buffer
+	err = encodeThumbnail(&buf, scaleThumbnail(img), format)
 	if err != nil {
 		return nil, err
 	}
 
-	// Calculate the square crop region from the center of the image
+	return buf.Bytes(), nil
+}
+
+// Crops the center square of an image and scales it to the thumbnail size. With
+// fitted thumbnails the whole image is scaled to fit the thumbnail size instead
+func scaleThumbnail(img image.Image) image.Image {
 	bounds := img.Bounds()
+	if appOptions.FitThumbnails() {
+		scale := float64(appOptions.ThumbnailSize) / float64(max(bounds.Dx(), bounds.Dy()))
+		thumbWidth := max(1, int(float64(bounds.Dx())*scale))
+		thumbHeight := max(1, int(float64(bounds.Dy())*scale))
+
+		thumbImg := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+		draw.ApproxBiLinear.Scale(thumbImg, thumbImg.Bounds(), img, bounds, draw.Over, nil)
+		return thumbImg
+	}
+
+	// Calculate the square crop region from the center of the image
 	size := bounds.Dx()
 	if bounds.Dy() < size {
 		size = bounds.Dy()
 	}
-	x := (bounds.Dx() - size) / 2
-	y := (bounds.Dy() - size) / 2
+	x := bounds.Min.X + (bounds.Dx()-size)/2
+	y := bounds.Min.Y + (bounds.Dy()-size)/2
 
 	// Create a new square image for the thumbnail
 	thumbImg := image.NewRGBA(image.Rect(0, 0, appOptions.ThumbnailSize, appOptions.ThumbnailSize))
@@ -914,58 +1691,245 @@ func loadImageResourceThumbnailEfficient(path string) (fyne.Resource, error) {
 		draw.Over,
 		nil,
 	)
+	return thumbImg
+}
 
-	// Encode the resized image
-	var buf bytes.Buffer
-	switch filepath.Ext(path) {
-	case ".jpg", ".jpeg":
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".png":
-		err = png.Encode(&buf, thumbImg)
-	case ".gif":
-		// err = gif.Encode(&buf, thumbImg, &gif.Options{NumColors: 256})
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".bmp":
-		// img, err = png.Decode(file)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".webp":
-		// img, err = webp.Decode(file)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".heic":
-		// img, err = goheif.Decode(file)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".avif":
-		// img, err = avif.Decode(file)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".qoi":
-		// img, err = qoi.Decode(file)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".tiff", ".tif":
-		// img, err = tiff.Decode(file)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	case ".svg":
-		// img, err = svg.Decode(file)
-		err = jpeg.Encode(&buf, thumbImg, &jpeg.Options{Quality: 85})
-	default:
-		return nil, fmt.Errorf("unsupported image format")
+// Decodes the frames of an animated image with its edits, cropped or fitted like its thumbnail
+func loadAnimationFrames(db *sql.DB, path string) ([]image.Image, []time.Duration, error) {
+	edit, err := database.GetFileEdit(db, database.GetImageId(db, path))
+	if err != nil {
+		appLogger.Println("Error getting edit:", err)
+	}
+	// the frames are scaled to thumbnails anyway, resizing every full size frame first is wasted
+	edit.Width, edit.Height = 0, 0
+	// frames are scaled as they are decoded, full size frames of long animations don't fit in memory
+	animation, _, err := imagecodec.DecodeAnimationFile(path, func(canvas *image.RGBA) image.Image {
+		var frame image.Image = canvas
+		if !edit.IsZero() {
+			frame = imagetransform.Apply(frame, edit)
+		}
+		return scaleThumbnail(frame)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return animation.Frames, animation.Delays, nil
+}
+
+// Returns when animated thumbnails play, as set in the options
+func animationPlayback() buttons.Playback {
+	switch appOptions.AnimationPlayback {
+	case options.PlayAlways:
+		return buttons.PlayAlways
+	case options.PlayNever:
+		return buttons.PlayNever
+	}
+	return buttons.PlayOnHover
+}
+
+// Decodes an image or the poster of a video, documents get their preview and no format
+func decodePreview(path string) (image.Image, *imagecodec.Format, error) {
+	if mediaType := fileutils.MediaType(path); mediaType != fileutils.ImageMedia && mediaType != fileutils.VideoMedia {
+		img, err := filepreview.Decode(path)
+		return img, nil, err
+	}
+	return imagecodec.DecodeFile(path)
+}
+
+// Returns the files shown in the grids of the content in the order of their tiles,
+// the grids of every loaded page are in it
+func gridPaths(content *fyne.Container) []string {
+	var paths []string
+	if content == nil {
+		return paths
+	}
+	for _, object := range content.Objects {
+		if path, ok := tilePaths.Load(object); ok {
+			paths = append(paths, path.(string))
+		} else if c, ok := object.(*fyne.Container); ok {
+			paths = append(paths, gridPaths(c)...)
+		}
+	}
+	return paths
+}
+
+// Returns every file of the current results in the order of the grid. The library is
+// shown page by page so it is read from the database, other results are shown in full
+func resultPaths(db *sql.DB) []string {
+	if !pagedGrid.Load() {
+		return gridPaths(gridContent)
+	}
+	paths, err := database.GetAllImagesFromDatabase(db, orderBy, appOptions.SortDesc)
+	if err != nil {
+		appLogger.Println("Failed to load the images of the library: ", err)
+		return gridPaths(gridContent)
+	}
+	return paths
+}
+
+// Decodes the image of a file shown in the viewer with its edits, frame is the
+// image of files like HEIF bursts that hold several
+func decodeViewerImage(db *sql.DB, path string, frame int) (image.Image, error) {
+	var img image.Image
+	var err error
+	if frame > 0 {
+		img, _, err = imagecodec.DecodeFileAt(path, frame)
+	} else {
+		img, _, err = decodePreview(path)
 	}
 	if err != nil {
 		return nil, err
 	}
+	return applyEdit(db, database.GetImageId(db, path), path, img), nil
+}
 
-	// Create a new static resource with the thumbnail image
-	resource := fyne.NewStaticResource(filepath.Base(path), buf.Bytes())
+// Returns the image with the saved edits of the file applied, only images are edited
+func applyEdit(db *sql.DB, imageId int, path string, img image.Image) image.Image {
+	if imageId == 0 || fileutils.MediaType(path) != fileutils.ImageMedia {
+		return img
+	}
+	edit, err := database.GetFileEdit(db, imageId)
+	if err != nil {
+		appLogger.Println("Error getting edit:", err)
+		return img
+	}
+	if edit.IsZero() {
+		return img
+	}
+	return imagetransform.Apply(img, edit)
+}
 
-	// Store in cache
-	resourceCache.Store(path, resource)
+// Returns the icon of the files of a media type
+func mediaIcon(mediaType string) fyne.Resource {
+	switch mediaType {
+	case fileutils.ImageMedia:
+		return theme.FileImageIcon()
+	case fileutils.VideoMedia:
+		return theme.FileVideoIcon()
+	case fileutils.AudioMedia:
+		return theme.FileAudioIcon()
+	case fileutils.DocumentMedia:
+		return theme.DocumentIcon()
+	case fileutils.TextMedia:
+		return theme.FileTextIcon()
+	case fileutils.ArchiveMedia:
+		return theme.StorageIcon()
+	case fileutils.DesignMedia:
+		return theme.ColorPaletteIcon()
+	}
+	return theme.FileIcon()
+}
 
-	return resource, nil
+// Thumbnails of PNG images stay PNG to keep their transparency, every other format
+// becomes a JPEG
+func encodeThumbnail(w io.Writer, img image.Image, format *imagecodec.Format) error {
+	if format != nil && format.Name == "PNG" {
+		return png.Encode(w, img)
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
+
+// Returns the duration of a video like 1:05 for its thumbnail, empty if it is unknown
+func videoDuration(db *sql.DB, path string) string {
+	var duration time.Duration
+	if imageId := database.GetImageId(db, path); imageId != 0 {
+		if meta, err := database.GetFileMetadata(db, imageId, path); err == nil {
+			duration = meta.Duration
+		}
+	} else if info, err := video.Probe(path); err == nil {
+		// videos shown from a directory before they are in the library
+		duration = info.Duration
+	}
+	if duration <= 0 {
+		return ""
+	}
+	return video.FormatDuration(duration)
+}
+
+// Logs the usage of the resource cache every minute while it changes
+func logResourceCacheStats() {
+	var last resourcecache.Stats
+	for range time.Tick(time.Minute) {
+		if stats := resourceCache.Stats(); stats != last {
+			appLogger.Println("Resource cache: ", stats)
+			last = stats
+		}
+	}
+}
+
+// Makes the thumbnails of the images in the library that aren't cached on disk yet,
+// in the order the grid shows them, until they fill the cache size in the options.
+// The cache is then trimmed of the thumbnails that weren't used
+func pregenerateThumbnails(db *sql.DB) {
+	const pageSize = 200
+	budget := int64(appOptions.ThumbnailCacheSize) << 20
+	// bytes of the thumbnails the grid shows first, cached or made now
+	var used atomic.Int64
+	var ready atomic.Int64
+	workers := max(runtime.NumCPU()/2, 1)
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				if used.Load() >= budget {
+					continue
+				}
+				thumbnail, err := loadThumbnail(db, path)
+				if err != nil && !errors.Is(err, video.ErrNoPoster) && !errors.Is(err, filepreview.ErrNoPreview) {
+					appLogger.Printf("Failed to make thumbnail of %s: %v", path, err)
+				}
+				if err == nil {
+					used.Add(int64(len(thumbnail)))
+					ready.Add(1)
+				}
+			}
+		}()
+	}
+
+	var cursor database.Cursor
+	for used.Load() < budget {
+		page, next, err := database.GetImagesFromDatabase(db, orderBy, appOptions.SortDesc, cursor, pageSize)
+		if err != nil {
+			appLogger.Println("Failed to load images for thumbnails: ", err)
+			break
+		}
+		for _, path := range page {
+			paths <- path
+		}
+		if len(page) < pageSize {
+			break
+		}
+		cursor = next
+	}
+	close(paths)
+	wg.Wait()
+	appLogger.Println("Thumbnails ready for ", ready.Load(), " images")
+
+	// the thumbnails used above are the newest, so only older ones are removed.
+	// Workers running when the budget was reached can go past it by a few thumbnails
+	removed, err := thumbcache.Prune(max(budget, used.Load()))
+	if err != nil {
+		appLogger.Println("Failed to trim thumbnail cache: ", err)
+	} else if removed > 0 {
+		appLogger.Println("Removed ", removed, " thumbnails from the cache")
+	}
 }
 
 // Function to update the main content based on search results
 func updateContentWithSearchResults(content *fyne.Container, imagePaths []string, db *sql.DB, w fyne.Window, sidebar *fyne.Container, sidebarScroll *container.Scroll, split *container.Split, a fyne.App) {
+	// pages still loading for the grid being replaced are dropped
+	pageMu.Lock()
+	pageGeneration++
 	content.RemoveAll()
-	imageContainer := container.NewAdaptiveGrid(5)
+	tilePaths.Clear()
+	thumbnails.Clear()
+	loadingPage.Store(false)
+	pagedGrid.Store(false)
+	pageMu.Unlock()
+	imageContainer := newImageGrid()
 	content.Add(imageContainer)
 
 	for _, path := range imagePaths {
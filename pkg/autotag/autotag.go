@@ -0,0 +1,122 @@
+// Package autotag derives meta tags like the capture year, camera and
+// orientation from the metadata of a file.
+//
+// The rules are fixed on purpose and have no settings. Auto tags are replaced
+// every time a file is tagged again, so changing a rule would rename the tags of
+// the whole library, and users add their own tags for anything the rules miss.
+package autotag
+
+import (
+	"main/pkg/metadata"
+	"math"
+)
+
+// Color of meta tags, the same as the file type and date added tags
+const TagColor = "#373c40"
+
+// Rule derives meta tags from the metadata of a file
+type Rule struct {
+	Name string
+	Tags func(m *metadata.Metadata) []string // returns no tags when the rule doesn't apply
+}
+
+// Resolution buckets by megapixels, a file gets the first bucket it fits under
+var resolutionBuckets = []struct {
+	maxMegapixels float64
+	tag           string
+}{
+	{1, "<1MP"},
+	{4, "1-4MP"},
+	{12, "4-12MP"},
+	{24, "12-24MP"},
+	{math.Inf(1), "24MP+"},
+}
+
+// Rules that are run on every file, in the order their tags get added
+var Rules = []Rule{
+	{
+		Name: "Capture year",
+		Tags: func(m *metadata.Metadata) []string {
+			if m.CaptureDate.IsZero() {
+				return nil
+			}
+			return []string{m.CaptureDate.Format("2006")}
+		},
+	},
+	{
+		Name: "Capture month",
+		Tags: func(m *metadata.Metadata) []string {
+			if m.CaptureDate.IsZero() {
+				return nil
+			}
+			return []string{m.CaptureDate.Format("2006-01")}
+		},
+	},
+	{
+		Name: "Camera",
+		Tags: func(m *metadata.Metadata) []string {
+			if camera := m.Camera(); camera != "" {
+				return []string{camera}
+			}
+			return nil
+		},
+	},
+	{
+		Name: "Orientation",
+		Tags: func(m *metadata.Metadata) []string {
+			width, height := m.DisplaySize()
+			if width == 0 || height == 0 {
+				return nil
+			}
+			// within 2% counts as square, cropping is rarely pixel exact
+			ratio := float64(width) / float64(height)
+			switch {
+			case math.Abs(ratio-1) <= 0.02:
+				return []string{"Square"}
+			case ratio > 1:
+				return []string{"Landscape"}
+			default:
+				return []string{"Portrait"}
+			}
+		},
+	},
+	{
+		Name: "Resolution",
+		Tags: func(m *metadata.Metadata) []string {
+			if m.Width == 0 || m.Height == 0 {
+				return nil
+			}
+			megapixels := float64(m.Width) * float64(m.Height) / 1e6
+			for _, bucket := range resolutionBuckets {
+				if megapixels < bucket.maxMegapixels {
+					return []string{bucket.tag}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name: "GPS",
+		Tags: func(m *metadata.Metadata) []string {
+			if m.HasGPS {
+				return []string{"Has GPS"}
+			}
+			return nil
+		},
+	},
+}
+
+// Returns the tags of every rule for the metadata without duplicates
+func Tags(m *metadata.Metadata) []string {
+	seen := map[string]bool{}
+	var tags []string
+	for _, rule := range Rules {
+		for _, tag := range rule.Tags(m) {
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
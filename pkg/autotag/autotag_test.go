@@ -0,0 +1,52 @@
+package autotag
+
+import (
+	"main/pkg/metadata"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	taken := time.Date(2021, 7, 14, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		metadata metadata.Metadata
+		want     []string
+	}{
+		{"no metadata", metadata.Metadata{}, nil},
+		{"capture date", metadata.Metadata{CaptureDate: taken}, []string{"2021", "2021-07"}},
+		{"camera", metadata.Metadata{Make: "Canon", Model: "Canon EOS R5"}, []string{"Canon EOS R5"}},
+		{"camera without model", metadata.Metadata{Make: "FUJIFILM"}, []string{"FUJIFILM"}},
+		{"camera make added", metadata.Metadata{Make: "SONY", Model: "ILCE-7M3"}, []string{"SONY ILCE-7M3"}},
+		{"landscape", metadata.Metadata{Width: 4000, Height: 3000}, []string{"Landscape", "12-24MP"}},
+		{"portrait", metadata.Metadata{Width: 600, Height: 800}, []string{"Portrait", "<1MP"}},
+		// turned by 90 degrees, the resolution is the same
+		{"turned to portrait", metadata.Metadata{Width: 4000, Height: 3000, Orientation: 6}, []string{"Portrait", "12-24MP"}},
+		{"mirrored stays landscape", metadata.Metadata{Width: 4000, Height: 3000, Orientation: 2}, []string{"Landscape", "12-24MP"}},
+		{"almost square", metadata.Metadata{Width: 1010, Height: 1000}, []string{"Square", "1-4MP"}},
+		{"not quite square", metadata.Metadata{Width: 1030, Height: 1000}, []string{"Landscape", "1-4MP"}},
+		{"megapixel boundary", metadata.Metadata{Width: 1000, Height: 1000}, []string{"Square", "1-4MP"}},
+		{"large", metadata.Metadata{Width: 8000, Height: 6000}, []string{"Landscape", "24MP+"}},
+		{"only width", metadata.Metadata{Width: 4000}, nil},
+		{"gps", metadata.Metadata{HasGPS: true}, []string{"Has GPS"}},
+		// coordinates without the flag come from files that had them removed
+		{"coordinates without gps", metadata.Metadata{Latitude: 56.95, Longitude: 24.1}, nil},
+		{"everything", metadata.Metadata{CaptureDate: taken, Make: "Apple", Model: "iPhone 12", Width: 4032, Height: 3024, HasGPS: true},
+			[]string{"2021", "2021-07", "Apple iPhone 12", "Landscape", "12-24MP", "Has GPS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Tags(&tt.metadata))
+		})
+	}
+}
+
+func TestTagsWithoutDuplicates(t *testing.T) {
+	defer func(rules []Rule) { Rules = rules }(Rules)
+	Rules = append(Rules,
+		Rule{Name: "Again", Tags: func(m *metadata.Metadata) []string { return []string{"Has GPS", "", "Extra"} }},
+	)
+	assert.Equal(t, []string{"Has GPS", "Extra"}, Tags(&metadata.Metadata{HasGPS: true}))
+}
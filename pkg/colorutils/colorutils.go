@@ -119,3 +119,15 @@ func HexToColor(hex string) (color.Color, error) {
 		A: 255,
 	}, nil
 }
+
+// Color labels that can be given to files, in the order they are shown
+var LabelNames = []string{"red", "yellow", "green", "blue", "purple"}
+
+// Hex colors of the color labels
+var LabelColors = map[string]string{
+	"red":    "#E53935",
+	"yellow": "#FDD835",
+	"green":  "#43A047",
+	"blue":   "#1E88E5",
+	"purple": "#8E24AA",
+}
@@ -0,0 +1,116 @@
+package colorutils
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Most pixels sampled along each side of an image for its palette
+const paletteSamples = 64
+
+// Colors closer than this are merged into one swatch of the palette
+const paletteMergeDistance = 60
+
+// Color of an image palette with the share of the image it covers
+type Swatch struct {
+	Red, Green, Blue uint8
+	Share            float64
+}
+
+// Returns the color as a hex string like #FF0000
+func (s Swatch) Hex() string {
+	return fmt.Sprintf("#%02X%02X%02X", s.Red, s.Green, s.Blue)
+}
+
+// Returns how far apart two colors look, from 0 for the same color up to 765.
+// Green is weighted the most as the eye tells its shades apart the best
+func RGBDistance(r1, g1, b1, r2, g2, b2 float64) float64 {
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return math.Sqrt(2*dr*dr + 4*dg*dg + 3*db*db)
+}
+
+// Returns up to count dominant colors of an image, the one covering the most first
+func DominantColors(img image.Image, count int) []Swatch {
+	bounds := img.Bounds()
+	if bounds.Empty() || count <= 0 {
+		return nil
+	}
+
+	// pixels are counted in buckets of 16 levels per channel, the swatch gets
+	// the average color of the pixels in it
+	type bucket struct {
+		red, green, blue float64
+		pixels           int
+	}
+	buckets := map[int]*bucket{}
+	stepX := max(bounds.Dx()/paletteSamples, 1)
+	stepY := max(bounds.Dy()/paletteSamples, 1)
+	total := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, a := img.At(x, y).RGBA()
+			// see-through pixels don't show a color
+			if a < 0x8000 {
+				continue
+			}
+			key := int(r>>12)<<8 | int(g>>12)<<4 | int(b>>12)
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.red += float64(r >> 8)
+			bk.green += float64(g >> 8)
+			bk.blue += float64(b >> 8)
+			bk.pixels++
+			total++
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	sorted := make([]*bucket, 0, len(buckets))
+	for _, bk := range buckets {
+		bk.red /= float64(bk.pixels)
+		bk.green /= float64(bk.pixels)
+		bk.blue /= float64(bk.pixels)
+		sorted = append(sorted, bk)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].pixels > sorted[j].pixels
+	})
+
+	// the biggest buckets become swatches and the smaller ones close to a swatch add to it
+	var picked []*bucket
+	for _, bk := range sorted {
+		merged := false
+		for _, swatch := range picked {
+			if RGBDistance(bk.red, bk.green, bk.blue, swatch.red, swatch.green, swatch.blue) < paletteMergeDistance {
+				swatch.pixels += bk.pixels
+				merged = true
+				break
+			}
+		}
+		if !merged && len(picked) < count {
+			copied := *bk
+			picked = append(picked, &copied)
+		}
+	}
+	sort.SliceStable(picked, func(i, j int) bool {
+		return picked[i].pixels > picked[j].pixels
+	})
+
+	palette := make([]Swatch, len(picked))
+	for i, bk := range picked {
+		palette[i] = Swatch{
+			Red:   uint8(math.Round(bk.red)),
+			Green: uint8(math.Round(bk.green)),
+			Blue:  uint8(math.Round(bk.blue)),
+			Share: float64(bk.pixels) / float64(total),
+		}
+	}
+	return palette
+}
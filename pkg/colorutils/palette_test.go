@@ -0,0 +1,68 @@
+package colorutils
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a 100x100 image of stripes, each color gets the number of rows given
+func testStripes(stripes ...any) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	y := 0
+	for i := 0; i < len(stripes); i += 2 {
+		c, rows := stripes[i].(color.NRGBA), stripes[i+1].(int)
+		for end := y + rows; y < end; y++ {
+			for x := 0; x < 100; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img
+}
+
+func TestDominantColors(t *testing.T) {
+	red := color.NRGBA{200, 0, 0, 255}
+	darkRed := color.NRGBA{220, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	green := color.NRGBA{0, 160, 0, 255}
+	clear := color.NRGBA{255, 255, 255, 0}
+
+	tests := []struct {
+		name  string
+		img   image.Image
+		count int
+		want  []Swatch
+	}{
+		{"solid", testStripes(red, 100), 5, []Swatch{{200, 0, 0, 1}}},
+		{"two colors", testStripes(blue, 25, red, 75), 5, []Swatch{{200, 0, 0, 0.75}, {0, 0, 255, 0.25}}},
+		// the shades are in different buckets but close enough to be one swatch
+		{"close shades merged", testStripes(red, 60, darkRed, 40), 5, []Swatch{{200, 0, 0, 1}}},
+		{"count", testStripes(red, 50, blue, 30, green, 20), 2, []Swatch{{200, 0, 0, 0.5}, {0, 0, 255, 0.3}}},
+		{"see-through pixels skipped", testStripes(clear, 50, blue, 50), 5, []Swatch{{0, 0, 255, 1}}},
+		{"only see-through pixels", testStripes(clear, 100), 5, nil},
+		{"no colors asked for", testStripes(red, 100), 0, nil},
+		{"empty", image.NewNRGBA(image.Rectangle{}), 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			palette := DominantColors(tt.img, tt.count)
+			if assert.Len(t, palette, len(tt.want)) {
+				for i, want := range tt.want {
+					assert.Equal(t, want.Hex(), palette[i].Hex())
+					assert.InDelta(t, want.Share, palette[i].Share, 0.001)
+				}
+			}
+		})
+	}
+}
+
+func TestRGBDistance(t *testing.T) {
+	assert.Equal(t, 0.0, RGBDistance(10, 20, 30, 10, 20, 30))
+	assert.Equal(t, 765.0, RGBDistance(0, 0, 0, 255, 255, 255))
+	// green is weighted the most
+	assert.Greater(t, RGBDistance(0, 0, 0, 0, 50, 0), RGBDistance(0, 0, 0, 0, 0, 50))
+	assert.Greater(t, RGBDistance(0, 0, 0, 0, 0, 50), RGBDistance(0, 0, 0, 50, 0, 0))
+}
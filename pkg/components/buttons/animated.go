@@ -0,0 +1,249 @@
+package buttons
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+)
+
+// When an animated thumbnail plays
+type Playback int
+
+const (
+	PlayOnHover Playback = iota // while the mouse is over it
+	PlayAlways                  // from the start, until it is paused
+	PlayNever                   // the still thumbnail is shown until play is pressed
+)
+
+// animation plays frames over the still thumbnail of an image button, the frames
+// are loaded the first time it plays
+type animation struct {
+	image     *canvas.Image
+	load      func() ([]image.Image, []time.Duration, error)
+	playback  Playback
+	frameRate float64 // frames per second, 0 keeps the delays of the animation
+
+	mu      sync.Mutex
+	frames  []image.Image
+	delays  []time.Duration
+	loaded  bool
+	hovered bool
+	paused  bool
+	stop    chan struct{} // closed to stop the playing animation, nil when it isn't playing
+	still   fyne.Resource
+
+	// the pause icon isn't a button so hovering it doesn't leave the thumbnail,
+	// presses on it toggle the animation instead of tapping the thumbnail
+	pause        *canvas.Image
+	pressedPause bool
+	controls     *fyne.Container
+}
+
+// NewAnimatedButton returns an image button showing the still resource that plays an
+// animation, load returns its frames and how long each is shown
+func NewAnimatedButton(resource fyne.Resource, playback Playback, load func() ([]image.Image, []time.Duration, error)) *imageButton {
+	b := NewImageButton(resource)
+	a := &animation{image: b.Image, load: load, playback: playback, still: resource, paused: playback == PlayNever}
+
+	a.pause = canvas.NewImageFromResource(theme.MediaPauseIcon())
+	a.pause.FillMode = canvas.ImageFillContain
+	a.pause.SetMinSize(fyne.NewSize(24, 24))
+	background := canvas.NewRectangle(color.NRGBA{A: 0xaa})
+	background.CornerRadius = 4
+	a.controls = container.NewBorder(nil, container.NewHBox(layout.NewSpacer(), container.NewStack(background, a.pause)), nil, nil)
+	a.updateControls()
+
+	b.animation = a
+	if playback == PlayAlways {
+		a.play()
+	}
+	return b
+}
+
+// SetResource sets the still thumbnail, animated buttons show it while they don't play
+func (b *imageButton) SetResource(resource fyne.Resource) {
+	if b.animation == nil {
+		b.Image.Resource = resource
+		canvas.Refresh(b.Image)
+		return
+	}
+	a := b.animation
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.still = resource
+	if a.stop == nil {
+		a.image.Resource = resource
+		a.image.Image = nil
+		canvas.Refresh(a.image)
+	}
+}
+
+// SetFrameRate sets the frames an animated button shows per second, 0 keeps the
+// delays of the animation
+func (b *imageButton) SetFrameRate(frameRate float64) {
+	if b.animation == nil {
+		return
+	}
+	b.animation.mu.Lock()
+	defer b.animation.mu.Unlock()
+	b.animation.frameRate = max(frameRate, 0)
+}
+
+// TogglePause pauses a playing animation on the frame it is on, or plays a paused one
+func (b *imageButton) TogglePause() {
+	if b.animation != nil {
+		b.animation.togglePause()
+	}
+}
+
+// MouseIn shows the pause icon and plays animations that play on hover
+func (b *imageButton) MouseIn(_ *desktop.MouseEvent) {
+	if a := b.animation; a != nil {
+		a.setHovered(true)
+		if a.playback == PlayOnHover {
+			a.play()
+		}
+	}
+}
+
+func (b *imageButton) MouseMoved(_ *desktop.MouseEvent) {}
+
+// MouseOut stops animations that play on hover
+func (b *imageButton) MouseOut() {
+	if a := b.animation; a != nil {
+		a.setHovered(false)
+		if a.playback == PlayOnHover {
+			a.stopPlaying()
+		}
+	}
+}
+
+// Starts the animation unless it is paused or already playing
+func (a *animation) play() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.paused || a.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	a.stop = stop
+	go a.run(stop)
+}
+
+// Stops the animation and shows the still thumbnail again
+func (a *animation) stopPlaying() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	a.stop = nil
+	a.image.Resource = a.still
+	a.image.Image = nil
+	canvas.Refresh(a.image)
+}
+
+func (a *animation) togglePause() {
+	a.mu.Lock()
+	a.paused = !a.paused
+	paused := a.paused
+	if paused && a.stop != nil {
+		// the frame stays, only the timer stops
+		close(a.stop)
+		a.stop = nil
+	}
+	a.mu.Unlock()
+
+	if !paused {
+		a.play()
+	}
+	a.updateControls()
+}
+
+func (a *animation) setHovered(hovered bool) {
+	a.mu.Lock()
+	a.hovered = hovered
+	a.mu.Unlock()
+	a.updateControls()
+}
+
+// Plays the frames until stop is closed, loading them first if needed
+func (a *animation) run(stop chan struct{}) {
+	a.mu.Lock()
+	if !a.loaded {
+		a.mu.Unlock()
+		frames, delays, err := a.load()
+		a.mu.Lock()
+		a.loaded = true
+		if err == nil && len(frames) == len(delays) {
+			a.frames, a.delays = frames, delays
+		}
+	}
+	frames, delays := a.frames, a.delays
+	a.mu.Unlock()
+	if len(frames) < 2 {
+		return
+	}
+
+	for i := 0; ; i = (i + 1) % len(frames) {
+		a.mu.Lock()
+		select {
+		case <-stop:
+			a.mu.Unlock()
+			return
+		default:
+		}
+		a.image.Resource = nil
+		a.image.Image = frames[i]
+		delay := delays[i]
+		if a.frameRate > 0 {
+			delay = time.Duration(float64(time.Second) / a.frameRate)
+		}
+		a.mu.Unlock()
+		canvas.Refresh(a.image)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Shows the pause icon while the mouse is over the thumbnail or it is paused
+func (a *animation) updateControls() {
+	a.mu.Lock()
+	paused, hovered := a.paused, a.hovered
+	a.mu.Unlock()
+
+	if paused {
+		a.pause.Resource = theme.MediaPlayIcon()
+	} else {
+		a.pause.Resource = theme.MediaPauseIcon()
+	}
+	a.pause.Refresh()
+	if paused || hovered {
+		a.controls.Show()
+	} else {
+		a.controls.Hide()
+	}
+}
+
+// Returns true if the position is on the pause icon
+func (a *animation) onPause(position fyne.Position) bool {
+	if !a.controls.Visible() {
+		return false
+	}
+	start := fyne.CurrentApp().Driver().AbsolutePositionForObject(a.pause)
+	end := start.Add(a.pause.Size())
+	return position.X >= start.X && position.X < end.X && position.Y >= start.Y && position.Y < end.Y
+}
@@ -0,0 +1,47 @@
+package buttons
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+)
+
+// badge is drawn in the top left of a thumbnail, an icon with a short text on a
+// dark background like the duration of a video or the name of a document
+type badge struct {
+	icon    *canvas.Image
+	text    *canvas.Text
+	badge   *fyne.Container
+	content *fyne.Container
+}
+
+func newBadge() *badge {
+	b := &badge{}
+
+	b.icon = canvas.NewImageFromResource(theme.MediaPlayIcon())
+	b.icon.FillMode = canvas.ImageFillContain
+	b.icon.SetMinSize(fyne.NewSize(14, 14))
+
+	b.text = canvas.NewText("", color.White)
+	b.text.TextSize = 11
+
+	background := canvas.NewRectangle(color.NRGBA{A: 0xaa})
+	background.CornerRadius = 4
+	b.badge = container.NewStack(background, container.NewHBox(b.icon, b.text))
+	b.badge.Hide()
+
+	b.content = container.NewBorder(container.NewHBox(b.badge, layout.NewSpacer()), nil, nil, nil)
+	return b
+}
+
+// set shows the badge with the icon and the text, an empty text shows just the icon
+func (b *badge) set(icon fyne.Resource, text string) {
+	b.icon.Resource = icon
+	b.text.Text = text
+	b.badge.Show()
+	b.content.Refresh()
+}
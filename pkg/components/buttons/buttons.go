@@ -22,6 +22,9 @@ type imageButton struct {
 	pressedTime  time.Time
 	longTapTimer *time.Timer
 	Selected     bool
+	marks        *marksOverlay
+	badge        *badge
+	animation    *animation // nil for still images
 }
 
 type FileButton struct {
@@ -149,6 +152,8 @@ func NewImageButton(resource fyne.Resource) *imageButton {
 	img.Image = canvas.NewImageFromResource(resource)
 	img.Image.FillMode = canvas.ImageFillContain
 	img.Image.SetMinSize(fyne.NewSize(150, 150))
+	img.marks = newMarksOverlay()
+	img.badge = newBadge()
 	return img
 }
 
@@ -185,6 +190,12 @@ func (b *imageButton) Refresh() {
 }
 
 func (b *imageButton) MouseDown(me *desktop.MouseEvent) {
+	if b.animation != nil {
+		b.animation.pressedPause = b.animation.onPause(me.AbsolutePosition)
+		if b.animation.pressedPause {
+			return
+		}
+	}
 	if me.Button == desktop.MouseButtonPrimary {
 		b.pressedTime = time.Now()
 		b.longTapTimer = time.AfterFunc(time.Millisecond*200, func() {
@@ -195,7 +206,13 @@ func (b *imageButton) MouseDown(me *desktop.MouseEvent) {
 	}
 }
 
-func (b *imageButton) MouseUp(_ *desktop.MouseEvent) {
+func (b *imageButton) MouseUp(me *desktop.MouseEvent) {
+	if b.animation != nil && b.animation.pressedPause {
+		if b.animation.onPause(me.AbsolutePosition) {
+			b.animation.togglePause()
+		}
+		return
+	}
 	if b.longTapTimer != nil {
 		b.longTapTimer.Stop()
 	}
@@ -205,7 +222,26 @@ func (b *imageButton) MouseUp(_ *desktop.MouseEvent) {
 }
 
 func (b *imageButton) CreateRenderer() fyne.WidgetRenderer {
-	return widget.NewSimpleRenderer(b.Image)
+	if b.animation != nil {
+		return widget.NewSimpleRenderer(container.NewStack(b.Image, b.marks.content, b.badge.content, b.animation.controls))
+	}
+	return widget.NewSimpleRenderer(container.NewStack(b.Image, b.marks.content, b.badge.content))
+}
+
+// SetMarks shows the rating, favorite flag and color label over the image, label can be nil
+func (b *imageButton) SetMarks(rating int, favorite bool, label color.Color) {
+	b.marks.set(rating, favorite, label)
+}
+
+// SetVideo marks the thumbnail as the poster of a video with the duration like 1:05
+func (b *imageButton) SetVideo(duration string) {
+	b.badge.set(theme.MediaPlayIcon(), duration)
+}
+
+// SetBadge shows an icon with a short text in the corner of the thumbnail, like
+// the type and name of a document
+func (b *imageButton) SetBadge(icon fyne.Resource, text string) {
+	b.badge.set(icon, text)
 }
 
 func (b *imageButton) SetOnTapped(f func()) {
@@ -231,6 +267,7 @@ type GifButton struct {
 	pressedTime  time.Time
 	longTapTimer *time.Timer
 	Selected     bool
+	marks        *marksOverlay
 }
 
 // NewGifButton creates a new animated GIF button from the specified resource
@@ -241,6 +278,7 @@ func NewGifButton(path fyne.URI) *GifButton {
 	gif.animation, _ = fyneGif.NewAnimatedGif(path)
 	gif.animation.SetMinSize(fyne.NewSize(150, 150))
 	gif.animation.Start() // Start the animation by default
+	gif.marks = newMarksOverlay()
 	return gif
 }
 
@@ -312,5 +350,10 @@ func (b *GifButton) Refresh() {
 
 // CreateRenderer implements the fyne.Widget interface
 func (b *GifButton) CreateRenderer() fyne.WidgetRenderer {
-	return widget.NewSimpleRenderer(b.animation)
+	return widget.NewSimpleRenderer(container.NewStack(b.animation, b.marks.content))
+}
+
+// SetMarks shows the rating, favorite flag and color label over the animation, label can be nil
+func (b *GifButton) SetMarks(rating int, favorite bool, label color.Color) {
+	b.marks.set(rating, favorite, label)
 }
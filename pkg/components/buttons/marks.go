@@ -0,0 +1,87 @@
+package buttons
+
+import (
+	"image/color"
+	"main/pkg/icon"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+)
+
+var (
+	starResource  = fyne.NewStaticResource("star", icon.StarIcon)
+	heartResource = fyne.NewStaticResource("heart", icon.HeartIcon)
+)
+
+// Markable is a thumbnail that can show the rating, favorite flag and color label of its file
+type Markable interface {
+	SetMarks(rating int, favorite bool, label color.Color)
+}
+
+// marksOverlay is drawn on top of a thumbnail, stars in the bottom left,
+// a heart in the top right and the color label as a strip along the bottom
+type marksOverlay struct {
+	stars   []*canvas.Image
+	heart   *canvas.Image
+	label   *canvas.Rectangle
+	content *fyne.Container
+}
+
+func newMarksOverlay() *marksOverlay {
+	m := &marksOverlay{}
+
+	starRow := container.NewHBox()
+	for i := 0; i < 5; i++ {
+		star := canvas.NewImageFromResource(starResource)
+		star.FillMode = canvas.ImageFillContain
+		star.SetMinSize(fyne.NewSize(14, 14))
+		star.Hide()
+		m.stars = append(m.stars, star)
+		starRow.Add(star)
+	}
+
+	m.heart = canvas.NewImageFromResource(heartResource)
+	m.heart.FillMode = canvas.ImageFillContain
+	m.heart.SetMinSize(fyne.NewSize(18, 18))
+	m.heart.Hide()
+
+	m.label = canvas.NewRectangle(color.Transparent)
+	m.label.SetMinSize(fyne.NewSize(0, 5))
+	m.label.Hide()
+
+	m.content = container.NewBorder(
+		container.NewHBox(layout.NewSpacer(), m.heart),
+		container.NewVBox(starRow, m.label),
+		nil,
+		nil,
+	)
+	return m
+}
+
+// set shows as many stars as the rating, the heart if favorite and the label strip if label isn't nil
+func (m *marksOverlay) set(rating int, favorite bool, label color.Color) {
+	for i, star := range m.stars {
+		if i < rating {
+			star.Show()
+		} else {
+			star.Hide()
+		}
+	}
+
+	if favorite {
+		m.heart.Show()
+	} else {
+		m.heart.Hide()
+	}
+
+	if label != nil {
+		m.label.FillColor = label
+		m.label.Show()
+	} else {
+		m.label.Hide()
+	}
+
+	m.content.Refresh()
+}
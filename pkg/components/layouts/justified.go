@@ -0,0 +1,115 @@
+// Package layouts has the layouts of the thumbnail grid that fyne doesn't have
+package layouts
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+)
+
+// Justified lays objects out in rows like photo sites do. Every object keeps its
+// aspect ratio, the rows are scaled to fill the width and have about the same height.
+// The last row isn't stretched
+type Justified struct {
+	RowHeight float32
+
+	mu      sync.Mutex
+	aspects map[fyne.CanvasObject]float32 // width / height, objects without one are square
+	width   float32                       // width of the last layout, MinSize depends on it
+}
+
+// Returns a justified layout with rows about rowHeight high
+func NewJustified(rowHeight float32) *Justified {
+	return &Justified{RowHeight: rowHeight, aspects: map[fyne.CanvasObject]float32{}}
+}
+
+// Returns a container with the objects in justified rows about rowHeight high
+func NewJustifiedContainer(rowHeight float32, objects ...fyne.CanvasObject) *fyne.Container {
+	return container.New(NewJustified(rowHeight), objects...)
+}
+
+// Sets the aspect ratio (width / height) of an object, e.g. once its image is loaded.
+// The container has to be refreshed after it
+func (j *Justified) SetAspect(object fyne.CanvasObject, aspect float32) {
+	if aspect <= 0 {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.aspects[object] = aspect
+}
+
+func (j *Justified) aspect(object fyne.CanvasObject) float32 {
+	if aspect, ok := j.aspects[object]; ok {
+		return aspect
+	}
+	return 1
+}
+
+// Splits the objects into rows for the width, returns the rows and their heights
+func (j *Justified) rows(objects []fyne.CanvasObject, width float32) ([][]fyne.CanvasObject, []float32) {
+	padding := theme.Padding()
+	var rows [][]fyne.CanvasObject
+	var heights []float32
+	var row []fyne.CanvasObject
+	var aspects float32
+	for _, object := range objects {
+		if !object.Visible() {
+			continue
+		}
+		row = append(row, object)
+		aspects += j.aspect(object)
+		gaps := padding * float32(len(row)-1)
+		// the row is full once it is at least as wide as the width at the row height
+		if aspects*j.RowHeight+gaps >= width {
+			rows = append(rows, row)
+			heights = append(heights, max(1, (width-gaps)/aspects))
+			row, aspects = nil, 0
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+		heights = append(heights, j.RowHeight)
+	}
+	return rows, heights
+}
+
+func (j *Justified) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.width = size.Width
+	padding := theme.Padding()
+	rows, heights := j.rows(objects, size.Width)
+	y := float32(0)
+	for i, row := range rows {
+		x := float32(0)
+		for _, object := range row {
+			width := heights[i] * j.aspect(object)
+			object.Move(fyne.NewPos(x, y))
+			object.Resize(fyne.NewSize(width, heights[i]))
+			x += width + padding
+		}
+		y += heights[i] + padding
+	}
+}
+
+// The height of the rows at the width of the last layout, before the first layout
+// every object gets a row of its own
+func (j *Justified) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	width := max(j.width, j.RowHeight)
+	_, heights := j.rows(objects, width)
+	height := float32(0)
+	for _, h := range heights {
+		height += h
+	}
+	if len(heights) > 0 {
+		height += theme.Padding() * float32(len(heights)-1)
+	}
+	return fyne.NewSize(j.RowHeight, height)
+}
@@ -0,0 +1,92 @@
+package layouts
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns objects for the aspect ratios and a layout that knows them, 0 leaves
+// the object without an aspect
+func testJustified(aspects ...float32) (*Justified, []fyne.CanvasObject) {
+	j := NewJustified(100)
+	objects := make([]fyne.CanvasObject, len(aspects))
+	for i, aspect := range aspects {
+		objects[i] = canvas.NewRectangle(nil)
+		j.SetAspect(objects[i], aspect)
+	}
+	return j, objects
+}
+
+// Returns the number of objects in each row
+func rowLengths(rows [][]fyne.CanvasObject) []int {
+	var lengths []int
+	for _, row := range rows {
+		lengths = append(lengths, len(row))
+	}
+	return lengths
+}
+
+func TestJustifiedRows(t *testing.T) {
+	padding := theme.Padding()
+	tests := []struct {
+		name    string
+		aspects []float32
+		hidden  []int
+		width   float32
+		lengths []int
+		heights []float32
+	}{
+		{"exactly full", []float32{1, 1, 1}, nil, 300 + 2*padding, []int{3}, []float32{100}},
+		// the full row shrinks to fit the width, the last one keeps the row height
+		{"last row not stretched", []float32{1, 1, 1, 1}, nil, 250, []int{3, 1}, []float32{(250 - 2*padding) / 3, 100}},
+		{"wide first", []float32{2, 1, 1}, nil, 250, []int{2, 1}, []float32{(250 - padding) / 3, 100}},
+		{"no aspect is square", []float32{0, 0, 0}, nil, 300 + 2*padding, []int{3}, []float32{100}},
+		{"hidden skipped", []float32{1, 5, 1, 1}, []int{1}, 300 + 2*padding, []int{3}, []float32{100}},
+		{"all hidden", []float32{1, 1}, []int{0, 1}, 300, nil, nil},
+		// a row of its own for every object, at least a pixel high
+		{"zero width", []float32{1, 2}, nil, 0, []int{1, 1}, []float32{1, 1}},
+		{"wider than the width", []float32{4, 1}, nil, 200, []int{1, 1}, []float32{50, 100}},
+		{"nothing", nil, nil, 300, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, objects := testJustified(tt.aspects...)
+			for _, i := range tt.hidden {
+				objects[i].Hide()
+			}
+			rows, heights := j.rows(objects, tt.width)
+			assert.Equal(t, tt.lengths, rowLengths(rows))
+			assert.InDeltaSlice(t, tt.heights, heights, 0.001)
+			for _, row := range rows {
+				for _, object := range row {
+					assert.True(t, object.Visible())
+				}
+			}
+		})
+	}
+}
+
+func TestJustifiedLayout(t *testing.T) {
+	padding := theme.Padding()
+	j, objects := testJustified(2, 1, 1)
+	j.Layout(objects, fyne.NewSize(300+padding, 500))
+
+	// the first row fills the width, the last one keeps the row height
+	assert.Equal(t, fyne.NewPos(0, 0), objects[0].Position())
+	assert.Equal(t, fyne.NewSize(200, 100), objects[0].Size())
+	assert.Equal(t, fyne.NewPos(200+padding, 0), objects[1].Position())
+	assert.Equal(t, fyne.NewPos(0, 100+padding), objects[2].Position())
+	assert.Equal(t, fyne.NewSize(100, 100), objects[2].Size())
+	assert.Equal(t, fyne.NewSize(100, 200+padding), j.MinSize(objects))
+}
+
+func TestJustifiedMinSizeBeforeLayout(t *testing.T) {
+	padding := theme.Padding()
+	j, objects := testJustified(1, 1)
+	// a row for each object
+	assert.Equal(t, fyne.NewSize(100, 200+padding), j.MinSize(objects))
+}
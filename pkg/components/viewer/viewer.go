@@ -0,0 +1,322 @@
+// Package viewer has the widget full size images are looked at in, it zooms
+// with the mouse wheel and pans by dragging.
+//
+// Pinching has no handler of its own: Fyne has no multi-touch gestures, the
+// desktop driver gets no touch events and the mobile one doesn't tell fingers
+// apart. Touchpads send a pinch to apps as scrolling with Ctrl held, in steps
+// smaller than a wheel notch, so it zooms smoothly through Scrolled
+package viewer
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// Limits of the zoom, in screen pixels per image pixel
+const (
+	MinZoom = 0.01
+	MaxZoom = 64
+)
+
+// Scroll distance that doubles the zoom, a mouse wheel notch scrolls 25 on most
+// systems and 10 on macOS
+const scrollPerDoubling = 100
+
+// Smallest side of the halved copies zoomed out images are drawn from
+const minLevelSize = 64
+
+// ImageViewer shows an image fitted to its size until it is zoomed. Zooming in past
+// 1:1 shows the pixels as sharp squares so they can be inspected one by one
+type ImageViewer struct {
+	widget.BaseWidget
+
+	OnZoomChanged  func(zoom float64)                          // called with the new zoom, 1 shows the image 1:1
+	OnPixelHovered func(x, y int, c color.Color, onImage bool) // called with the image pixel under the mouse
+
+	mu     sync.Mutex
+	img    image.Image
+	levels []image.Image // the image halved again and again, zoomed out views are drawn from them
+	zoom   float64       // screen pixels per image pixel
+	fit    bool          // the zoom follows the size of the viewer
+	cx, cy float64       // point of the image in the middle of the viewer
+	width  int           // screen pixels of the last drawing
+	height int
+	scale  float64 // screen pixels per fyne unit of the last drawing
+	raster *canvas.Raster
+}
+
+// Returns a viewer showing the image fitted to its size, the image can be nil
+func NewImageViewer(img image.Image) *ImageViewer {
+	v := &ImageViewer{scale: 1}
+	v.ExtendBaseWidget(v)
+	v.raster = canvas.NewRaster(v.draw)
+	v.SetImage(img)
+	return v
+}
+
+// Shows another image, fitted to the viewer
+func (v *ImageViewer) SetImage(img image.Image) {
+	v.mu.Lock()
+	v.img = img
+	v.levels = nil
+	if img != nil {
+		v.levels = []image.Image{img}
+	}
+	v.fit = true
+	v.fitView()
+	v.mu.Unlock()
+	v.changed()
+}
+
+// Returns the image shown
+func (v *ImageViewer) Image() image.Image {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.img
+}
+
+// Returns the zoom in screen pixels per image pixel
+func (v *ImageViewer) Zoom() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.zoom
+}
+
+// Fits the whole image in the viewer, images smaller than it are shown 1:1
+func (v *ImageViewer) ZoomFit() {
+	v.mu.Lock()
+	v.fit = true
+	v.fitView()
+	v.mu.Unlock()
+	v.changed()
+}
+
+// Shows one image pixel on every screen pixel, keeping the middle of the view
+func (v *ImageViewer) ZoomActual() {
+	v.SetZoom(1)
+}
+
+// Zooms keeping the middle of the view in place
+func (v *ImageViewer) SetZoom(zoom float64) {
+	v.mu.Lock()
+	v.zoomAround(zoom, float64(v.width)/2, float64(v.height)/2)
+	v.mu.Unlock()
+	v.changed()
+}
+
+// Zooms in by a step, keeping the middle of the view in place
+func (v *ImageViewer) ZoomIn() {
+	v.SetZoom(v.Zoom() * math.Sqrt2)
+}
+
+// Zooms out by a step, keeping the middle of the view in place
+func (v *ImageViewer) ZoomOut() {
+	v.SetZoom(v.Zoom() / math.Sqrt2)
+}
+
+// Scrolling zooms around the mouse so the pixel under it stays there, touchpad
+// pinches arrive as scrolling too
+func (v *ImageViewer) Scrolled(ev *fyne.ScrollEvent) {
+	factor := math.Pow(2, float64(ev.Scrolled.DY)/scrollPerDoubling)
+	v.mu.Lock()
+	v.zoomAround(v.zoom*factor, float64(ev.Position.X)*v.scale, float64(ev.Position.Y)*v.scale)
+	v.mu.Unlock()
+	v.changed()
+	v.hover(ev.Position)
+}
+
+// Dragging pans the image with the mouse
+func (v *ImageViewer) Dragged(ev *fyne.DragEvent) {
+	v.mu.Lock()
+	if v.img == nil {
+		v.mu.Unlock()
+		return
+	}
+	v.fit = false
+	v.cx -= float64(ev.Dragged.DX) * v.scale / v.zoom
+	v.cy -= float64(ev.Dragged.DY) * v.scale / v.zoom
+	v.clampCenter()
+	v.mu.Unlock()
+	v.raster.Refresh()
+	v.hover(ev.Position)
+}
+
+func (v *ImageViewer) DragEnd() {}
+
+// Double tapping zooms to 1:1 around the mouse, or fits the image again
+func (v *ImageViewer) DoubleTapped(ev *fyne.PointEvent) {
+	v.mu.Lock()
+	if v.fit && v.zoom != 1 {
+		v.zoomAround(1, float64(ev.Position.X)*v.scale, float64(ev.Position.Y)*v.scale)
+	} else {
+		v.fit = true
+		v.fitView()
+	}
+	v.mu.Unlock()
+	v.changed()
+	v.hover(ev.Position)
+}
+
+func (v *ImageViewer) MouseIn(ev *desktop.MouseEvent) {
+	v.hover(ev.Position)
+}
+
+func (v *ImageViewer) MouseMoved(ev *desktop.MouseEvent) {
+	v.hover(ev.Position)
+}
+
+func (v *ImageViewer) MouseOut() {
+	if v.OnPixelHovered != nil {
+		v.OnPixelHovered(0, 0, nil, false)
+	}
+}
+
+func (v *ImageViewer) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(v.raster)
+}
+
+func (v *ImageViewer) MinSize() fyne.Size {
+	v.ExtendBaseWidget(v)
+	return fyne.NewSize(100, 100)
+}
+
+// Tells about the image pixel under a position of the viewer
+func (v *ImageViewer) hover(position fyne.Position) {
+	if v.OnPixelHovered == nil {
+		return
+	}
+	v.mu.Lock()
+	img := v.img
+	x, y := v.imagePoint(float64(position.X)*v.scale, float64(position.Y)*v.scale)
+	v.mu.Unlock()
+	if img == nil {
+		v.OnPixelHovered(0, 0, nil, false)
+		return
+	}
+	bounds := img.Bounds()
+	px, py := int(math.Floor(x)), int(math.Floor(y))
+	if px < 0 || py < 0 || px >= bounds.Dx() || py >= bounds.Dy() {
+		v.OnPixelHovered(px, py, nil, false)
+		return
+	}
+	v.OnPixelHovered(px, py, img.At(bounds.Min.X+px, bounds.Min.Y+py), true)
+}
+
+// Redraws the image and tells about the zoom
+func (v *ImageViewer) changed() {
+	v.raster.Refresh()
+	if v.OnZoomChanged != nil {
+		v.OnZoomChanged(v.Zoom())
+	}
+}
+
+// Returns the point of the image shown at a screen pixel of the viewer, v.mu is held
+func (v *ImageViewer) imagePoint(x, y float64) (float64, float64) {
+	return v.cx + (x-float64(v.width)/2)/v.zoom, v.cy + (y-float64(v.height)/2)/v.zoom
+}
+
+// Zooms keeping the image point at the screen pixel x, y in place, v.mu is held
+func (v *ImageViewer) zoomAround(zoom float64, x, y float64) {
+	if v.img == nil {
+		return
+	}
+	ix, iy := v.imagePoint(x, y)
+	v.fit = false
+	v.zoom = min(max(zoom, MinZoom), MaxZoom)
+	v.cx = ix - (x-float64(v.width)/2)/v.zoom
+	v.cy = iy - (y-float64(v.height)/2)/v.zoom
+	v.clampCenter()
+}
+
+// Keeps some of the image in the view, v.mu is held
+func (v *ImageViewer) clampCenter() {
+	bounds := v.img.Bounds()
+	v.cx = min(max(v.cx, 0), float64(bounds.Dx()))
+	v.cy = min(max(v.cy, 0), float64(bounds.Dy()))
+}
+
+// Sets the zoom that fits the image in the last drawing, v.mu is held
+func (v *ImageViewer) fitView() {
+	if v.img == nil {
+		v.zoom = 1
+		return
+	}
+	if v.width == 0 || v.height == 0 {
+		size := v.Size()
+		v.width, v.height = int(float64(size.Width)*v.scale), int(float64(size.Height)*v.scale)
+	}
+	bounds := v.img.Bounds()
+	v.zoom = 1
+	if bounds.Dx() > 0 && bounds.Dy() > 0 && v.width > 0 && v.height > 0 {
+		v.zoom = min(float64(v.width)/float64(bounds.Dx()), float64(v.height)/float64(bounds.Dy()), 1)
+	}
+	v.zoom = max(v.zoom, MinZoom)
+	v.cx, v.cy = float64(bounds.Dx())/2, float64(bounds.Dy())/2
+}
+
+// Draws the visible part of the image, zoomed in images are drawn pixel by pixel
+// and zoomed out ones from the halved copy closest to the zoom
+func (v *ImageViewer) draw(w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	sizeChanged := w != v.width || h != v.height
+	v.width, v.height = w, h
+	if size := v.Size(); size.Width > 0 {
+		v.scale = float64(w) / float64(size.Width)
+	}
+	if v.img == nil {
+		return dst
+	}
+	if v.fit && sizeChanged {
+		v.fitView()
+		if v.OnZoomChanged != nil {
+			go v.OnZoomChanged(v.zoom)
+		}
+	}
+
+	bounds := v.img.Bounds()
+	level := v.level(v.zoom)
+	levelBounds := level.Bounds()
+	// screen pixels per pixel of the level
+	zoom := v.zoom * float64(bounds.Dx()) / float64(levelBounds.Dx())
+	s2d := f64.Aff3{
+		zoom, 0, float64(w)/2 - v.cx*v.zoom - float64(levelBounds.Min.X)*zoom,
+		0, zoom, float64(h)/2 - v.cy*v.zoom - float64(levelBounds.Min.Y)*zoom,
+	}
+	var interpolator xdraw.Interpolator = xdraw.ApproxBiLinear
+	if zoom >= 1 {
+		interpolator = xdraw.NearestNeighbor
+	}
+	interpolator.Transform(dst, s2d, level, levelBounds, xdraw.Src, nil)
+	return dst
+}
+
+// Returns the smallest halved copy of the image that still has a pixel for every
+// screen pixel at the zoom, the copies are made when first needed. v.mu is held
+func (v *ImageViewer) level(zoom float64) image.Image {
+	for i := 0; ; i++ {
+		if zoom*math.Pow(2, float64(i+1)) > 1 {
+			return v.levels[i]
+		}
+		if i+1 == len(v.levels) {
+			previous := v.levels[i].Bounds()
+			if previous.Dx() < minLevelSize*2 || previous.Dy() < minLevelSize*2 {
+				return v.levels[i]
+			}
+			half := image.NewRGBA(image.Rect(0, 0, (previous.Dx()+1)/2, (previous.Dy()+1)/2))
+			xdraw.ApproxBiLinear.Scale(half, half.Bounds(), v.levels[i], previous, xdraw.Src, nil)
+			v.levels = append(v.levels, half)
+		}
+	}
+}
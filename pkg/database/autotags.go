@@ -0,0 +1,99 @@
+package database
+
+import (
+	"database/sql"
+	"main/pkg/autotag"
+	"main/pkg/metadata"
+	"os"
+)
+
+// Replaces the auto tags of a file with the ones the autotag rules derive from its metadata
+func ApplyAutoTags(db *sql.DB, fileId int, m *metadata.Metadata) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM FileTag WHERE fileId = ? AND auto = true", fileId); err != nil {
+		return err
+	}
+
+	for _, name := range autotag.Tags(m) {
+		_, err := tx.Exec("INSERT INTO Tag (name, color) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM Tag WHERE name = ?)", name, autotag.TagColor, name)
+		if err != nil {
+			return err
+		}
+
+		var tagId int
+		if err := tx.QueryRow("SELECT id FROM Tag WHERE name = ?", name).Scan(&tagId); err != nil {
+			return err
+		}
+
+		// a tag the user already added by hand stays a manual tag
+		_, err = tx.Exec(`INSERT INTO FileTag (fileId, tagId, auto)
+		SELECT ?, ?, true
+		WHERE NOT EXISTS (
+		SELECT 1 FROM FileTag
+		WHERE fileId = ? AND tagId = ?
+		)`, fileId, tagId, fileId, tagId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Reads the metadata of every file in the library again and replaces their auto tags,
+// returns the number of files that were tagged
+func RetagLibrary(db *sql.DB) (int, error) {
+	rows, err := db.Query("SELECT id, path FROM File")
+	if err != nil {
+		return 0, err
+	}
+
+	// the rows are read first so the connection is free for the updates
+	type file struct {
+		id   int
+		path string
+	}
+	var files []file
+	for rows.Next() {
+		var f file
+		if err := rows.Scan(&f.id, &f.path); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		files = append(files, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tagged := 0
+	for _, f := range files {
+		// files that were moved or deleted keep their stored metadata
+		var m *metadata.Metadata
+		if _, statErr := os.Stat(f.path); statErr == nil {
+			m, err = ReadFileMetadata(db, f.id, f.path)
+		} else {
+			m, err = GetStoredMetadata(db, f.id)
+		}
+		if err != nil {
+			if err != sql.ErrNoRows {
+				appLogger.Println("Failed to read metadata of ", replaceHomeDir(f.path), ": ", err)
+			}
+			continue
+		}
+
+		if err := ApplyAutoTags(db, f.id, m); err != nil {
+			return tagged, err
+		}
+		tagged++
+	}
+
+	appLogger.Println("Auto tagged ", tagged, " files")
+	return tagged, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"image"
+	"main/pkg/colorutils"
+	"strings"
+)
+
+// Colors in a palette of an image
+const paletteSize = 5
+
+// Colors covering less of an image than this aren't matched by color searches
+const minColorShare = 0.05
+
+// Most RGBDistance between a searched color and a palette color for them to match
+const colorSearchDistance = 110
+
+func setupColors(db *sql.DB) {
+	tables := []string{
+		"CREATE TABLE IF NOT EXISTS `FileColor`(`fileId` INTEGER NOT NULL, `position` INTEGER NOT NULL, `red` INTEGER NOT NULL, `green` INTEGER NOT NULL, `blue` INTEGER NOT NULL, `share` REAL NOT NULL, PRIMARY KEY (`fileId`, `position`));",
+		"CREATE TRIGGER IF NOT EXISTS file_color_delete AFTER DELETE ON File BEGIN DELETE FROM FileColor WHERE fileId = old.id; END;",
+	}
+	for _, table := range tables {
+		if _, err := db.Exec(table); err != nil {
+			appLogger.Fatal("Failed to create color table: ", err)
+		}
+	}
+}
+
+// Replaces the stored palette of a file
+func SaveFilePalette(db *sql.DB, fileId int, palette []colorutils.Swatch) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM FileColor WHERE fileId = ?", fileId); err != nil {
+		return err
+	}
+	for position, swatch := range palette {
+		_, err := tx.Exec("INSERT INTO FileColor (fileId, position, red, green, blue, share) VALUES (?, ?, ?, ?, ?, ?)",
+			fileId, position, swatch.Red, swatch.Green, swatch.Blue, swatch.Share)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Extracts the palette of a decoded image and stores it for the file
+func SaveImagePalette(db *sql.DB, fileId int, img image.Image) error {
+	return SaveFilePalette(db, fileId, colorutils.DominantColors(img, paletteSize))
+}
+
+// Returns the palette of a file, the dominant color first, empty if it hasn't been extracted yet
+func GetFilePalette(db *sql.DB, fileId int) ([]colorutils.Swatch, error) {
+	rows, err := db.Query("SELECT red, green, blue, share FROM FileColor WHERE fileId = ? ORDER BY position", fileId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var palette []colorutils.Swatch
+	for rows.Next() {
+		var swatch colorutils.Swatch
+		if err := rows.Scan(&swatch.Red, &swatch.Green, &swatch.Blue, &swatch.Share); err != nil {
+			return nil, err
+		}
+		palette = append(palette, swatch)
+	}
+	return palette, rows.Err()
+}
+
+// Returns true if the palette of the file has been extracted
+func HasPalette(db *sql.DB, fileId int) bool {
+	var exists int
+	db.QueryRow("SELECT 1 FROM FileColor WHERE fileId = ? LIMIT 1", fileId).Scan(&exists)
+	return exists == 1
+}
+
+// Search filter for images with a dominant color close to a hex color or a label color,
+// e.g. color:#ff0000 color:red color:!=blue
+func colorFilter(op string, value string) (string, []any, error) {
+	if op != "=" && op != "!=" {
+		return "", nil, fmt.Errorf("only = and != can be used with colors")
+	}
+	hex := value
+	if labelColor, ok := colorutils.LabelColors[strings.ToLower(value)]; ok {
+		hex = labelColor
+	} else if !strings.HasPrefix(hex, "#") {
+		hex = "#" + hex
+	}
+	if _, err := colorutils.HexToColor(hex); err != nil {
+		return "", nil, fmt.Errorf("%s is not a color like #ff0000 or red", value)
+	}
+
+	r, g, b := colorutils.HexToRgb(hex)
+	// the same weights as colorutils.RGBDistance, squared so SQLite doesn't need sqrt
+	condition := `EXISTS (SELECT 1 FROM FileColor WHERE FileColor.fileId = File.id AND FileColor.share >= ?
+		AND 2 * (FileColor.red - ?) * (FileColor.red - ?) + 4 * (FileColor.green - ?) * (FileColor.green - ?) + 3 * (FileColor.blue - ?) * (FileColor.blue - ?) <= ?)`
+	if op == "!=" {
+		condition = "NOT " + condition
+	}
+	return condition, []any{minColorShare, r, r, g, g, b, b, colorSearchDistance * colorSearchDistance}, nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"main/pkg/colorutils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The color search window sends a hex color, the SQL distance has to agree with
+// colorutils.RGBDistance right up to the threshold
+func TestColorFilterThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		hex         string // as the color search window sends it
+		offset      [3]int // of the palette color from the searched one
+		wantMatches bool
+	}{
+		{"same color", colorutils.HSVToHex(200, 0.5, 1), [3]int{0, 0, 0}, true},
+		{"green at the threshold", colorutils.HSVToHex(200, 0.5, 1), [3]int{0, -55, 0}, true},
+		{"green past the threshold", colorutils.HSVToHex(200, 0.5, 1), [3]int{0, -56, 0}, false},
+		{"red at the threshold", colorutils.HSVToHex(200, 0.5, 1), [3]int{-77, 0, 0}, true},
+		{"red past the threshold", colorutils.HSVToHex(200, 0.5, 1), [3]int{-78, 0, 0}, false},
+		{"blue at the threshold", "#FF8000", [3]int{0, 0, 63}, true},
+		{"blue past the threshold", "#FF8000", [3]int{0, 0, 64}, false},
+		{"all channels", "#FF8000", [3]int{-40, 30, 30}, true},
+		{"all channels past the threshold", "#FF8000", [3]int{-40, 40, 30}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			r, g, b := colorutils.HexToRgb(tt.hex)
+			palette := [3]int{int(r) + tt.offset[0], int(g) + tt.offset[1], int(b) + tt.offset[2]}
+			addTestFile(t, db, testFile{path: "a.jpg", color: palette})
+
+			distance := colorutils.RGBDistance(r, g, b, float64(palette[0]), float64(palette[1]), float64(palette[2]))
+			assert.Equal(t, tt.wantMatches, distance <= colorSearchDistance, "distance %.1f", distance)
+
+			paths, err := SearchImages(db, "color:"+tt.hex)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.wantMatches, len(paths) == 1, fmt.Sprint(palette))
+			}
+			paths, err = SearchImages(db, "color:!="+tt.hex)
+			if assert.NoError(t, err) {
+				assert.Equal(t, !tt.wantMatches, len(paths) == 1)
+			}
+		})
+	}
+}
+
+func TestColorFilterMinShare(t *testing.T) {
+	db := testDB(t)
+	id := addTestFile(t, db, testFile{path: "a.jpg", color: [3]int{0, 0, 255}})
+	if _, err := db.Exec("INSERT INTO FileColor (fileId, position, red, green, blue, share) VALUES (?, 1, 255, 0, 0, ?)", id, minColorShare/2); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := SearchImages(db, "color:#0000ff")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"a.jpg"}, paths)
+	}
+	// the red covers too little of the image
+	paths, err = SearchImages(db, "color:#ff0000")
+	if assert.NoError(t, err) {
+		assert.Empty(t, paths)
+	}
+}
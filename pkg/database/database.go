@@ -4,13 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"main/pkg/colorutils"
 	"main/pkg/fileutils"
 	"main/pkg/imageconv"
 	"main/pkg/logger"
 	"main/pkg/options"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -39,10 +42,16 @@ func Init() *sql.DB {
 }
 
 func setupTables(db *sql.DB) {
+	if err := migrateFileUniqueness(db); err != nil {
+		appLogger.Fatal("Failed to migrate File table: ", err)
+	}
+
 	tables := []string{
 		"CREATE TABLE IF NOT EXISTS `Tag`(`id` INTEGER PRIMARY KEY NOT NULL, `name` VARCHAR(255) NOT NULL UNIQUE, `color` VARCHAR(7) NOT NULL);",
-		"CREATE TABLE IF NOT EXISTS `File`(`id` INTEGER PRIMARY KEY NOT NULL, `path` VARCHAR(1024) NOT NULL UNIQUE, `name` VARCHAR(256) NOT NULL UNIQUE, `md5` VARCHAR(32) NOT NULL UNIQUE, `dateAdded` DATETIME NOT NULL);",
-		"CREATE INDEX IF NOT EXISTS idx_image_path ON File(path);", // Creates index on File.path to make searching by path faster
+		"CREATE TABLE IF NOT EXISTS `File`(`id` INTEGER PRIMARY KEY NOT NULL, `path` VARCHAR(1024) NOT NULL UNIQUE, `name` VARCHAR(256) NOT NULL, `md5` VARCHAR(32) NOT NULL, `dateAdded` DATETIME NOT NULL);",
+		"CREATE INDEX IF NOT EXISTS idx_image_path ON File(path);",            // Creates index on File.path to make searching by path faster
+		"CREATE INDEX IF NOT EXISTS idx_file_name_id ON File(name, id);",      // Keyset pagination when sorting by name
+		"CREATE INDEX IF NOT EXISTS idx_file_date_id ON File(dateAdded, id);", // Keyset pagination when sorting by date added
 		"CREATE TABLE IF NOT EXISTS `FileTag`(`id` INTEGER PRIMARY KEY NOT NULL, `fileId` INTEGER NOT NULL, `tagId` INTEGER NOT NULL);",
 		"CREATE TABLE IF NOT EXISTS `Options`(`id` INTEGER PRIMARY KEY NOT NULL, `DatabasePath` VARCHAR(255) NOT NULL, `ExcludedDirs` VARCHAR(255) NOT NULL, `Timezone` VARCHAR(1024) NOT NULL, `SortDesc` BOOLEAN DEFAULT true, `UseRGB` BOOLEAN DEFAULT false, `ImageNumber` INTEGER NOT NULL DEFAULT 20, `ThumbnailSize` INTEGER NOT NULL DEFAULT 256, `Profiling` BOOLEAN DEFAULT false, `ExifFields` VARCHAR(255), `FirstBoot` BOOLEAN DEFAULT false);",
 		"PRAGMA journal_mode=WAL;",
@@ -52,6 +61,111 @@ func setupTables(db *sql.DB) {
 			appLogger.Fatal("Failed to create table: ", err)
 		}
 	}
+
+	// Columns added after the tables were first released, older databases get them on startup
+	columns := []struct{ table, column, definition string }{
+		{"File", "title", "VARCHAR(256) NOT NULL DEFAULT ''"},
+		{"File", "description", "TEXT NOT NULL DEFAULT ''"},
+		{"File", "notes", "TEXT NOT NULL DEFAULT ''"},
+		{"File", "rating", "INTEGER NOT NULL DEFAULT 0"},
+		{"File", "favorite", "BOOLEAN NOT NULL DEFAULT false"},
+		{"File", "label", "VARCHAR(16) NOT NULL DEFAULT ''"},
+		// one of fileutils.MediaTypes
+		{"File", "mediaType", "VARCHAR(16) NOT NULL DEFAULT 'image'"},
+		{"FileTag", "auto", "BOOLEAN NOT NULL DEFAULT false"}, // added by the auto tagger, replaced when it runs again
+		{"Options", "ThumbnailCacheSize", "INTEGER NOT NULL DEFAULT 512"},
+		{"Options", "MediaTypes", `VARCHAR(255) NOT NULL DEFAULT '["image","video"]'`},
+		{"Options", "GridLayout", "VARCHAR(16) NOT NULL DEFAULT 'square'"},       // one of options.GridLayouts
+		{"Options", "AnimationPlayback", "VARCHAR(16) NOT NULL DEFAULT 'hover'"}, // one of options.AnimationPlaybacks
+		{"Options", "AnimationFrameRate", "INTEGER NOT NULL DEFAULT 0"},
+		{"Options", "SlideshowInterval", "INTEGER NOT NULL DEFAULT 5"},
+		{"Options", "SlideshowShuffle", "BOOLEAN NOT NULL DEFAULT false"},
+		{"Options", "SlideshowLoop", "BOOLEAN NOT NULL DEFAULT true"},
+		{"Options", "SlideshowTransition", "VARCHAR(16) NOT NULL DEFAULT 'fade'"}, // one of options.SlideshowTransitions
+		{"Options", "SlideshowInfo", "BOOLEAN NOT NULL DEFAULT true"},
+	}
+	for _, c := range columns {
+		if err := addColumn(db, c.table, c.column, c.definition); err != nil {
+			appLogger.Fatal("Failed to add column: ", err)
+		}
+	}
+
+	setupMetadata(db)
+	setupEdits(db)
+	setupVideos(db)
+	setupDuplicates(db)
+	setupColors(db)
+	setupSearch(db)
+	setupSavedSearches(db)
+}
+
+// AVI files were listed as images before videos got their own media type, their
+// metadata is read again for the video details
+func setupVideos(db *sql.DB) {
+	tx, err := db.Begin()
+	if err != nil {
+		appLogger.Println("Failed to update media types: ", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// only the metadata of the files reclassified now is dropped, not that of every video
+	const aviImages = "SELECT id FROM File WHERE mediaType = ? AND lower(path) LIKE '%.avi'"
+	if _, err := tx.Exec("DELETE FROM FileMetadata WHERE fileId IN ("+aviImages+");", fileutils.ImageMedia); err != nil {
+		appLogger.Println("Failed to update media types: ", err)
+		return
+	}
+	result, err := tx.Exec("UPDATE File SET mediaType = ? WHERE id IN ("+aviImages+");", fileutils.VideoMedia, fileutils.ImageMedia)
+	if err != nil {
+		appLogger.Println("Failed to update media types: ", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		appLogger.Println("Failed to update media types: ", err)
+		return
+	}
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		appLogger.Println("Marked ", updated, " AVI files as videos")
+	}
+}
+
+// Returns the column names of a table
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(`%s`);", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// Adds a column to a table if the table doesn't have it yet
+func addColumn(db *sql.DB, table string, column string, definition string) error {
+	columns, err := tableColumns(db, table)
+	if err != nil {
+		return err
+	}
+	if columns[column] {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s;", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("error adding column %s.%s: %w", table, column, err)
+	}
+	appLogger.Printf("Added column %s.%s", table, column)
+	return nil
 }
 
 func VacuumDb(db *sql.DB) error {
@@ -104,23 +218,75 @@ func GetImageCount(db *sql.DB) int {
 	return imgCount
 }
 
-func GetImagesFromDatabase(db *sql.DB, page int, imageCount uint) ([]string, error) {
-	images, err := db.Query("SELECT path FROM File ORDER BY name DESC LIMIT ?,?", page, imageCount)
+// Cursor marks the last image of a loaded page so the next page continues right after it.
+// The zero value starts from the first page.
+type Cursor struct {
+	Key string // sort column value of the last loaded image
+	Id  int    // id of the last loaded image, breaks ties between equal keys
+}
+
+// Maps the sort options from the filter window to File columns
+func sortColumn(orderBy string) string {
+	switch orderBy {
+	case "Date Added":
+		return "dateAdded"
+	default:
+		return "name"
+	}
+}
+
+// Returns the query for the page of images after the cursor and its arguments
+func pageQuery(orderBy string, desc bool, after Cursor, imageCount uint) (string, []any) {
+	column := sortColumn(orderBy)
+	direction, compare := "ASC", ">"
+	if desc {
+		direction, compare = "DESC", "<"
+	}
+
+	// the first page has no cursor to compare with, an OR to skip the comparison
+	// would stop SQLite from seeking to the cursor in the index
+	if after.Id == 0 {
+		query := fmt.Sprintf("SELECT id, path, CAST(%[1]s AS TEXT) FROM File ORDER BY %[1]s %[2]s, id %[2]s LIMIT ?", column, direction)
+		return query, []any{imageCount}
+	}
+	query := fmt.Sprintf(
+		"SELECT id, path, CAST(%[1]s AS TEXT) FROM File WHERE (%[1]s, id) %[2]s (?, ?) ORDER BY %[1]s %[3]s, id %[3]s LIMIT ?",
+		column, compare, direction,
+	)
+	return query, []any{after.Key, after.Id, imageCount}
+}
+
+// Gets the next page of images after the cursor using keyset pagination,
+// returns the image paths and the cursor to pass in for the page after it
+func GetImagesFromDatabase(db *sql.DB, orderBy string, desc bool, after Cursor, imageCount uint) ([]string, Cursor, error) {
+	query, args := pageQuery(orderBy, desc, after, imageCount)
+	images, err := db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, after, err
 	}
 	defer images.Close()
 
+	next := after
 	var imagePaths []string
 	for images.Next() {
 		var path string
-		if err := images.Scan(&path); err != nil {
-			return nil, err
+		if err := images.Scan(&next.Id, &path, &next.Key); err != nil {
+			return nil, after, err
 		}
 		imagePaths = append(imagePaths, path)
 	}
 
-	return imagePaths, nil
+	if err := images.Err(); err != nil {
+		return nil, after, err
+	}
+
+	return imagePaths, next, nil
+}
+
+// Returns every image in the order GetImagesFromDatabase pages through them
+func GetAllImagesFromDatabase(db *sql.DB, orderBy string, desc bool) ([]string, error) {
+	paths, _, err := GetImagesFromDatabase(db, orderBy, desc, Cursor{}, math.MaxInt32)
+	return paths, err
 }
 
 func GetImageId(db *sql.DB, path string) int {
@@ -133,6 +299,13 @@ func GetImageId(db *sql.DB, path string) int {
 	return imageId
 }
 
+// Returns the MD5 hash of the content of a file in the library
+func GetFileMD5(db *sql.DB, path string) (string, error) {
+	var hash string
+	err := db.QueryRow("SELECT md5 FROM File WHERE path = ?", path).Scan(&hash)
+	return hash, err
+}
+
 func GetDate(db *sql.DB, path string) string {
 	var date string
 	err := db.QueryRow("SELECT STRFTIME('%H:%M %d-%m-%Y', DATETIME(dateAdded, '+3 HOURS')) FROM File WHERE path = ?", path).Scan(&date)
@@ -143,11 +316,94 @@ func GetDate(db *sql.DB, path string) string {
 	return date
 }
 
+// Title, description and notes written by the user for a file
+type FileNotes struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Notes       string `json:"notes"`
+}
+
+// Returns true if no notes have been written for the file
+func (n FileNotes) IsEmpty() bool {
+	return n.Title == "" && n.Description == "" && n.Notes == ""
+}
+
+func GetFileNotes(db *sql.DB, fileId int) (FileNotes, error) {
+	var notes FileNotes
+	err := db.QueryRow("SELECT title, description, notes FROM File WHERE id = ?", fileId).Scan(&notes.Title, &notes.Description, &notes.Notes)
+	return notes, err
+}
+
+func SetFileNotes(db *sql.DB, fileId int, notes FileNotes) error {
+	_, err := db.Exec("UPDATE File SET title = ?, description = ?, notes = ? WHERE id = ?", notes.Title, notes.Description, notes.Notes, fileId)
+	return err
+}
+
+// Returns the notes of every listed file that has any, keyed by file path
+func GetNotesByPaths(db *sql.DB, paths []string) (map[string]FileNotes, error) {
+	stmt, err := db.Prepare("SELECT title, description, notes FROM File WHERE path = ?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	notesByPath := map[string]FileNotes{}
+	for _, path := range paths {
+		var notes FileNotes
+		err := stmt.QueryRow(path).Scan(&notes.Title, &notes.Description, &notes.Notes)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !notes.IsEmpty() {
+			notesByPath[path] = notes
+		}
+	}
+	return notesByPath, nil
+}
+
+// Rating (0-5), favorite flag and color label of a file
+type FileMarks struct {
+	Rating   int
+	Favorite bool
+	Label    string // one of colorutils.LabelNames or empty
+}
+
+func GetFileMarks(db *sql.DB, path string) (FileMarks, error) {
+	var marks FileMarks
+	err := db.QueryRow("SELECT rating, favorite, label FROM File WHERE path = ?", path).Scan(&marks.Rating, &marks.Favorite, &marks.Label)
+	return marks, err
+}
+
+func SetRating(db *sql.DB, path string, rating int) error {
+	if rating < 0 || rating > 5 {
+		return fmt.Errorf("rating must be between 0 and 5, got %d", rating)
+	}
+	_, err := db.Exec("UPDATE File SET rating = ? WHERE path = ?", rating, path)
+	return err
+}
+
+func SetFavorite(db *sql.DB, path string, favorite bool) error {
+	_, err := db.Exec("UPDATE File SET favorite = ? WHERE path = ?", favorite, path)
+	return err
+}
+
+func SetLabel(db *sql.DB, path string, label string) error {
+	if _, ok := colorutils.LabelColors[label]; !ok && label != "" {
+		return fmt.Errorf("unknown color label %s", label)
+	}
+	_, err := db.Exec("UPDATE File SET label = ? WHERE path = ?", label, path)
+	return err
+}
+
 func GetImagePathsByTag(db *sql.DB, tagName string) ([]string, error) {
 	query := `SELECT DISTINCT File.path FROM File JOIN FileTag ON File.id = FileTag.fileId JOIN Tag ON FileTag.tagId = Tag.id WHERE Tag.name LIKE ? OR File.name LIKE ?;`
 
 	if tagName == "" || tagName == "%%" {
-		return GetImagesFromDatabase(db, 0, 20)
+		paths, _, err := GetImagesFromDatabase(db, "", true, Cursor{}, 20)
+		return paths, err
 	}
 
 	stmt, err := db.Prepare(query)
@@ -210,7 +466,8 @@ func replaceHomeDir(path string) string {
 	return strings.Replace(path, userHome, "~", 1)
 }
 
-func DiscoverImages(db *sql.DB, blacklist map[string]int) (bool, error) {
+// Adds the files of the media types under the home directory to the library
+func DiscoverImages(db *sql.DB, blacklist map[string]int, mediaTypes []string) (bool, error) {
 	var count int = 0
 
 	appLogger.Println("Discovery started.")
@@ -225,11 +482,7 @@ func DiscoverImages(db *sql.DB, blacklist map[string]int) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	appLogger.Println("Created timeout context")
-	stmt, err := db.PrepareContext(ctx, `
-    INSERT INTO File (path, name, dateAdded, md5) 
-    SELECT ?, ?, DATETIME('now'), ? 
-    WHERE NOT EXISTS (SELECT 1 FROM File WHERE path = ?)
-	`)
+	stmt, err := db.PrepareContext(ctx, insertFileQuery)
 	if err != nil {
 		return false, fmt.Errorf("error preparing SQL statement: %w", err)
 	}
@@ -260,60 +513,20 @@ func DiscoverImages(db *sql.DB, blacklist map[string]int) (bool, error) {
 				appLogger.Println("Skipping hidden/blacklisted directory: ", replaceHomeDir(path))
 				return filepath.SkipDir
 			}
-			if fileutils.IsImageFileMap(path) {
-				// this needs to hash the whole image content not path
-				imageHash, err := fileutils.GetFileMD5HashBuffered(path)
-				if err != nil {
-					return fmt.Errorf("error hashing image: %w", err)
-				}
-
-				// inserts image path into database
-				insertId, err := stmt.Exec(path, strings.Split(filepath.Base(path), ".")[0], imageHash, path)
-				if err != nil {
-					return fmt.Errorf("failed to insert image into database: %w", err)
-				}
-				lastId, _ := insertId.LastInsertId()
-
-				extension := filepath.Ext(path)[1:]
-				extension = strings.ToUpper(extension)
-
-				var extensionId int
-
-				// check if extension is already in database
-				db.QueryRow("SELECT id FROM Tag WHERE name = ?", extension).Scan(&extensionId)
-				if extensionId != 0 {
-					// add extension tag to image
-					db.Exec(`INSERT INTO FileTag (fileId, tagId)
-    				SELECT ?, ?
-    				WHERE NOT EXISTS (
-					SELECT 1 FROM FileTag
-        			WHERE fileId = ? AND tagId = ?
-    				)`, lastId, extensionId, lastId, extensionId)
+			mediaType := ""
+			if !info.IsDir() {
+				mediaType = fileutils.MediaType(path)
+			}
+			if mediaType != "" && slices.Contains(mediaTypes, mediaType) {
+				// files already in the library aren't read again, hashing videos takes a while
+				var known int
+				db.QueryRow("SELECT 1 FROM File WHERE path = ?", path).Scan(&known)
+				if known == 1 {
+					return nil
 				}
 
-				// check if date tag in db
-				var date int
-				db.QueryRow("SELECT id from Tag where name like ?", currentTime+"%").Scan(&date)
-				// var dateExists int
-				// db.QueryRow("SELECT 1 FROM FileTag WHERE fileId = ? AND tagId = ?", lastId, date).Scan(&dateExists)
-				// insert date in db if doesn't exist
-				if date == 0 {
-					dateInsert, _ := db.Exec("INSERT INTO Tag (name, color) VALUES (?, ?)", currentTime, "#373c40")
-					dateId, _ := dateInsert.LastInsertId()
-					if dateId != 0 {
-						// insert date if date tag id is not 0
-						db.Exec(`INSERT INTO FileTag (fileId, tagId)
-						SELECT ?, ?
-						WHERE NOT EXISTS (
-						SELECT 1 FROM FileTag
-						WHERE fileId = ? AND tagId = ?
-						)`, lastId, dateId, lastId, dateId)
-						// db.Exec("INSERT INTO FileTag (fileId, tagId) VALUES (?, ?)", lastId, dateId)
-					}
-				}
-				// if date tag exists add date tag to image
-				if date != 0 {
-					db.Exec("INSERT INTO FileTag (fileId, tagId) VALUES (?, ?)", lastId, date)
+				if err := addFile(db, stmt, path, mediaType); err != nil {
+					return err
 				}
 
 				count++
@@ -330,6 +543,106 @@ func DiscoverImages(db *sql.DB, blacklist map[string]int) (bool, error) {
 	return true, nil
 }
 
+// insertFileQuery adds a file unless its path is already in the library
+const insertFileQuery = `
+    INSERT INTO File (path, name, dateAdded, md5, mediaType) 
+    SELECT ?, ?, DATETIME('now'), ?, ? 
+    WHERE NOT EXISTS (SELECT 1 FROM File WHERE path = ?)
+	`
+
+// Adds one file to the library like discovery does, e.g. a file made by the app
+func AddFile(db *sql.DB, path string) error {
+	mediaType := fileutils.MediaType(path)
+	if mediaType == "" {
+		return fmt.Errorf("%s can't be added to the library", filepath.Base(path))
+	}
+	if GetImageId(db, path) != 0 {
+		return nil
+	}
+	stmt, err := db.Prepare(insertFileQuery)
+	if err != nil {
+		return fmt.Errorf("error preparing SQL statement: %w", err)
+	}
+	defer stmt.Close()
+	return addFile(db, stmt, path, mediaType)
+}
+
+// Hashes a file, inserts it with stmt and gives it its metadata, file type and date added tags
+func addFile(db *sql.DB, stmt *sql.Stmt, path string, mediaType string) error {
+	// this needs to hash the whole image content not path
+	imageHash, err := fileutils.GetFileMD5HashBuffered(path)
+	if err != nil {
+		return fmt.Errorf("error hashing image: %w", err)
+	}
+
+	// inserts image path into database
+	insertId, err := stmt.Exec(path, strings.Split(filepath.Base(path), ".")[0], imageHash, mediaType, path)
+	if err != nil {
+		return fmt.Errorf("failed to insert image into database: %w", err)
+	}
+	lastId, _ := insertId.LastInsertId()
+
+	// reads EXIF and XMP of newly added images and tags them from it
+	if inserted, _ := insertId.RowsAffected(); inserted > 0 {
+		meta, err := ReadFileMetadata(db, int(lastId), path)
+		if err != nil {
+			appLogger.Println("Failed to read metadata: ", err)
+		}
+		if meta != nil {
+			if err := ApplyAutoTags(db, int(lastId), meta); err != nil {
+				appLogger.Println("Failed to add auto tags: ", err)
+			}
+		}
+	}
+
+	extension := fileTypeTag(path)
+
+	var extensionId int
+
+	// documents have too many extensions to add their tags up front
+	if mediaType != fileutils.ImageMedia && mediaType != fileutils.VideoMedia && extension != "" {
+		db.Exec("INSERT INTO Tag (name, color) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM Tag WHERE name = ?)", extension, "#373c40", extension)
+	}
+
+	// check if extension is already in database
+	db.QueryRow("SELECT id FROM Tag WHERE name = ?", extension).Scan(&extensionId)
+	if extensionId != 0 {
+		// add extension tag to image
+		db.Exec(`INSERT INTO FileTag (fileId, tagId)
+		SELECT ?, ?
+		WHERE NOT EXISTS (
+		SELECT 1 FROM FileTag
+		WHERE fileId = ? AND tagId = ?
+		)`, lastId, extensionId, lastId, extensionId)
+	}
+
+	// check if date tag in db
+	var date int
+	db.QueryRow("SELECT id from Tag where name like ?", currentTime+"%").Scan(&date)
+	// var dateExists int
+	// db.QueryRow("SELECT 1 FROM FileTag WHERE fileId = ? AND tagId = ?", lastId, date).Scan(&dateExists)
+	// insert date in db if doesn't exist
+	if date == 0 {
+		dateInsert, _ := db.Exec("INSERT INTO Tag (name, color) VALUES (?, ?)", currentTime, "#373c40")
+		dateId, _ := dateInsert.LastInsertId()
+		if dateId != 0 {
+			// insert date if date tag id is not 0
+			db.Exec(`INSERT INTO FileTag (fileId, tagId)
+			SELECT ?, ?
+			WHERE NOT EXISTS (
+			SELECT 1 FROM FileTag
+			WHERE fileId = ? AND tagId = ?
+			)`, lastId, dateId, lastId, dateId)
+			// db.Exec("INSERT INTO FileTag (fileId, tagId) VALUES (?, ?)", lastId, dateId)
+		}
+	}
+	// if date tag exists add date tag to image
+	if date != 0 {
+		db.Exec("INSERT INTO FileTag (fileId, tagId) VALUES (?, ?)", lastId, date)
+	}
+	return nil
+}
+
 // Add a function to remove a tag from an image
 func RemoveTagFromImage(db *sql.DB, imageId int, tagId int) error {
 	_, err := db.Exec("DELETE FROM FileTag WHERE fileId = ? AND tagId = ?", imageId, tagId)
@@ -359,6 +672,25 @@ func GetTags(db *sql.DB) (map[int]string, error) {
 	return tag, nil
 }
 
+// Returns the names of the tags of a file sorted by name
+func GetFileTagNames(db *sql.DB, fileId int) ([]string, error) {
+	rows, err := db.Query("SELECT Tag.name FROM FileTag INNER JOIN Tag ON FileTag.tagId = Tag.id WHERE FileTag.fileId = ? ORDER BY Tag.name COLLATE NOCASE", fileId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
 func GetTagColorById(db *sql.DB, tagId int) (string, error) {
 	var tagColor string
 	err := db.QueryRow("SELECT color FROM Tag WHERE id = ?", tagId).Scan(&tagColor)
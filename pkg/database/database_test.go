@@ -0,0 +1,137 @@
+package database
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Opens an empty library in memory, a single connection keeps every query on the same database
+func testDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	setupTables(db)
+	return db
+}
+
+func TestGetImagesFromDatabase(t *testing.T) {
+	db := testDB(t)
+	// ids follow the order the rows are inserted in, dates repeat
+	files := []struct{ path, name, dateAdded string }{
+		{"/a/1", "b.jpg", "2024-01-02 00:00:00"},
+		{"/a/2", "a.jpg", "2024-01-03 00:00:00"},
+		{"/a/3", "d.jpg", "2024-01-01 00:00:00"},
+		{"/a/4", "e.jpg", "2024-01-02 00:00:00"},
+		{"/a/5", "c.jpg", "2024-01-02 00:00:00"},
+	}
+	for _, f := range files {
+		if _, err := db.Exec("INSERT INTO File (path, name, md5, dateAdded) VALUES (?, ?, ?, ?)", f.path, f.name, f.path, f.dateAdded); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		orderBy  string
+		desc     bool
+		pageSize uint
+		want     [][]string
+	}{
+		{"name ascending", "Name", false, 2, [][]string{{"/a/2", "/a/1"}, {"/a/5", "/a/3"}, {"/a/4"}}},
+		{"name descending", "Name", true, 2, [][]string{{"/a/4", "/a/3"}, {"/a/5", "/a/1"}, {"/a/2"}}},
+		{"date ascending", "Date Added", false, 2, [][]string{{"/a/3", "/a/1"}, {"/a/4", "/a/5"}, {"/a/2"}}},
+		// the page ends inside the dates that repeat, the id picks up after it
+		{"ties across pages", "Date Added", false, 3, [][]string{{"/a/3", "/a/1", "/a/4"}, {"/a/5", "/a/2"}}},
+		{"date descending", "Date Added", true, 3, [][]string{{"/a/2", "/a/5", "/a/4"}, {"/a/1", "/a/3"}}},
+		{"one page", "Name", false, 5, [][]string{{"/a/2", "/a/1", "/a/5", "/a/3", "/a/4"}}},
+		{"single images", "Date Added", true, 1, [][]string{{"/a/2"}, {"/a/5"}, {"/a/4"}, {"/a/1"}, {"/a/3"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages [][]string
+			var cursor Cursor
+			for range len(files) + 1 {
+				page, next, err := GetImagesFromDatabase(db, tt.orderBy, tt.desc, cursor, tt.pageSize)
+				if !assert.NoError(t, err) || len(page) == 0 {
+					break
+				}
+				pages = append(pages, page)
+				cursor = next
+			}
+			assert.Equal(t, tt.want, pages)
+
+			// the slideshow and the viewer go through every page at once
+			all, err := GetAllImagesFromDatabase(db, tt.orderBy, tt.desc)
+			assert.NoError(t, err)
+			assert.Equal(t, slices.Concat(tt.want...), all)
+		})
+	}
+}
+
+// Pages after the first seek to the cursor in the sort index instead of reading every row before it
+func TestPageQuerySeeks(t *testing.T) {
+	db := testDB(t)
+	tests := []struct {
+		orderBy string
+		desc    bool
+		after   Cursor
+		want    string
+	}{
+		{"Name", false, Cursor{Key: "a.jpg", Id: 1}, "SEARCH File USING INDEX idx_file_name_id (name>?)"},
+		{"Name", true, Cursor{Key: "a.jpg", Id: 1}, "SEARCH File USING INDEX idx_file_name_id (name<?)"},
+		{"Date Added", false, Cursor{Key: "2024-01-01 00:00:00", Id: 1}, "SEARCH File USING INDEX idx_file_date_id (dateAdded>?)"},
+		{"Date Added", true, Cursor{Key: "2024-01-01 00:00:00", Id: 1}, "SEARCH File USING INDEX idx_file_date_id (dateAdded<?)"},
+		{"Name", false, Cursor{}, "SCAN File USING INDEX idx_file_name_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			query, args := pageQuery(tt.orderBy, tt.desc, tt.after, 10)
+			var id, parent, unused int
+			var detail string
+			err := db.QueryRow("EXPLAIN QUERY PLAN "+query, args...).Scan(&id, &parent, &unused, &detail)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, detail)
+			}
+		})
+	}
+}
+
+func TestSetupVideos(t *testing.T) {
+	db := testDB(t)
+	files := []struct {
+		file      testFile
+		mediaType string
+		metadata  bool
+	}{
+		// listed as an image before videos had their own media type
+		{testFile{path: "/videos/old.AVI", taken: "2020-01-01 00:00:00"}, "video", false},
+		{testFile{path: "/videos/clip.mp4", mediaType: "video", taken: "2021-01-01 00:00:00", duration: 30}, "video", true},
+		{testFile{path: "/videos/probed.avi", mediaType: "video", taken: "2021-01-01 00:00:00", duration: 60}, "video", true},
+		{testFile{path: "/photos/cat.jpg", taken: "2022-01-01 00:00:00"}, "image", true},
+		{testFile{path: "/photos/avi.png", taken: "2022-01-01 00:00:00"}, "image", true},
+	}
+	ids := make([]int, len(files))
+	for i, f := range files {
+		ids[i] = addTestFile(t, db, f.file)
+	}
+
+	// runs twice, the second time there is nothing left to reclassify
+	setupVideos(db)
+	setupVideos(db)
+	for i, f := range files {
+		t.Run(f.file.path, func(t *testing.T) {
+			var mediaType string
+			var metadata int
+			db.QueryRow("SELECT mediaType FROM File WHERE id = ?", ids[i]).Scan(&mediaType)
+			db.QueryRow("SELECT COUNT(*) FROM FileMetadata WHERE fileId = ?", ids[i]).Scan(&metadata)
+			assert.Equal(t, f.mediaType, mediaType)
+			assert.Equal(t, f.metadata, metadata == 1)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Date of a file that images can be searched and grouped by
+type DateField int
+
+const (
+	CaptureDate DateField = iota
+	DateAdded
+)
+
+// Returns the SQL expression of the date for a row of File, NULL if the file doesn't have it
+func (f DateField) expression() string {
+	if f == CaptureDate {
+		return "(SELECT captureDate FROM FileMetadata WHERE FileMetadata.fileId = File.id)"
+	}
+	return "File.dateAdded"
+}
+
+// DateRange covers the dates from From up to but not including To,
+// a zero From or To leaves that side open
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Periods a date can be written as, with the start of the period after it
+var periodLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// Parses a year, month or day like 2021, 2021-07 or 2021-07-04 into the range it covers
+func parsePeriod(value string) (DateRange, error) {
+	for _, period := range periodLayouts {
+		if start, err := time.Parse(period.layout, value); err == nil {
+			return DateRange{From: start, To: period.next(start)}, nil
+		}
+	}
+	return DateRange{}, fmt.Errorf("%s is not a date like 2021, 2021-07 or 2021-07-04", value)
+}
+
+// Parses a period like 2021-07 or a range of periods like 2021-01..2021-06,
+// either side of the range can be left out to leave it open
+func ParseDateRange(value string) (DateRange, error) {
+	start, end, isRange := strings.Cut(value, "..")
+	if !isRange {
+		return parsePeriod(value)
+	}
+
+	var r DateRange
+	if start != "" {
+		from, err := parsePeriod(start)
+		if err != nil {
+			return r, err
+		}
+		r.From = from.From
+	}
+	if end != "" {
+		to, err := parsePeriod(end)
+		if err != nil {
+			return r, err
+		}
+		r.To = to.To
+	}
+	if r.From.IsZero() && r.To.IsZero() {
+		return r, fmt.Errorf("a date range needs a start or an end")
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return r, fmt.Errorf("the start of %s is after its end", value)
+	}
+	return r, nil
+}
+
+// Returns the SQL condition that matches the dates of expression inside the range
+func (r DateRange) condition(expression string) (string, []any) {
+	conditions := []string{expression + " IS NOT NULL"}
+	var args []any
+	if !r.From.IsZero() {
+		conditions = append(conditions, expression+" >= ?")
+		args = append(args, r.From.Format(captureDateLayout))
+	}
+	if !r.To.IsZero() {
+		conditions = append(conditions, expression+" < ?")
+		args = append(args, r.To.Format(captureDateLayout))
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")", args
+}
+
+// Search filter for a date, = matches the period, < and > compare with its start and end,
+// e.g. taken:2021-07 taken:2020..2022 added:>=2024-01
+func dateFilter(field DateField) searchFilter {
+	return func(op string, value string) (string, []any, error) {
+		r, err := ParseDateRange(value)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var bounds DateRange
+		switch op {
+		case "=":
+			bounds = r
+		case "!=":
+			condition, args := r.condition(field.expression())
+			return field.expression() + " IS NOT NULL AND NOT " + condition, args, nil
+		case ">", "<=":
+			if r.To.IsZero() {
+				return "", nil, fmt.Errorf("%s has no end to compare with", value)
+			}
+			if op == ">" {
+				bounds.From = r.To
+			} else {
+				bounds.To = r.To
+			}
+		default:
+			if r.From.IsZero() {
+				return "", nil, fmt.Errorf("%s has no start to compare with", value)
+			}
+			if op == ">=" {
+				bounds.From = r.From
+			} else {
+				bounds.To = r.From
+			}
+		}
+
+		condition, args := bounds.condition(field.expression())
+		return condition, args, nil
+	}
+}
+
+// Returns the paths of the images with a date inside the range, oldest first
+func GetImagesByDate(db *sql.DB, field DateField, r DateRange) ([]string, error) {
+	condition, args := r.condition(field.expression())
+	rows, err := db.Query(fmt.Sprintf("SELECT File.path FROM File WHERE %s ORDER BY %s, File.id;", condition, field.expression()), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// A year, month or day of the timeline with the number of images in it
+type TimelinePeriod struct {
+	Key   string // 2021, 2021-07 or 2021-07-04, the same format the date filters take
+	Count int
+}
+
+// Returns the years of the timeline when parent is empty, the months of a year
+// or the days of a month, newest first
+func GetTimeline(db *sql.DB, field DateField, parent string) ([]TimelinePeriod, error) {
+	var format string
+	var r DateRange
+	switch len(parent) {
+	case 0:
+		format = "%Y"
+	case 4:
+		format = "%Y-%m"
+	case 7:
+		format = "%Y-%m-%d"
+	default:
+		// days have no periods inside them
+		return nil, nil
+	}
+	if parent != "" {
+		var err error
+		if r, err = parsePeriod(parent); err != nil {
+			return nil, err
+		}
+	}
+
+	condition, args := r.condition(field.expression())
+	rows, err := db.Query(fmt.Sprintf(
+		"SELECT STRFTIME('%s', %s) AS period, count(File.id) FROM File WHERE %s GROUP BY period ORDER BY period DESC;",
+		format, field.expression(), condition,
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []TimelinePeriod
+	for rows.Next() {
+		var period sql.NullString
+		var count int
+		if err := rows.Scan(&period, &count); err != nil {
+			return nil, err
+		}
+		// dates SQLite can't read have no period
+		if period.Valid {
+			periods = append(periods, TimelinePeriod{Key: period.String, Count: count})
+		}
+	}
+	return periods, rows.Err()
+}
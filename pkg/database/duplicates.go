@@ -0,0 +1,304 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"main/pkg/autotag"
+	"main/pkg/fileutils"
+	"main/pkg/imagecodec"
+	"main/pkg/imagehash"
+	"strings"
+)
+
+// Most bits the hashes of two images can differ in for them to count as similar
+const SimilarDistance = 8
+
+func setupDuplicates(db *sql.DB) {
+	tables := []string{
+		// phash is NULL for files that couldn't be decoded so they aren't hashed again
+		"CREATE TABLE IF NOT EXISTS `FileHash`(`fileId` INTEGER PRIMARY KEY NOT NULL, `phash` INTEGER);",
+		"CREATE INDEX IF NOT EXISTS idx_file_md5 ON File(md5);",
+		"CREATE TRIGGER IF NOT EXISTS file_hash_delete AFTER DELETE ON File BEGIN DELETE FROM FileHash WHERE fileId = old.id; END;",
+	}
+	for _, table := range tables {
+		if _, err := db.Exec(table); err != nil {
+			appLogger.Fatal("Failed to create hash table: ", err)
+		}
+	}
+
+	// Files hashed before histograms were stored get them on the next HashImages
+	if err := addColumn(db, "FileHash", "histogram", "BLOB"); err != nil {
+		appLogger.Fatal("Failed to add column: ", err)
+	}
+}
+
+// Older databases made File.name and File.md5 UNIQUE, so copies of an image and
+// images with the same name in another folder couldn't be added. SQLite can't drop
+// a constraint, so the table is copied into one without them keeping every id
+func migrateFileUniqueness(db *sql.DB) error {
+	var schema string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'File'").Scan(&schema)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	migrated := strings.NewReplacer(
+		"`name` VARCHAR(256) NOT NULL UNIQUE", "`name` VARCHAR(256) NOT NULL",
+		"`md5` VARCHAR(32) NOT NULL UNIQUE", "`md5` VARCHAR(32) NOT NULL",
+	).Replace(schema)
+	if migrated == schema {
+		return nil
+	}
+	migrated = strings.Replace(migrated, "CREATE TABLE `File`", "CREATE TABLE `FileMigration`", 1)
+
+	// the search triggers read File and would stop the rename, setupSearch recreates them
+	for _, trigger := range searchTriggers {
+		if _, err := db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`;", trigger)); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"DROP TABLE IF EXISTS `FileMigration`;",
+		migrated,
+		"INSERT INTO `FileMigration` SELECT * FROM `File`;",
+		"DROP TABLE `File`;",
+		"ALTER TABLE `FileMigration` RENAME TO `File`;",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("error migrating File table: %w", err)
+		}
+	}
+
+	appLogger.Println("Removed UNIQUE from File.name and File.md5")
+	return tx.Commit()
+}
+
+// Computes the perceptual hash, color histogram and palette of every file that
+// doesn't have them yet, returns the number of files that were hashed
+func HashImages(db *sql.DB) (int, error) {
+	// files that couldn't be decoded have no hash and aren't tried again, documents
+	// are only found by their MD5
+	rows, err := db.Query(`SELECT id, path FROM File WHERE mediaType IN (?, ?) AND NOT EXISTS (
+		SELECT 1 FROM FileHash WHERE FileHash.fileId = File.id AND (FileHash.phash IS NULL OR (
+			FileHash.histogram IS NOT NULL AND EXISTS (SELECT 1 FROM FileColor WHERE FileColor.fileId = File.id)
+		))
+	)`, fileutils.ImageMedia, fileutils.VideoMedia)
+	if err != nil {
+		return 0, err
+	}
+
+	// the rows are read first so the connection is free for the inserts
+	type file struct {
+		id   int
+		path string
+	}
+	var files []file
+	for rows.Next() {
+		var f file
+		if err := rows.Scan(&f.id, &f.path); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		files = append(files, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	hashed := 0
+	for _, f := range files {
+		ok, err := hashFile(db, f.id, f.path)
+		if err != nil {
+			return hashed, err
+		}
+		if ok {
+			hashed++
+		}
+	}
+
+	if hashed > 0 {
+		invalidateSimilarIndex()
+	}
+	appLogger.Println("Hashed ", hashed, " files")
+	return hashed, nil
+}
+
+// Decodes a file and stores its perceptual hash, color histogram and palette,
+// false when the file couldn't be decoded
+func hashFile(db *sql.DB, fileId int, path string) (bool, error) {
+	var phash, histogram any
+	img, _, err := imagecodec.DecodeFile(path)
+	if err != nil {
+		appLogger.Println("Failed to hash ", replaceHomeDir(path), ": ", err)
+	} else {
+		// SQLite integers are signed, the bits are kept as they are
+		phash = int64(imagehash.DHash(img))
+		histogram = imagehash.ColorHistogram(img).Bytes()
+	}
+	if _, err := db.Exec("INSERT OR REPLACE INTO FileHash (fileId, phash, histogram) VALUES (?, ?, ?)", fileId, phash, histogram); err != nil {
+		return false, err
+	}
+	if img == nil {
+		return false, nil
+	}
+	return true, SaveImagePalette(db, fileId, img)
+}
+
+// File in a group of duplicates
+type DuplicateFile struct {
+	Id   int
+	Path string
+	MD5  string
+}
+
+// Files with the same content, or when Exact is false, files that look alike
+type DuplicateGroup struct {
+	Exact bool
+	Files []DuplicateFile
+}
+
+// Returns the groups of files with the same content or with perceptual hashes at
+// most maxDistance bits apart, exact groups first. A negative maxDistance only
+// groups exact copies. Every pair of hashes is compared so it takes a moment on
+// big libraries
+func GetDuplicateGroups(db *sql.DB, maxDistance int) ([]DuplicateGroup, error) {
+	rows, err := db.Query("SELECT File.id, File.path, File.md5, FileHash.phash FROM File LEFT JOIN FileHash ON FileHash.fileId = File.id ORDER BY File.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []hashedFile
+	for rows.Next() {
+		var f hashedFile
+		if err := rows.Scan(&f.Id, &f.Path, &f.MD5, &f.hash); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groupDuplicates(files, maxDistance), nil
+}
+
+// File with its perceptual hash, which is NULL for files that couldn't be decoded
+type hashedFile struct {
+	DuplicateFile
+	hash sql.NullInt64
+}
+
+// Groups copies of a file by their md5, then groups the copies with others that look
+// alike. Each similar group is built around its first file and the others must be
+// close to that one, so A close to B and B close to C doesn't put A and C together
+func groupDuplicates(files []hashedFile, maxDistance int) []DuplicateGroup {
+	var copies [][]hashedFile
+	copyIndex := map[string]int{}
+	for _, f := range files {
+		i, ok := copyIndex[f.MD5]
+		if !ok {
+			i = len(copies)
+			copyIndex[f.MD5] = i
+			copies = append(copies, nil)
+		}
+		copies[i] = append(copies[i], f)
+	}
+
+	grouped := make([]bool, len(copies))
+	var exactGroups, similarGroups []DuplicateGroup
+	for i, representative := range copies {
+		if grouped[i] {
+			continue
+		}
+		grouped[i] = true
+		members := representative
+		if maxDistance >= 0 && representative[0].hash.Valid {
+			for j := i + 1; j < len(copies); j++ {
+				other := copies[j][0]
+				if !grouped[j] && other.hash.Valid && imagehash.Distance(uint64(representative[0].hash.Int64), uint64(other.hash.Int64)) <= maxDistance {
+					grouped[j] = true
+					members = append(members, copies[j]...)
+				}
+			}
+		}
+		if len(members) < 2 {
+			continue
+		}
+
+		group := DuplicateGroup{Exact: len(members) == len(representative)}
+		for _, f := range members {
+			group.Files = append(group.Files, f.DuplicateFile)
+		}
+		if group.Exact {
+			exactGroups = append(exactGroups, group)
+		} else {
+			similarGroups = append(similarGroups, group)
+		}
+	}
+	return append(exactGroups, similarGroups...)
+}
+
+// Removes a file from the library together with its tags, the file on disk is left alone
+func DeleteFile(db *sql.DB, fileId int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM FileTag WHERE fileId = ?", fileId); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM File WHERE id = ?", fileId); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	invalidateSimilarIndex()
+	return nil
+}
+
+// Gives every file the tags any of the files has. Auto tags and meta tags like the
+// file type and date added describe one file so they aren't copied
+func MergeTags(db *sql.DB, fileIds []int) error {
+	if len(fileIds) < 2 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(fileIds)), ", ")
+	ids := make([]any, len(fileIds))
+	for i, id := range fileIds {
+		ids[i] = id
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, fileId := range fileIds {
+		args := append([]any{fileId, autotag.TagColor}, ids...)
+		args = append(args, fileId)
+		_, err := tx.Exec(fmt.Sprintf(`INSERT INTO FileTag (fileId, tagId)
+		SELECT DISTINCT ?, FileTag.tagId FROM FileTag JOIN Tag ON Tag.id = FileTag.tagId
+		WHERE FileTag.auto = false AND Tag.color != ? AND FileTag.fileId IN (%s)
+		AND FileTag.tagId NOT IN (SELECT tagId FROM FileTag WHERE fileId = ?)`, placeholders), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
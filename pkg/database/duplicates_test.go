@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Schema of the File table before name and md5 could repeat
+const oldFileTable = "CREATE TABLE `File`(`id` INTEGER PRIMARY KEY NOT NULL, `path` VARCHAR(1024) NOT NULL UNIQUE, `name` VARCHAR(256) NOT NULL UNIQUE, `md5` VARCHAR(32) NOT NULL UNIQUE, `dateAdded` DATETIME NOT NULL);"
+
+func TestMigrateFileUniqueness(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"no File table", ""},
+		{"old schema", oldFileTable},
+		{"already migrated", "CREATE TABLE `File`(`id` INTEGER PRIMARY KEY NOT NULL, `path` VARCHAR(1024) NOT NULL UNIQUE, `name` VARCHAR(256) NOT NULL, `md5` VARCHAR(32) NOT NULL, `dateAdded` DATETIME NOT NULL);"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			db.SetMaxOpenConns(1)
+			defer db.Close()
+			if tt.schema != "" {
+				db.Exec(tt.schema)
+				// ids left by deleted files are kept
+				db.Exec("INSERT INTO File (id, path, name, md5, dateAdded) VALUES (3, '/a/cat.jpg', 'cat.jpg', 'abc', '2024-01-01'), (7, '/a/dog.jpg', 'dog.jpg', 'def', '2024-01-02')")
+			}
+
+			// runs twice, the second time there is nothing left to change
+			assert.NoError(t, migrateFileUniqueness(db))
+			assert.NoError(t, migrateFileUniqueness(db))
+			if tt.schema == "" {
+				var tables int
+				db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'File'").Scan(&tables)
+				assert.Zero(t, tables)
+				return
+			}
+
+			var ids []int
+			rows, err := db.Query("SELECT id FROM File ORDER BY id")
+			if assert.NoError(t, err) {
+				for rows.Next() {
+					var id int
+					rows.Scan(&id)
+					ids = append(ids, id)
+				}
+				rows.Close()
+			}
+			assert.Equal(t, []int{3, 7}, ids)
+
+			// a copy in another folder has the same name and md5
+			_, err = db.Exec("INSERT INTO File (path, name, md5, dateAdded) VALUES ('/b/cat.jpg', 'cat.jpg', 'abc', '2024-01-03')")
+			assert.NoError(t, err)
+			_, err = db.Exec("INSERT INTO File (path, name, md5, dateAdded) VALUES ('/b/cat.jpg', 'cat.jpg', 'abc', '2024-01-03')")
+			assert.Error(t, err, "paths stay unique")
+		})
+	}
+}
+
+// Older databases are migrated and get the tables and search triggers back
+func TestSetupTablesMigratesOldFileTable(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	db.Exec(oldFileTable)
+	db.Exec("INSERT INTO File (id, path, name, md5, dateAdded) VALUES (5, '/a/cat.jpg', 'cat.jpg', 'abc', '2024-01-01')")
+
+	setupTables(db)
+	addTestFile(t, db, testFile{path: "/b/cat.jpg", tags: []string{"cat"}})
+	paths, err := SearchImages(db, "cat")
+	assert.NoError(t, err)
+	assert.Contains(t, paths, "/b/cat.jpg")
+
+	var title string
+	assert.NoError(t, db.QueryRow("SELECT title FROM File WHERE id = 5").Scan(&title))
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	file := func(id int, md5 string, hash int64) hashedFile {
+		return hashedFile{DuplicateFile: DuplicateFile{Id: id, Path: fmt.Sprintf("/photos/%d.jpg", id), MD5: md5}, hash: sql.NullInt64{Int64: hash, Valid: true}}
+	}
+	unhashed := func(id int, md5 string) hashedFile {
+		return hashedFile{DuplicateFile: DuplicateFile{Id: id, Path: fmt.Sprintf("/photos/%d.jpg", id), MD5: md5}}
+	}
+	type group struct {
+		exact bool
+		ids   []int
+	}
+
+	tests := []struct {
+		name        string
+		files       []hashedFile
+		maxDistance int
+		want        []group
+	}{
+		{"no files", nil, SimilarDistance, nil},
+		{"copies", []hashedFile{file(1, "a", 0), file(2, "b", 0xF0F0), file(3, "a", 0)}, -1, []group{{true, []int{1, 3}}}},
+		{"copies without hashes", []hashedFile{unhashed(1, "a"), unhashed(2, "a"), unhashed(3, "b")}, SimilarDistance, []group{{true, []int{1, 2}}}},
+		{"similar at the distance", []hashedFile{file(1, "a", 0), file(2, "b", 0xFF)}, 8, []group{{false, []int{1, 2}}}},
+		{"one bit past the distance", []hashedFile{file(1, "a", 0), file(2, "b", 0x1FF)}, 8, nil},
+		{"similar ignored for copies only", []hashedFile{file(1, "a", 0), file(2, "b", 0)}, -1, nil},
+		// 2 is close to both, 1 and 3 are 16 bits apart so 3 isn't grouped with them
+		{"no chaining", []hashedFile{file(1, "a", 0), file(2, "b", 0xFF), file(3, "c", 0xFFFF)}, 8, []group{{false, []int{1, 2}}}},
+		{"chain starts a new group", []hashedFile{file(1, "a", 0), file(2, "b", 0xFF), file(3, "c", 0xFFFF), file(4, "d", 0xFFFFF)}, 8,
+			[]group{{false, []int{1, 2}}, {false, []int{3, 4}}}},
+		{"copies join a similar group together", []hashedFile{file(1, "a", 0), file(2, "b", 0x3), file(3, "b", 0x3), file(4, "c", 0x1FF000)}, 8,
+			[]group{{false, []int{1, 2, 3}}}},
+		{"exact groups first", []hashedFile{file(1, "a", 0), file(2, "b", 0x1), file(3, "c", 0xFFFF0000), file(4, "c", 0xFFFF0000)}, 8,
+			[]group{{true, []int{3, 4}}, {false, []int{1, 2}}}},
+		{"unhashed files aren't similar", []hashedFile{unhashed(1, "a"), file(2, "b", 0), unhashed(3, "c")}, 64, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []group
+			for _, g := range groupDuplicates(tt.files, tt.maxDistance) {
+				var ids []int
+				for _, f := range g.Files {
+					ids = append(ids, f.Id)
+				}
+				got = append(got, group{g.Exact, ids})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"database/sql"
+	"main/pkg/imagetransform"
+)
+
+func setupEdits(db *sql.DB) {
+	tables := []string{
+		// crop is in fractions of the rotated image, width and height are 0 when the image isn't resized
+		"CREATE TABLE IF NOT EXISTS `FileEdit`(`fileId` INTEGER PRIMARY KEY NOT NULL, `rotation` INTEGER NOT NULL DEFAULT 0, `flipH` BOOLEAN NOT NULL DEFAULT false, `flipV` BOOLEAN NOT NULL DEFAULT false, `cropX` REAL NOT NULL DEFAULT 0, `cropY` REAL NOT NULL DEFAULT 0, `cropW` REAL NOT NULL DEFAULT 0, `cropH` REAL NOT NULL DEFAULT 0, `width` INTEGER NOT NULL DEFAULT 0, `height` INTEGER NOT NULL DEFAULT 0);",
+		// edits go away together with their file
+		"CREATE TRIGGER IF NOT EXISTS file_edit_delete AFTER DELETE ON File BEGIN DELETE FROM FileEdit WHERE fileId = old.id; END;",
+	}
+	for _, table := range tables {
+		if _, err := db.Exec(table); err != nil {
+			appLogger.Fatal("Failed to create edit table: ", err)
+		}
+	}
+}
+
+// Returns the edit recipe of a file, the zero edit if it has none
+func GetFileEdit(db *sql.DB, fileId int) (imagetransform.Edit, error) {
+	var e imagetransform.Edit
+	err := db.QueryRow("SELECT rotation, flipH, flipV, cropX, cropY, cropW, cropH, width, height FROM FileEdit WHERE fileId = ?", fileId).
+		Scan(&e.Rotation, &e.FlipH, &e.FlipV, &e.Crop.X, &e.Crop.Y, &e.Crop.W, &e.Crop.H, &e.Width, &e.Height)
+	if err == sql.ErrNoRows {
+		return imagetransform.Edit{}, nil
+	}
+	return e, err
+}
+
+// Saves the edit recipe of a file, the zero edit removes it
+func SetFileEdit(db *sql.DB, fileId int, e imagetransform.Edit) error {
+	if e.IsZero() {
+		_, err := db.Exec("DELETE FROM FileEdit WHERE fileId = ?", fileId)
+		return err
+	}
+	_, err := db.Exec(`INSERT OR REPLACE INTO FileEdit (fileId, rotation, flipH, flipV, cropX, cropY, cropW, cropH, width, height)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fileId, e.Rotation, e.FlipH, e.FlipV, e.Crop.X, e.Crop.Y, e.Crop.W, e.Crop.H, e.Width, e.Height)
+	return err
+}
+
+// Copies the tags added by hand from one file to another, e.g. to an edited copy
+func CopyManualTags(db *sql.DB, fromId int, toId int) error {
+	_, err := db.Exec(`INSERT INTO FileTag (fileId, tagId)
+	SELECT ?, tagId FROM FileTag WHERE fileId = ? AND auto = false
+	AND tagId NOT IN (SELECT tagId FROM FileTag WHERE fileId = ?)`, toId, fromId, toId)
+	return err
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"main/pkg/imagecodec"
+	"main/pkg/imageconv"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Returns the name of the file type tag of an image like JPG, told by its content
+// so a PNG saved as .jpg is tagged PNG
+func fileTypeTag(path string) string {
+	if format, err := imagecodec.DetectFile(path); err == nil {
+		return format.Name
+	}
+	return strings.ToUpper(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// Image whose extension doesn't match the format of its content
+type ExtensionMismatch struct {
+	Id     int
+	Path   string
+	Format *imagecodec.Format // the format the content is in
+}
+
+// Returns the path the image gets once its extension is fixed
+func (m ExtensionMismatch) FixedPath() string {
+	return strings.TrimSuffix(m.Path, filepath.Ext(m.Path)) + m.Format.Extensions[0]
+}
+
+// Returns the format of an image and true if its extension belongs to another format,
+// an uppercase extension like .JPG is not a mismatch. Raw camera files are TIFF files
+// not every camera is recognised in, so their extension is never a mismatch
+func CheckExtension(path string) (*imagecodec.Format, bool) {
+	format, err := imagecodec.DetectFile(path)
+	if err != nil {
+		return nil, false
+	}
+	ext := filepath.Ext(path)
+	if current, ok := imagecodec.ByExtension(ext); ok && (current.Name == "RAW" || current.Name == "DNG") {
+		return format, false
+	}
+	return format, !format.HasExtension(ext)
+}
+
+// Returns every image in the library with an extension that doesn't match its content
+func GetExtensionMismatches(db *sql.DB) ([]ExtensionMismatch, error) {
+	rows, err := db.Query("SELECT id, path FROM File ORDER BY path")
+	if err != nil {
+		return nil, err
+	}
+	var files []ExtensionMismatch
+	for rows.Next() {
+		var file ExtensionMismatch
+		if err := rows.Scan(&file.Id, &file.Path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var mismatches []ExtensionMismatch
+	for _, file := range files {
+		if format, mismatch := CheckExtension(file.Path); mismatch {
+			file.Format = format
+			mismatches = append(mismatches, file)
+		}
+	}
+	return mismatches, nil
+}
+
+// Renames an image to the extension of its format and updates its path and file
+// type tag, returns the new path
+func FixExtension(db *sql.DB, mismatch ExtensionMismatch) (string, error) {
+	newPath := mismatch.FixedPath()
+	if _, err := os.Stat(newPath); err == nil {
+		return "", fmt.Errorf("can't rename %s, %s already exists", filepath.Base(mismatch.Path), filepath.Base(newPath))
+	}
+	if err := os.Rename(mismatch.Path, newPath); err != nil {
+		return "", err
+	}
+	if _, err := db.Exec("UPDATE File SET path = ? WHERE id = ?", newPath, mismatch.Id); err != nil {
+		// the file is put back so the library still points at it
+		os.Rename(newPath, mismatch.Path)
+		return "", err
+	}
+	return newPath, setFileTypeTag(db, mismatch.Id, mismatch.Format.Name)
+}
+
+// Renames every image with a mismatched extension, returns the number of renamed
+// images and the first error, the other images are still renamed after an error
+func FixExtensions(db *sql.DB, mismatches []ExtensionMismatch) (int, error) {
+	var firstErr error
+	fixed := 0
+	for _, mismatch := range mismatches {
+		if _, err := FixExtension(db, mismatch); err != nil {
+			appLogger.Println("Failed to fix extension: ", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		fixed++
+	}
+	return fixed, firstErr
+}
+
+// Replaces the file type tag of a file, e.g. JPG with PNG
+func setFileTypeTag(db *sql.DB, fileId int, typeName string) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(imageconv.ImageTypes)), ", ")
+	args := []any{fileId}
+	for _, imageType := range imageconv.ImageTypes {
+		args = append(args, imageType)
+	}
+	_, err := db.Exec("DELETE FROM FileTag WHERE fileId = ? AND tagId IN (SELECT id FROM Tag WHERE name IN ("+placeholders+"))", args...)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO FileTag (fileId, tagId)
+		SELECT ?, id FROM Tag WHERE name = ?
+		AND NOT EXISTS (SELECT 1 FROM FileTag WHERE fileId = ? AND tagId = Tag.id)`, fileId, typeName, fileId)
+	return err
+}
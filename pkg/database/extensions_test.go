@@ -0,0 +1,147 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"main/pkg/imagecodec"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/tiff"
+)
+
+// Writes a 2x2 image in the format to the path
+func writeTestImage(t *testing.T, path string, encode func(*bytes.Buffer, image.Image) error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func encodePNG(buf *bytes.Buffer, img image.Image) error  { return png.Encode(buf, img) }
+func encodeJPEG(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }
+func encodeTIFF(buf *bytes.Buffer, img image.Image) error { return tiff.Encode(buf, img, nil) }
+
+// Returns the names of the tags of a file
+func fileTags(t *testing.T, db *sql.DB, id int) []string {
+	rows, err := db.Query("SELECT Tag.name FROM FileTag JOIN Tag ON Tag.id = FileTag.tagId WHERE fileId = ? ORDER BY Tag.name", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var tag string
+		rows.Scan(&tag)
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func TestGetExtensionMismatches(t *testing.T) {
+	db := testDB(t)
+	dir := t.TempDir()
+	files := []struct {
+		name   string
+		encode func(*bytes.Buffer, image.Image) error
+	}{
+		{"png.jpg", encodePNG},
+		{"jpeg.jpg", encodeJPEG},
+		{"upper.PNG", encodePNG},
+		{"jpeg.png", encodeJPEG},
+		// a raw file of a camera that isn't recognised looks like a TIFF
+		{"camera.nef", encodeTIFF},
+		{"camera.dng", encodeTIFF},
+		{"scan.tif", encodeTIFF},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		writeTestImage(t, path, f.encode)
+		addTestFile(t, db, testFile{path: path})
+	}
+	// files that are gone aren't mismatches
+	addTestFile(t, db, testFile{path: filepath.Join(dir, "missing.jpg")})
+
+	mismatches, err := GetExtensionMismatches(db)
+	if assert.NoError(t, err) {
+		var got []string
+		for _, m := range mismatches {
+			got = append(got, filepath.Base(m.Path)+" "+m.Format.Name+" "+filepath.Base(m.FixedPath()))
+		}
+		assert.Equal(t, []string{"jpeg.png JPG jpeg.jpg", "png.jpg PNG png.png"}, got)
+	}
+}
+
+func TestFixExtension(t *testing.T) {
+	db := testDB(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.jpg")
+	writeTestImage(t, path, encodePNG)
+	id := addTestFile(t, db, testFile{path: path, tags: []string{"JPG", "beach"}})
+	db.Exec("INSERT INTO Tag (name, color) VALUES ('PNG', '#373c40')")
+
+	mismatches, err := GetExtensionMismatches(db)
+	if !assert.NoError(t, err) || !assert.Len(t, mismatches, 1) {
+		return
+	}
+	newPath, err := FixExtension(db, mismatches[0])
+	if assert.NoError(t, err) {
+		assert.Equal(t, filepath.Join(dir, "photo.png"), newPath)
+		assert.NoFileExists(t, path)
+		assert.FileExists(t, newPath)
+		var dbPath string
+		db.QueryRow("SELECT path FROM File WHERE id = ?", id).Scan(&dbPath)
+		assert.Equal(t, newPath, dbPath)
+		assert.Equal(t, []string{"PNG", "beach"}, fileTags(t, db, id))
+	}
+}
+
+func TestFixExtensionFails(t *testing.T) {
+	t.Run("name taken", func(t *testing.T) {
+		db := testDB(t)
+		dir := t.TempDir()
+		path := filepath.Join(dir, "photo.jpg")
+		writeTestImage(t, path, encodePNG)
+		writeTestImage(t, filepath.Join(dir, "photo.png"), encodeJPEG)
+		id := addTestFile(t, db, testFile{path: path})
+
+		_, err := FixExtension(db, ExtensionMismatch{Id: id, Path: path, Format: mustCheckExtension(t, path)})
+		assert.ErrorContains(t, err, "already exists")
+		assert.FileExists(t, path)
+	})
+
+	t.Run("database fails", func(t *testing.T) {
+		db := testDB(t)
+		dir := t.TempDir()
+		path := filepath.Join(dir, "photo.jpg")
+		writeTestImage(t, path, encodePNG)
+		id := addTestFile(t, db, testFile{path: path})
+		if _, err := db.Exec("CREATE TRIGGER no_rename BEFORE UPDATE OF path ON File BEGIN SELECT RAISE(ABORT, 'read only'); END"); err != nil {
+			t.Fatal(err)
+		}
+
+		mismatch := ExtensionMismatch{Id: id, Path: path, Format: mustCheckExtension(t, path)}
+		_, err := FixExtension(db, mismatch)
+		assert.ErrorContains(t, err, "read only")
+		// the file is put back where the library has it
+		assert.FileExists(t, path)
+		assert.NoFileExists(t, mismatch.FixedPath())
+	})
+}
+
+// Returns the format of a file that has the wrong extension
+func mustCheckExtension(t *testing.T, path string) *imagecodec.Format {
+	format, mismatch := CheckExtension(path)
+	if !mismatch {
+		t.Fatalf("%s has the right extension", path)
+	}
+	return format
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"main/pkg/metadata"
+	"time"
+)
+
+// Format capture dates are stored in, the same one SQLite uses for DATETIME
+const captureDateLayout = "2006-01-02 15:04:05"
+
+func setupMetadata(db *sql.DB) {
+	tables := []string{
+		"CREATE TABLE IF NOT EXISTS `FileMetadata`(`fileId` INTEGER PRIMARY KEY NOT NULL, `captureDate` DATETIME, `make` VARCHAR(255) NOT NULL DEFAULT '', `model` VARCHAR(255) NOT NULL DEFAULT '', `lens` VARCHAR(255) NOT NULL DEFAULT '', `exposureTime` VARCHAR(32) NOT NULL DEFAULT '', `fNumber` REAL NOT NULL DEFAULT 0, `iso` INTEGER NOT NULL DEFAULT 0, `focalLength` REAL NOT NULL DEFAULT 0, `width` INTEGER NOT NULL DEFAULT 0, `height` INTEGER NOT NULL DEFAULT 0, `orientation` INTEGER NOT NULL DEFAULT 0, `fields` TEXT NOT NULL DEFAULT '{}');",
+		"CREATE INDEX IF NOT EXISTS idx_metadata_capture_date ON FileMetadata(captureDate);",
+		// metadata goes away together with its file
+		"CREATE TRIGGER IF NOT EXISTS file_metadata_delete AFTER DELETE ON File BEGIN DELETE FROM FileMetadata WHERE fileId = old.id; END;",
+	}
+	for _, table := range tables {
+		if _, err := db.Exec(table); err != nil {
+			appLogger.Fatal("Failed to create metadata table: ", err)
+		}
+	}
+
+	// Columns added after the table was first released
+	columns := []struct{ column, definition string }{
+		{"hasGPS", "BOOLEAN NOT NULL DEFAULT false"},
+		{"latitude", "REAL"}, // NULL without GPS
+		{"longitude", "REAL"},
+		{"duration", "REAL NOT NULL DEFAULT 0"}, // in seconds, 0 for images
+		{"videoCodec", "VARCHAR(32) NOT NULL DEFAULT ''"},
+		{"audioCodec", "VARCHAR(32) NOT NULL DEFAULT ''"},
+	}
+	for _, c := range columns {
+		if err := addColumn(db, "FileMetadata", c.column, c.definition); err != nil {
+			appLogger.Fatal("Failed to add column: ", err)
+		}
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_metadata_location ON FileMetadata(latitude, longitude);"); err != nil {
+		appLogger.Fatal("Failed to create metadata index: ", err)
+	}
+}
+
+// Saves the metadata read from a file, replacing what was stored for it before
+func SaveFileMetadata(db *sql.DB, fileId int, m *metadata.Metadata) error {
+	fields, err := json.Marshal(m.Fields)
+	if err != nil {
+		return fmt.Errorf("error marshaling metadata fields: %w", err)
+	}
+
+	var captureDate, latitude, longitude any
+	if !m.CaptureDate.IsZero() {
+		captureDate = m.CaptureDate.Format(captureDateLayout)
+	}
+	if m.HasGPS {
+		latitude, longitude = m.Latitude, m.Longitude
+	}
+
+	_, err = db.Exec(`
+	INSERT OR REPLACE INTO FileMetadata (fileId, captureDate, make, model, lens, exposureTime, fNumber, iso, focalLength, width, height, orientation, hasGPS, latitude, longitude, duration, videoCodec, audioCodec, fields)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fileId, captureDate, m.Make, m.Model, m.Lens, m.ExposureTime, m.FNumber, m.ISO, m.FocalLength, m.Width, m.Height, m.Orientation, m.HasGPS, latitude, longitude, m.Duration.Seconds(), m.VideoCodec, m.AudioCodec, string(fields),
+	)
+	return err
+}
+
+// Returns the stored metadata of a file, sql.ErrNoRows if it hasn't been read yet
+func GetStoredMetadata(db *sql.DB, fileId int) (*metadata.Metadata, error) {
+	m := &metadata.Metadata{}
+	var captureDate sql.NullString
+	var latitude, longitude sql.NullFloat64
+	var duration float64
+	var fields string
+	err := db.QueryRow(`
+	SELECT captureDate, make, model, lens, exposureTime, fNumber, iso, focalLength, width, height, orientation, hasGPS, latitude, longitude, duration, videoCodec, audioCodec, fields
+	FROM FileMetadata WHERE fileId = ?`, fileId).Scan(
+		&captureDate, &m.Make, &m.Model, &m.Lens, &m.ExposureTime, &m.FNumber, &m.ISO, &m.FocalLength, &m.Width, &m.Height, &m.Orientation, &m.HasGPS, &latitude, &longitude, &duration, &m.VideoCodec, &m.AudioCodec, &fields,
+	)
+	if err != nil {
+		return nil, err
+	}
+	m.Latitude, m.Longitude = latitude.Float64, longitude.Float64
+	m.Duration = time.Duration(duration * float64(time.Second))
+
+	if captureDate.Valid {
+		// the sqlite driver may hand DATETIME columns back in RFC 3339
+		for _, layout := range []string{captureDateLayout, time.RFC3339} {
+			if date, err := time.Parse(layout, captureDate.String); err == nil {
+				m.CaptureDate = date
+				break
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(fields), &m.Fields); err != nil {
+		return nil, fmt.Errorf("error unmarshaling metadata fields: %w", err)
+	}
+	return m, nil
+}
+
+// Reads the metadata of a file and stores it, files without any metadata
+// get an empty row so they aren't read again
+func ReadFileMetadata(db *sql.DB, fileId int, path string) (*metadata.Metadata, error) {
+	m, err := metadata.Read(path)
+	if errors.Is(err, metadata.ErrUnsupportedFormat) {
+		m, err = &metadata.Metadata{Fields: map[string]string{}}, nil
+	}
+	if m == nil {
+		return nil, err
+	}
+	if err != nil {
+		appLogger.Println("Metadata of ", replaceHomeDir(path), " is partly unreadable: ", err)
+	}
+
+	if err := SaveFileMetadata(db, fileId, m); err != nil {
+		return m, fmt.Errorf("error saving metadata: %w", err)
+	}
+	return m, nil
+}
+
+// Returns the metadata of a file, reading it from the file the first time
+func GetFileMetadata(db *sql.DB, fileId int, path string) (*metadata.Metadata, error) {
+	m, err := GetStoredMetadata(db, fileId)
+	if err == sql.ErrNoRows {
+		return ReadFileMetadata(db, fileId, path)
+	}
+	return m, err
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"main/pkg/geo"
+	"math"
+)
+
+// Length of a degree of latitude in km
+const degreeLength = 6371.0 * math.Pi / 180
+
+// Search filter for files taken within a radius in km of a point, e.g. near:56.95,24.1,10
+func nearFilter(op string, value string) (string, []any, error) {
+	if op != "=" {
+		return "", nil, fmt.Errorf("only = can be used with near")
+	}
+	latitude, longitude, radius, err := geo.ParseArea(value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// SQLite has no trigonometry so the distance is measured on a flat map scaled
+	// to the latitude, which is close enough for the radius of a search
+	scale := math.Max(math.Cos(latitude*math.Pi/180), 0.01)
+	degrees := radius / degreeLength
+	west, east := longitude-degrees/scale, longitude+degrees/scale
+
+	// a box crossing the antimeridian is split into the part on each side of it,
+	// one wider than the whole map doesn't limit the longitude
+	longitudeBox, longitudeArgs := "longitude BETWEEN ? AND ?", []any{west, east}
+	switch {
+	case east-west >= 360:
+		longitudeBox, longitudeArgs = "1", nil
+	case west < -180:
+		longitudeBox, longitudeArgs = "(longitude >= ? OR longitude <= ?)", []any{west + 360, east}
+	case east > 180:
+		longitudeBox, longitudeArgs = "(longitude >= ? OR longitude <= ?)", []any{west, east - 360}
+	}
+
+	// a radius reaching over a pole covers every longitude. The flat map is useless
+	// there, so points on the other side match if the way over the pole is short enough
+	overPole, poleArgs := "0", []any(nil)
+	if pole := math.Copysign(90, latitude); math.Abs(latitude)+degrees >= 90 {
+		longitudeBox, longitudeArgs = "1", nil
+		overPole, poleArgs = "abs(? - latitude) + ? <= ?", []any{pole, math.Abs(pole - latitude), degrees}
+	}
+
+	// the difference in longitude is taken the short way around, -180 to 180
+	condition := `EXISTS (SELECT 1 FROM FileMetadata WHERE FileMetadata.fileId = File.id
+		AND latitude BETWEEN ? AND ? AND ` + longitudeBox + `
+		AND ((latitude - ?) * (latitude - ?) + (longitude - ? - 360 * round((longitude - ?) / 360)) * (longitude - ? - 360 * round((longitude - ?) / 360)) * ? <= ?
+		OR ` + overPole + `))`
+	args := []any{latitude - degrees, latitude + degrees}
+	args = append(args, longitudeArgs...)
+	args = append(args, latitude, latitude, longitude, longitude, longitude, longitude, scale*scale, degrees*degrees)
+	args = append(args, poleArgs...)
+	return condition, args, nil
+}
+
+// Group of files that are in the same cell of the coordinate grid
+type LocationCluster struct {
+	Cell      geo.Cell
+	Latitude  float64 // average of the files in the cell
+	Longitude float64
+	Count     int
+	Place     string // name of the nearest city
+}
+
+// Groups every file with GPS coordinates into cells of cellSize degrees, biggest groups first
+func GetLocationClusters(db *sql.DB, cellSize float64) ([]LocationCluster, error) {
+	// latitude and longitude are shifted to be positive, casting them to INTEGER rounds them down
+	rows, err := db.Query(`
+	SELECT CAST((latitude + 90) / ? AS INTEGER) AS cellRow, CAST((longitude + 180) / ? AS INTEGER) AS cellCol,
+		avg(latitude), avg(longitude), count(fileId)
+	FROM FileMetadata WHERE latitude IS NOT NULL AND longitude IS NOT NULL
+	GROUP BY cellRow, cellCol ORDER BY count(fileId) DESC`, cellSize, cellSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clusters []LocationCluster
+	for rows.Next() {
+		var c LocationCluster
+		if err := rows.Scan(&c.Cell.Row, &c.Cell.Col, &c.Latitude, &c.Longitude, &c.Count); err != nil {
+			return nil, err
+		}
+		c.Place = geo.PlaceName(c.Latitude, c.Longitude)
+		clusters = append(clusters, c)
+	}
+	return clusters, rows.Err()
+}
+
+// Returns the paths of the files in a cell of the coordinate grid, oldest capture date first
+func GetImagesInCell(db *sql.DB, cell geo.Cell, cellSize float64) ([]string, error) {
+	// the cell is computed the same way as in GetLocationClusters so no file falls between cells
+	rows, err := db.Query(`
+	SELECT File.path FROM File JOIN FileMetadata ON FileMetadata.fileId = File.id
+	WHERE latitude IS NOT NULL AND longitude IS NOT NULL
+		AND CAST((latitude + 90) / ? AS INTEGER) = ? AND CAST((longitude + 180) / ? AS INTEGER) = ?
+	ORDER BY captureDate, File.id`, cellSize, cell.Row, cellSize, cell.Col)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNearFilter(t *testing.T) {
+	db := testDB(t)
+	places := []struct {
+		path                string
+		latitude, longitude float64
+	}{
+		{"/photos/riga.jpg", 56.95, 24.1},
+		{"/photos/fiji-east.jpg", -17, 179.9},
+		{"/photos/fiji-west.jpg", -17, -179.9},
+		{"/photos/samoa.jpg", -13.8, -171.8},
+		{"/photos/north-pole.jpg", 89.9, 0},
+		{"/photos/north-pole-back.jpg", 89.9, 180},
+		{"/photos/north-pole-far.jpg", 89.5, 180},
+		{"/photos/south-pole.jpg", -89.8, 90},
+		{"/photos/south-pole-back.jpg", -89.8, -90},
+	}
+	for _, p := range places {
+		addTestFile(t, db, testFile{path: p.path, taken: "2024-01-01 00:00:00", latitude: p.latitude, longitude: p.longitude})
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"near:56.95,24.1,10", []string{"/photos/riga.jpg"}},
+		// about 21 km between the two, across the antimeridian
+		{"near:-17,179.9,30", []string{"/photos/fiji-east.jpg", "/photos/fiji-west.jpg"}},
+		{"near:-17,-179.9,30", []string{"/photos/fiji-east.jpg", "/photos/fiji-west.jpg"}},
+		{"near:-17,180,5", nil},
+		{"near:-15,-175,800", []string{"/photos/fiji-east.jpg", "/photos/fiji-west.jpg", "/photos/samoa.jpg"}},
+		// about 22 km over the north pole, the way over it is also the shortest
+		{"near:89.9,0,50", []string{"/photos/north-pole.jpg", "/photos/north-pole-back.jpg"}},
+		{"near:89.9,0,5", []string{"/photos/north-pole.jpg"}},
+		{"near:89.9,180,70", []string{"/photos/north-pole.jpg", "/photos/north-pole-back.jpg", "/photos/north-pole-far.jpg"}},
+		{"near:-89.8,90,50", []string{"/photos/south-pole.jpg", "/photos/south-pole-back.jpg"}},
+		// the box is wider than the map, so only the distance is checked
+		{"near:60,0,15000", []string{"/photos/riga.jpg", "/photos/fiji-east.jpg", "/photos/fiji-west.jpg", "/photos/samoa.jpg",
+			"/photos/north-pole.jpg", "/photos/north-pole-back.jpg", "/photos/north-pole-far.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			paths, err := SearchImages(db, tt.query)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, paths)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// Search bar query kept under a name, e.g. a set of images shown to a client
+type SavedSearch struct {
+	Id    int
+	Name  string
+	Query string
+}
+
+func setupSavedSearches(db *sql.DB) {
+	table := "CREATE TABLE IF NOT EXISTS `SavedSearch`(`id` INTEGER PRIMARY KEY NOT NULL, `name` VARCHAR(255) NOT NULL UNIQUE, `query` TEXT NOT NULL);"
+	if _, err := db.Exec(table); err != nil {
+		appLogger.Fatal("Failed to create saved search table: ", err)
+	}
+}
+
+// Returns the saved searches sorted by name
+func GetSavedSearches(db *sql.DB) ([]SavedSearch, error) {
+	rows, err := db.Query("SELECT id, name, query FROM SavedSearch ORDER BY name COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		var s SavedSearch
+		if err := rows.Scan(&s.Id, &s.Name, &s.Query); err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+// Saves the query under the name, a search already saved under it is replaced
+func SaveSearch(db *sql.DB, name string, query string) error {
+	_, err := db.Exec("INSERT INTO SavedSearch (name, query) VALUES (?, ?) ON CONFLICT(name) DO UPDATE SET query = excluded.query", name, query)
+	return err
+}
+
+func DeleteSavedSearch(db *sql.DB, id int) error {
+	_, err := db.Exec("DELETE FROM SavedSearch WHERE id = ?", id)
+	return err
+}
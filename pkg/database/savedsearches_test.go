@@ -0,0 +1,38 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedSearches(t *testing.T) {
+	db := testDB(t)
+	for _, s := range [][2]string{
+		{"wedding", "tag:wedding"},
+		{"Beach", "tag:beach"},
+		{"archive", "rating>=4"},
+		// saving under a name in use replaces its query
+		{"wedding", "tag:wedding fav:true"},
+	} {
+		if err := SaveSearch(db, s[0], s[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	searches, err := GetSavedSearches(db)
+	if assert.NoError(t, err) && assert.Len(t, searches, 3) {
+		// sorted by name ignoring case
+		assert.Equal(t, "archive", searches[0].Name)
+		assert.Equal(t, "Beach", searches[1].Name)
+		assert.Equal(t, "wedding", searches[2].Name)
+		assert.Equal(t, "tag:wedding fav:true", searches[2].Query)
+	}
+
+	assert.NoError(t, DeleteSavedSearch(db, searches[1].Id))
+	searches, err = GetSavedSearches(db)
+	if assert.NoError(t, err) && assert.Len(t, searches, 2) {
+		assert.Equal(t, "archive", searches[0].Name)
+		assert.Equal(t, "wedding", searches[1].Name)
+	}
+}
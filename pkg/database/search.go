@@ -0,0 +1,321 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"main/pkg/colorutils"
+	"main/pkg/fileutils"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// True when SQLite was built with FTS5 (build with -tags sqlite_fts5),
+// otherwise searching falls back to LIKE scans and a warning is logged at startup
+var ftsEnabled = false
+
+// Columns of File copied into FileSearch, other writes to File like ratings and
+// hashes leave the search row alone
+var searchFileColumns = []string{"name", "path", "title", "description", "notes"}
+
+// Columns of the FileSearch table, the table is recreated when they change
+var searchColumns = []string{"name", "path", "tags", "title", "description", "notes"}
+
+// Names of the triggers that keep FileSearch in sync, dropped together with the table
+var searchTriggers = []string{
+	"file_search_insert",
+	"file_search_update",
+	"file_search_delete",
+	"file_tag_search_insert",
+	"file_tag_search_delete",
+	"tag_search_update",
+}
+
+// Inserts the search rows of the files matched by the WHERE clause appended to it
+const searchRowSelect = `
+	INSERT INTO FileSearch (rowid, name, path, tags, title, description, notes)
+	SELECT File.id, File.name, File.path,
+		COALESCE((SELECT group_concat(Tag.name, ' ') FROM FileTag JOIN Tag ON Tag.id = FileTag.tagId WHERE FileTag.fileId = File.id), ''),
+		File.title, File.description, File.notes
+	FROM File`
+
+// Rebuilds the search row of one file, %[1]s is replaced with the file id expression
+const refreshSearchRow = `
+	DELETE FROM FileSearch WHERE rowid = %[1]s;` + searchRowSelect + ` WHERE File.id = %[1]s;
+`
+
+func setupSearch(db *sql.DB) {
+	// Drops the index when it was created with different columns so it gets rebuilt below
+	existing, err := tableColumns(db, "FileSearch")
+	changed := err == nil && len(existing) > 0 && len(existing) != len(searchColumns)
+	for _, column := range searchColumns {
+		if len(existing) > 0 && !existing[column] {
+			changed = true
+		}
+	}
+	if changed {
+		appLogger.Println("Search index columns changed, recreating it")
+		for _, trigger := range searchTriggers {
+			db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`;", trigger))
+		}
+		db.Exec("DROP TABLE IF EXISTS `FileSearch`;")
+	}
+
+	_, err = db.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS `FileSearch` USING fts5(%s, tokenize = 'unicode61', prefix = '2 3');", strings.Join(searchColumns, ", ")))
+	if err == nil {
+		// an existing table is only readable if this build has FTS5
+		_, err = db.Exec("SELECT rowid FROM FileSearch LIMIT 1;")
+	}
+	if err != nil {
+		appLogger.Println("WARNING: full-text search is off, this build of SQLite has no FTS5 (build with -tags sqlite_fts5). Searching falls back to slower LIKE matching: ", err)
+		// triggers left by a build with FTS5 would make every write to File fail
+		for _, trigger := range searchTriggers {
+			db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`;", trigger))
+		}
+		return
+	}
+
+	// Without the triggers the index may have missed changes, so it gets rebuilt below
+	var existingTriggers int
+	db.QueryRow(
+		"SELECT count(name) FROM sqlite_master WHERE type = 'trigger' AND name IN ('" + strings.Join(searchTriggers, "', '") + "')",
+	).Scan(&existingTriggers)
+
+	// older databases rewrite the search row on every update of File
+	var updateTrigger string
+	db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'file_search_update'").Scan(&updateTrigger)
+	if updateTrigger != "" && !strings.Contains(updateTrigger, "AFTER UPDATE OF") {
+		db.Exec("DROP TRIGGER IF EXISTS `file_search_update`;")
+	}
+
+	if err := createSearchTriggers(db); err != nil {
+		appLogger.Println("Failed to create search trigger: ", err)
+		return
+	}
+
+	// Fills the index for files added while the search table or its triggers didn't exist
+	var files, indexed int
+	db.QueryRow("SELECT count(id) FROM File").Scan(&files)
+	db.QueryRow("SELECT count(rowid) FROM FileSearch").Scan(&indexed)
+	if files != indexed || existingTriggers != len(searchTriggers) {
+		if err := RebuildSearchIndex(db); err != nil {
+			appLogger.Println("Failed to rebuild search index: ", err)
+			return
+		}
+	}
+
+	ftsEnabled = true
+}
+
+// Creates the triggers that keep FileSearch in sync with File, FileTag and Tag
+func createSearchTriggers(db *sql.DB) error {
+	triggers := []string{
+		"CREATE TRIGGER IF NOT EXISTS file_search_insert AFTER INSERT ON File BEGIN" + fmt.Sprintf(refreshSearchRow, "new.id") + "END;",
+		"CREATE TRIGGER IF NOT EXISTS file_search_update AFTER UPDATE OF " + strings.Join(searchFileColumns, ", ") + " ON File BEGIN" + fmt.Sprintf(refreshSearchRow, "new.id") + "END;",
+		"CREATE TRIGGER IF NOT EXISTS file_search_delete AFTER DELETE ON File BEGIN DELETE FROM FileSearch WHERE rowid = old.id; END;",
+		"CREATE TRIGGER IF NOT EXISTS file_tag_search_insert AFTER INSERT ON FileTag BEGIN" + fmt.Sprintf(refreshSearchRow, "new.fileId") + "END;",
+		"CREATE TRIGGER IF NOT EXISTS file_tag_search_delete AFTER DELETE ON FileTag BEGIN" + fmt.Sprintf(refreshSearchRow, "old.fileId") + "END;",
+		`CREATE TRIGGER IF NOT EXISTS tag_search_update AFTER UPDATE OF name ON Tag BEGIN
+			UPDATE FileSearch SET tags = COALESCE((SELECT group_concat(Tag.name, ' ') FROM FileTag JOIN Tag ON Tag.id = FileTag.tagId WHERE FileTag.fileId = FileSearch.rowid), '')
+			WHERE rowid IN (SELECT fileId FROM FileTag WHERE tagId = new.id);
+		END;`,
+	}
+	for _, trigger := range triggers {
+		if _, err := db.Exec(trigger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recreates every row of the full-text search index from the File table
+func RebuildSearchIndex(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM FileSearch;"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(searchRowSelect + ";"); err != nil {
+		return err
+	}
+
+	appLogger.Println("Search index rebuilt")
+	return tx.Commit()
+}
+
+// Turns free text typed by the user into an FTS5 query where every word
+// has to match the start of a word in any of the searchColumns
+func ftsQuery(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// A key:value filter typed in the search bar, gets the comparison operator
+// and the value and returns an SQL condition on File with its arguments
+type searchFilter func(op string, value string) (string, []any, error)
+
+// Filters that can be used in the search bar, e.g. rating:>=4 fav:true label:red taken:2021-07
+// near:56.95,24.1,10 color:#ff0000 type:video duration:>60
+var searchFilters = map[string]searchFilter{
+	"rating":   numberFilter("File.rating"),
+	"fav":      boolFilter("File.favorite"),
+	"favorite": boolFilter("File.favorite"),
+	"label":    labelFilter,
+	"taken":    dateFilter(CaptureDate),
+	"added":    dateFilter(DateAdded),
+	"near":     nearFilter,
+	"color":    colorFilter,
+	"type":     mediaTypeFilter,
+	"duration": numberFilter("(SELECT duration FROM FileMetadata WHERE FileMetadata.fileId = File.id)"), // in seconds
+}
+
+// Longer operators first so >= isn't read as >
+var filterOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// Search typed in the search bar split into free text and filter conditions
+type searchQuery struct {
+	text  string
+	where []string
+	args  []any
+}
+
+func parseSearch(input string) (searchQuery, error) {
+	var query searchQuery
+	var words []string
+
+	for _, field := range strings.Fields(input) {
+		key, value, found := strings.Cut(field, ":")
+		filter, ok := searchFilters[strings.ToLower(key)]
+		if !found || !ok {
+			words = append(words, field)
+			continue
+		}
+
+		op := "="
+		for _, operator := range filterOperators {
+			if strings.HasPrefix(value, operator) {
+				op = operator
+				value = value[len(operator):]
+				break
+			}
+		}
+
+		condition, args, err := filter(op, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid filter %s: %w", field, err)
+		}
+		query.where = append(query.where, condition)
+		query.args = append(query.args, args...)
+	}
+
+	query.text = strings.Join(words, " ")
+	return query, nil
+}
+
+func numberFilter(column string) searchFilter {
+	return func(op string, value string) (string, []any, error) {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s is not a number", value)
+		}
+		return column + " " + op + " ?", []any{number}, nil
+	}
+}
+
+func boolFilter(column string) searchFilter {
+	return func(op string, value string) (string, []any, error) {
+		if op != "=" && op != "!=" {
+			return "", nil, fmt.Errorf("only = and != can be used with true or false")
+		}
+		switch strings.ToLower(value) {
+		case "true", "yes", "1":
+			return column + " " + op + " ?", []any{true}, nil
+		case "false", "no", "0":
+			return column + " " + op + " ?", []any{false}, nil
+		default:
+			return "", nil, fmt.Errorf("%s is not true or false", value)
+		}
+	}
+}
+
+func labelFilter(op string, value string) (string, []any, error) {
+	if op != "=" && op != "!=" {
+		return "", nil, fmt.Errorf("only = and != can be used with labels")
+	}
+	label := strings.ToLower(value)
+	if label == "none" {
+		label = ""
+	} else if _, ok := colorutils.LabelColors[label]; !ok {
+		return "", nil, fmt.Errorf("unknown label %s", value)
+	}
+	return "File.label " + op + " ?", []any{label}, nil
+}
+
+func mediaTypeFilter(op string, value string) (string, []any, error) {
+	if op != "=" && op != "!=" {
+		return "", nil, fmt.Errorf("only = and != can be used with media types")
+	}
+	mediaType := strings.ToLower(value)
+	if !slices.Contains(fileutils.MediaTypes, mediaType) {
+		return "", nil, fmt.Errorf("unknown media type %s, use one of %s", value, strings.Join(fileutils.MediaTypes, ", "))
+	}
+	return "File.mediaType " + op + " ?", []any{mediaType}, nil
+}
+
+// Matches the text anywhere in the file name, path, notes or tag names when FTS5 isn't available
+const likeCondition = `(File.name LIKE ? OR File.path LIKE ? OR File.title LIKE ? OR File.description LIKE ? OR File.notes LIKE ?
+	OR EXISTS (SELECT 1 FROM FileTag JOIN Tag ON Tag.id = FileTag.tagId WHERE FileTag.fileId = File.id AND Tag.name LIKE ?))`
+
+// Searches file names, paths, tag names, titles, descriptions and notes, best matches first.
+// Filters like rating:>=4 narrow down the results
+func SearchImages(db *sql.DB, input string) ([]string, error) {
+	query, err := parseSearch(input)
+	if err != nil {
+		return nil, err
+	}
+	if query.text == "" && len(query.where) == 0 {
+		return GetImagePathsByTag(db, "%%")
+	}
+
+	from := "File"
+	order := "File.name DESC"
+	where := query.where
+	args := query.args
+
+	if query.text != "" && ftsEnabled {
+		from = "FileSearch JOIN File ON File.id = FileSearch.rowid"
+		// bm25 weights in searchColumns order
+		order = "bm25(FileSearch, 10.0, 1.0, 5.0, 10.0, 3.0, 2.0)"
+		where = append([]string{"FileSearch MATCH ?"}, where...)
+		args = append([]any{ftsQuery(query.text)}, args...)
+	} else if query.text != "" {
+		like := "%" + query.text + "%"
+		where = append([]string{likeCondition}, where...)
+		args = append([]any{like, like, like, like, like, like}, args...)
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT File.path FROM %s WHERE %s ORDER BY %s;", from, strings.Join(where, " AND "), order), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFile struct {
+	path      string
+	rating    int
+	favorite  bool
+	label     string
+	mediaType string
+	taken     string // capture date, no metadata when empty
+	latitude  any
+	longitude any
+	duration  float64
+	tags      []string
+	color     [3]int // dominant color
+}
+
+// Adds a file with its metadata, tags and dominant color, returns its id
+func addTestFile(t *testing.T, db *sql.DB, f testFile) int {
+	if f.mediaType == "" {
+		f.mediaType = "image"
+	}
+	result, err := db.Exec("INSERT INTO File (path, name, md5, dateAdded, rating, favorite, label, mediaType) VALUES (?, ?, ?, '2024-03-01 12:00:00', ?, ?, ?, ?)",
+		f.path, f.path, f.path, f.rating, f.favorite, f.label, f.mediaType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id64, _ := result.LastInsertId()
+	id := int(id64)
+	if f.taken != "" {
+		_, err := db.Exec("INSERT INTO FileMetadata (fileId, captureDate, latitude, longitude, duration) VALUES (?, ?, ?, ?, ?)", id, f.taken, f.latitude, f.longitude, f.duration)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tag := range f.tags {
+		db.Exec("INSERT OR IGNORE INTO Tag (name, color) VALUES (?, '#000000')", tag)
+		if _, err := db.Exec("INSERT INTO FileTag (fileId, tagId) SELECT ?, id FROM Tag WHERE name = ?", id, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO FileColor (fileId, position, red, green, blue, share) VALUES (?, 0, ?, ?, ?, 0.6)", id, f.color[0], f.color[1], f.color[2]); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		text    string
+		where   []string
+		args    []any
+		wantErr bool
+	}{
+		{"text only", "  beach   sunset ", "beach sunset", nil, nil, false},
+		{"filter and text", "rating:>=4 beach", "beach", []string{"File.rating >= ?"}, []any{4}, false},
+		{"keys ignore case", "RATING:5", "", []string{"File.rating = ?"}, []any{5}, false},
+		{"not equal", "fav:!=yes", "", []string{"File.favorite != ?"}, []any{true}, false},
+		{"unknown key is text", "http://example.com", "http://example.com", nil, nil, false},
+		{"label none", "label:none", "", []string{"File.label = ?"}, []any{""}, false},
+		{"media type", "type:VIDEO", "", []string{"File.mediaType = ?"}, []any{"video"}, false},
+		{"date period", "taken:2021-07", "", []string{"((SELECT captureDate FROM FileMetadata WHERE FileMetadata.fileId = File.id) IS NOT NULL AND (SELECT captureDate FROM FileMetadata WHERE FileMetadata.fileId = File.id) >= ? AND (SELECT captureDate FROM FileMetadata WHERE FileMetadata.fileId = File.id) < ?)"}, []any{"2021-07-01 00:00:00", "2021-08-01 00:00:00"}, false},
+		{"date after", "added:>2023", "", []string{"(File.dateAdded IS NOT NULL AND File.dateAdded >= ?)"}, []any{"2024-01-01 00:00:00"}, false},
+		{"empty number", "rating:", "", nil, nil, true},
+		{"not a number", "rating:>=four", "", nil, nil, true},
+		{"bool with order", "fav:>true", "", nil, nil, true},
+		{"not a bool", "favorite:maybe", "", nil, nil, true},
+		{"unknown label", "label:orange", "", nil, nil, true},
+		{"label with order", "label:>red", "", nil, nil, true},
+		{"unknown media type", "type:photo", "", nil, nil, true},
+		{"bad date", "taken:July", "", nil, nil, true},
+		{"open range without end", "taken:>2020..", "", nil, nil, true},
+		{"open range without start", "taken:<..2020", "", nil, nil, true},
+		{"reversed range", "taken:2022..2020", "", nil, nil, true},
+		{"bad color", "color:#zzzzzz", "", nil, nil, true},
+		{"color with order", "color:>red", "", nil, nil, true},
+		{"near with too few parts", "near:56.95,24.1", "", nil, nil, true},
+		{"near off the map", "near:91,24.1,10", "", nil, nil, true},
+		{"near with zero radius", "near:56.95,24.1,0", "", nil, nil, true},
+		{"near with order", "near:>56.95,24.1,10", "", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parseSearch(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.text, query.text)
+				assert.Equal(t, tt.where, query.where)
+				assert.Equal(t, tt.args, query.args)
+			}
+		})
+	}
+}
+
+func TestSearchImages(t *testing.T) {
+	db := testDB(t)
+	addTestFile(t, db, testFile{path: "/photos/riga.jpg", rating: 5, favorite: true, label: "red", taken: "2021-07-04 12:00:00",
+		latitude: 56.95, longitude: 24.1, tags: []string{"beach"}, color: [3]int{230, 57, 53}})
+	addTestFile(t, db, testFile{path: "/photos/jurmala.jpg", rating: 3, label: "blue", taken: "2020-01-10 08:00:00",
+		latitude: 56.97, longitude: 23.8, tags: []string{"beach", "sunset"}, color: [3]int{30, 136, 229}})
+	addTestFile(t, db, testFile{path: "/photos/tokyo.png", rating: 4, taken: "2023-05-05 10:00:00",
+		latitude: 35.68, longitude: 139.69, color: [3]int{67, 160, 71}})
+	addTestFile(t, db, testFile{path: "/videos/clip.mp4", mediaType: "video", taken: "2021-07-20 18:00:00", duration: 95})
+	addTestFile(t, db, testFile{path: "/photos/scan.png"})
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"rating:>=4", []string{"/photos/riga.jpg", "/photos/tokyo.png"}},
+		{"rating:<4", []string{"/photos/jurmala.jpg", "/videos/clip.mp4", "/photos/scan.png"}},
+		{"fav:true", []string{"/photos/riga.jpg"}},
+		{"fav:false rating:>0", []string{"/photos/jurmala.jpg", "/photos/tokyo.png"}},
+		{"label:blue", []string{"/photos/jurmala.jpg"}},
+		{"label:!=none", []string{"/photos/riga.jpg", "/photos/jurmala.jpg"}},
+		{"type:video", []string{"/videos/clip.mp4"}},
+		{"duration:>60", []string{"/videos/clip.mp4"}},
+		{"taken:2021-07", []string{"/photos/riga.jpg", "/videos/clip.mp4"}},
+		{"taken:2020..2021", []string{"/photos/riga.jpg", "/photos/jurmala.jpg", "/videos/clip.mp4"}},
+		{"taken:>2021", []string{"/photos/tokyo.png"}},
+		{"taken:!=2021", []string{"/photos/jurmala.jpg", "/photos/tokyo.png"}},
+		{"near:56.95,24.1,5", []string{"/photos/riga.jpg"}},
+		{"near:56.95,24.1,50", []string{"/photos/riga.jpg", "/photos/jurmala.jpg"}},
+		{"color:red", []string{"/photos/riga.jpg"}},
+		{"color:#43a047", []string{"/photos/tokyo.png"}},
+		{"sunset", []string{"/photos/jurmala.jpg"}},
+		{"beach rating:5", []string{"/photos/riga.jpg"}},
+		{"nothing-matches", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			paths, err := SearchImages(db, tt.input)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, paths)
+		})
+	}
+
+	_, err := SearchImages(db, "rating:high")
+	assert.Error(t, err)
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"beach", `"beach"*`},
+		{"beach  sunset", `"beach"* "sunset"*`},
+		{`say "hi"`, `"say"* """hi"""*`},
+		{"AND OR NOT", `"AND"* "OR"* "NOT"*`},
+		{"name:cat", `"name:cat"*`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, ftsQuery(tt.text))
+		})
+	}
+}
+
+// The triggers only touch FileSearch through plain SQL, so without FTS5 a plain
+// table stands in for the index and the tests run in every build
+func TestSearchTriggers(t *testing.T) {
+	db := testDB(t)
+	if !ftsEnabled {
+		if _, err := db.Exec("CREATE TABLE FileSearch (rowid INTEGER PRIMARY KEY, name, path, tags, title, description, notes)"); err != nil {
+			t.Fatal(err)
+		}
+		if err := createSearchTriggers(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id := addTestFile(t, db, testFile{path: "/photos/cat.jpg", tags: []string{"pets"}})
+
+	tests := []struct {
+		update    string
+		rewritten bool
+	}{
+		{"UPDATE File SET rating = 5", false},
+		{"UPDATE File SET favorite = 1, label = 'red'", false},
+		{"UPDATE File SET mediaType = 'video'", false},
+		{"UPDATE File SET name = 'kitten.jpg'", true},
+		{"UPDATE File SET path = '/photos/kitten.jpg'", true},
+		{"UPDATE File SET title = 'Kitten'", true},
+		{"UPDATE File SET notes = 'on the sofa'", true},
+		{"UPDATE Tag SET name = 'animals'", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.update, func(t *testing.T) {
+			// a marker left in the row shows if the trigger wrote it again
+			if _, err := db.Exec("UPDATE FileSearch SET description = 'stale', tags = 'stale' WHERE rowid = ?", id); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.Exec(tt.update+" WHERE id = ?", id); err != nil {
+				t.Fatal(err)
+			}
+			var description, tags string
+			db.QueryRow("SELECT description, tags FROM FileSearch WHERE rowid = ?", id).Scan(&description, &tags)
+			assert.Equal(t, tt.rewritten, description != "stale" || tags != "stale")
+		})
+	}
+
+	var name, path, tags string
+	db.QueryRow("SELECT name, path, tags FROM FileSearch WHERE rowid = ?", id).Scan(&name, &path, &tags)
+	assert.Equal(t, []string{"kitten.jpg", "/photos/kitten.jpg", "animals"}, []string{name, path, tags})
+
+	_, err := db.Exec("DELETE FROM File WHERE id = ?", id)
+	assert.NoError(t, err)
+	var rows int
+	db.QueryRow("SELECT count(*) FROM FileSearch").Scan(&rows)
+	assert.Zero(t, rows)
+}
+
+func TestSetupSearch(t *testing.T) {
+	db := testDB(t)
+	var triggers int
+	db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE '%search%'").Scan(&triggers)
+	if !ftsEnabled {
+		// triggers without the index would make every write to File fail
+		assert.Zero(t, triggers)
+		paths, err := SearchImages(db, "anything")
+		assert.NoError(t, err)
+		assert.Empty(t, paths)
+		return
+	}
+	assert.Equal(t, len(searchTriggers), triggers)
+
+	// the update trigger of older databases is replaced by one limited to the searched columns
+	db.Exec("DROP TRIGGER file_search_update")
+	db.Exec("CREATE TRIGGER file_search_update AFTER UPDATE ON File BEGIN SELECT 1; END;")
+	setupSearch(db)
+	var trigger string
+	db.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'file_search_update'").Scan(&trigger)
+	assert.Contains(t, trigger, "AFTER UPDATE OF name, path, title, description, notes")
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"main/pkg/imagehash"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Most bits the hash of an image can differ in to be shown as similar
+const similarSearchRadius = 16
+
+// Perceptual hashes and color histograms of the library kept in memory for
+// similar image lookups, loaded on the first lookup after the hashes changed
+var similarIndex struct {
+	sync.Mutex
+	tree       *imagehash.BKTree
+	hashes     map[int]uint64
+	histograms map[int]imagehash.Histogram
+}
+
+// Makes the next similar image lookup load the hashes again
+func invalidateSimilarIndex() {
+	similarIndex.Lock()
+	defer similarIndex.Unlock()
+	similarIndex.tree = nil
+}
+
+// Loads the hashes into the index if they changed since the last lookup,
+// the caller holds the lock
+func loadSimilarIndex(db *sql.DB) error {
+	if similarIndex.tree != nil {
+		return nil
+	}
+
+	rows, err := db.Query("SELECT fileId, phash, histogram FROM FileHash WHERE phash IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tree := &imagehash.BKTree{}
+	hashes := map[int]uint64{}
+	histograms := map[int]imagehash.Histogram{}
+	for rows.Next() {
+		var fileId int
+		var phash int64
+		var encoded []byte
+		if err := rows.Scan(&fileId, &phash, &encoded); err != nil {
+			return err
+		}
+		tree.Add(uint64(phash), fileId)
+		hashes[fileId] = uint64(phash)
+		if histogram, ok := imagehash.HistogramFromBytes(encoded); ok {
+			histograms[fileId] = histogram
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	similarIndex.tree, similarIndex.hashes, similarIndex.histograms = tree, hashes, histograms
+	appLogger.Println("Loaded ", tree.Len(), " hashes for similar image search")
+	return nil
+}
+
+// Returns up to limit images that look like the file, the closest first. The
+// hash finds the candidates and the colors break ties between them
+func GetSimilarImages(db *sql.DB, fileId int, path string, limit int) ([]string, error) {
+	similarIndex.Lock()
+	defer similarIndex.Unlock()
+
+	if err := loadSimilarIndex(db); err != nil {
+		return nil, err
+	}
+	// an image opened before the background hashing got to it is hashed right away
+	if _, ok := similarIndex.hashes[fileId]; !ok {
+		decoded, err := hashFile(db, fileId, path)
+		if err != nil {
+			return nil, err
+		}
+		if !decoded {
+			return nil, fmt.Errorf("%s can't be compared with other images", path)
+		}
+		similarIndex.tree = nil
+		if err := loadSimilarIndex(db); err != nil {
+			return nil, err
+		}
+	}
+
+	hash := similarIndex.hashes[fileId]
+	histogram, hasHistogram := similarIndex.histograms[fileId]
+
+	type ranked struct {
+		id    int
+		score float64
+	}
+	var matches []ranked
+	for _, match := range similarIndex.tree.Search(hash, similarSearchRadius) {
+		if match.Id == fileId {
+			continue
+		}
+		// both distances go from 0 to 1 and weigh the same
+		score := float64(match.Distance) / 64
+		if other, ok := similarIndex.histograms[match.Id]; ok && hasHistogram {
+			score = (score + imagehash.HistogramDistance(histogram, other)) / 2
+		}
+		matches = append(matches, ranked{match.Id, score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].id < matches[j].id
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]any, len(matches))
+	for i, match := range matches {
+		ids[i] = match.id
+	}
+	rows, err := db.Query(fmt.Sprintf("SELECT id, path FROM File WHERE id IN (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")), ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := map[int]string{}
+	for rows.Next() {
+		var id int
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, err
+		}
+		paths[id] = path
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the paths keep the order of the ranking
+	var similar []string
+	for _, match := range matches {
+		if path, ok := paths[match.id]; ok {
+			similar = append(similar, path)
+		}
+	}
+	return similar, nil
+}
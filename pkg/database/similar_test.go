@@ -0,0 +1,60 @@
+package database
+
+import (
+	"main/pkg/imagehash"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSimilarImages(t *testing.T) {
+	invalidateSimilarIndex()
+	t.Cleanup(invalidateSimilarIndex)
+	db := testDB(t)
+
+	var red, blue imagehash.Histogram
+	red[48], blue[3] = 1, 1
+	files := []struct {
+		path      string
+		hash      uint64
+		histogram []byte
+	}{
+		{"a.jpg", 0, red.Bytes()},
+		{"b.jpg", 0x1, blue.Bytes()},    // 1 bit away in other colors
+		{"c.jpg", 0xFF, red.Bytes()},    // 8 bits away in the same colors
+		{"d.jpg", 0x3, nil},             // 2 bits away without colors
+		{"e.jpg", 0x1FFFF, red.Bytes()}, // past the search radius
+		{"f.jpg", 0xFF00, red.Bytes()},  // as close as c.jpg
+		{"g.jpg", 0, red.Bytes()},       // a copy
+	}
+	ids := map[string]int{}
+	for _, f := range files {
+		id := addTestFile(t, db, testFile{path: f.path})
+		ids[f.path] = id
+		if _, err := db.Exec("INSERT INTO FileHash (fileId, phash, histogram) VALUES (?, ?, ?)", id, int64(f.hash), f.histogram); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		limit int
+		want  []string
+	}{
+		// the score is the hash and color distance averaged, ties go by id
+		{"closest first", "a.jpg", 10, []string{"g.jpg", "d.jpg", "c.jpg", "f.jpg", "b.jpg"}},
+		{"limit", "a.jpg", 2, []string{"g.jpg", "d.jpg"}},
+		// e.jpg is only past the radius of a.jpg
+		{"from another image", "c.jpg", 3, []string{"a.jpg", "g.jpg", "e.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similar, err := GetSimilarImages(db, ids[tt.path], filepath.Join("unused", tt.path), tt.limit)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, similar)
+			}
+		})
+	}
+}
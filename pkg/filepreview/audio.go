@@ -0,0 +1,251 @@
+package filepreview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"os"
+)
+
+// Picture type of the front cover in ID3 and FLAC pictures
+const frontCover = 3
+
+// Finds the cover art in the ID3v2 tag at the start of an MP3 file, the front
+// cover comes first, otherwise the first picture is taken
+func decodeID3Cover(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tag, err := readID3Tag(file)
+	if err != nil {
+		return nil, err
+	}
+	picture := findID3Picture(tag)
+	if picture == nil {
+		return nil, ErrNoPreview
+	}
+	return decodeEmbedded(picture)
+}
+
+type id3Tag struct {
+	version byte // 2, 3 or 4
+	frames  []byte
+}
+
+// Reads the ID3v2 tag at the start of a file, the extended header is skipped
+func readID3Tag(r io.Reader) (*id3Tag, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:3]) != "ID3" {
+		return nil, ErrNoPreview
+	}
+	version, flags := header[3], header[5]
+	size := syncsafe(header[6:10])
+	if version < 2 || version > 4 || size > maxPreviewSize {
+		return nil, ErrNoPreview
+	}
+	frames := make([]byte, size)
+	if _, err := io.ReadFull(r, frames); err != nil {
+		return nil, ErrNoPreview
+	}
+
+	// a tag can be unsynchronised as a whole up to version 3, version 4 marks it per frame
+	if flags&0x80 != 0 && version < 4 {
+		frames = resync(frames)
+	}
+	if flags&0x40 != 0 && version >= 3 && len(frames) >= 4 {
+		extended := int(binary.BigEndian.Uint32(frames)) + 4
+		if version == 4 {
+			extended = int(syncsafe(frames[:4]))
+		}
+		if extended > len(frames) {
+			return nil, ErrNoPreview
+		}
+		frames = frames[extended:]
+	}
+	return &id3Tag{version: version, frames: frames}, nil
+}
+
+// Returns the image data of the front cover, or of the first picture if there's no front cover
+func findID3Picture(tag *id3Tag) []byte {
+	idSize, headerSize := 4, 10
+	if tag.version == 2 {
+		idSize, headerSize = 3, 6
+	}
+
+	var first []byte
+	for data := tag.frames; len(data) >= headerSize && data[0] != 0; {
+		id := string(data[:idSize])
+		var size int
+		switch tag.version {
+		case 2:
+			size = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(data[4:]))
+		case 4:
+			size = int(syncsafe(data[4:8]))
+		}
+		if size > len(data)-headerSize {
+			break
+		}
+		body := data[headerSize : headerSize+size]
+		if tag.version == 4 && data[9]&0x02 != 0 {
+			body = resync(body)
+		}
+		data = data[headerSize+size:]
+
+		if id != "APIC" && id != "PIC" {
+			continue
+		}
+		pictureType, picture, ok := parseID3Picture(body, id == "PIC")
+		if !ok {
+			continue
+		}
+		if pictureType == frontCover {
+			return picture
+		}
+		if first == nil {
+			first = picture
+		}
+	}
+	return first
+}
+
+// Splits an APIC frame, or a PIC frame of version 2, into its picture type and image data
+func parseID3Picture(body []byte, v2 bool) (byte, []byte, bool) {
+	if len(body) < 2 {
+		return 0, nil, false
+	}
+	encoding := body[0]
+	rest := body[1:]
+	if v2 {
+		// a 3 letter image format instead of a MIME type
+		if len(rest) < 3 {
+			return 0, nil, false
+		}
+		rest = rest[3:]
+	} else {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return 0, nil, false
+		}
+		rest = rest[end+1:]
+	}
+	if len(rest) < 1 {
+		return 0, nil, false
+	}
+	pictureType := rest[0]
+	rest = rest[1:]
+
+	// the description ends with a null of the width of its encoding, UTF-16 uses two bytes
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(rest); i += 2 {
+			if rest[i] == 0 && rest[i+1] == 0 {
+				return pictureType, rest[i+2:], true
+			}
+		}
+		return 0, nil, false
+	}
+	end := bytes.IndexByte(rest, 0)
+	if end < 0 {
+		return 0, nil, false
+	}
+	return pictureType, rest[end+1:], true
+}
+
+// Reads a 28 bit integer stored in 4 bytes of 7 bits
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+// Removes the zero bytes unsynchronisation puts after every 0xFF
+func resync(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xff, 0x00}, []byte{0xff})
+}
+
+// Finds the cover art in the PICTURE metadata blocks of a FLAC file
+func decodeFLACCover(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// some taggers put an ID3 tag before the FLAC stream
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, ErrNoPreview
+	}
+	offset := int64(0)
+	if string(header[:3]) == "ID3" {
+		offset = 10 + int64(syncsafe(header[6:10]))
+	}
+	marker := make([]byte, 4)
+	if _, err := file.ReadAt(marker, offset); err != nil || string(marker) != "fLaC" {
+		return nil, ErrNoPreview
+	}
+
+	var first []byte
+	blockHeader := make([]byte, 4)
+	for offset += 4; ; {
+		if _, err := file.ReadAt(blockHeader, offset); err != nil {
+			break
+		}
+		last, kind := blockHeader[0]&0x80 != 0, blockHeader[0]&0x7f
+		size := int64(blockHeader[1])<<16 | int64(blockHeader[2])<<8 | int64(blockHeader[3])
+		if kind == 6 && size <= maxPreviewSize {
+			block := make([]byte, size)
+			if _, err := file.ReadAt(block, offset+4); err == nil {
+				if pictureType, picture, ok := parseFLACPicture(block); ok {
+					if pictureType == frontCover {
+						return decodeEmbedded(picture)
+					}
+					if first == nil {
+						first = picture
+					}
+				}
+			}
+		}
+		if last {
+			break
+		}
+		offset += 4 + size
+	}
+	if first == nil {
+		return nil, ErrNoPreview
+	}
+	return decodeEmbedded(first)
+}
+
+// Splits a PICTURE block into its picture type and image data
+func parseFLACPicture(block []byte) (uint32, []byte, bool) {
+	// every field is prefixed by its length, the size and colors of the image come before the data
+	readLength := func(pos int) (int, bool) {
+		if pos+4 > len(block) {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint32(block[pos:])), true
+	}
+	if len(block) < 4 {
+		return 0, nil, false
+	}
+	pictureType := binary.BigEndian.Uint32(block)
+	pos := 4
+	for i := 0; i < 2; i++ {
+		// the MIME type and the description
+		length, ok := readLength(pos)
+		if !ok || length > len(block) {
+			return 0, nil, false
+		}
+		pos += 4 + length
+	}
+	pos += 16
+	length, ok := readLength(pos)
+	if !ok || pos+4+length > len(block) {
+		return 0, nil, false
+	}
+	return pictureType, block[pos+4 : pos+4+length], true
+}
@@ -0,0 +1,43 @@
+// Package filepreview makes preview images of the files in the library that
+// aren't images or videos: the cover art of audio files, the thumbnails office
+// and design tools save inside their files and the first lines of text files
+package filepreview
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"main/pkg/fileutils"
+	"main/pkg/imagecodec"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNoPreview = errors.New("file has no preview")
+
+// Biggest embedded image read, cover art and thumbnails are far smaller
+const maxPreviewSize = 32 << 20
+
+// Makes the preview of a file, ErrNoPreview when the file has none
+func Decode(path string) (image.Image, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".mp3":
+		return decodeID3Cover(path)
+	case ext == ".flac":
+		return decodeFLACCover(path)
+	case zipThumbnails[ext] != nil:
+		return decodeZipThumbnail(path, zipThumbnails[ext])
+	case fileutils.MediaType(path) == fileutils.TextMedia:
+		return renderText(path)
+	}
+	return nil, ErrNoPreview
+}
+
+// Decodes an embedded image in any format the image codecs know
+func decodeEmbedded(data []byte) (image.Image, error) {
+	img, _, err := imagecodec.Decode(bytes.NewReader(data), "")
+	if err != nil {
+		return nil, errors.Join(ErrNoPreview, err)
+	}
+	return img, nil
+}
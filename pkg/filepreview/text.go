@@ -0,0 +1,62 @@
+package filepreview
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Size of the page the start of a text file is drawn on and the margin around the text
+const (
+	textPageSize   = 256
+	textPageMargin = 8
+)
+
+// Most bytes of a text file read for its preview
+const maxTextPreview = 4096
+
+// Draws the first lines of a text file on a white page, binary files have no preview
+func renderText(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxTextPreview))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || bytes.IndexByte(data, 0) >= 0 {
+		return nil, ErrNoPreview
+	}
+
+	face := basicfont.Face7x13
+	page := image.NewRGBA(image.Rect(0, 0, textPageSize, textPageSize))
+	draw.Draw(page, page.Bounds(), image.White, image.Point{}, draw.Src)
+	drawer := &font.Drawer{Dst: page, Src: image.NewUniform(color.Gray{Y: 0x30}), Face: face}
+
+	columns := (textPageSize - 2*textPageMargin) / face.Advance
+	rows := (textPageSize - 2*textPageMargin) / face.Height
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range lines[:min(len(lines), rows)] {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		// the last line read can end in the middle of a character
+		line = strings.ToValidUTF8(line, "")
+		if utf8.RuneCountInString(line) > columns {
+			line = string([]rune(line)[:columns])
+		}
+		drawer.Dot = fixed.P(textPageMargin, textPageMargin+face.Ascent+i*face.Height)
+		drawer.DrawString(line)
+	}
+	return page, nil
+}
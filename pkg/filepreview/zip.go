@@ -0,0 +1,56 @@
+package filepreview
+
+import (
+	"archive/zip"
+	"image"
+	"io"
+)
+
+// OpenDocument, OpenRaster and Office Open XML files and Krita and Sketch files
+// are zip archives that keep a rendered thumbnail, these are the names it can
+// have by extension, the first one found is used
+var zipThumbnails = map[string][]string{
+	".odt":    {"Thumbnails/thumbnail.png"},
+	".ods":    {"Thumbnails/thumbnail.png"},
+	".odp":    {"Thumbnails/thumbnail.png"},
+	".odg":    {"Thumbnails/thumbnail.png"},
+	".ora":    {"mergedimage.png", "Thumbnails/thumbnail.png"},
+	".kra":    {"mergedimage.png", "preview.png"},
+	".sketch": {"previews/preview.png"},
+	".docx":   {"docProps/thumbnail.jpeg", "docProps/thumbnail.png"},
+	".xlsx":   {"docProps/thumbnail.jpeg", "docProps/thumbnail.png"},
+	".pptx":   {"docProps/thumbnail.jpeg", "docProps/thumbnail.png"},
+}
+
+// Decodes the first of the thumbnails found in a zip based file
+func decodeZipThumbnail(path string, names []string) (image.Image, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, ErrNoPreview
+	}
+	defer archive.Close()
+
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	for _, name := range names {
+		f, ok := files[name]
+		if !ok || f.UncompressedSize64 > maxPreviewSize {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			continue
+		}
+		if img, err := decodeEmbedded(data); err == nil {
+			return img, nil
+		}
+	}
+	return nil, ErrNoPreview
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"main/pkg/imagecodec"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -26,12 +28,63 @@ var imageMap = map[string]bool{
 	".ico":  false,
 	".raw":  true,
 	".heic": true,
+	".heif": true,
+	".hif":  true,
 	".avif": true,
-	".avi":  true,
 	".qoi":  true,
 	".dng":  true,
+	".cr2":  true,
+	".nef":  true,
+	".arw":  true,
+	".rw2":  true,
+	".pef":  true,
+	".srw":  true,
+}
+
+var videoMap = map[string]bool{
+	".mp4":  true,
+	".m4v":  true,
+	".3gp":  true,
+	".mov":  true,
+	".qt":   true,
+	".mkv":  true,
+	".webm": true,
+	".avi":  true,
 }
 
+// Extensions of the other media types, these files are told by their extension only
+var documentMap = map[string]string{
+	".pdf": DocumentMedia, ".doc": DocumentMedia, ".docx": DocumentMedia, ".odt": DocumentMedia, ".rtf": DocumentMedia,
+	".xls": DocumentMedia, ".xlsx": DocumentMedia, ".ods": DocumentMedia, ".ppt": DocumentMedia, ".pptx": DocumentMedia,
+	".odp": DocumentMedia, ".epub": DocumentMedia,
+	".txt": TextMedia, ".md": TextMedia, ".csv": TextMedia, ".json": TextMedia, ".xml": TextMedia, ".yaml": TextMedia,
+	".yml": TextMedia, ".toml": TextMedia, ".ini": TextMedia, ".log": TextMedia, ".html": TextMedia, ".tex": TextMedia,
+	".mp3": AudioMedia, ".flac": AudioMedia, ".wav": AudioMedia, ".ogg": AudioMedia, ".oga": AudioMedia, ".opus": AudioMedia,
+	".m4a": AudioMedia, ".aac": AudioMedia, ".wma": AudioMedia, ".aiff": AudioMedia,
+	".zip": ArchiveMedia, ".tar": ArchiveMedia, ".gz": ArchiveMedia, ".tgz": ArchiveMedia, ".bz2": ArchiveMedia,
+	".xz": ArchiveMedia, ".7z": ArchiveMedia, ".rar": ArchiveMedia,
+	".psd": DesignMedia, ".ai": DesignMedia, ".xcf": DesignMedia, ".kra": DesignMedia, ".ora": DesignMedia,
+	".sketch": DesignMedia, ".fig": DesignMedia, ".afdesign": DesignMedia, ".afphoto": DesignMedia, ".blend": DesignMedia,
+	".odg": DesignMedia,
+}
+
+// Media types of the files in the library
+const (
+	ImageMedia    = "image"
+	VideoMedia    = "video"
+	AudioMedia    = "audio"
+	DocumentMedia = "document" // PDFs, office documents and e-books
+	TextMedia     = "text"
+	ArchiveMedia  = "archive"
+	DesignMedia   = "design" // layered files of image editors and design tools
+)
+
+// Every media type in the order they are listed in the settings
+var MediaTypes = []string{ImageMedia, VideoMedia, AudioMedia, DocumentMedia, TextMedia, ArchiveMedia, DesignMedia}
+
+// Media types added to the library unless the options say otherwise
+var DefaultMediaTypes = []string{ImageMedia, VideoMedia}
+
 func IsFile(path string) (bool, error) {
 	fileInfo, err := os.Stat(path)
 	if err != nil {
@@ -47,10 +100,46 @@ func IsFile(path string) (bool, error) {
 // }
 
 func IsImageFileMap(filename string) bool {
+	return MediaType(filename) == ImageMedia
+}
+
+func IsVideoFile(filename string) bool {
+	return MediaType(filename) == VideoMedia
+}
+
+// Returns the media type of a file, empty for files that can't go in the library
+func MediaType(filename string) string {
 	// get the file extension
 	ext := strings.ToLower(filepath.Ext(filename))
-	// if the file extension is in the image file map return true
-	return imageMap[ext]
+	// files with a media extension or none are told by their content, so files
+	// without an extension or with the wrong one are found too. Files with any
+	// other extension aren't read, nor are programs and other special files
+	_, isImage := imageMap[ext]
+	_, isVideo := videoMap[ext]
+	if isImage || isVideo || (ext == "" && isPlainFile(filename)) {
+		if format, err := imagecodec.DetectFile(filename); err == nil {
+			ext = format.Extensions[0]
+		}
+	}
+	switch {
+	case imageMap[ext]:
+		return ImageMedia
+	case videoMap[ext]:
+		return VideoMedia
+	}
+	return documentMap[ext]
+}
+
+// Returns true if the file is of one of the media types
+func HasMediaType(filename string, mediaTypes []string) bool {
+	mediaType := MediaType(filename)
+	return mediaType != "" && slices.Contains(mediaTypes, mediaType)
+}
+
+// Regular files that can't be run, extensionless programs are never media
+func isPlainFile(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 == 0
 }
 
 func GetFileMD5HashBuffered(filePath string) (string, error) {
@@ -135,3 +224,17 @@ func GetAbsDirFiles(path string) ([]string, error) {
 
 	return files, nil
 }
+
+// Formats a file size in bytes like 512 B, 3.4 MB or 1.2 GB
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
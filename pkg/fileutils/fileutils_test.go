@@ -0,0 +1,52 @@
+package fileutils
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaType(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	pngData := buf.Bytes()
+	mp4Data := []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2")
+
+	tests := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+		want string
+	}{
+		{"photo.png", pngData, 0o644, ImageMedia},
+		{"photo.JPG", pngData, 0o644, ImageMedia},
+		{"photo", pngData, 0o644, ImageMedia},
+		{"clip", mp4Data, 0o644, VideoMedia},
+		// a video saved with an image extension
+		{"clip.jpg", mp4Data, 0o644, VideoMedia},
+		// other extensions are never read
+		{"photo.txt", pngData, 0o644, TextMedia},
+		{"photo.bin", pngData, 0o644, ""},
+		{"program", pngData, 0o755, ""},
+		{"README", []byte("hello"), 0o644, ""},
+		{"notes.md", []byte("hello"), 0o644, TextMedia},
+		{"song.MP3", nil, 0o644, AudioMedia},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, tt.data, tt.perm); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, MediaType(path))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package geo works with GPS coordinates offline, it measures distances, groups
+// coordinates into a grid and names places from a bundled list of cities
+package geo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Mean radius of the Earth in km
+const earthRadius = 6371.0
+
+// Places further than this from every city are named by their coordinates
+const maxPlaceDistance = 300.0
+
+// Capitals and major cities as name,country,latitude,longitude
+//
+//go:embed cities.csv
+var citiesCSV string
+
+// City from the bundled dataset
+type City struct {
+	Name      string
+	Country   string
+	Latitude  float64
+	Longitude float64
+}
+
+var (
+	cities     []City
+	citiesOnce sync.Once
+)
+
+// Parses the bundled cities the first time they are needed
+func loadCities() []City {
+	citiesOnce.Do(func() {
+		records, err := csv.NewReader(strings.NewReader(citiesCSV)).ReadAll()
+		if err != nil {
+			panic(fmt.Sprintf("invalid bundled cities: %v", err))
+		}
+		// the first record is the header
+		for _, record := range records[1:] {
+			latitude, latErr := strconv.ParseFloat(record[2], 64)
+			longitude, lonErr := strconv.ParseFloat(record[3], 64)
+			if latErr != nil || lonErr != nil {
+				continue
+			}
+			cities = append(cities, City{Name: record[0], Country: record[1], Latitude: latitude, Longitude: longitude})
+		}
+	})
+	return cities
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// Returns the great circle distance between two coordinates in km
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Returns the bundled city closest to the coordinates and the distance to it in km
+func NearestCity(latitude, longitude float64) (City, float64) {
+	var nearest City
+	best := math.Inf(1)
+	for _, city := range loadCities() {
+		if distance := Distance(latitude, longitude, city.Latitude, city.Longitude); distance < best {
+			nearest, best = city, distance
+		}
+	}
+	return nearest, best
+}
+
+// Names the place at the coordinates after the nearest city like "Riga, Latvia",
+// places far from every city are written as coordinates
+func PlaceName(latitude, longitude float64) string {
+	city, distance := NearestCity(latitude, longitude)
+	switch {
+	case distance <= 50:
+		return city.Name + ", " + city.Country
+	case distance <= maxPlaceDistance:
+		return "Near " + city.Name + ", " + city.Country
+	default:
+		return FormatCoordinates(latitude, longitude)
+	}
+}
+
+// Formats coordinates like 56.94960, 24.10520
+func FormatCoordinates(latitude, longitude float64) string {
+	return fmt.Sprintf("%.5f, %.5f", latitude, longitude)
+}
+
+// Cell of a coordinate grid, rows count from the south pole and columns from
+// the antimeridian so both are never negative
+type Cell struct {
+	Row int
+	Col int
+}
+
+// Parses a search area written as latitude,longitude,radius in km like 56.95,24.1,10
+func ParseArea(value string) (latitude, longitude, radius float64, err error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("%s is not latitude,longitude,radius like 56.95,24.1,10", value)
+	}
+	numbers := make([]float64, len(parts))
+	for i, part := range parts {
+		if numbers[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+			return 0, 0, 0, fmt.Errorf("%s is not a number", part)
+		}
+	}
+	latitude, longitude, radius = numbers[0], numbers[1], numbers[2]
+	if math.Abs(latitude) > 90 || math.Abs(longitude) > 180 {
+		return 0, 0, 0, fmt.Errorf("%s is outside of the map", value)
+	}
+	if radius <= 0 {
+		return 0, 0, 0, fmt.Errorf("the radius has to be more than 0 km")
+	}
+	return latitude, longitude, radius, nil
+}
@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 56.95, 24.1, 56.95, 24.1, 0},
+		{"a degree on the equator", 0, 0, 0, 1, 111.19},
+		{"across the antimeridian", -17, 179.9, -17, -179.9, 21.27},
+		{"over the pole", 89.9, 0, 89.9, 180, 22.24},
+		{"opposite sides of the Earth", 0, 0, 0, 180, 20015.09},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2), 0.01)
+			assert.InDelta(t, tt.want, Distance(tt.lat2, tt.lon2, tt.lat1, tt.lon1), 0.01)
+		})
+	}
+}
+
+func TestNearestCity(t *testing.T) {
+	tests := []struct {
+		name                string
+		latitude, longitude float64
+		want                string
+	}{
+		{"in the city", 56.95, 24.11, "Riga"},
+		{"at sea", 64.15, -24, "Reykjavík"},
+		// Suva is on the other side of the antimeridian but closer than Nukuʻalofa
+		{"across the antimeridian", -18, -179.8, "Suva"},
+		{"east of the antimeridian", -13.9, -171.5, "Apia"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			city, distance := NearestCity(tt.latitude, tt.longitude)
+			assert.Equal(t, tt.want, city.Name)
+			assert.InDelta(t, Distance(tt.latitude, tt.longitude, city.Latitude, city.Longitude), distance, 1e-9)
+		})
+	}
+}
+
+func TestPlaceName(t *testing.T) {
+	tests := []struct {
+		latitude, longitude float64
+		want                string
+	}{
+		{56.95, 24.11, "Riga, Latvia"},
+		{64.15, -24, "Near Reykjavík, Iceland"},
+		{-18, -179.8, "Near Suva, Fiji"},
+		// the middle of the Pacific
+		{0, -140, "0.00000, -140.00000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, PlaceName(tt.latitude, tt.longitude))
+		})
+	}
+}
+
+func TestParseArea(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    [3]float64
+		wantErr bool
+	}{
+		{"56.95,24.1,10", [3]float64{56.95, 24.1, 10}, false},
+		{" -17, 180 , 0.5", [3]float64{-17, 180, 0.5}, false},
+		{"90,-180,1", [3]float64{90, -180, 1}, false},
+		{"56.95,24.1", [3]float64{}, true},
+		{"56.95,24.1,10,1", [3]float64{}, true},
+		{"north,24.1,10", [3]float64{}, true},
+		{"91,24.1,10", [3]float64{}, true},
+		{"56.95,-181,10", [3]float64{}, true},
+		{"56.95,24.1,0", [3]float64{}, true},
+		{"56.95,24.1,-5", [3]float64{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			latitude, longitude, radius, err := ParseArea(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, [3]float64{latitude, longitude, radius})
+			}
+		})
+	}
+}
@@ -11,3 +11,11 @@ var FilterIconDark []byte = []byte{
 var FilterIconLight []byte = []byte{
 	137, 80, 78, 71, 13, 10, 26, 10, 0, 0, 0, 13, 73, 72, 68, 82, 0, 0, 0, 96, 0, 0, 0, 96, 8, 6, 0, 0, 0, 226, 152, 119, 56, 0, 0, 2, 29, 73, 68, 65, 84, 120, 156, 236, 155, 65, 142, 19, 49, 16, 69, 171, 17, 151, 153, 217, 192, 158, 171, 176, 159, 83, 193, 85, 131, 88, 68, 138, 34, 33, 197, 184, 220, 239, 123, 250, 189, 117, 166, 199, 254, 47, 85, 229, 88, 201, 151, 18, 20, 5, 192, 40, 0, 70, 1, 48, 10, 128, 81, 0, 140, 2, 96, 20, 0, 163, 0, 24, 5, 192, 40, 0, 70, 1, 48, 10, 128, 81, 0, 140, 2, 96, 20, 0, 243, 181, 227, 33, 183, 219, 237, 214, 241, 156, 221, 56, 142, 227, 152, 125, 198, 116, 5, 92, 53, 252, 106, 218, 187, 45, 8, 70, 1, 48, 83, 2, 126, 126, 124, 252, 232, 91, 202, 53, 153, 30, 34, 87, 158, 1, 213, 48, 136, 109, 65, 48, 211, 2, 58, 142, 98, 187, 210, 177, 247, 182, 240, 70, 90, 81, 170, 52, 98, 15, 72, 11, 74, 156, 27, 212, 154, 218, 4, 140, 190, 35, 146, 36, 140, 174, 165, 179, 130, 91, 43, 96, 71, 9, 100, 248, 181, 162, 5, 165, 246, 247, 14, 86, 236, 109, 201, 12, 24, 89, 40, 89, 5, 9, 7, 135, 101, 67, 248, 253, 253, 251, 239, 87, 95, 75, 72, 24, 249, 159, 35, 123, 25, 101, 105, 187, 160, 251, 235, 191, 72, 90, 215, 210, 99, 104, 226, 80, 78, 10, 191, 206, 248, 28, 176, 243, 80, 62, 99, 237, 113, 119, 65, 43, 171, 32, 225, 216, 251, 204, 41, 2, 18, 90, 81, 90, 235, 185, 115, 90, 5, 144, 18, 82, 195, 175, 179, 91, 16, 33, 33, 57, 252, 34, 102, 64, 242, 80, 38, 214, 22, 55, 132, 159, 153, 169, 130, 196, 161, 251, 12, 34, 224, 140, 86, 148, 222, 122, 238, 96, 21, 48, 186, 225, 183, 183, 111, 191, 94, 125, 237, 46, 225, 215, 234, 171, 136, 87, 88, 113, 33, 150, 112, 201, 246, 42, 248, 12, 232, 190, 57, 221, 41, 252, 74, 16, 80, 141, 55, 167, 41, 55, 156, 35, 224, 239, 128, 59, 179, 125, 123, 167, 190, 255, 72, 68, 5, 212, 228, 201, 104, 215, 240, 43, 73, 64, 157, 20, 76, 82, 248, 149, 212, 130, 30, 89, 249, 1, 42, 77, 64, 84, 5, 172, 38, 45, 252, 74, 21, 176, 34, 168, 196, 240, 43, 85, 64, 53, 7, 150, 26, 126, 37, 11, 168, 174, 47, 191, 6, 135, 95, 233, 2, 174, 128, 2, 96, 20, 0, 163, 0, 24, 5, 192, 40, 0, 70, 1, 48, 10, 128, 81, 0, 140, 2, 96, 20, 0, 163, 0, 24, 5, 192, 40, 0, 70, 1, 48, 10, 128, 81, 0, 140, 2, 96, 20, 0, 163, 0, 24, 5, 192, 40, 0, 70, 1, 48, 10, 128, 81, 0, 140, 2, 96, 226, 5, 28, 199, 113, 252, 207, 207, 137, 254, 254, 77, 250, 215, 18, 37, 128, 248, 10, 248, 236, 40, 0, 70, 1, 48, 10, 128, 81, 0, 140, 2, 96, 20, 0, 163, 0, 24, 5, 192, 40, 0, 70, 1, 48, 10, 128, 81, 0, 140, 2, 96, 20, 0, 243, 39, 0, 0, 255, 255, 91, 196, 186, 42, 36, 253, 161, 189, 0, 0, 0, 0, 73, 69, 78, 68, 174, 66, 96, 130,
 }
+
+var StarIcon []byte = []byte{
+	137, 80, 78, 71, 13, 10, 26, 10, 0, 0, 0, 13, 73, 72, 68, 82, 0, 0, 0, 48, 0, 0, 0, 48, 8, 6, 0, 0, 0, 87, 2, 249, 135, 0, 0, 2, 73, 73, 68, 65, 84, 120, 156, 236, 89, 221, 109, 219, 48, 16, 38, 133, 2, 124, 140, 55, 168, 55, 168, 55, 160, 51, 65, 185, 65, 221, 9, 170, 78, 210, 100, 130, 58, 27, 56, 27, 132, 27, 52, 27, 184, 27, 168, 143, 124, 98, 65, 224, 187, 64, 144, 169, 63, 234, 168, 31, 192, 223, 33, 240, 241, 44, 159, 248, 233, 142, 199, 19, 83, 136, 141, 227, 78, 160, 139, 128, 183, 234, 33, 252, 209, 120, 115, 4, 132, 16, 6, 127, 217, 228, 19, 41, 153, 80, 226, 243, 5, 159, 236, 34, 73, 225, 134, 183, 234, 179, 16, 226, 138, 225, 94, 106, 247, 23, 250, 102, 82, 168, 108, 209, 55, 67, 192, 180, 232, 235, 39, 224, 173, 210, 33, 109, 104, 28, 116, 216, 182, 65, 64, 8, 113, 34, 165, 199, 182, 190, 69, 140, 186, 31, 22, 239, 142, 108, 64, 133, 197, 252, 143, 12, 107, 141, 128, 137, 76, 94, 192, 102, 182, 144, 66, 38, 241, 187, 229, 83, 168, 81, 251, 219, 132, 117, 79, 40, 102, 124, 250, 99, 174, 89, 140, 64, 201, 116, 205, 252, 4, 188, 85, 95, 26, 181, 191, 77, 246, 184, 118, 185, 102, 14, 19, 216, 77, 168, 243, 165, 183, 234, 76, 3, 160, 146, 218, 189, 211, 96, 244, 34, 246, 86, 125, 139, 76, 226, 72, 202, 194, 120, 35, 5, 56, 75, 237, 94, 110, 170, 144, 183, 234, 135, 16, 226, 137, 198, 43, 69, 41, 181, 123, 190, 137, 64, 163, 143, 185, 68, 82, 100, 105, 9, 59, 185, 145, 218, 89, 50, 68, 9, 128, 196, 3, 194, 118, 32, 219, 194, 248, 19, 210, 57, 214, 134, 68, 9, 144, 120, 171, 126, 143, 92, 156, 57, 36, 228, 251, 119, 26, 140, 42, 163, 248, 225, 9, 225, 155, 27, 225, 158, 167, 174, 201, 247, 70, 160, 81, 54, 47, 3, 235, 60, 7, 174, 200, 247, 119, 150, 141, 12, 142, 14, 145, 114, 150, 3, 225, 30, 135, 33, 147, 31, 28, 129, 186, 120, 171, 126, 113, 183, 3, 53, 60, 73, 237, 126, 210, 32, 11, 1, 144, 248, 26, 54, 19, 198, 82, 75, 249, 254, 74, 134, 172, 4, 106, 36, 46, 13, 115, 42, 76, 202, 228, 7, 175, 129, 22, 225, 92, 208, 201, 190, 166, 16, 224, 236, 147, 146, 125, 77, 73, 33, 79, 58, 7, 164, 118, 114, 182, 8, 32, 255, 89, 37, 213, 103, 49, 119, 200, 185, 125, 22, 25, 223, 125, 103, 241, 89, 36, 158, 60, 12, 169, 26, 21, 38, 101, 160, 247, 97, 15, 223, 121, 9, 12, 12, 245, 27, 142, 79, 94, 81, 223, 247, 176, 113, 248, 158, 76, 160, 43, 212, 21, 222, 152, 30, 235, 189, 123, 208, 165, 118, 143, 104, 65, 170, 68, 223, 108, 4, 142, 61, 77, 216, 115, 195, 254, 1, 124, 215, 213, 20, 230, 141, 0, 94, 55, 119, 29, 79, 189, 247, 196, 45, 156, 202, 117, 68, 99, 135, 123, 228, 33, 16, 9, 113, 239, 83, 79, 136, 134, 201, 73, 224, 152, 242, 212, 71, 70, 227, 152, 165, 149, 64, 137, 187, 226, 137, 157, 166, 76, 188, 195, 255, 25, 4, 248, 255, 41, 24, 182, 122, 156, 27, 101, 149, 112, 143, 28, 173, 202, 106, 81, 164, 253, 236, 78, 224, 78, 128, 8, 252, 31, 0, 64, 63, 194, 183, 148, 36, 242, 245, 0, 0, 0, 0, 73, 69, 78, 68, 174, 66, 96, 130,
+}
+
+var HeartIcon []byte = []byte{
+	137, 80, 78, 71, 13, 10, 26, 10, 0, 0, 0, 13, 73, 72, 68, 82, 0, 0, 0, 48, 0, 0, 0, 48, 8, 6, 0, 0, 0, 87, 2, 249, 135, 0, 0, 2, 51, 73, 68, 65, 84, 120, 156, 236, 153, 223, 141, 155, 64, 16, 198, 39, 40, 143, 72, 113, 7, 113, 7, 225, 137, 215, 96, 81, 64, 220, 65, 72, 7, 164, 3, 119, 16, 58, 136, 221, 1, 87, 0, 58, 238, 149, 39, 174, 3, 174, 3, 78, 162, 128, 211, 158, 190, 145, 16, 222, 93, 243, 103, 193, 139, 197, 55, 178, 108, 230, 206, 48, 191, 157, 217, 185, 219, 221, 175, 212, 67, 77, 232, 127, 39, 162, 19, 17, 5, 68, 180, 103, 127, 75, 53, 17, 165, 226, 229, 102, 197, 19, 59, 101, 106, 66, 255, 23, 17, 29, 241, 218, 177, 191, 165, 138, 136, 114, 241, 60, 55, 43, 222, 216, 169, 210, 23, 254, 32, 83, 19, 250, 223, 136, 40, 33, 162, 136, 125, 61, 36, 2, 136, 187, 32, 8, 60, 81, 12, 128, 74, 103, 220, 235, 157, 29, 189, 1, 154, 208, 255, 129, 27, 120, 236, 27, 168, 207, 135, 227, 243, 208, 65, 104, 171, 20, 223, 117, 179, 226, 149, 29, 55, 1, 48, 242, 229, 192, 209, 146, 41, 197, 251, 17, 239, 99, 37, 178, 234, 201, 50, 161, 154, 3, 169, 129, 224, 77, 4, 206, 218, 35, 166, 3, 59, 88, 14, 127, 96, 53, 161, 255, 27, 147, 213, 54, 11, 16, 155, 30, 0, 221, 198, 86, 59, 105, 1, 48, 113, 77, 148, 206, 92, 218, 35, 70, 57, 192, 132, 78, 177, 164, 69, 58, 128, 177, 45, 115, 73, 121, 15, 13, 32, 251, 211, 110, 155, 118, 58, 128, 213, 217, 195, 1, 212, 43, 0, 168, 117, 0, 229, 10, 0, 74, 29, 64, 181, 2, 128, 74, 7, 144, 175, 0, 32, 127, 92, 0, 44, 225, 108, 158, 7, 101, 119, 153, 217, 205, 0, 175, 158, 108, 213, 85, 108, 50, 128, 212, 210, 118, 202, 27, 7, 122, 0, 44, 219, 108, 204, 66, 34, 91, 82, 58, 154, 84, 217, 148, 133, 90, 53, 168, 82, 0, 144, 198, 124, 109, 129, 148, 91, 43, 202, 109, 21, 172, 208, 158, 45, 88, 31, 231, 110, 86, 28, 20, 63, 83, 150, 16, 91, 116, 231, 82, 170, 187, 43, 176, 65, 0, 232, 185, 247, 44, 165, 184, 219, 247, 7, 1, 0, 226, 162, 154, 64, 51, 43, 193, 179, 181, 210, 206, 129, 182, 53, 161, 255, 255, 86, 58, 13, 234, 236, 102, 197, 31, 190, 152, 148, 129, 150, 197, 11, 253, 155, 81, 14, 41, 219, 222, 0, 104, 99, 193, 204, 16, 226, 222, 129, 170, 101, 78, 42, 161, 5, 202, 169, 119, 217, 140, 202, 64, 219, 240, 32, 147, 19, 59, 25, 19, 252, 232, 12, 116, 54, 130, 147, 9, 219, 49, 53, 90, 229, 165, 231, 239, 155, 5, 152, 120, 16, 162, 61, 184, 88, 12, 128, 173, 9, 253, 127, 3, 186, 135, 40, 153, 191, 124, 97, 5, 0, 32, 126, 34, 27, 170, 29, 238, 10, 163, 254, 194, 142, 169, 114, 38, 124, 247, 74, 8, 204, 83, 76, 240, 4, 199, 68, 198, 130, 55, 158, 1, 201, 169, 228, 25, 151, 209, 173, 227, 87, 43, 77, 148, 20, 202, 106, 51, 149, 57, 51, 220, 115, 3, 216, 0, 54, 128, 13, 160, 63, 192, 199, 0, 221, 78, 169, 15, 255, 25, 125, 98, 0, 0, 0, 0, 73, 69, 78, 68, 174, 66, 96, 130,
+}
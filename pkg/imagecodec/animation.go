@@ -0,0 +1,187 @@
+package imagecodec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"time"
+)
+
+// Returned when an animated file has no frames that can be shown
+var ErrNoFrames = errors.New("animation has no frames")
+
+// Shortest delay between frames, browsers show frames with shorter delays for
+// 100ms too because old GIFs were made for that
+const (
+	minFrameDelay     = 20 * time.Millisecond
+	defaultFrameDelay = 100 * time.Millisecond
+)
+
+// Returned when the canvas of an animation is too big to be composed in memory
+var ErrAnimationTooBig = errors.New("animation is too big")
+
+// Biggest canvas frames are composed on, a canvas is 4 bytes per pixel
+const (
+	maxAnimationSide   = 16384
+	maxAnimationPixels = 64 << 20
+)
+
+// Most frames decoded of an animation, the ones after them are dropped
+const maxAnimationFrames = 1000
+
+// Returns the image kept for a frame composed on the canvas, e.g. a thumbnail of it.
+// The canvas is drawn over for the next frame, so it can't be kept itself
+type FrameFunc func(canvas *image.RGBA) image.Image
+
+// Animation holds every frame of an animated image already composed on the
+// canvas, so any frame can be shown on its own
+type Animation struct {
+	Frames    []image.Image
+	Delays    []time.Duration // how long each frame is shown
+	LoopCount int             // times the animation is played, 0 loops forever
+}
+
+// Returns the canvas the frames of an animation are composed on, canvases too
+// big to be allocated are an error
+func newAnimationCanvas(bounds image.Rectangle) (*image.RGBA, error) {
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= 0 || height <= 0 || width > maxAnimationSide || height > maxAnimationSide || width*height > maxAnimationPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrAnimationTooBig, width, height)
+	}
+	return image.NewRGBA(bounds), nil
+}
+
+// Adds the frame composed on the canvas, keep makes the image kept of it and a
+// copy of the canvas is kept when it is nil
+func (a *Animation) addFrame(canvas *image.RGBA, delay time.Duration, keep FrameFunc) {
+	if keep == nil {
+		a.Frames = append(a.Frames, cloneRGBA(canvas))
+	} else {
+		a.Frames = append(a.Frames, keep(canvas))
+	}
+	a.Delays = append(a.Delays, frameDelay(delay))
+}
+
+// Returns the delay a frame is shown for, too short delays get the default
+func frameDelay(delay time.Duration) time.Duration {
+	if delay < minFrameDelay {
+		return defaultFrameDelay
+	}
+	return delay
+}
+
+// Returns true if the file is in a format with animations and its start says it is
+// animated. GIFs are always treated as animated, a still GIF has a single frame
+func IsAnimated(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	f, err := detect(header[:n], path)
+	if err != nil || f.Animated == nil {
+		return false
+	}
+	return f.Animated(header[:n])
+}
+
+// Decodes every frame of an animated image file, keep makes the image kept of
+// each frame and full size frames are kept when it is nil
+func DecodeAnimationFile(path string, keep FrameFunc) (*Animation, *Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	buffered, f, err := sniffReader(file, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.Animate == nil {
+		return nil, f, errors.New(f.Name + " files aren't animated")
+	}
+	animation, err := f.Animate(buffered, keep)
+	if err == nil && len(animation.Frames) == 0 {
+		err = ErrNoFrames
+	}
+	return animation, f, err
+}
+
+// Composes the frames of a GIF, the frames only hold the part of the canvas that changed
+func decodeGIFAnimation(r io.Reader, keep FrameFunc) (*Animation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// every frame is as big as the canvas at most, so its size is checked first
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := newAnimationCanvas(image.Rect(0, 0, max(config.Width, 1), max(config.Height, 1))); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+
+	// LoopCount of a GIF is the number of times it is restarted, -1 plays it once
+	animation := &Animation{LoopCount: g.LoopCount + 1}
+	if g.LoopCount == 0 {
+		animation.LoopCount = 0
+	}
+
+	canvas, err := newAnimationCanvas(bounds)
+	if err != nil {
+		return nil, err
+	}
+	var previous *image.RGBA
+	for i, frame := range g.Image[:min(len(g.Image), maxAnimationFrames)] {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		rect := frame.Bounds().Intersect(canvas.Bounds())
+		draw.Draw(canvas, rect, frame, rect.Min, draw.Over)
+		delay := 0
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		animation.addFrame(canvas, time.Duration(delay)*10*time.Millisecond, keep)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return animation, nil
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Bounds())
+	copy(clone.Pix, img.Pix)
+	return clone
+}
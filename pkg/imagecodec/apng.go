@@ -0,0 +1,220 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"time"
+)
+
+var errInvalidAPNG = errors.New("invalid animated PNG")
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// Frame disposal and blending of APNG frames
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendOver         = 1
+)
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// Splits a PNG file into its chunks, the CRCs aren't checked
+func pngChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return nil, errInvalidAPNG
+	}
+	data = data[len(pngSignature):]
+	var chunks []pngChunk
+	for len(data) >= 12 {
+		size := binary.BigEndian.Uint32(data[:4])
+		if uint64(size) > uint64(len(data)-12) {
+			return chunks, errInvalidAPNG
+		}
+		chunks = append(chunks, pngChunk{kind: string(data[4:8]), data: data[8 : 8+size]})
+		data = data[12+size:]
+	}
+	return chunks, nil
+}
+
+// Returns true if a PNG file is animated, the acTL chunk comes before the image data
+func isAPNG(header []byte) bool {
+	chunks, _ := pngChunks(header)
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "acTL":
+			return true
+		case "IDAT":
+			return false
+		}
+	}
+	// chunks cut off by the end of the header are read up to their start
+	if i := bytes.Index(header, []byte("acTL")); i >= 0 {
+		return !bytes.Contains(header[:i], []byte("IDAT"))
+	}
+	return false
+}
+
+// Control of one APNG frame, from its fcTL chunk
+type apngFrame struct {
+	width, height int
+	x, y          int
+	delay         time.Duration
+	dispose       byte
+	blend         byte
+	data          [][]byte // the zlib stream split over IDAT or fdAT chunks
+}
+
+// Decodes every frame of an animated PNG, still PNGs have a single frame
+func decodeAPNG(r io.Reader, keep FrameFunc) (*Animation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := pngChunks(data)
+	if err != nil && len(chunks) == 0 {
+		return nil, err
+	}
+	if len(chunks) == 0 || chunks[0].kind != "IHDR" || len(chunks[0].data) != 13 {
+		return nil, errInvalidAPNG
+	}
+	ihdr := chunks[0].data
+	canvasWidth, canvasHeight := int(binary.BigEndian.Uint32(ihdr[0:4])), int(binary.BigEndian.Uint32(ihdr[4:8]))
+	canvas, err := newAnimationCanvas(image.Rect(0, 0, canvasWidth, canvasHeight))
+	if err != nil {
+		return nil, err
+	}
+
+	animation := &Animation{}
+	var frames []*apngFrame
+	var current *apngFrame
+	// chunks like PLTE and tRNS that every frame needs to be decoded
+	var shared []pngChunk
+	seenIDAT, animated := false, false
+	for _, chunk := range chunks[1:] {
+		switch chunk.kind {
+		case "acTL":
+			animated = true
+			if len(chunk.data) >= 8 {
+				animation.LoopCount = int(binary.BigEndian.Uint32(chunk.data[4:8]))
+			}
+		case "fcTL":
+			if len(chunk.data) < 26 {
+				return nil, errInvalidAPNG
+			}
+			d := chunk.data
+			numerator, denominator := binary.BigEndian.Uint16(d[20:22]), binary.BigEndian.Uint16(d[22:24])
+			if denominator == 0 {
+				denominator = 100
+			}
+			current = &apngFrame{
+				width:   int(binary.BigEndian.Uint32(d[4:8])),
+				height:  int(binary.BigEndian.Uint32(d[8:12])),
+				x:       int(binary.BigEndian.Uint32(d[12:16])),
+				y:       int(binary.BigEndian.Uint32(d[16:20])),
+				delay:   time.Duration(numerator) * time.Second / time.Duration(denominator),
+				dispose: d[24],
+				blend:   d[25],
+			}
+			// frames are decoded at their own size, which can't be bigger than the canvas
+			if current.width <= 0 || current.height <= 0 || current.width > canvasWidth || current.height > canvasHeight {
+				return nil, errInvalidAPNG
+			}
+			frames = append(frames, current)
+		case "IDAT":
+			seenIDAT = true
+			// the default image is only a frame when an fcTL comes before it
+			if current != nil {
+				current.data = append(current.data, chunk.data)
+			}
+		case "fdAT":
+			if current != nil && len(chunk.data) > 4 {
+				// the sequence number comes first
+				current.data = append(current.data, chunk.data[4:])
+			}
+		case "IEND":
+		default:
+			if !seenIDAT {
+				shared = append(shared, chunk)
+			}
+		}
+	}
+
+	if !animated || len(frames) == 0 {
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &Animation{Frames: []image.Image{img}, Delays: []time.Duration{defaultFrameDelay}}, nil
+	}
+
+	for i, frame := range frames[:min(len(frames), maxAnimationFrames)] {
+		if len(frame.data) == 0 {
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(apngFramePNG(ihdr, frame, shared)))
+		if err != nil {
+			return nil, err
+		}
+
+		rect := image.Rect(frame.x, frame.y, frame.x+frame.width, frame.y+frame.height).Intersect(canvas.Bounds())
+		var previous *image.RGBA
+		if frame.dispose == apngDisposePrevious && i > 0 {
+			previous = cloneRGBA(canvas)
+		}
+		op := draw.Src
+		if frame.blend == apngBlendOver {
+			op = draw.Over
+		}
+		draw.Draw(canvas, rect, img, img.Bounds().Min, op)
+		animation.addFrame(canvas, frame.delay, keep)
+
+		switch {
+		case previous != nil:
+			canvas = previous
+		// the first frame can't go back to a previous one, it is cleared instead
+		case frame.dispose == apngDisposeBackground, frame.dispose == apngDisposePrevious:
+			draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+		}
+	}
+	return animation, nil
+}
+
+// Builds a still PNG of one frame, the IHDR of the file with the size of the frame,
+// the shared chunks and the image data of the frame
+func apngFramePNG(ihdr []byte, frame *apngFrame, shared []pngChunk) []byte {
+	var file bytes.Buffer
+	writeChunk := func(kind string, data []byte) {
+		binary.Write(&file, binary.BigEndian, uint32(len(data)))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(kind))
+		crc.Write(data)
+		file.WriteString(kind)
+		file.Write(data)
+		binary.Write(&file, binary.BigEndian, crc.Sum32())
+	}
+
+	file.WriteString(pngSignature)
+	frameIHDR := bytes.Clone(ihdr)
+	binary.BigEndian.PutUint32(frameIHDR[0:4], uint32(frame.width))
+	binary.BigEndian.PutUint32(frameIHDR[4:8], uint32(frame.height))
+	writeChunk("IHDR", frameIHDR)
+	for _, chunk := range shared {
+		writeChunk(chunk.kind, chunk.data)
+	}
+	for _, data := range frame.data {
+		writeChunk("IDAT", data)
+	}
+	writeChunk("IEND", nil)
+	return file.Bytes()
+}
@@ -0,0 +1,232 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Appends a PNG chunk with its CRC
+func appendPNGChunk(file []byte, kind string, data []byte) []byte {
+	file = binary.BigEndian.AppendUint32(file, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	file = append(file, kind...)
+	file = append(file, data...)
+	return binary.BigEndian.AppendUint32(file, crc.Sum32())
+}
+
+// Returns the IHDR and the image data of a still PNG filled with one color
+func testPNGParts(t *testing.T, width, height int, c color.Color) (ihdr []byte, idat []byte) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		r, g, b, a := c.RGBA()
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = byte(r>>8), byte(g>>8), byte(b>>8), byte(a>>8)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := pngChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "IHDR":
+			ihdr = chunk.data
+		case "IDAT":
+			idat = append(idat, chunk.data...)
+		}
+	}
+	return ihdr, idat
+}
+
+// Builds an fcTL chunk body
+func testFcTL(sequence, width, height, x, y uint32, delayMs uint16, dispose, blend byte) []byte {
+	d := binary.BigEndian.AppendUint32(nil, sequence)
+	for _, v := range []uint32{width, height, x, y} {
+		d = binary.BigEndian.AppendUint32(d, v)
+	}
+	d = binary.BigEndian.AppendUint16(d, delayMs)
+	d = binary.BigEndian.AppendUint16(d, 1000)
+	return append(d, dispose, blend)
+}
+
+// Builds a 4x4 APNG, a red frame and a green 2x2 frame over its top left corner
+func testAPNG(t *testing.T) []byte {
+	ihdr, red := testPNGParts(t, 4, 4, color.RGBA{R: 255, A: 255})
+	_, green := testPNGParts(t, 2, 2, color.RGBA{G: 255, A: 255})
+
+	file := []byte(pngSignature)
+	file = appendPNGChunk(file, "IHDR", ihdr)
+	file = appendPNGChunk(file, "acTL", []byte{0, 0, 0, 2, 0, 0, 0, 3})
+	file = appendPNGChunk(file, "fcTL", testFcTL(0, 4, 4, 0, 0, 50, apngDisposeNone, 0))
+	file = appendPNGChunk(file, "IDAT", red)
+	file = appendPNGChunk(file, "fcTL", testFcTL(1, 2, 2, 0, 0, 5, apngDisposeNone, apngBlendOver))
+	file = appendPNGChunk(file, "fdAT", append([]byte{0, 0, 0, 2}, green...))
+	return appendPNGChunk(file, "IEND", nil)
+}
+
+func TestDecodeAPNG(t *testing.T) {
+	animation, err := decodeAPNG(bytes.NewReader(testAPNG(t)), nil)
+	if !assert.NoError(t, err) || !assert.Len(t, animation.Frames, 2) {
+		return
+	}
+	assert.Equal(t, 3, animation.LoopCount)
+	// the second delay is too short and gets the default
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, defaultFrameDelay}, animation.Delays)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, animation.Frames[0].At(0, 0))
+	assert.Equal(t, color.RGBA{G: 255, A: 255}, animation.Frames[1].At(0, 0))
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, animation.Frames[1].At(3, 3))
+}
+
+func TestDecodeAPNGKeep(t *testing.T) {
+	kept := 0
+	animation, err := decodeAPNG(bytes.NewReader(testAPNG(t)), func(canvas *image.RGBA) image.Image {
+		kept++
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, kept)
+		assert.Equal(t, image.Rect(0, 0, 1, 1), animation.Frames[1].Bounds())
+	}
+}
+
+func TestDecodeAPNGInvalid(t *testing.T) {
+	valid := testAPNG(t)
+	ihdr, red := testPNGParts(t, 4, 4, color.RGBA{R: 255, A: 255})
+	withIHDR := func(width, height uint32, chunks ...func([]byte) []byte) []byte {
+		header := bytes.Clone(ihdr)
+		binary.BigEndian.PutUint32(header[0:4], width)
+		binary.BigEndian.PutUint32(header[4:8], height)
+		file := appendPNGChunk([]byte(pngSignature), "IHDR", header)
+		file = appendPNGChunk(file, "acTL", []byte{0, 0, 0, 1, 0, 0, 0, 0})
+		for _, chunk := range chunks {
+			file = chunk(file)
+		}
+		return appendPNGChunk(file, "IEND", nil)
+	}
+	frame := func(width, height, x, y uint32) func([]byte) []byte {
+		return func(file []byte) []byte {
+			file = appendPNGChunk(file, "fcTL", testFcTL(0, width, height, x, y, 100, 0, 0))
+			return appendPNGChunk(file, "IDAT", red)
+		}
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"signature only", []byte(pngSignature)},
+		{"truncated IHDR", valid[:20]},
+		{"truncated fcTL", valid[:60]},
+		{"huge canvas", withIHDR(0x7fffffff, 0x7fffffff, frame(4, 4, 0, 0))},
+		{"canvas over the pixel limit", withIHDR(maxAnimationSide, maxAnimationSide, frame(4, 4, 0, 0))},
+		{"zero canvas", withIHDR(0, 4, frame(4, 4, 0, 0))},
+		{"frame bigger than the canvas", withIHDR(4, 4, frame(0x7fffffff, 4, 0, 0))},
+		{"empty frame", withIHDR(4, 4, frame(0, 4, 0, 0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				_, err := decodeAPNG(bytes.NewReader(tt.data), nil)
+				assert.Error(t, err)
+			})
+		})
+	}
+
+	// frames placed past the edge of the canvas are clipped
+	assert.NotPanics(t, func() {
+		animation, err := decodeAPNG(bytes.NewReader(withIHDR(4, 4, frame(4, 4, 2, 0x7ffffff0))), nil)
+		if assert.NoError(t, err) {
+			assert.Len(t, animation.Frames, 1)
+		}
+	})
+}
+
+func TestDecodeAnimationFileTooBig(t *testing.T) {
+	ihdr, red := testPNGParts(t, 4, 4, color.RGBA{R: 255, A: 255})
+	binary.BigEndian.PutUint32(ihdr[0:4], 0x7fffffff)
+	binary.BigEndian.PutUint32(ihdr[4:8], 0x7fffffff)
+	file := appendPNGChunk([]byte(pngSignature), "IHDR", ihdr)
+	file = appendPNGChunk(file, "acTL", []byte{0, 0, 0, 1, 0, 0, 0, 0})
+	file = appendPNGChunk(file, "fcTL", testFcTL(0, 4, 4, 0, 0, 100, 0, 0))
+	file = appendPNGChunk(file, "IDAT", red)
+	file = appendPNGChunk(file, "IEND", nil)
+	path := filepath.Join(t.TempDir(), "huge.png")
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotPanics(t, func() {
+		_, _, err := DecodeAnimationFile(path, nil)
+		assert.ErrorIs(t, err, ErrAnimationTooBig)
+	})
+}
+
+func TestIsAPNG(t *testing.T) {
+	valid := testAPNG(t)
+	ihdr, red := testPNGParts(t, 4, 4, color.RGBA{A: 255})
+	still := appendPNGChunk(appendPNGChunk([]byte(pngSignature), "IHDR", ihdr), "IDAT", red)
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   bool
+	}{
+		{"animated", valid, true},
+		{"still", still, false},
+		{"empty", nil, false},
+		{"not a PNG", []byte("GIF89a"), false},
+		// the acTL chunk is cut off by the end of the header
+		{"truncated acTL", valid[:len(pngSignature)+25+10], true},
+		{"truncated IHDR", valid[:len(pngSignature)+10], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isAPNG(tt.header))
+		})
+	}
+}
+
+func TestPNGChunks(t *testing.T) {
+	valid := testAPNG(t)
+	chunks, err := pngChunks(valid)
+	if assert.NoError(t, err) {
+		var kinds []string
+		for _, chunk := range chunks {
+			kinds = append(kinds, chunk.kind)
+		}
+		assert.Equal(t, []string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "IEND"}, kinds)
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		chunks int
+	}{
+		{"not a PNG", []byte("not a png"), 0},
+		{"oversized length", append([]byte(pngSignature), 0xff, 0xff, 0xff, 0xff, 'I', 'D', 'A', 'T', 0, 0, 0, 0), 0},
+		// the fdAT chunk runs past the end, the chunks before it are still returned
+		{"truncated", valid[:len(valid)-20], 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := pngChunks(tt.data)
+			assert.ErrorIs(t, err, errInvalidAPNG)
+			assert.Len(t, chunks, tt.chunks)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"main/pkg/imagetransform"
+
+	"github.com/gen2brain/avif"
+)
+
+// Decodes an AVIF image, libavif leaves its rotation and mirroring to the caller
+func decodeAVIF(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	img, err := avif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, property := range readHEIFTransforms(data) {
+		if len(property.data) < 1 {
+			continue
+		}
+		switch property.kind {
+		case "irot":
+			// counterclockwise in steps of 90 degrees
+			img = imagetransform.Rotate(img, -90*int(property.data[0]&3))
+		case "imir":
+			// mirrored along a vertical axis, or a horizontal one when the lowest bit is set
+			if property.data[0]&1 == 0 {
+				img = imagetransform.FlipHorizontal(img)
+			} else {
+				img = imagetransform.FlipVertical(img)
+			}
+		}
+	}
+	return img, nil
+}
+
+// Returns the irot and imir properties of the primary image in the order they are applied
+func readHEIFTransforms(data []byte) []heifBox {
+	meta, ok := findHEIFBox(readHEIFBoxes(data, 0), "meta")
+	if !ok || len(meta.data) < 4 {
+		return nil
+	}
+	info, err := parseHEIFMeta(meta.data, meta.offset)
+	if err != nil {
+		return nil
+	}
+	children := readHEIFBoxes(meta.data[4:], meta.offset+4)
+	iprp, ok := findHEIFBox(children, "iprp")
+	if !ok {
+		return nil
+	}
+	properties := readHEIFBoxes(iprp.data, iprp.offset)
+	ipco, ok := findHEIFBox(properties, "ipco")
+	if !ok {
+		return nil
+	}
+	ipma, ok := findHEIFBox(properties, "ipma")
+	if !ok || len(ipma.data) < 8 {
+		return nil
+	}
+	propertyBoxes := readHEIFBoxes(ipco.data, ipco.offset)
+
+	// the associations of every item list 1 based indexes into ipco
+	version, flags := ipma.data[0], ipma.data[3]
+	idSize, indexSize := 2, 1
+	if version > 0 {
+		idSize = 4
+	}
+	if flags&1 != 0 {
+		indexSize = 2
+	}
+	entries := binary.BigEndian.Uint32(ipma.data[4:])
+	rest := ipma.data[8:]
+	for i := uint32(0); i < entries; i++ {
+		var id uint32
+		if id, rest, ok = readHEIFId(rest, idSize); !ok || len(rest) < 1 {
+			return nil
+		}
+		count := int(rest[0])
+		rest = rest[1:]
+		if len(rest) < count*indexSize {
+			return nil
+		}
+		associations := rest[:count*indexSize]
+		rest = rest[count*indexSize:]
+		if id != info.primary {
+			continue
+		}
+
+		var transforms []heifBox
+		for a := 0; a < count; a++ {
+			var index int
+			if indexSize == 2 {
+				index = int(binary.BigEndian.Uint16(associations[a*2:]) & 0x7fff)
+			} else {
+				index = int(associations[a] & 0x7f)
+			}
+			if index < 1 || index > len(propertyBoxes) {
+				continue
+			}
+			if property := propertyBoxes[index-1]; property.kind == "irot" || property.kind == "imir" {
+				transforms = append(transforms, property)
+			}
+		}
+		return transforms
+	}
+	return nil
+}
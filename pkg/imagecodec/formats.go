@@ -0,0 +1,215 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"main/pkg/video"
+
+	chaiWebp "github.com/chai2010/webp"
+	"github.com/gen2brain/avif"
+	"github.com/gen2brain/svg"
+	"github.com/xfmoulet/qoi"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// Quality lossy formats are written with
+const quality = 85
+
+func hasPrefix(prefixes ...string) func(header []byte) bool {
+	return func(header []byte) bool {
+		for _, prefix := range prefixes {
+			if bytes.HasPrefix(header, []byte(prefix)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Matches ISO base media files (AVIF, HEIC) whose ftyp box lists one of the brands
+func hasBrand(brands ...string) func(header []byte) bool {
+	return func(header []byte) bool {
+		if len(header) < 12 || string(header[4:8]) != "ftyp" {
+			return false
+		}
+		size := int(header[0])<<24 | int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		end := min(max(size, 12), len(header))
+		// the major brand, then the minor version and the compatible brands
+		for offset := 8; offset+4 <= end; offset += 4 {
+			if offset == 12 {
+				continue
+			}
+			for _, brand := range brands {
+				if string(header[offset:offset+4]) == brand {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// SVG files are text, they are told apart by an svg element near the start
+func isSVG(header []byte) bool {
+	text := bytes.TrimLeft(header, "\xef\xbb\xbf \t\r\n")
+	if !bytes.HasPrefix(text, []byte("<")) {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(text), []byte("<svg"))
+}
+
+// BMP files only start with "BM", so the file header and the size of the DIB header are checked too
+func isBMP(header []byte) bool {
+	if len(header) < 18 || !bytes.HasPrefix(header, []byte("BM")) {
+		return false
+	}
+	fileSize := binary.LittleEndian.Uint32(header[2:])
+	reserved := binary.LittleEndian.Uint32(header[6:])
+	pixels := binary.LittleEndian.Uint32(header[10:])
+	switch dib := binary.LittleEndian.Uint32(header[14:]); dib {
+	case 12, 40, 52, 56, 64, 108, 124:
+		return reserved == 0 && fileSize >= 14+dib && pixels >= 14+dib && pixels <= fileSize
+	}
+	return false
+}
+
+func init() {
+	Register(&Format{
+		Name:       "JPG",
+		Extensions: []string{".jpg", ".jpeg"},
+		Match:      hasPrefix("\xff\xd8\xff"),
+		Decode:     jpeg.Decode,
+		Encode: func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		},
+	})
+	Register(&Format{
+		Name:       "PNG",
+		Extensions: []string{".png"},
+		Match:      hasPrefix("\x89PNG\r\n\x1a\n"),
+		Decode:     png.Decode, // the default image of animated PNGs
+		Encode:     png.Encode,
+		Animated:   isAPNG,
+		Animate:    decodeAPNG,
+	})
+	Register(&Format{
+		Name:       "GIF",
+		Extensions: []string{".gif"},
+		Match:      hasPrefix("GIF87a", "GIF89a"),
+		Decode:     gif.Decode, // the first frame
+		Encode: func(w io.Writer, img image.Image) error {
+			return gif.Encode(w, img, &gif.Options{})
+		},
+		Animated: func(header []byte) bool { return true },
+		Animate:  decodeGIFAnimation,
+	})
+	Register(&Format{
+		Name:       "BMP",
+		Extensions: []string{".bmp"},
+		Match:      isBMP,
+		Decode:     bmp.Decode,
+		Encode:     bmp.Encode,
+	})
+	// raw camera files are TIFF files too, so they are sniffed first
+	Register(&Format{
+		Name:       "DNG",
+		Extensions: []string{".dng"},
+		Match: func(header []byte) bool {
+			return rawKind(header) == "DNG"
+		},
+		Decode: decodeRawPreview,
+	})
+	Register(&Format{
+		Name:       "RAW",
+		Extensions: []string{".raw", ".cr2", ".nef", ".arw", ".rw2", ".pef", ".srw"},
+		Match: func(header []byte) bool {
+			return rawKind(header) == "RAW"
+		},
+		Decode: decodeRawPreview,
+	})
+	Register(&Format{
+		Name:       "TIFF",
+		Extensions: []string{".tiff", ".tif"},
+		Match:      hasPrefix("II*\x00", "MM\x00*"),
+		Decode:     tiff.Decode,
+		Encode: func(w io.Writer, img image.Image) error {
+			return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate})
+		},
+	})
+	Register(&Format{
+		Name:       "WEBP",
+		Extensions: []string{".webp"},
+		Match: func(header []byte) bool {
+			return len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WEBP"
+		},
+		Decode: decodeWebP, // the first frame of animated ones
+		Encode: func(w io.Writer, img image.Image) error {
+			return chaiWebp.Encode(w, img, &chaiWebp.Options{Quality: quality})
+		},
+		Animated: isAnimatedWebP,
+		Animate:  decodeWebPAnimation,
+	})
+	Register(&Format{
+		Name:       "AVIF",
+		Extensions: []string{".avif"},
+		Match:      hasBrand("avif", "avis"),
+		Decode:     decodeAVIF,
+		Encode: func(w io.Writer, img image.Image) error {
+			return avif.Encode(w, img, avif.Options{Quality: quality, QualityAlpha: quality})
+		},
+		// HEIF based images are turned by their irot and imir boxes, the EXIF
+		// orientation only describes them
+		Oriented: true,
+	})
+	// after AVIF, AVIF files list the HEIF brand mif1 too
+	Register(&Format{
+		Name:       "HEIC",
+		Extensions: []string{".heic", ".heif", ".hif"},
+		Match:      hasBrand("heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1"),
+		Decode:     decodeHEIF,
+		Count:      countHEIF,
+		DecodeAt:   decodeHEIFAt,
+		Oriented:   true, // libheif applies irot and imir
+	})
+	Register(&Format{
+		Name:       "QOI",
+		Extensions: []string{".qoi"},
+		Match:      hasPrefix("qoif"),
+		Decode:     qoi.Decode,
+		Encode:     qoi.Encode,
+	})
+	Register(&Format{
+		Name:       "SVG",
+		Extensions: []string{".svg"},
+		Match:      isSVG,
+		Decode:     svg.Decode,
+	})
+	// after the images, MP4 files share the ftyp box with AVIF and HEIC
+	for _, container := range []struct {
+		name       string
+		extensions []string
+	}{
+		{video.MP4, []string{".mp4", ".m4v", ".3gp"}},
+		{video.MOV, []string{".mov", ".qt"}},
+		{video.MKV, []string{".mkv"}},
+		{video.WEBM, []string{".webm"}},
+		{video.AVI, []string{".avi"}},
+	} {
+		Register(&Format{
+			Name:       container.name,
+			Extensions: container.extensions,
+			Match: func(header []byte) bool {
+				return video.Detect(header) == container.name
+			},
+			Decode:   video.DecodePoster,
+			Video:    true,
+			Oriented: true,
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package imagecodec
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/bmp"
+)
+
+func TestSniffBMP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := bmp.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 2))); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+	with := func(offset int, b ...byte) []byte {
+		header := bytes.Clone(valid)
+		copy(header[offset:], b)
+		return header
+	}
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   bool
+	}{
+		{"BMP", valid, true},
+		{"OS/2 header", with(14, 12, 0, 0, 0), true},
+		{"text", []byte("BM is a band name and this is a text file"), false},
+		{"truncated", valid[:17], false},
+		{"reserved bytes set", with(6, 1), false},
+		{"unknown DIB header", with(14, 41), false},
+		{"file smaller than its headers", with(2, 20, 0, 0, 0), false},
+		{"pixels past the end", with(10, 0xff, 0xff, 0, 0), false},
+		{"pixels inside the headers", with(10, 20, 0, 0, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := Sniff(tt.header)
+			assert.Equal(t, tt.want, ok && f.Name == "BMP")
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"sync"
+
+	"github.com/gen2brain/heic"
+)
+
+// HEIF files are ISO base media files, the images are items listed in the meta
+// box. The decoder only decodes the primary item, applying its rotation and
+// mirroring, so the other images are decoded by pointing the primary item at them.
+
+var errNoHEIFMeta = errors.New("HEIF file has no meta box")
+
+// Biggest meta box read when counting images, the image data isn't in it
+const maxHEIFMetaSize = 16 << 20
+
+// Item types of coded images, thumbnails and alpha planes are left out through their references
+var heifImageTypes = map[string]bool{"hvc1": true, "grid": true, "iden": true, "iovl": true}
+
+// The decoder runs in a single WASM instance and returns images backed by its
+// memory, so one image is decoded at a time and copied out before the next
+var heicMu sync.Mutex
+
+type heifBox struct {
+	kind   string
+	offset int    // offset of the box contents in the file
+	data   []byte // contents after the header
+}
+
+// Lists the boxes in data, which starts at offset in the file
+func readHEIFBoxes(data []byte, offset int) []heifBox {
+	var boxes []heifBox
+	for pos := 0; pos+8 <= len(data); {
+		size := uint64(binary.BigEndian.Uint32(data[pos:]))
+		boxHeaderSize := 8
+		switch size {
+		case 0:
+			// box runs to the end of the file
+			size = uint64(len(data) - pos)
+		case 1:
+			if pos+16 > len(data) {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[pos+8:])
+			boxHeaderSize = 16
+		}
+		// 64 bit sizes don't fit an int, so they are compared with what is left of data
+		if size < uint64(boxHeaderSize) || size > uint64(len(data)-pos) {
+			break
+		}
+		boxes = append(boxes, heifBox{
+			kind:   string(data[pos+4 : pos+8]),
+			offset: offset + pos + boxHeaderSize,
+			data:   data[pos+boxHeaderSize : pos+int(size)],
+		})
+		pos += int(size)
+	}
+	return boxes
+}
+
+func findHEIFBox(boxes []heifBox, kind string) (heifBox, bool) {
+	for _, b := range boxes {
+		if b.kind == kind {
+			return b, true
+		}
+	}
+	return heifBox{}, false
+}
+
+// Reads a big endian id of 2 or 4 bytes
+func readHEIFId(data []byte, size int) (uint32, []byte, bool) {
+	if len(data) < size {
+		return 0, nil, false
+	}
+	if size == 2 {
+		return uint32(binary.BigEndian.Uint16(data)), data[2:], true
+	}
+	return binary.BigEndian.Uint32(data), data[4:], true
+}
+
+// Images of a HEIF file as listed in its meta box
+type heifImages struct {
+	primary  uint32
+	pitm     int      // offset of the primary item id in the file
+	pitmSize int      // 2 or 4 bytes
+	images   []uint32 // ids of the images shown to the user, the primary first
+}
+
+// Reads the image items of the contents of a meta box at offset in the file
+func parseHEIFMeta(meta []byte, offset int) (*heifImages, error) {
+	if len(meta) < 4 {
+		return nil, errNoHEIFMeta
+	}
+	// meta is a full box, version and flags come before its children
+	children := readHEIFBoxes(meta[4:], offset+4)
+	info := &heifImages{}
+
+	pitm, ok := findHEIFBox(children, "pitm")
+	if !ok || len(pitm.data) < 6 {
+		return nil, fmt.Errorf("HEIF file has no primary image")
+	}
+	info.pitm, info.pitmSize = pitm.offset+4, 2
+	if pitm.data[0] > 0 {
+		info.pitmSize = 4
+	}
+	info.primary, _, ok = readHEIFId(pitm.data[4:], info.pitmSize)
+	if !ok {
+		return nil, fmt.Errorf("HEIF file has no primary image")
+	}
+
+	// thumbnails and alpha planes reference the image they belong to, grids reference their tiles
+	excluded := map[uint32]bool{}
+	if iref, ok := findHEIFBox(children, "iref"); ok && len(iref.data) >= 4 {
+		idSize := 2
+		if iref.data[0] > 0 {
+			idSize = 4
+		}
+		for _, reference := range readHEIFBoxes(iref.data[4:], iref.offset+4) {
+			from, rest, ok := readHEIFId(reference.data, idSize)
+			if !ok || len(rest) < 2 {
+				continue
+			}
+			count := int(binary.BigEndian.Uint16(rest))
+			rest = rest[2:]
+			switch reference.kind {
+			case "thmb", "auxl":
+				excluded[from] = true
+			case "dimg":
+				for i := 0; i < count; i++ {
+					var to uint32
+					if to, rest, ok = readHEIFId(rest, idSize); !ok {
+						break
+					}
+					excluded[to] = true
+				}
+			}
+		}
+	}
+
+	iinf, ok := findHEIFBox(children, "iinf")
+	if !ok || len(iinf.data) < 6 {
+		return nil, fmt.Errorf("HEIF file has no item info")
+	}
+	entries := iinf.data[6:]
+	if iinf.data[0] > 0 {
+		entries = iinf.data[8:]
+	}
+	info.images = []uint32{info.primary}
+	for _, infe := range readHEIFBoxes(entries, 0) {
+		if infe.kind != "infe" || len(infe.data) < 4 || infe.data[0] < 2 {
+			continue
+		}
+		hidden := infe.data[3]&1 == 1
+		idSize := 2
+		if infe.data[0] == 3 {
+			idSize = 4
+		}
+		id, rest, ok := readHEIFId(infe.data[4:], idSize)
+		// the protection index comes before the item type
+		if !ok || len(rest) < 6 || hidden || excluded[id] || id == info.primary {
+			continue
+		}
+		if heifImageTypes[string(rest[2:6])] {
+			info.images = append(info.images, id)
+		}
+	}
+	return info, nil
+}
+
+// Reads the image items of a whole HEIF file
+func readHEIFImages(data []byte) (*heifImages, error) {
+	meta, ok := findHEIFBox(readHEIFBoxes(data, 0), "meta")
+	if !ok {
+		return nil, errNoHEIFMeta
+	}
+	return parseHEIFMeta(meta.data, meta.offset)
+}
+
+// Decodes a HEIF file with the WASM decoder
+func decodeHEIC(data []byte) (image.Image, error) {
+	heicMu.Lock()
+	defer heicMu.Unlock()
+
+	img, err := heic.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	res := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(res, res.Bounds(), img, bounds.Min, draw.Src)
+	return res, nil
+}
+
+// Decodes the primary image of a HEIF file
+func decodeHEIF(r io.Reader) (image.Image, error) {
+	return decodeHEIFAt(r, 0)
+}
+
+// Decodes one of the images of a HEIF file, the primary image is index 0
+func decodeHEIFAt(r io.Reader, index int) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := readHEIFImages(data)
+	if err != nil {
+		// files the meta box can't be read from are left to the decoder
+		if index == 0 {
+			return decodeHEIC(data)
+		}
+		return nil, err
+	}
+	if index < 0 || index >= len(info.images) {
+		return nil, fmt.Errorf("HEIF file has %d images, there is no image %d", len(info.images), index+1)
+	}
+
+	id := info.images[index]
+	if id != info.primary {
+		if info.pitmSize == 2 && id > 0xFFFF {
+			return nil, fmt.Errorf("HEIF image %d can't be decoded", index+1)
+		}
+		data = bytes.Clone(data)
+		if info.pitmSize == 2 {
+			binary.BigEndian.PutUint16(data[info.pitm:], uint16(id))
+		} else {
+			binary.BigEndian.PutUint32(data[info.pitm:], id)
+		}
+	}
+
+	return decodeHEIC(data)
+}
+
+// Returns the number of images in a HEIF file, only the meta box is read into memory
+func countHEIF(r io.Reader) (int, error) {
+	header := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return 0, errNoHEIFMeta
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		boxHeaderSize := int64(8)
+		if size == 1 {
+			if _, err := io.ReadFull(r, header[8:]); err != nil {
+				return 0, errNoHEIFMeta
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			boxHeaderSize = 16
+		}
+		if size != 0 && size < boxHeaderSize {
+			return 0, errNoHEIFMeta
+		}
+		if string(header[4:8]) == "meta" && (size == 0 || size > maxHEIFMetaSize) {
+			return 0, fmt.Errorf("HEIF meta box is too big")
+		}
+
+		if string(header[4:8]) != "meta" {
+			// the box runs to the end of the file
+			if size == 0 {
+				return 0, errNoHEIFMeta
+			}
+			if _, err := io.CopyN(io.Discard, r, size-boxHeaderSize); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		meta := make([]byte, size-boxHeaderSize)
+		if _, err := io.ReadFull(r, meta); err != nil {
+			return 0, err
+		}
+		info, err := parseHEIFMeta(meta, 0)
+		if err != nil {
+			return 0, err
+		}
+		return len(info.images), nil
+	}
+}
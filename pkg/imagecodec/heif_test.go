@@ -0,0 +1,217 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a box with a 32 bit size
+func testBox(kind string, data []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(box, kind...), data...)
+}
+
+// Builds a box header with a 64 bit size, the contents aren't added
+func testLargeBoxHeader(kind string, size uint64) []byte {
+	box := binary.BigEndian.AppendUint32(nil, 1)
+	box = append(box, kind...)
+	return binary.BigEndian.AppendUint64(box, size)
+}
+
+func heicFtyp() []byte {
+	// major brand, minor version and one compatible brand
+	return testBox("ftyp", []byte("heic\x00\x00\x00\x00mif1"))
+}
+
+func TestReadHEIFBoxes(t *testing.T) {
+	two := append(testBox("free", []byte{1, 2}), testBox("meta", []byte{3})...)
+	tests := []struct {
+		name  string
+		data  []byte
+		kinds []string
+	}{
+		{"empty", nil, nil},
+		{"two boxes", two, []string{"free", "meta"}},
+		{"truncated header", two[:5], nil},
+		{"truncated second box", two[:len(two)-1], []string{"free"}},
+		{"size smaller than header", []byte{0, 0, 0, 4, 'f', 'r', 'e', 'e'}, nil},
+		{"size past the end", []byte{0, 0, 0, 64, 'f', 'r', 'e', 'e', 0}, nil},
+		{"size 0 runs to the end", []byte{0, 0, 0, 0, 'm', 'd', 'a', 't', 1, 2, 3}, []string{"mdat"}},
+		{"64 bit size", append(testLargeBoxHeader("mdat", 18), 1, 2), []string{"mdat"}},
+		{"64 bit size truncated", testLargeBoxHeader("mdat", 18)[:12], nil},
+		{"64 bit size past the end", testLargeBoxHeader("mdat", 1<<40), nil},
+		{"64 bit size overflowing int", testLargeBoxHeader("meta", 0x7FFFFFFFFFFFFFFF), nil},
+		{"64 bit size above int", testLargeBoxHeader("meta", 0xFFFFFFFFFFFFFFFF), nil},
+		{"64 bit size smaller than header", testLargeBoxHeader("meta", 12), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds []string
+			for _, box := range readHEIFBoxes(tt.data, 0) {
+				kinds = append(kinds, box.kind)
+			}
+			assert.Equal(t, tt.kinds, kinds)
+		})
+	}
+}
+
+func TestReadHEIFBoxesOffsets(t *testing.T) {
+	data := append(testBox("free", []byte{1, 2}), append(testLargeBoxHeader("mdat", 19), 3, 4, 5)...)
+	boxes := readHEIFBoxes(data, 100)
+	if assert.Len(t, boxes, 2) {
+		assert.Equal(t, 108, boxes[0].offset)
+		assert.Equal(t, []byte{1, 2}, boxes[0].data)
+		assert.Equal(t, 100+10+16, boxes[1].offset)
+		assert.Equal(t, []byte{3, 4, 5}, boxes[1].data)
+	}
+}
+
+// A meta box with a 64 bit size that overflows an int used to panic
+func TestDecodeFileHEIFOversizedMeta(t *testing.T) {
+	data := append(heicFtyp(), testLargeBoxHeader("meta", 0x7FFFFFFFFFFFFFFF)...)
+	data = append(data, make([]byte, 72-len(data))...)
+	path := filepath.Join(t.TempDir(), "oversized.heic")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotPanics(t, func() {
+		_, _, err := DecodeFile(path)
+		assert.Error(t, err)
+	})
+	assert.NotPanics(t, func() {
+		assert.Equal(t, 1, ImageCount(path))
+	})
+}
+
+// Builds a version 2 infe box of an item
+func testInfe(id uint16, itemType string, hidden bool) []byte {
+	flags := byte(0)
+	if hidden {
+		flags = 1
+	}
+	data := []byte{2, 0, 0, flags}
+	data = binary.BigEndian.AppendUint16(data, id)
+	data = append(data, 0, 0)
+	data = append(data, itemType...)
+	return testBox("infe", append(data, 0))
+}
+
+// Builds a meta box body with the primary item 1, the items and the references
+func testHEIFMeta(items [][]byte, references [][]byte) []byte {
+	meta := []byte{0, 0, 0, 0}
+	meta = append(meta, testBox("pitm", []byte{0, 0, 0, 0, 0, 1})...)
+	iinf := binary.BigEndian.AppendUint16([]byte{0, 0, 0, 0}, uint16(len(items)))
+	for _, item := range items {
+		iinf = append(iinf, item...)
+	}
+	meta = append(meta, testBox("iinf", iinf)...)
+	if len(references) > 0 {
+		iref := []byte{0, 0, 0, 0}
+		for _, reference := range references {
+			iref = append(iref, reference...)
+		}
+		meta = append(meta, testBox("iref", iref)...)
+	}
+	return meta
+}
+
+// Builds a reference box with 16 bit ids
+func testReference(kind string, from uint16, to ...uint16) []byte {
+	data := binary.BigEndian.AppendUint16(nil, from)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(to)))
+	for _, id := range to {
+		data = binary.BigEndian.AppendUint16(data, id)
+	}
+	return testBox(kind, data)
+}
+
+func TestParseHEIFMeta(t *testing.T) {
+	burst := testHEIFMeta([][]byte{
+		testInfe(1, "hvc1", false),
+		testInfe(2, "hvc1", false),
+		testInfe(3, "hvc1", false), // thumbnail of 1
+		testInfe(4, "Exif", false),
+		testInfe(5, "hvc1", true),
+		testInfe(6, "grid", false),
+		testInfe(7, "hvc1", false), // tile of 6
+	}, [][]byte{
+		testReference("thmb", 3, 1),
+		testReference("dimg", 6, 7),
+	})
+	pitmOnly := append([]byte{0, 0, 0, 0}, testBox("pitm", []byte{0, 0, 0, 0, 0, 1})...)
+
+	tests := []struct {
+		name    string
+		meta    []byte
+		images  []uint32
+		wantErr bool
+	}{
+		{"burst", burst, []uint32{1, 2, 6}, false},
+		{"single image", testHEIFMeta([][]byte{testInfe(1, "hvc1", false)}, nil), []uint32{1}, false},
+		{"empty", nil, nil, true},
+		{"only version", pitmOnly[:4], nil, true},
+		{"truncated pitm", pitmOnly[:len(pitmOnly)-1], nil, true},
+		{"pitm without id", append([]byte{0, 0, 0, 0}, testBox("pitm", []byte{0, 0, 0, 0})...), nil, true},
+		{"no item info", pitmOnly, nil, true},
+		// the references come last, without them thumbnails and tiles are listed
+		{"truncated references", burst[:len(burst)-1], []uint32{1, 2, 3, 6, 7}, false},
+		{"truncated item info", burst[:len(burst)-45], nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := parseHEIFMeta(tt.meta, 0)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, uint32(1), info.primary)
+				assert.Equal(t, tt.images, info.images)
+			}
+		})
+	}
+}
+
+func TestCountHEIF(t *testing.T) {
+	meta := testBox("meta", testHEIFMeta([][]byte{
+		testInfe(1, "hvc1", false),
+		testInfe(2, "hvc1", false),
+	}, nil))
+	valid := append(heicFtyp(), meta...)
+	withMdat := append(append(heicFtyp(), testBox("mdat", make([]byte, 100))...), meta...)
+	largeMeta := append(heicFtyp(), testLargeBoxHeader("meta", uint64(len(meta)+8))...)
+	largeMeta = append(largeMeta, meta[8:]...)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		count int
+	}{
+		{"two images", valid, 2},
+		{"meta after the image data", withMdat, 2},
+		{"64 bit meta size", largeMeta, 2},
+		{"empty", nil, 0},
+		{"no meta", heicFtyp(), 0},
+		{"truncated meta", valid[:len(valid)-10], 0},
+		{"size smaller than header", append(heicFtyp(), 0, 0, 0, 4, 'm', 'e', 't', 'a'), 0},
+		{"meta runs to the end", append(heicFtyp(), append([]byte{0, 0, 0, 0, 'm', 'e', 't', 'a'}, meta[8:]...)...), 0},
+		{"meta too big", append(heicFtyp(), testLargeBoxHeader("meta", maxHEIFMetaSize+1)...), 0},
+		{"64 bit size above int", append(heicFtyp(), testLargeBoxHeader("meta", 0xFFFFFFFFFFFFFFFF)...), 0},
+		{"box past the end", append(heicFtyp(), testLargeBoxHeader("mdat", 0x7FFFFFFFFFFFFFFF)...), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				count, err := countHEIF(bytes.NewReader(tt.data))
+				assert.Equal(t, tt.count, count)
+				assert.Equal(t, tt.count == 0, err != nil)
+			})
+		})
+	}
+}
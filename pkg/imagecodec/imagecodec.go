@@ -0,0 +1,279 @@
+// Package imagecodec is the one place image formats are known. Every format is
+// registered with the magic bytes that identify it, its file extensions and the
+// functions that decode and encode it, so adding a format is one Register call
+package imagecodec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"main/pkg/imagetransform"
+	"main/pkg/metadata"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Returned when neither the content nor the extension of a file is a registered format
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// Bytes read from the start of a file to tell its format, enough for the first
+// IFD of raw camera files
+const headerSize = 4096
+
+// Image format with the functions that read and write it
+type Format struct {
+	Name       string                                   // e.g. JPG, the same names as the file type tags
+	Extensions []string                                 // lowercase with the dot, the first one is used for new files
+	Match      func(header []byte) bool                 // true when the start of a file is in this format
+	Decode     func(r io.Reader) (image.Image, error)   // nil when the format can't be read
+	Encode     func(w io.Writer, img image.Image) error // nil when the format can't be written
+
+	// Formats whose files can hold several images, nil for the others
+	Count    func(r io.Reader) (int, error)                    // number of images in a file
+	DecodeAt func(r io.Reader, index int) (image.Image, error) // decodes one of them, the first one is the main image
+
+	// Formats with animations, nil for the others
+	Animated func(header []byte) bool                              // true when the start of a file says it is animated
+	Animate  func(r io.Reader, keep FrameFunc) (*Animation, error) // decodes every frame, keep makes the images kept of them
+
+	Video    bool // video containers, Decode returns their poster image
+	Oriented bool // Decode already turns the image upright, the EXIF orientation isn't applied again
+}
+
+// Returns true if images can be read from the format
+func (f *Format) CanDecode() bool {
+	return f.Decode != nil
+}
+
+// Returns true if images can be written in the format
+func (f *Format) CanEncode() bool {
+	return f.Encode != nil
+}
+
+// Returns true if ext like .JPG is one of the extensions of the format, ignoring case
+func (f *Format) HasExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, e := range f.Extensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	registryMu sync.RWMutex
+	formats    []*Format
+)
+
+// Adds a format, formats registered first are sniffed first
+func Register(f *Format) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	formats = append(formats, f)
+}
+
+// Returns every registered format
+func Formats() []*Format {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]*Format(nil), formats...)
+}
+
+// Returns the format with the name like JPG or its extension like jpeg, ignoring case
+func ByName(name string) (*Format, bool) {
+	name = strings.ToLower(strings.TrimPrefix(name, "."))
+	for _, f := range Formats() {
+		if strings.ToLower(f.Name) == name {
+			return f, true
+		}
+	}
+	return ByExtension("." + name)
+}
+
+// Returns the format a file extension like .JPG belongs to, ignoring case
+func ByExtension(ext string) (*Format, bool) {
+	for _, f := range Formats() {
+		if f.HasExtension(ext) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the format the start of a file is in
+func Sniff(header []byte) (*Format, bool) {
+	for _, f := range Formats() {
+		if f.Match != nil && f.Match(header) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the format of a file from its content, files that can't be sniffed
+// get the format of their extension
+func DetectFile(path string) (*Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return detect(header[:n], path)
+}
+
+func detect(header []byte, path string) (*Format, error) {
+	if f, ok := Sniff(header); ok {
+		return f, nil
+	}
+	if f, ok := ByExtension(filepath.Ext(path)); ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("%w %s", ErrUnsupportedFormat, filepath.Ext(path))
+}
+
+// Reader handed to the decoders of files, it can be read at any offset too so
+// decoders of containers like videos don't have to read the whole file
+type fileReader struct {
+	*bufio.Reader
+	file io.ReaderAt
+	size int64
+}
+
+func (f *fileReader) ReadAt(p []byte, off int64) (int, error) {
+	return f.file.ReadAt(p, off)
+}
+
+func (f *fileReader) Size() int64 {
+	return f.size
+}
+
+// Tells the format of a reader without consuming it, reading goes on from the returned
+// reader. Files and other readers with a size can be read at any offset through it,
+// r is read from its start then
+func sniffReader(r io.Reader, name string) (io.Reader, *Format, error) {
+	buffered := bufio.NewReaderSize(r, headerSize)
+	header, err := buffered.Peek(headerSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, nil, err
+	}
+	f, err := detect(header, name)
+
+	switch source := r.(type) {
+	case *os.File:
+		if info, statErr := source.Stat(); statErr == nil {
+			return &fileReader{buffered, source, info.Size()}, f, err
+		}
+	case interface {
+		io.ReaderAt
+		Size() int64
+	}:
+		return &fileReader{buffered, source, source.Size()}, f, err
+	}
+	return buffered, f, err
+}
+
+// Decodes an image in any registered format, the format is told by the content
+// first and by the extension of name when the content can't be sniffed. The image
+// is returned as it is stored, DecodeFile applies the EXIF orientation too
+func Decode(r io.Reader, name string) (image.Image, *Format, error) {
+	buffered, f, err := sniffReader(r, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !f.CanDecode() {
+		return nil, f, fmt.Errorf("%w: %s can't be decoded", ErrUnsupportedFormat, f.Name)
+	}
+	img, err := f.Decode(buffered)
+	return img, f, err
+}
+
+// Decodes the image file at path, turned upright by its EXIF orientation
+func DecodeFile(path string) (image.Image, *Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	img, f, err := Decode(file, path)
+	if err != nil {
+		return nil, f, err
+	}
+	return orient(img, f, path), f, nil
+}
+
+// Turns a decoded image upright by the EXIF orientation of its file
+func orient(img image.Image, f *Format, path string) image.Image {
+	if f.Oriented {
+		return img
+	}
+	m, _ := metadata.Read(path)
+	if m == nil {
+		return img
+	}
+	return imagetransform.Orient(img, m.Orientation)
+}
+
+// Returns the number of images in a file, 1 for formats that hold a single image
+func ImageCount(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return 1
+	}
+	defer file.Close()
+
+	buffered, f, err := sniffReader(file, path)
+	if err != nil || f.Count == nil {
+		return 1
+	}
+	count, err := f.Count(buffered)
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
+
+// Decodes the image at index in a file holding several images, index 0 is the
+// same image Decode returns
+func DecodeFileAt(path string, index int) (image.Image, *Format, error) {
+	if index == 0 {
+		return DecodeFile(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	buffered, f, err := sniffReader(file, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.DecodeAt == nil {
+		return nil, f, fmt.Errorf("%s files hold a single image", f.Name)
+	}
+	img, err := f.DecodeAt(buffered, index)
+	if err != nil {
+		return nil, f, err
+	}
+	return orient(img, f, path), f, nil
+}
+
+// Encodes an image in the format with the name like PNG or JPG
+func Encode(w io.Writer, img image.Image, name string) error {
+	f, ok := ByName(name)
+	if !ok || !f.CanEncode() {
+		return fmt.Errorf("%w: %s can't be encoded", ErrUnsupportedFormat, name)
+	}
+	return f.Encode(w, img)
+}
@@ -0,0 +1,91 @@
+package imagecodec
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"JPG", "\xff\xd8\xff\xe0\x00\x10JFIF", "JPG"},
+		{"PNG", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "PNG"},
+		{"GIF", "GIF89a\x04\x00\x02\x00", "GIF"},
+		{"TIFF little endian", "II*\x00\x08\x00\x00\x00", "TIFF"},
+		{"TIFF big endian", "MM\x00*\x00\x00\x00\x08", "TIFF"},
+		{"WEBP", "RIFF\x24\x00\x00\x00WEBPVP8 ", "WEBP"},
+		{"AVIF", "\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf", "AVIF"},
+		{"AVIF compatible brand", "\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00avis", "AVIF"},
+		// the minor version is not a brand
+		{"brand in the minor version", "\x00\x00\x00\x14ftypM4A avif\x00\x00\x00\x00", ""},
+		{"QOI", "qoif\x00\x00\x00\x04\x00\x00\x00\x02\x04\x00", "QOI"},
+		{"SVG", `<svg xmlns="http://www.w3.org/2000/svg"/>`, "SVG"},
+		{"SVG with BOM and declaration", "\xef\xbb\xbf\n<?xml version=\"1.0\"?>\n<SVG>", "SVG"},
+		{"HTML", "<html><body></body></html>", ""},
+		{"text mentioning svg", "draw an <svg> element", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := Sniff([]byte(tt.header))
+			name := ""
+			if ok {
+				name = f.Name
+			}
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestByName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"JPG", "JPG"},
+		{"jpeg", "JPG"},
+		{".Tif", "TIFF"},
+		{"webp", "WEBP"},
+		{"txt", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := ByName(tt.name)
+			name := ""
+			if ok {
+				name = f.Name
+			}
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for _, name := range []string{"JPG", "PNG", "GIF", "BMP", "TIFF", "QOI"} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, img, name); err != nil {
+				t.Fatal(err)
+			}
+			// the content wins over a wrong extension
+			decoded, f, err := Decode(&buf, "image.txt")
+			if assert.NoError(t, err) {
+				assert.Equal(t, name, f.Name)
+				assert.Equal(t, img.Bounds(), decoded.Bounds())
+			}
+		})
+	}
+
+	_, _, err := Decode(strings.NewReader("not an image"), "notes.txt")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	assert.ErrorIs(t, Encode(&bytes.Buffer{}, img, "SVG"), ErrUnsupportedFormat)
+}
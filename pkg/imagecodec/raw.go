@@ -0,0 +1,267 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Raw camera files are TIFF files holding the sensor data that can't be decoded
+// here, next to JPEG previews made by the camera. The biggest preview is shown.
+
+var errNoRawPreview = errors.New("raw file has no embedded preview")
+
+// TIFF tags that locate the previews
+const (
+	tagCompression      = 0x0103
+	tagPhotometric      = 0x0106
+	tagMake             = 0x010F
+	tagStripOffsets     = 0x0111
+	tagStripByteCounts  = 0x0117
+	tagSubIFDs          = 0x014A
+	tagJPEGOffset       = 0x0201
+	tagJPEGLength       = 0x0202
+	tagDNGVersion       = 0xC612
+	tagPanasonicPreview = 0x002E // JpgFromRaw of Panasonic RW2 files
+)
+
+// Previews bigger than this are skipped
+const maxRawPreviewSize = 64 << 20
+
+// Most IFDs followed, raw files have a handful
+const maxRawIFDs = 32
+
+// Reads a stream on demand so only the part of a raw file up to its preview is kept in memory
+type lazyReaderAt struct {
+	r    io.Reader
+	data []byte
+	err  error
+}
+
+func (l *lazyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	end := off + int64(len(p))
+	for int64(len(l.data)) < end && l.err == nil {
+		chunk := make([]byte, max(end-int64(len(l.data)), 64<<10))
+		n, err := io.ReadFull(l.r, chunk)
+		l.data = append(l.data, chunk[:n]...)
+		if err != nil {
+			l.err = err
+		}
+	}
+	if off >= int64(len(l.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, l.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Sizes of the TIFF value types read, bytes, ASCII, shorts, longs, undefined and IFD offsets
+var rawTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 7: 1, 13: 4}
+
+type rawEntry struct {
+	typ    uint16
+	count  uint32
+	offset uint32   // the last 4 bytes of the entry, where longer values are
+	values []uint32 // the integer values, empty for other types and long lists
+	value  []byte   // the raw value bytes
+}
+
+type rawIFD map[uint16]rawEntry
+
+func (ifd rawIFD) uint(tag uint16) (uint32, bool) {
+	entry, ok := ifd[tag]
+	if !ok || len(entry.values) == 0 {
+		return 0, false
+	}
+	return entry.values[0], true
+}
+
+// Reads a TIFF IFD, value bytes are only read for short tags and integer lists
+func readRawIFD(r io.ReaderAt, order binary.ByteOrder, offset int64) (rawIFD, int64, error) {
+	countBytes := make([]byte, 2)
+	if _, err := r.ReadAt(countBytes, offset); err != nil {
+		return nil, 0, err
+	}
+	count := int(order.Uint16(countBytes))
+	if count == 0 || count > 1000 {
+		return nil, 0, errNoRawPreview
+	}
+	entries := make([]byte, count*12+4)
+	if _, err := r.ReadAt(entries, offset+2); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+
+	ifd := rawIFD{}
+	for i := 0; i < count; i++ {
+		entry := entries[i*12 : i*12+12]
+		e := rawEntry{typ: order.Uint16(entry[2:]), count: order.Uint32(entry[4:]), offset: order.Uint32(entry[8:])}
+		size := rawTypeSizes[e.typ]
+		if size == 0 || e.count > 1024 {
+			// long values like the preview of RW2 files are located by offset and count
+			ifd[order.Uint16(entry)] = e
+			continue
+		}
+		length := int(e.count) * size
+		if length <= 4 {
+			e.value = entry[8 : 8+length]
+		} else {
+			e.value = make([]byte, length)
+			if _, err := r.ReadAt(e.value, int64(e.offset)); err != nil {
+				continue
+			}
+		}
+		for j := 0; j < int(e.count); j++ {
+			switch size {
+			case 1:
+				e.values = append(e.values, uint32(e.value[j]))
+			case 2:
+				e.values = append(e.values, uint32(order.Uint16(e.value[2*j:])))
+			case 4:
+				e.values = append(e.values, order.Uint32(e.value[4*j:]))
+			}
+		}
+		ifd[order.Uint16(entry)] = e
+	}
+	return ifd, int64(order.Uint32(entries[count*12:])), nil
+}
+
+// Returns the byte order of a TIFF based raw file, RW2 files use their own magic number
+func rawByteOrder(header []byte) (binary.ByteOrder, bool) {
+	if len(header) < 8 {
+		return nil, false
+	}
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, false
+	}
+	switch order.Uint16(header[2:]) {
+	case 42, 0x55:
+		return order, true
+	}
+	return nil, false
+}
+
+// Tells raw files apart from plain TIFF images by the first IFD in the header
+func rawKind(header []byte) string {
+	order, ok := rawByteOrder(header)
+	if !ok {
+		return ""
+	}
+	magic := order.Uint16(header[2:])
+	if magic == 0x55 || (len(header) >= 10 && string(header[8:10]) == "CR") {
+		return "RAW" // Panasonic RW2 and Canon CR2
+	}
+
+	ifd, _, err := readRawIFD(bytes.NewReader(header), order, int64(order.Uint32(header[4:])))
+	if err != nil {
+		return ""
+	}
+	if _, ok := ifd[tagDNGVersion]; ok {
+		return "DNG"
+	}
+	_, hasSubIFDs := ifd[tagSubIFDs]
+	_, hasPreview := ifd[tagJPEGOffset]
+	cameraMake := strings.ToUpper(string(bytes.TrimRight(ifd[tagMake].value, "\x00 ")))
+	for _, vendor := range []string{"NIKON", "SONY", "PENTAX", "RICOH", "SAMSUNG"} {
+		if strings.HasPrefix(cameraMake, vendor) && (hasSubIFDs || hasPreview) {
+			return "RAW"
+		}
+	}
+	return ""
+}
+
+type rawPreview struct {
+	offset int64
+	length int64
+}
+
+// Lists the JPEG previews in the IFD chain and the sub IFDs of a raw file
+func findRawPreviews(r io.ReaderAt, order binary.ByteOrder, first int64) []rawPreview {
+	var previews []rawPreview
+	visited := map[int64]bool{}
+	queue := []int64{first}
+	for len(queue) > 0 && len(visited) < maxRawIFDs {
+		offset := queue[0]
+		queue = queue[1:]
+		if offset <= 0 || visited[offset] {
+			continue
+		}
+		visited[offset] = true
+		ifd, next, err := readRawIFD(r, order, offset)
+		if err != nil {
+			continue
+		}
+		queue = append(queue, next)
+		if subIFDs, ok := ifd[tagSubIFDs]; ok {
+			for _, sub := range subIFDs.values {
+				queue = append(queue, int64(sub))
+			}
+		}
+
+		if start, ok := ifd.uint(tagJPEGOffset); ok {
+			if length, ok := ifd.uint(tagJPEGLength); ok {
+				previews = append(previews, rawPreview{int64(start), int64(length)})
+			}
+		}
+		if preview, ok := ifd[tagPanasonicPreview]; ok {
+			previews = append(previews, rawPreview{int64(preview.offset), int64(preview.count)})
+		}
+		// images stored as a single JPEG strip, the sensor data is left out by its photometric interpretation
+		compression, _ := ifd.uint(tagCompression)
+		photometric, _ := ifd.uint(tagPhotometric)
+		strips, stripCounts := ifd[tagStripOffsets], ifd[tagStripByteCounts]
+		if (compression == 6 || compression == 7) && photometric != 32803 && photometric != 34892 &&
+			len(strips.values) == 1 && len(stripCounts.values) == 1 {
+			previews = append(previews, rawPreview{int64(strips.values[0]), int64(stripCounts.values[0])})
+		}
+	}
+	return previews
+}
+
+// Decodes the biggest embedded JPEG preview of a raw file
+func decodeRawPreview(r io.Reader) (image.Image, error) {
+	file := &lazyReaderAt{r: r}
+	header := make([]byte, 8)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	order, ok := rawByteOrder(header)
+	if !ok {
+		return nil, errNoRawPreview
+	}
+	previews := findRawPreviews(file, order, int64(order.Uint32(header[4:])))
+	sort.Slice(previews, func(i, j int) bool {
+		return previews[i].length > previews[j].length
+	})
+
+	// the biggest one may be lossless JPEG the decoder can't read, the next one is tried then
+	for _, preview := range previews {
+		if preview.length <= 0 || preview.length > maxRawPreviewSize {
+			continue
+		}
+		data := make([]byte, preview.length)
+		if _, err := file.ReadAt(data, preview.offset); err != nil {
+			continue
+		}
+		if !bytes.HasPrefix(data, []byte("\xff\xd8")) {
+			continue
+		}
+		if img, err := jpeg.Decode(bytes.NewReader(data)); err == nil {
+			return img, nil
+		}
+	}
+	return nil, errNoRawPreview
+}
@@ -0,0 +1,203 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+var errInvalidWebP = errors.New("invalid animated WebP")
+
+// Flags of the VP8X chunk of extended WebP files
+const (
+	webpAnimationFlag = 1 << 1
+	webpAlphaFlag     = 1 << 4
+)
+
+// Returns true if a WebP file is animated, the VP8X chunk right after the RIFF
+// header has the animation flag
+func isAnimatedWebP(header []byte) bool {
+	return len(header) >= 21 && string(header[12:16]) == "VP8X" && header[20]&webpAnimationFlag != 0
+}
+
+// Decodes a WebP image, animated ones give their first frame
+func decodeWebP(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !isAnimatedWebP(data) {
+		return webp.Decode(bytes.NewReader(data))
+	}
+	animation, err := parseWebPAnimation(data, 1, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(animation.Frames) == 0 {
+		return nil, ErrNoFrames
+	}
+	return animation.Frames[0], nil
+}
+
+// Decodes every frame of an animated WebP
+func decodeWebPAnimation(r io.Reader, keep FrameFunc) (*Animation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !isAnimatedWebP(data) {
+		img, err := webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &Animation{Frames: []image.Image{img}, Delays: []time.Duration{defaultFrameDelay}}, nil
+	}
+	return parseWebPAnimation(data, 0, keep)
+}
+
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// Splits the data of a RIFF container into its chunks
+func riffChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) >= 8 {
+		size := binary.LittleEndian.Uint32(data[4:8])
+		if uint64(size) > uint64(len(data)-8) {
+			return chunks, errInvalidWebP
+		}
+		chunks = append(chunks, riffChunk{id: string(data[:4]), data: data[8 : 8+size]})
+		// chunks are padded to an even size
+		next := 8 + int(size) + int(size&1)
+		data = data[min(next, len(data)):]
+	}
+	return chunks, nil
+}
+
+// Returns a 24 bit little endian number
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// Decodes the frames of an animated WebP and composes them on the canvas,
+// at most maxFrames of them when it isn't 0
+func parseWebPAnimation(data []byte, maxFrames int, keep FrameFunc) (*Animation, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errInvalidWebP
+	}
+	chunks, err := riffChunks(data[12:])
+	if err != nil && len(chunks) == 0 {
+		return nil, err
+	}
+
+	animation := &Animation{}
+	var canvas *image.RGBA
+	for _, chunk := range chunks {
+		switch chunk.id {
+		case "VP8X":
+			if len(chunk.data) < 10 {
+				return nil, errInvalidWebP
+			}
+			canvas, err = newAnimationCanvas(image.Rect(0, 0, uint24(chunk.data[4:])+1, uint24(chunk.data[7:])+1))
+			if err != nil {
+				return nil, err
+			}
+		case "ANIM":
+			if len(chunk.data) >= 6 {
+				animation.LoopCount = int(binary.LittleEndian.Uint16(chunk.data[4:6]))
+			}
+		case "ANMF":
+			if canvas == nil || len(chunk.data) < 16 {
+				return nil, errInvalidWebP
+			}
+			x, y := uint24(chunk.data[0:])*2, uint24(chunk.data[3:])*2
+			width, height := uint24(chunk.data[6:])+1, uint24(chunk.data[9:])+1
+			duration := time.Duration(uint24(chunk.data[12:])) * time.Millisecond
+			flags := chunk.data[15]
+			// frames are decoded at their own size, which can't be bigger than the canvas
+			if width > canvas.Bounds().Dx() || height > canvas.Bounds().Dy() {
+				return nil, errInvalidWebP
+			}
+			frame, err := decodeWebPFrame(chunk.data[16:], width, height)
+			if err != nil {
+				return nil, err
+			}
+
+			rect := image.Rect(x, y, x+width, y+height).Intersect(canvas.Bounds())
+			op := draw.Over
+			// bit 1 says the frame replaces the pixels under it instead of being blended
+			if flags&0x02 != 0 {
+				op = draw.Src
+			}
+			draw.Draw(canvas, rect, frame, frame.Bounds().Min, op)
+			animation.addFrame(canvas, duration, keep)
+			if len(animation.Frames) >= maxAnimationFrames || maxFrames > 0 && len(animation.Frames) >= maxFrames {
+				return animation, nil
+			}
+			// bit 0 clears the frame to the background before the next one
+			if flags&0x01 != 0 {
+				draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return animation, nil
+}
+
+// Decodes the bitstream of one frame, an optional ALPH chunk and a VP8 or VP8L
+// chunk, by wrapping it in a WebP file of its own
+func decodeWebPFrame(data []byte, width int, height int) (image.Image, error) {
+	chunks, err := riffChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writeChunk := func(id string, data []byte) {
+		body.WriteString(id)
+		binary.Write(&body, binary.LittleEndian, uint32(len(data)))
+		body.Write(data)
+		if len(data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	for _, chunk := range chunks {
+		if chunk.id == "ALPH" {
+			// lossy frames with alpha need a VP8X chunk saying so
+			vp8x := make([]byte, 10)
+			vp8x[0] = webpAlphaFlag
+			vp8x[4], vp8x[5], vp8x[6] = byte(width-1), byte((width-1)>>8), byte((width-1)>>16)
+			vp8x[7], vp8x[8], vp8x[9] = byte(height-1), byte((height-1)>>8), byte((height-1)>>16)
+			writeChunk("VP8X", vp8x)
+			writeChunk("ALPH", chunk.data)
+		}
+	}
+	for _, chunk := range chunks {
+		if chunk.id == "VP8 " || chunk.id == "VP8L" {
+			writeChunk(chunk.id, chunk.data)
+			break
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(4+body.Len()))
+	file.WriteString("WEBP")
+	file.Write(body.Bytes())
+	// the bitstream has a size of its own, bigger than the frame it is allocated for nothing
+	config, err := webp.DecodeConfig(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > width || config.Height > height {
+		return nil, errInvalidWebP
+	}
+	return webp.Decode(&file)
+}
@@ -0,0 +1,241 @@
+package imagecodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+
+	chaiWebp "github.com/chai2010/webp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Appends a RIFF chunk, padded to an even size
+func appendRIFFChunk(file []byte, id string, data []byte) []byte {
+	file = append(file, id...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(data)))
+	file = append(file, data...)
+	if len(data)%2 == 1 {
+		file = append(file, 0)
+	}
+	return file
+}
+
+// Appends a 24 bit little endian number
+func appendUint24(b []byte, v int) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16))
+}
+
+// Returns the VP8L bitstream of a lossless WebP filled with one color
+func testWebPBitstream(t *testing.T, width, height int, c color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	var buf bytes.Buffer
+	if err := chaiWebp.Encode(&buf, img, &chaiWebp.Options{Lossless: true}); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := riffChunks(buf.Bytes()[12:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		if chunk.id == "VP8L" {
+			return appendRIFFChunk(nil, "VP8L", chunk.data)
+		}
+	}
+	t.Fatal("encoded WebP has no VP8L chunk")
+	return nil
+}
+
+// Builds an ANMF chunk body
+func testANMF(x, y, width, height, durationMs int, flags byte, bitstream []byte) []byte {
+	d := appendUint24(nil, x/2)
+	d = appendUint24(d, y/2)
+	d = appendUint24(d, width-1)
+	d = appendUint24(d, height-1)
+	d = appendUint24(d, durationMs)
+	d = append(d, flags)
+	return append(d, bitstream...)
+}
+
+// Wraps chunks in a WebP file with a VP8X chunk of the canvas size
+func testAnimatedWebP(width, height int, chunks []byte) []byte {
+	vp8x := []byte{webpAnimationFlag, 0, 0, 0}
+	vp8x = appendUint24(vp8x, width-1)
+	vp8x = appendUint24(vp8x, height-1)
+	body := appendRIFFChunk([]byte("WEBP"), "VP8X", vp8x)
+	body = appendRIFFChunk(body, "ANIM", []byte{0, 0, 0, 0, 2, 0})
+	body = append(body, chunks...)
+	file := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body)))
+	return append(file, body...)
+}
+
+// Builds a 4x4 animated WebP, a red frame and a green 2x2 frame at 2, 2
+func testWebPAnimation(t *testing.T) []byte {
+	red := testWebPBitstream(t, 4, 4, color.RGBA{R: 255, A: 255})
+	green := testWebPBitstream(t, 2, 2, color.RGBA{G: 255, A: 255})
+	chunks := appendRIFFChunk(nil, "ANMF", testANMF(0, 0, 4, 4, 50, 0, red))
+	chunks = appendRIFFChunk(chunks, "ANMF", testANMF(2, 2, 2, 2, 0, 0, green))
+	return testAnimatedWebP(4, 4, chunks)
+}
+
+func TestParseWebPAnimation(t *testing.T) {
+	data := testWebPAnimation(t)
+	assert.True(t, isAnimatedWebP(data))
+
+	animation, err := parseWebPAnimation(data, 0, nil)
+	if !assert.NoError(t, err) || !assert.Len(t, animation.Frames, 2) {
+		return
+	}
+	assert.Equal(t, 2, animation.LoopCount)
+	assert.Equal(t, []time.Duration{50 * time.Millisecond, defaultFrameDelay}, animation.Delays)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, animation.Frames[0].At(3, 3))
+	assert.Equal(t, color.RGBA{G: 255, A: 255}, animation.Frames[1].At(3, 3))
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, animation.Frames[1].At(0, 0))
+
+	// the still image decoder stops after the first frame
+	animation, err = parseWebPAnimation(data, 1, nil)
+	if assert.NoError(t, err) {
+		assert.Len(t, animation.Frames, 1)
+	}
+
+	kept := 0
+	animation, err = parseWebPAnimation(data, 0, func(canvas *image.RGBA) image.Image {
+		kept++
+		return image.NewRGBA(image.Rect(0, 0, 1, 1))
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, kept)
+		assert.Equal(t, image.Rect(0, 0, 1, 1), animation.Frames[0].Bounds())
+	}
+}
+
+func TestParseWebPAnimationInvalid(t *testing.T) {
+	valid := testWebPAnimation(t)
+	red := testWebPBitstream(t, 4, 4, color.RGBA{R: 255, A: 255})
+	big := testWebPBitstream(t, 8, 8, color.RGBA{B: 255, A: 255})
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, errInvalidWebP},
+		{"not a WebP", []byte("RIFF\x04\x00\x00\x00AVI "), errInvalidWebP},
+		{"huge canvas", testAnimatedWebP(1<<24, 1<<24, appendRIFFChunk(nil, "ANMF", testANMF(0, 0, 4, 4, 100, 0, red))), ErrAnimationTooBig},
+		{"canvas over the pixel limit", testAnimatedWebP(maxAnimationSide, maxAnimationSide, nil), ErrAnimationTooBig},
+		{"frame bigger than the canvas", testAnimatedWebP(4, 4, appendRIFFChunk(nil, "ANMF", testANMF(0, 0, 1<<24, 4, 100, 0, red))), errInvalidWebP},
+		{"bitstream bigger than the frame", testAnimatedWebP(8, 8, appendRIFFChunk(nil, "ANMF", testANMF(0, 0, 4, 4, 100, 0, big))), errInvalidWebP},
+		{"truncated frame header", testAnimatedWebP(4, 4, appendRIFFChunk(nil, "ANMF", make([]byte, 10))), errInvalidWebP},
+		{"frame before the canvas", append(valid[:12:12], appendRIFFChunk(nil, "ANMF", testANMF(0, 0, 4, 4, 100, 0, red))...), errInvalidWebP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				_, err := parseWebPAnimation(tt.data, 0, nil)
+				assert.ErrorIs(t, err, tt.err)
+			})
+		})
+	}
+
+	// a truncated last frame leaves the frames before it
+	animation, err := parseWebPAnimation(valid[:len(valid)-10], 0, nil)
+	if assert.NoError(t, err) {
+		assert.Len(t, animation.Frames, 1)
+	}
+
+	// frames placed past the edge of the canvas are clipped
+	assert.NotPanics(t, func() {
+		data := testAnimatedWebP(4, 4, appendRIFFChunk(nil, "ANMF", testANMF(1<<24, 1<<24, 4, 4, 100, 0, red)))
+		animation, err := parseWebPAnimation(data, 0, nil)
+		if assert.NoError(t, err) {
+			assert.Len(t, animation.Frames, 1)
+		}
+	})
+}
+
+func TestRIFFChunks(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		ids    []string
+		hasErr bool
+	}{
+		{"empty", nil, nil, false},
+		{"odd size is padded", appendRIFFChunk(appendRIFFChunk(nil, "ALPH", []byte{1, 2, 3}), "VP8L", []byte{4}), []string{"ALPH", "VP8L"}, false},
+		{"padding missing at the end", appendRIFFChunk(nil, "VP8L", []byte{4})[:9], []string{"VP8L"}, false},
+		{"oversized length", []byte("VP8L\xff\xff\xff\xff\x00\x00"), nil, true},
+		{"truncated", appendRIFFChunk(appendRIFFChunk(nil, "ANIM", make([]byte, 6)), "ANMF", make([]byte, 20))[:30], []string{"ANIM"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := riffChunks(tt.data)
+			var ids []string
+			for _, chunk := range chunks {
+				ids = append(ids, chunk.id)
+			}
+			assert.Equal(t, tt.ids, ids)
+			assert.Equal(t, tt.hasErr, err != nil)
+		})
+	}
+}
+
+func TestDecodeGIFAnimation(t *testing.T) {
+	palette := color.Palette{color.RGBA{A: 255}, color.RGBA{R: 255, A: 255}}
+	frame := func(rect image.Rectangle, index uint8) *image.Paletted {
+		img := image.NewPaletted(rect, palette)
+		for i := range img.Pix {
+			img.Pix[i] = index
+		}
+		return img
+	}
+	encode := func(g *gif.GIF) []byte {
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	valid := encode(&gif.GIF{
+		Image: []*image.Paletted{frame(image.Rect(0, 0, 4, 4), 0), frame(image.Rect(2, 2, 4, 4), 1)},
+		Delay: []int{5, 0},
+	})
+	huge := bytes.Clone(valid)
+	// the logical screen size of the GIF header
+	binary.LittleEndian.PutUint16(huge[6:], 0xffff)
+	binary.LittleEndian.PutUint16(huge[8:], 0xffff)
+
+	animation, err := decodeGIFAnimation(bytes.NewReader(valid), nil)
+	if assert.NoError(t, err) && assert.Len(t, animation.Frames, 2) {
+		assert.Equal(t, []time.Duration{50 * time.Millisecond, defaultFrameDelay}, animation.Delays)
+		assert.Equal(t, color.RGBA{R: 255, A: 255}, animation.Frames[1].At(3, 3))
+		assert.Equal(t, color.RGBA{A: 255}, animation.Frames[1].At(0, 0))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"huge canvas", huge, ErrAnimationTooBig},
+		{"truncated", valid[:len(valid)/2], nil},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				_, err := decodeGIFAnimation(bytes.NewReader(tt.data), nil)
+				if tt.err != nil {
+					assert.ErrorIs(t, err, tt.err)
+				} else {
+					assert.Error(t, err)
+				}
+			})
+		})
+	}
+}
@@ -2,26 +2,11 @@ package imageconv
 
 import (
 	"fmt"
-	"image"
-	"image/gif"
-	"image/jpeg"
-	"image/png"
+	"main/pkg/imagecodec"
+	"main/pkg/imagetransform"
 	"os"
 	"path/filepath"
 	"strings"
-
-	chaiWebp "github.com/chai2010/webp"
-
-	// svgo "github.com/ajstarks/svgo"
-	"github.com/gen2brain/avif"
-	"github.com/gen2brain/svg"
-
-	// "github.com/jdeng/goheif"
-	// strukHeif "github.com/strukturag/libheif/go/heif"
-	"github.com/xfmoulet/qoi"
-	"golang.org/x/image/bmp"
-	"golang.org/x/image/tiff"
-	"golang.org/x/image/webp"
 )
 
 var ImageTypes []string = []string{
@@ -35,6 +20,14 @@ var ImageTypes []string = []string{
 	"AVIF",
 	"HEIC",
 	"QOI",
+	"DNG",
+	"RAW",
+	// videos
+	"MP4",
+	"MOV",
+	"MKV",
+	"WEBM",
+	"AVI",
 }
 
 // var home, _ = os.UserHomeDir()
@@ -47,76 +40,17 @@ func ConvertImage(selectedFiles []string, selectedFormat string, selectedDir str
 
 	// loops through selected files and decodes them
 	for key := range selectedFiles {
-		// gets the file extension
-		ext := filepath.Ext(selectedFiles[key])
-		// open the image file
 		fmt.Println("Selected File: ", selectedFiles[key])
-		file, err := os.Open(selectedFiles[key])
+
+		// decode the image turned upright, the format is told by the content of the file.
+		// The converted file has no EXIF so the orientation is baked into the pixels
+		img, format, err := imagecodec.DecodeFile(selectedFiles[key])
+		if format != nil && format.Video {
+			return false, fmt.Errorf("%s is a video, only images can be converted", filepath.Base(selectedFiles[key]))
+		}
 		if err != nil {
-			// return error if failed to open file
-			// should be changed to not crash the func
 			return false, err
 		}
-		defer file.Close()
-
-		// decode the image
-		var img image.Image
-
-		// switch on the image extension
-		switch ext {
-		case ".jpg", ".jpeg":
-			img, _, err = image.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		case ".png":
-			img, _, err = image.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		case ".gif":
-			img, _, err = image.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		case ".bmp":
-			img, err = bmp.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		case ".tiff", ".tif":
-			img, err = tiff.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		case ".webp":
-			img, err = webp.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		case ".svg":
-			img, err = svg.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		case ".avif":
-			img, err = avif.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		// case ".heif", ".heic":
-		// 	img, err = goheif.Decode(file)
-		// 	if err != nil {
-		// 		return false, err
-		// 	}
-		case ".qoi":
-			img, err = qoi.Decode(file)
-			if err != nil {
-				return false, err
-			}
-		default:
-			return false, fmt.Errorf("selected file not an image")
-		}
 
 		// switch on the selected format
 		// encode the image
@@ -139,67 +73,11 @@ func ConvertImage(selectedFiles []string, selectedFormat string, selectedDir str
 		}
 		defer resFile.Close()
 
-		switch selectedFormat {
-		case "PNG":
-			err = png.Encode(res, img)
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-			// resImages[key] = res
-		case "JPG", "JPEG":
-			err = jpeg.Encode(res, img, &jpeg.Options{Quality: 85})
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-		case "WEBP":
-			err = chaiWebp.Encode(res, img, &chaiWebp.Options{Quality: 85})
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-		case "GIF":
-			err = gif.Encode(res, img, &gif.Options{})
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-		case "BMP":
-			err = bmp.Encode(res, img)
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-		case "TIFF", "TIF":
-			err = tiff.Encode(res, img, &tiff.Options{Compression: tiff.Deflate})
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-		case "AVIF":
-			err = avif.Encode(res, img, avif.Options{Quality: 85, QualityAlpha: 85})
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-		// case "HEIC":
-		// 	// heif_ctx, _ := strukHeif.NewContext()
-		// 	_, err = strukHeif.EncodeFromImage(img, strukHeif.Compression(strukHeif.CompressionHEVC), 85, strukHeif.LosslessModeEnabled, strukHeif.LoggingLevelBasic)
-		// 	if err != nil {
-		// 		return false, err
-		// 	}
-		// 	fmt.Println("File Converted: ", res.Name())
-		// strukHeif.NewImage()
-		case "QOI":
-			err = qoi.Encode(res, img)
-			if err != nil {
-				return false, err
-			}
-			fmt.Println("File Converted: ", res.Name())
-		default:
-			return false, fmt.Errorf("selected format not an image type")
+		err = imagecodec.Encode(res, img, selectedFormat)
+		if err != nil {
+			return false, err
 		}
+		fmt.Println("File Converted: ", res.Name())
 
 		// loop through resImages array and batch write images to disk
 		// for imageName, image := range resImages {
@@ -221,3 +99,42 @@ func ConvertImage(selectedFiles []string, selectedFormat string, selectedDir str
 
 	return true, nil
 }
+
+// Writes an image with its edit applied as a new file next to it in the format,
+// named like photo_edited.jpg or photo_edited_2.jpg so no file is overwritten.
+// Returns the path of the new file
+func BakeImage(path string, edit imagetransform.Edit, selectedFormat string) (string, error) {
+	img, format, err := imagecodec.DecodeFile(path)
+	if format != nil && format.Video {
+		return "", fmt.Errorf("%s is a video, only images can be edited", filepath.Base(path))
+	}
+	if err != nil {
+		return "", err
+	}
+	img = imagetransform.Apply(img, edit)
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + "_edited"
+	ext := "." + strings.ToLower(selectedFormat)
+	resPath := filepath.Join(filepath.Dir(path), name+ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(resPath); os.IsNotExist(err) {
+			break
+		}
+		resPath = filepath.Join(filepath.Dir(path), fmt.Sprintf("%s_%d%s", name, i, ext))
+	}
+
+	// O_EXCL so a file made in the meantime isn't overwritten either
+	res, err := os.OpenFile(resPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if err := imagecodec.Encode(res, img, selectedFormat); err != nil {
+		res.Close()
+		os.Remove(resPath)
+		return "", err
+	}
+	if err := res.Close(); err != nil {
+		return "", err
+	}
+	return resPath, nil
+}
@@ -0,0 +1,80 @@
+package imagehash
+
+// BKTree indexes hashes by their Hamming distance so the hashes close to one can be
+// found without comparing it to every hash. The zero value is an empty tree
+type BKTree struct {
+	root *bkNode
+	size int
+}
+
+type bkNode struct {
+	hash     uint64
+	ids      []int // images with exactly this hash
+	children map[int]*bkNode
+}
+
+// Image found in a BKTree with the distance of its hash to the one searched for
+type Match struct {
+	Id       int
+	Distance int
+}
+
+// Adds the hash of an image to the tree
+func (t *BKTree) Add(hash uint64, id int) {
+	t.size++
+	if t.root == nil {
+		t.root = &bkNode{hash: hash, ids: []int{id}}
+		return
+	}
+
+	node := t.root
+	for {
+		distance := Distance(hash, node.hash)
+		if distance == 0 {
+			node.ids = append(node.ids, id)
+			return
+		}
+		child, ok := node.children[distance]
+		if !ok {
+			if node.children == nil {
+				node.children = map[int]*bkNode{}
+			}
+			node.children[distance] = &bkNode{hash: hash, ids: []int{id}}
+			return
+		}
+		node = child
+	}
+}
+
+// Returns every image with a hash at most radius bits from hash
+func (t *BKTree) Search(hash uint64, radius int) []Match {
+	var matches []Match
+	if t.root == nil {
+		return matches
+	}
+
+	pending := []*bkNode{t.root}
+	for len(pending) > 0 {
+		node := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		distance := Distance(hash, node.hash)
+		if distance <= radius {
+			for _, id := range node.ids {
+				matches = append(matches, Match{Id: id, Distance: distance})
+			}
+		}
+		// by the triangle inequality only these children can hold matches
+		for childDistance, child := range node.children {
+			if childDistance >= distance-radius && childDistance <= distance+radius {
+				pending = append(pending, child)
+			}
+		}
+	}
+	return matches
+}
+
+// Returns the number of hashes in the tree
+func (t *BKTree) Len() int {
+	return t.size
+}
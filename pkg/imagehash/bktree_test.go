@@ -0,0 +1,79 @@
+package imagehash
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the matches ordered by id so searches can be compared
+func sortedMatches(matches []Match) []Match {
+	slices.SortFunc(matches, func(a, b Match) int { return a.Id - b.Id })
+	return matches
+}
+
+func TestBKTreeSearch(t *testing.T) {
+	var tree BKTree
+	hashes := []uint64{
+		0x0000000000000000, // 1
+		0x0000000000000001, // 2, 1 bit from 1
+		0x00000000000000FF, // 3, 8 bits from 1
+		0x000000000000FFFF, // 4, 16 bits from 1
+		0xFFFFFFFFFFFFFFFF, // 5, 64 bits from 1
+		0x0000000000000000, // 6, the same hash as 1
+	}
+	for i, hash := range hashes {
+		tree.Add(hash, i+1)
+	}
+	assert.Equal(t, 6, tree.Len())
+
+	tests := []struct {
+		name   string
+		hash   uint64
+		radius int
+		want   []Match
+	}{
+		{"same hash only", 0, 0, []Match{{1, 0}, {6, 0}}},
+		{"one bit", 0, 1, []Match{{1, 0}, {2, 1}, {6, 0}}},
+		{"radius boundary is inside", 0, 8, []Match{{1, 0}, {2, 1}, {3, 8}, {6, 0}}},
+		{"just short of the boundary", 0, 7, []Match{{1, 0}, {2, 1}, {6, 0}}},
+		{"from another hash", 0xFFFF, 8, []Match{{3, 8}, {4, 0}}},
+		{"whole tree", 0, 64, []Match{{1, 0}, {2, 1}, {3, 8}, {4, 16}, {5, 64}, {6, 0}}},
+		{"nothing near", 0xFFFFFFFF00000000, 4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sortedMatches(tree.Search(tt.hash, tt.radius)))
+		})
+	}
+}
+
+func TestBKTreeEmpty(t *testing.T) {
+	var tree BKTree
+	assert.Equal(t, 0, tree.Len())
+	assert.Empty(t, tree.Search(0, 64))
+}
+
+// The tree skips branches, so it is checked against comparing every hash
+func TestBKTreeSearchMatchesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var tree BKTree
+	hashes := make([]uint64, 500)
+	for i := range hashes {
+		// few bits are set so the hashes are close to each other
+		hashes[i] = random.Uint64() & random.Uint64() & random.Uint64()
+		tree.Add(hashes[i], i)
+	}
+	for _, radius := range []int{0, 4, 10, 20} {
+		query := random.Uint64() & random.Uint64() & random.Uint64()
+		var want []Match
+		for id, hash := range hashes {
+			if distance := Distance(query, hash); distance <= radius {
+				want = append(want, Match{id, distance})
+			}
+		}
+		assert.Equal(t, want, sortedMatches(tree.Search(query, radius)), "radius %d", radius)
+	}
+}
@@ -0,0 +1,75 @@
+package imagehash
+
+import (
+	"image"
+	"math"
+)
+
+// Levels each color channel is split into, the histogram has one bin per combination
+const histogramLevels = 4
+
+// Number of bins of a color histogram
+const HistogramBins = histogramLevels * histogramLevels * histogramLevels
+
+// Most pixels sampled along each side of the image for its histogram
+const histogramSamples = 64
+
+// Share of the pixels of an image that fall into each color bin, the shares add up to 1
+type Histogram [HistogramBins]float64
+
+// Returns the color histogram of an image from an evenly spread sample of its pixels
+func ColorHistogram(img image.Image) Histogram {
+	var h Histogram
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return h
+	}
+
+	stepX := max(bounds.Dx()/histogramSamples, 1)
+	stepY := max(bounds.Dy()/histogramSamples, 1)
+	count := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			// the 16 bit channels are scaled down to the levels
+			bin := (int(r)*histogramLevels>>16)*histogramLevels*histogramLevels + (int(g)*histogramLevels>>16)*histogramLevels + int(b)*histogramLevels>>16
+			h[bin]++
+			count++
+		}
+	}
+	for i := range h {
+		h[i] /= float64(count)
+	}
+	return h
+}
+
+// Returns how different the colors of two histograms are, 0 when they share every
+// color and 1 when they share none
+func HistogramDistance(a, b Histogram) float64 {
+	intersection := 0.0
+	for i := range a {
+		intersection += math.Min(a[i], b[i])
+	}
+	return math.Max(0, 1-intersection)
+}
+
+// Encodes the histogram for storage with a byte per bin
+func (h Histogram) Bytes() []byte {
+	encoded := make([]byte, HistogramBins)
+	for i, share := range h {
+		encoded[i] = byte(math.Round(share * 255))
+	}
+	return encoded
+}
+
+// Decodes a histogram stored with Bytes, false when the bytes aren't one
+func HistogramFromBytes(encoded []byte) (Histogram, bool) {
+	var h Histogram
+	if len(encoded) != HistogramBins {
+		return h, false
+	}
+	for i, share := range encoded {
+		h[i] = float64(share) / 255
+	}
+	return h, true
+}
@@ -0,0 +1,72 @@
+package imagehash
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds an image with its left half in one color and its right half in another
+func testHalves(left, right color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			if x < 50 {
+				img.Set(x, y, left)
+			} else {
+				img.Set(x, y, right)
+			}
+		}
+	}
+	return img
+}
+
+func TestColorHistogram(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	h := ColorHistogram(testHalves(red, red))
+	assert.Equal(t, 1.0, h[3*histogramLevels*histogramLevels])
+
+	h = ColorHistogram(testHalves(red, blue))
+	assert.InDelta(t, 0.5, h[3*histogramLevels*histogramLevels], 0.02)
+	assert.InDelta(t, 0.5, h[3], 0.02)
+
+	assert.Equal(t, Histogram{}, ColorHistogram(image.NewRGBA(image.Rectangle{})))
+}
+
+func TestHistogramDistance(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	green := color.RGBA{0, 255, 0, 255}
+
+	tests := []struct {
+		name string
+		a, b image.Image
+		want float64
+	}{
+		{"same colors", testHalves(red, blue), testHalves(blue, red), 0},
+		{"no shared colors", testHalves(red, red), testHalves(green, blue), 1},
+		{"half shared", testHalves(red, blue), testHalves(red, green), 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, HistogramDistance(ColorHistogram(tt.a), ColorHistogram(tt.b)), 0.02)
+		})
+	}
+}
+
+func TestHistogramBytes(t *testing.T) {
+	h := ColorHistogram(testHalves(color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}))
+	decoded, ok := HistogramFromBytes(h.Bytes())
+	if assert.True(t, ok) {
+		assert.InDelta(t, 0, HistogramDistance(h, decoded), 0.01)
+	}
+
+	_, ok = HistogramFromBytes(nil)
+	assert.False(t, ok)
+	_, ok = HistogramFromBytes(make([]byte, HistogramBins-1))
+	assert.False(t, ok)
+}
@@ -0,0 +1,79 @@
+// Package imagehash computes perceptual hashes of images, images that look
+// alike get hashes that differ in only a few bits
+package imagehash
+
+import (
+	"image"
+	"math/bits"
+)
+
+// Width and height of the grid the image is shrunk to, one column more than
+// the hash needs as each bit compares two neighbouring cells
+const (
+	gridWidth  = 9
+	gridHeight = 8
+)
+
+// Most pixels sampled along each side of a grid cell, big images are sampled
+// instead of averaged in full
+const maxSamples = 16
+
+// Returns the difference hash (dHash) of an image, each bit tells if a cell of
+// a 9x8 grayscale grid is brighter than the cell to the right of it
+func DHash(img image.Image) uint64 {
+	grid := shrink(img)
+	var hash uint64
+	for y := 0; y < gridHeight; y++ {
+		for x := 0; x < gridWidth-1; x++ {
+			hash <<= 1
+			if grid[y][x] > grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Returns the number of bits two hashes differ in, 0 for the same picture and
+// up to about 10 for resized, recompressed or slightly edited copies
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Averages the luminance of the image over a 9x8 grid
+func shrink(img image.Image) [gridHeight][gridWidth]float64 {
+	var grid [gridHeight][gridWidth]float64
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return grid
+	}
+
+	for row := 0; row < gridHeight; row++ {
+		top := bounds.Min.Y + row*bounds.Dy()/gridHeight
+		bottom := max(bounds.Min.Y+(row+1)*bounds.Dy()/gridHeight, top+1)
+		for col := 0; col < gridWidth; col++ {
+			left := bounds.Min.X + col*bounds.Dx()/gridWidth
+			right := max(bounds.Min.X+(col+1)*bounds.Dx()/gridWidth, left+1)
+			grid[row][col] = averageLuminance(img, left, top, right, bottom)
+		}
+	}
+	return grid
+}
+
+// Returns the mean luminance of the pixels inside the rectangle
+func averageLuminance(img image.Image, left, top, right, bottom int) float64 {
+	stepX := max((right-left)/maxSamples, 1)
+	stepY := max((bottom-top)/maxSamples, 1)
+
+	var sum float64
+	var count int
+	for y := top; y < bottom; y += stepY {
+		for x := left; x < right; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			// ITU-R 601 luma
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	return sum / float64(count)
+}
@@ -0,0 +1,100 @@
+package imagehash
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns an image that gets brighter from left to right, or darker when reversed
+func testGradient(width, height int, reversed bool) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := x * 255 / max(width-1, 1)
+			if reversed {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(v)})
+		}
+	}
+	return img
+}
+
+// Returns an image with a bright block on the left half of the rows given
+func testBlocks(width, height int, rows ...int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for _, row := range rows {
+		for y := row * height / gridHeight; y < (row+1)*height/gridHeight; y++ {
+			for x := 0; x < width/2; x++ {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestDHash(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		want uint64
+	}{
+		// every cell is darker than the one to its right
+		{"brightening", testGradient(90, 80, false), 0},
+		{"darkening", testGradient(90, 80, true), ^uint64(0)},
+		{"flat", image.NewGray(image.Rect(0, 0, 90, 80)), 0},
+		{"empty", image.NewGray(image.Rect(0, 0, 0, 0)), 0},
+		// smaller than the grid, the first five cells of a row share the bright pixel
+		{"tiny", testGradient(2, 2, true), 0x0808080808080808},
+		// the block ends in the middle of the fifth cell, which is darker than the
+		// fourth and brighter than the sixth
+		{"block on the first row", testBlocks(90, 80, 0), 0x1800000000000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DHash(tt.img))
+		})
+	}
+}
+
+func TestDHashSimilarImages(t *testing.T) {
+	original := testBlocks(900, 800, 0, 3, 6)
+	tests := []struct {
+		name string
+		img  image.Image
+		max  int
+	}{
+		{"same image", testBlocks(900, 800, 0, 3, 6), 0},
+		{"resized", testBlocks(450, 400, 0, 3, 6), 0},
+		{"offset bounds", original.SubImage(original.Bounds()), 0},
+		{"one more block", testBlocks(900, 800, 0, 3, 5, 6), 2},
+		{"different", testGradient(900, 800, true), 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.LessOrEqual(t, Distance(DHash(original), DHash(tt.img)), tt.max)
+		})
+	}
+	assert.Greater(t, Distance(DHash(original), DHash(testGradient(900, 800, true))), 8)
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0xFF, 0xFF, 0},
+		{0, 1, 1},
+		{0, 0xFF, 8},
+		{0xF0, 0x0F, 8},
+		{0, ^uint64(0), 64},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Distance(tt.a, tt.b))
+		assert.Equal(t, tt.want, Distance(tt.b, tt.a))
+	}
+}
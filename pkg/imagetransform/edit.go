@@ -0,0 +1,121 @@
+package imagetransform
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Edit is a recipe of basic edits applied to an image when it is shown, the file
+// itself isn't changed. The edits are applied in the order of the fields
+type Edit struct {
+	Rotation int  // degrees clockwise, 0, 90, 180 or 270
+	FlipH    bool // mirrored left to right after the rotation
+	FlipV    bool // mirrored top to bottom after the rotation
+	Crop     Rect // part of the rotated image kept, the zero Rect keeps all of it
+	Width    int  // size the cropped image is resized to, 0 follows the aspect ratio of the other side
+	Height   int  // and both 0 keep the size
+}
+
+// Rect is a part of an image in fractions 0-1 of its width and height, so it
+// fits the image at any size, e.g. a thumbnail
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Returns true if the edit leaves the image as it is
+func (e Edit) IsZero() bool {
+	return e == Edit{}
+}
+
+// Returns a short key that tells edits apart, e.g. in the names of cached thumbnails,
+// empty for the zero edit
+func (e Edit) Key() string {
+	if e.IsZero() {
+		return ""
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%+v", e)
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// Returns the image with the edit applied
+func Apply(img image.Image, e Edit) image.Image {
+	img = Rotate(img, e.Rotation)
+	if e.FlipH {
+		img = FlipHorizontal(img)
+	}
+	if e.FlipV {
+		img = FlipVertical(img)
+	}
+	if e.Crop.W > 0 && e.Crop.H > 0 {
+		img = Crop(img, e.Crop.Bounds(img.Bounds()))
+	}
+	if e.Width > 0 || e.Height > 0 {
+		width, height := e.Width, e.Height
+		bounds := img.Bounds()
+		if width == 0 {
+			width = max(1, int(math.Round(float64(height)*float64(bounds.Dx())/float64(bounds.Dy()))))
+		}
+		if height == 0 {
+			height = max(1, int(math.Round(float64(width)*float64(bounds.Dy())/float64(bounds.Dx()))))
+		}
+		img = Resize(img, width, height)
+	}
+	return img
+}
+
+// Returns the pixels the rect covers in an image with the bounds, at least one pixel
+func (r Rect) Bounds(bounds image.Rectangle) image.Rectangle {
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	crop := image.Rect(
+		int(math.Round(r.X*w)), int(math.Round(r.Y*h)),
+		int(math.Round((r.X+r.W)*w)), int(math.Round((r.Y+r.H)*h)),
+	).Add(bounds.Min).Intersect(bounds)
+	if crop.Empty() {
+		return image.Rectangle{Min: bounds.Min, Max: bounds.Min.Add(image.Pt(1, 1))}.Intersect(bounds)
+	}
+	return crop
+}
+
+// Returns the biggest rect with the aspect ratio (width / height) in an image of the
+// size, scaled by zoom 0-1 and centered on cx, cy as far as it fits. An aspect of 0
+// keeps the aspect of the image
+func AspectCrop(size image.Point, aspect float64, zoom float64, cx float64, cy float64) Rect {
+	if size.X <= 0 || size.Y <= 0 {
+		return Rect{}
+	}
+	imageAspect := float64(size.X) / float64(size.Y)
+	if aspect <= 0 {
+		aspect = imageAspect
+	}
+	// fractions of the image width and height
+	w, h := 1.0, imageAspect/aspect
+	if aspect < imageAspect {
+		w, h = aspect/imageAspect, 1.0
+	}
+	zoom = math.Min(math.Max(zoom, 0.05), 1)
+	w, h = w*zoom, h*zoom
+	x := math.Min(math.Max(cx-w/2, 0), 1-w)
+	y := math.Min(math.Max(cy-h/2, 0), 1-h)
+	return Rect{X: x, Y: y, W: w, H: h}
+}
+
+// Returns a copy of the part of an image
+func Crop(img image.Image, r image.Rectangle) image.Image {
+	r = r.Intersect(img.Bounds())
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+// Scales an image to the size
+func Resize(img image.Image, width int, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
@@ -0,0 +1,104 @@
+package imagetransform
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   Edit
+		want   [][]uint8
+		width  int // of the result when its pixels aren't compared
+		height int
+	}{
+		{"no edit", Edit{}, testRows, 0, 0},
+		// flipped after the rotation, flipping first would give a transverse
+		{"rotate then flip", Edit{Rotation: 90, FlipH: true}, [][]uint8{{0, 10, 20}, {1, 11, 21}}, 0, 0},
+		{"flip both", Edit{FlipH: true, FlipV: true}, [][]uint8{{21, 20}, {11, 10}, {1, 0}}, 0, 0},
+		// the crop is of the rotated image
+		{"rotate then crop", Edit{Rotation: 90, Crop: Rect{W: 1.0 / 3, H: 1}}, [][]uint8{{20}, {21}}, 0, 0},
+		{"flip then crop", Edit{FlipV: true, Crop: Rect{Y: 2.0 / 3, W: 1, H: 1.0 / 3}}, [][]uint8{{0, 1}}, 0, 0},
+		// the resize is of the cropped image, the height follows its aspect
+		{"crop then resize", Edit{Rotation: 90, Crop: Rect{W: 1.0 / 3, H: 1}, Width: 4}, nil, 4, 8},
+		{"resize by height", Edit{Height: 6}, nil, 4, 6},
+		{"resize both", Edit{Width: 5, Height: 5}, nil, 5, 5},
+		// a crop without an area is no crop
+		{"zero width crop", Edit{Crop: Rect{X: 0.5, Y: 0.5, H: 0.5}}, testRows, 0, 0},
+		{"zero height crop", Edit{Crop: Rect{X: 0.5, Y: 0.5, W: 0.5}}, testRows, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := Apply(testImage(testRows), tt.edit)
+			if tt.want != nil {
+				assert.Equal(t, tt.want, pixelRows(img))
+			} else {
+				assert.Equal(t, image.Pt(tt.width, tt.height), img.Bounds().Size())
+			}
+		})
+	}
+}
+
+func TestRectBounds(t *testing.T) {
+	bounds := image.Rect(10, 20, 110, 70)
+	tests := []struct {
+		name string
+		rect Rect
+		want image.Rectangle
+	}{
+		{"whole image", Rect{W: 1, H: 1}, bounds},
+		{"right half", Rect{X: 0.5, W: 0.5, H: 1}, image.Rect(60, 20, 110, 70)},
+		{"past the edge", Rect{X: 0.8, Y: 0.8, W: 0.5, H: 0.5}, image.Rect(90, 60, 110, 70)},
+		// always at least a pixel
+		{"smaller than a pixel", Rect{X: 0.5, Y: 0.5, W: 0.001, H: 0.001}, image.Rect(10, 20, 11, 21)},
+		{"outside", Rect{X: 2, Y: 2, W: 1, H: 1}, image.Rect(10, 20, 11, 21)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rect.Bounds(bounds))
+		})
+	}
+}
+
+func TestAspectCrop(t *testing.T) {
+	wide := image.Pt(400, 200)
+	tests := []struct {
+		name   string
+		size   image.Point
+		aspect float64
+		zoom   float64
+		cx, cy float64
+		want   Rect
+	}{
+		{"keep the aspect", wide, 0, 1, 0.5, 0.5, Rect{W: 1, H: 1}},
+		{"square in the middle", wide, 1, 1, 0.5, 0.5, Rect{X: 0.25, W: 0.5, H: 1}},
+		{"tall", wide, 0.5, 1, 0.5, 0.5, Rect{X: 0.375, W: 0.25, H: 1}},
+		{"wider than the image", wide, 4, 1, 0.5, 0.5, Rect{Y: 0.25, W: 1, H: 0.5}},
+		// the center is moved so the crop stays inside
+		{"clamped right", wide, 1, 1, 0.9, 0.5, Rect{X: 0.5, W: 0.5, H: 1}},
+		{"clamped left", wide, 1, 1, 0, 0, Rect{W: 0.5, H: 1}},
+		{"zoomed", wide, 1, 0.5, 0.5, 0.5, Rect{X: 0.375, Y: 0.25, W: 0.25, H: 0.5}},
+		{"zoomed into a corner", wide, 1, 0.5, 1, 1, Rect{X: 0.75, Y: 0.5, W: 0.25, H: 0.5}},
+		{"zoom past 1", wide, 1, 2, 0.5, 0.5, Rect{X: 0.25, W: 0.5, H: 1}},
+		{"zoom at least 5%", wide, 0, 0, 0, 0, Rect{W: 0.05, H: 0.05}},
+		{"no image", image.Point{}, 1, 1, 0.5, 0.5, Rect{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AspectCrop(tt.size, tt.aspect, tt.zoom, tt.cx, tt.cy)
+			assert.InDelta(t, tt.want.X, got.X, 1e-9)
+			assert.InDelta(t, tt.want.Y, got.Y, 1e-9)
+			assert.InDelta(t, tt.want.W, got.W, 1e-9)
+			assert.InDelta(t, tt.want.H, got.H, 1e-9)
+		})
+	}
+}
+
+func TestEditKey(t *testing.T) {
+	assert.Empty(t, Edit{}.Key())
+	assert.Equal(t, Edit{Rotation: 90}.Key(), Edit{Rotation: 90}.Key())
+	assert.NotEqual(t, Edit{Rotation: 90}.Key(), Edit{Rotation: 90, FlipH: true}.Key())
+}
@@ -0,0 +1,88 @@
+// Package imagetransform rotates and flips images, e.g. to show a photo the way
+// its EXIF orientation says it was taken
+package imagetransform
+
+import (
+	"image"
+	"image/draw"
+)
+
+// EXIF orientations, the transform that makes the stored image upright
+const (
+	Normal     = 1
+	FlipH      = 2 // mirrored left to right
+	Rotate180  = 3
+	FlipV      = 4 // mirrored top to bottom
+	Transpose  = 5 // mirrored along the top left to bottom right diagonal
+	Rotate90   = 6 // clockwise
+	Transverse = 7 // mirrored along the top right to bottom left diagonal
+	Rotate270  = 8 // clockwise, 90 counterclockwise
+)
+
+// Returns the image turned upright for an EXIF orientation, the image itself for
+// orientation 1 and unknown ones
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= Normal || orientation > Rotate270 {
+		return img
+	}
+
+	// the pixels are copied to RGBA first, draw has fast paths for the decoded formats
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// orientations 5 to 8 swap the width and height
+	dstW, dstH := w, h
+	if orientation >= Transpose {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case FlipH:
+				sx, sy = w-1-x, y
+			case Rotate180:
+				sx, sy = w-1-x, h-1-y
+			case FlipV:
+				sx, sy = x, h-1-y
+			case Transpose:
+				sx, sy = y, x
+			case Rotate90:
+				sx, sy = y, h-1-x
+			case Transverse:
+				sx, sy = w-1-y, h-1-x
+			case Rotate270:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// Rotates an image clockwise by 90, 180 or 270 degrees, other angles leave it as it is
+func Rotate(img image.Image, degrees int) image.Image {
+	switch (degrees%360 + 360) % 360 {
+	case 90:
+		return Orient(img, Rotate90)
+	case 180:
+		return Orient(img, Rotate180)
+	case 270:
+		return Orient(img, Rotate270)
+	}
+	return img
+}
+
+// Mirrors an image left to right
+func FlipHorizontal(img image.Image) image.Image {
+	return Orient(img, FlipH)
+}
+
+// Mirrors an image top to bottom
+func FlipVertical(img image.Image) image.Image {
+	return Orient(img, FlipV)
+}
@@ -0,0 +1,76 @@
+package imagetransform
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds an image from rows of gray values
+func testImage(rows [][]uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, v := range row {
+			img.SetGray(x, y, color.Gray{v})
+		}
+	}
+	return img
+}
+
+// Returns the gray values of an image row by row
+func pixelRows(img image.Image) [][]uint8 {
+	bounds := img.Bounds()
+	rows := make([][]uint8, bounds.Dy())
+	for y := range rows {
+		rows[y] = make([]uint8, bounds.Dx())
+		for x := range rows[y] {
+			rows[y][x] = color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+		}
+	}
+	return rows
+}
+
+// 2 pixels wide and 3 high, the tens are the row and the ones the column
+var testRows = [][]uint8{
+	{0, 1},
+	{10, 11},
+	{20, 21},
+}
+
+func TestOrient(t *testing.T) {
+	tests := []struct {
+		name        string
+		orientation int
+		want        [][]uint8
+	}{
+		{"unknown", 0, testRows},
+		{"normal", Normal, testRows},
+		{"flip horizontal", FlipH, [][]uint8{{1, 0}, {11, 10}, {21, 20}}},
+		{"rotate 180", Rotate180, [][]uint8{{21, 20}, {11, 10}, {1, 0}}},
+		{"flip vertical", FlipV, [][]uint8{{20, 21}, {10, 11}, {0, 1}}},
+		{"transpose", Transpose, [][]uint8{{0, 10, 20}, {1, 11, 21}}},
+		{"rotate 90", Rotate90, [][]uint8{{20, 10, 0}, {21, 11, 1}}},
+		{"transverse", Transverse, [][]uint8{{21, 11, 1}, {20, 10, 0}}},
+		{"rotate 270", Rotate270, [][]uint8{{1, 11, 21}, {0, 10, 20}}},
+		{"past the last", 9, testRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pixelRows(Orient(testImage(testRows), tt.orientation)))
+		})
+	}
+}
+
+func TestOrientSubImage(t *testing.T) {
+	// the bounds of a sub image don't start at 0, 0
+	img := testImage([][]uint8{{99, 99, 99}, {99, 0, 1}, {99, 10, 11}, {99, 20, 21}}).SubImage(image.Rect(1, 1, 3, 4))
+	assert.Equal(t, [][]uint8{{20, 10, 0}, {21, 11, 1}}, pixelRows(Orient(img, Rotate90)))
+}
+
+func TestRotate(t *testing.T) {
+	for degrees, want := range map[int]int{0: Normal, 90: Rotate90, 180: Rotate180, 270: Rotate270, -90: Rotate270, 450: Rotate90, 45: Normal} {
+		assert.Equal(t, pixelRows(Orient(testImage(testRows), want)), pixelRows(Rotate(testImage(testRows), degrees)), "%d degrees", degrees)
+	}
+}
@@ -0,0 +1,423 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// raw is the metadata found in a container before it gets decoded
+type raw struct {
+	exif   map[uint16]tag // IFD0 and Exif IFD tags
+	xmp    []byte         // XMP packet
+	width  int            // dimensions from the container itself
+	height int
+}
+
+// Container formats that metadata can be read from
+const (
+	formatUnknown = iota
+	formatJPEG
+	formatPNG
+	formatWebP
+	formatTIFF
+	formatISOBMFF // HEIC, HEIF and AVIF
+)
+
+// Detects the container format from the first bytes of the file
+func detectFormat(header []byte) int {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return formatJPEG
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return formatPNG
+	case len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return formatWebP
+	case bytes.HasPrefix(header, []byte("II*\x00")), bytes.HasPrefix(header, []byte("MM\x00*")), bytes.HasPrefix(header, []byte("IIU\x00")):
+		return formatTIFF // raw camera files like DNG, CR2, NEF and RW2 too
+	case len(header) >= 8 && string(header[4:8]) == "ftyp":
+		return formatISOBMFF
+	}
+	return formatUnknown
+}
+
+// Reads the EXIF block starting at the TIFF header at offset
+func (m *raw) readTIFF(r io.ReaderAt, offset int64) error {
+	t, first, err := newTIFFReader(r, offset)
+	if err != nil {
+		return err
+	}
+	tags, err := t.readEXIF(first)
+	if err != nil {
+		return err
+	}
+	m.exif = tags
+	return nil
+}
+
+// Adobe's namespace header in front of XMP packets in JPEG APP1 segments
+var xmpHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+// Walks the JPEG segments up to the image data
+func readJPEG(r io.ReaderAt) (*raw, error) {
+	m := &raw{}
+	offset := int64(2)
+	marker := make([]byte, 4)
+	for {
+		if _, err := r.ReadAt(marker, offset); err != nil {
+			return m, nil
+		}
+		if marker[0] != 0xFF {
+			return m, fmt.Errorf("invalid JPEG marker at %d", offset)
+		}
+		// fill bytes before a marker
+		if marker[1] == 0xFF {
+			offset++
+			continue
+		}
+		kind := marker[1]
+		// start of scan, only image data follows
+		if kind == 0xDA || kind == 0xD9 {
+			return m, nil
+		}
+		// markers without a length
+		if kind == 0x01 || (kind >= 0xD0 && kind <= 0xD7) {
+			offset += 2
+			continue
+		}
+
+		length := int64(binary.BigEndian.Uint16(marker[2:]))
+		data := offset + 4
+		switch {
+		case kind == 0xE1:
+			prefix := make([]byte, len(xmpHeader))
+			n, _ := r.ReadAt(prefix, data)
+			prefix = prefix[:n]
+			if bytes.HasPrefix(prefix, []byte("Exif\x00\x00")) && m.exif == nil {
+				m.readTIFF(r, data+6)
+			} else if bytes.Equal(prefix, xmpHeader) && m.xmp == nil && length > int64(len(xmpHeader))+2 {
+				m.xmp = make([]byte, length-2-int64(len(xmpHeader)))
+				r.ReadAt(m.xmp, data+int64(len(xmpHeader)))
+			}
+		case kind >= 0xC0 && kind <= 0xCF && kind != 0xC4 && kind != 0xC8 && kind != 0xCC:
+			// start of frame holds the precision, height and width
+			frame := make([]byte, 5)
+			if _, err := r.ReadAt(frame, data); err == nil {
+				m.height = int(binary.BigEndian.Uint16(frame[1:]))
+				m.width = int(binary.BigEndian.Uint16(frame[3:]))
+			}
+		}
+		offset += 2 + length
+	}
+}
+
+// Walks the PNG chunks for the header, eXIf and iTXt XMP chunks
+func readPNG(r io.ReaderAt) (*raw, error) {
+	m := &raw{}
+	offset := int64(8)
+	header := make([]byte, 8)
+	for {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return m, nil
+		}
+		length := int64(binary.BigEndian.Uint32(header))
+		data := offset + 8
+		switch string(header[4:]) {
+		case "IHDR":
+			size := make([]byte, 8)
+			if _, err := r.ReadAt(size, data); err == nil {
+				m.width = int(binary.BigEndian.Uint32(size))
+				m.height = int(binary.BigEndian.Uint32(size[4:]))
+			}
+		case "eXIf":
+			m.readTIFF(r, data)
+		case "iTXt":
+			if length > maxTagSize {
+				break
+			}
+			chunk := make([]byte, length)
+			if _, err := r.ReadAt(chunk, data); err != nil {
+				break
+			}
+			// keyword, null, compression flag, compression method, language, null, translated keyword, null, text
+			parts := bytes.SplitN(chunk, []byte{0}, 2)
+			if len(parts) < 2 || string(parts[0]) != "XML:com.adobe.xmp" || len(parts[1]) < 2 || parts[1][0] != 0 {
+				break
+			}
+			rest := bytes.SplitN(parts[1][2:], []byte{0}, 3)
+			if len(rest) == 3 {
+				m.xmp = rest[2]
+			}
+		case "IEND":
+			return m, nil
+		}
+		// data is followed by a 4 byte CRC
+		offset = data + length + 4
+	}
+}
+
+// Walks the RIFF chunks of a WebP file for the canvas size, EXIF and XMP chunks
+func readWebP(r io.ReaderAt) (*raw, error) {
+	m := &raw{}
+	offset := int64(12)
+	header := make([]byte, 8)
+	for {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return m, nil
+		}
+		length := int64(binary.LittleEndian.Uint32(header[4:]))
+		data := offset + 8
+		switch string(header[:4]) {
+		case "VP8X":
+			// 24 bit canvas width and height minus one after the flags
+			size := make([]byte, 10)
+			if _, err := r.ReadAt(size, data); err == nil {
+				m.width = int(uint32(size[4])|uint32(size[5])<<8|uint32(size[6])<<16) + 1
+				m.height = int(uint32(size[7])|uint32(size[8])<<8|uint32(size[9])<<16) + 1
+			}
+		case "VP8 ":
+			// lossy frames store 14 bit dimensions after the frame tag and start code
+			size := make([]byte, 10)
+			if _, err := r.ReadAt(size, data); err == nil && m.width == 0 {
+				m.width = int(binary.LittleEndian.Uint16(size[6:]) & 0x3FFF)
+				m.height = int(binary.LittleEndian.Uint16(size[8:]) & 0x3FFF)
+			}
+		case "VP8L":
+			// lossless frames pack 14 bit dimensions minus one after the signature byte
+			size := make([]byte, 5)
+			if _, err := r.ReadAt(size, data); err == nil && m.width == 0 {
+				bits := binary.LittleEndian.Uint32(size[1:])
+				m.width = int(bits&0x3FFF) + 1
+				m.height = int(bits>>14&0x3FFF) + 1
+			}
+		case "EXIF":
+			// some writers keep the JPEG style Exif prefix
+			prefix := make([]byte, 6)
+			r.ReadAt(prefix, data)
+			if bytes.Equal(prefix, []byte("Exif\x00\x00")) {
+				m.readTIFF(r, data+6)
+			} else {
+				m.readTIFF(r, data)
+			}
+		case "XMP ":
+			if length <= maxTagSize {
+				m.xmp = make([]byte, length)
+				r.ReadAt(m.xmp, data)
+			}
+		}
+		// chunks are padded to an even size
+		offset = data + length + length%2
+	}
+}
+
+// box is an ISO base media file format box
+type box struct {
+	kind   string
+	offset int64 // offset of the box contents after the header
+	size   int64 // size of the box contents
+}
+
+// Lists the boxes between start and end
+func readBoxes(r io.ReaderAt, start int64, end int64) []box {
+	var boxes []box
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			break
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// box runs to the end of the file
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:], offset+8); err != nil {
+				return boxes
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		// 64 bit sizes can overflow offset+size, so they are compared with what is left
+		if size < headerSize || size > end-offset {
+			break
+		}
+		boxes = append(boxes, box{kind: string(header[4:8]), offset: offset + headerSize, size: size - headerSize})
+		offset += size
+	}
+	return boxes
+}
+
+// Returns the first box of the kind
+func findBox(boxes []box, kind string) (box, bool) {
+	for _, b := range boxes {
+		if b.kind == kind {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// Reads an unsigned big endian integer of 0, 2, 4 or 8 bytes
+func readUint(data []byte, size int) (uint64, []byte) {
+	if len(data) < size {
+		return 0, nil
+	}
+	switch size {
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:]
+	case 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:]
+	case 8:
+		return binary.BigEndian.Uint64(data), data[8:]
+	}
+	return 0, data
+}
+
+// Reads the Exif item of a HEIC/HEIF file, the item is found through the
+// item info box and located through the item location box of the meta box
+func readISOBMFF(r io.ReaderAt, fileSize int64) (*raw, error) {
+	m := &raw{}
+	meta, ok := findBox(readBoxes(r, 0, fileSize), "meta")
+	if !ok {
+		return m, nil
+	}
+	// meta is a full box, version and flags come before its children
+	children := readBoxes(r, meta.offset+4, meta.offset+meta.size)
+
+	// image spatial extents hold the dimensions, the biggest one is the full image
+	if iprp, ok := findBox(children, "iprp"); ok {
+		if ipco, ok := findBox(readBoxes(r, iprp.offset, iprp.offset+iprp.size), "ipco"); ok {
+			for _, b := range readBoxes(r, ipco.offset, ipco.offset+ipco.size) {
+				size := make([]byte, 12)
+				if b.kind != "ispe" || b.size < 12 {
+					continue
+				}
+				if _, err := r.ReadAt(size, b.offset); err != nil {
+					continue
+				}
+				width, height := int(binary.BigEndian.Uint32(size[4:])), int(binary.BigEndian.Uint32(size[8:]))
+				if width*height > m.width*m.height {
+					m.width, m.height = width, height
+				}
+			}
+		}
+	}
+
+	iinf, ok := findBox(children, "iinf")
+	if !ok {
+		return m, nil
+	}
+	iloc, ok := findBox(children, "iloc")
+	if !ok || iloc.size > maxTagSize {
+		return m, nil
+	}
+
+	// finds the ids of the Exif and XMP items
+	var exifItem, xmpItem uint64
+	version := make([]byte, 1)
+	r.ReadAt(version, iinf.offset)
+	entriesStart := iinf.offset + 6
+	if version[0] > 0 {
+		entriesStart = iinf.offset + 8
+	}
+	for _, infe := range readBoxes(r, entriesStart, iinf.offset+iinf.size) {
+		if infe.kind != "infe" || infe.size > 1024 {
+			continue
+		}
+		data := make([]byte, infe.size)
+		if _, err := r.ReadAt(data, infe.offset); err != nil || len(data) < 4 || data[0] < 2 {
+			continue
+		}
+		// version 2 has 16 bit ids, version 3 has 32 bit ids
+		idSize := 2
+		if data[0] == 3 {
+			idSize = 4
+		}
+		id, rest := readUint(data[4:], idSize)
+		if len(rest) < 6 {
+			continue
+		}
+		// protection index comes before the item type
+		switch string(rest[2:6]) {
+		case "Exif":
+			exifItem = id
+		case "mime":
+			// XMP is stored as a mime item with the content type application/rdf+xml
+			if bytes.Contains(rest[6:], []byte("application/rdf+xml")) {
+				xmpItem = id
+			}
+		}
+	}
+	if exifItem == 0 && xmpItem == 0 {
+		return m, nil
+	}
+
+	locations := readItemLocations(r, iloc)
+	if extent, ok := locations[exifItem]; ok && exifItem != 0 {
+		// the Exif item starts with the offset to the TIFF header
+		skip := make([]byte, 4)
+		if _, err := r.ReadAt(skip, extent[0]); err == nil {
+			m.readTIFF(r, extent[0]+4+int64(binary.BigEndian.Uint32(skip)))
+		}
+	}
+	if extent, ok := locations[xmpItem]; ok && xmpItem != 0 && extent[1] >= 0 && extent[1] <= maxTagSize {
+		m.xmp = make([]byte, extent[1])
+		r.ReadAt(m.xmp, extent[0])
+	}
+	return m, nil
+}
+
+// Reads the item location box, returns the file offset and length of the
+// first extent of every item stored in the file itself
+func readItemLocations(r io.ReaderAt, iloc box) map[uint64][2]int64 {
+	locations := map[uint64][2]int64{}
+	data := make([]byte, iloc.size)
+	if _, err := r.ReadAt(data, iloc.offset); err != nil || len(data) < 8 {
+		return locations
+	}
+
+	version := data[0]
+	offsetSize, lengthSize := int(data[4]>>4), int(data[4]&0xF)
+	baseOffsetSize, indexSize := int(data[5]>>4), int(data[5]&0xF)
+	if version == 0 {
+		indexSize = 0
+	}
+	data = data[6:]
+
+	var itemCount uint64
+	if version < 2 {
+		itemCount, data = readUint(data, 2)
+	} else {
+		itemCount, data = readUint(data, 4)
+	}
+
+	for i := uint64(0); i < itemCount && data != nil; i++ {
+		var id, method, baseOffset, extentCount uint64
+		if version < 2 {
+			id, data = readUint(data, 2)
+		} else {
+			id, data = readUint(data, 4)
+		}
+		if version > 0 {
+			method, data = readUint(data, 2)
+			method &= 0xF
+		}
+		_, data = readUint(data, 2) // data reference index
+		baseOffset, data = readUint(data, baseOffsetSize)
+		extentCount, data = readUint(data, 2)
+
+		for e := uint64(0); e < extentCount && data != nil; e++ {
+			var extentOffset, extentLength uint64
+			_, data = readUint(data, indexSize)
+			extentOffset, data = readUint(data, offsetSize)
+			extentLength, data = readUint(data, lengthSize)
+			// only file offsets are supported, not items built from other items
+			if e == 0 && method == 0 {
+				locations[id] = [2]int64{int64(baseOffset + extentOffset), int64(extentLength)}
+			}
+		}
+	}
+	return locations
+}
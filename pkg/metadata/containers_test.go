@@ -0,0 +1,270 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testXMPPacket = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF><rdf:Description tiff:Make="Fujifilm" tiff:Model="X-T4"/></rdf:RDF></x:xmpmeta>`
+
+// Appends a JPEG segment with its length
+func appendJPEGSegment(file []byte, kind byte, data []byte) []byte {
+	file = append(file, 0xFF, kind)
+	file = binary.BigEndian.AppendUint16(file, uint16(len(data)+2))
+	return append(file, data...)
+}
+
+// Builds a 640x480 JPEG header with EXIF and XMP, the image data is left out
+func testJPEG() []byte {
+	file := []byte{0xFF, 0xD8}
+	file = appendJPEGSegment(file, 0xE1, append([]byte("Exif\x00\x00"), testPhotoTIFF(binary.BigEndian)...))
+	file = appendJPEGSegment(file, 0xE1, append(bytes.Clone(xmpHeader), testXMPPacket...))
+	file = appendJPEGSegment(file, 0xC0, []byte{8, 0x01, 0xE0, 0x02, 0x80, 3})
+	return appendJPEGSegment(file, 0xDA, []byte{0})
+}
+
+func TestReadJPEG(t *testing.T) {
+	valid := testJPEG()
+	xmpOnly := appendJPEGSegment([]byte{0xFF, 0xD8}, 0xE1, append(bytes.Clone(xmpHeader), testXMPPacket...))
+	// the XMP segment claims more bytes than the file has
+	oversizedXMP := bytes.Clone(xmpOnly)
+	binary.BigEndian.PutUint16(oversizedXMP[4:], 0xFFFF)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		hasErr bool
+		camera string
+		size   string
+	}{
+		{"valid", valid, false, "Canon EOS R6", "640x480"},
+		{"fill bytes", append([]byte{0xFF, 0xD8, 0xFF, 0xFF}, valid[2:]...), false, "Canon EOS R6", "640x480"},
+		{"XMP only", xmpOnly, false, "Fujifilm X-T4", ""},
+		// what the file has of the segment is still read
+		{"oversized XMP", oversizedXMP, false, "Fujifilm X-T4", ""},
+		{"truncated EXIF", valid[:60], false, "", ""},
+		// the size falls back to the one in EXIF
+		{"truncated frame", valid[:len(valid)-8], false, "Canon EOS R6", "6000x4000"},
+		{"invalid marker", []byte{0xFF, 0xD8, 0x00, 0x01, 0x00, 0x00}, true, "", ""},
+		{"empty", nil, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				r, err := readJPEG(bytes.NewReader(tt.data))
+				assert.Equal(t, tt.hasErr, err != nil, err)
+				m := r.decode()
+				assert.Equal(t, tt.camera, m.Camera())
+				assert.Equal(t, tt.size, m.Dimensions())
+			})
+		})
+	}
+}
+
+// Appends a PNG chunk, the CRC isn't checked so it is left zero
+func appendTestPNGChunk(file []byte, kind string, data []byte) []byte {
+	file = binary.BigEndian.AppendUint32(file, uint32(len(data)))
+	file = append(file, kind...)
+	file = append(file, data...)
+	return append(file, 0, 0, 0, 0)
+}
+
+func TestReadPNG(t *testing.T) {
+	ihdr := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 800), 600)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	withEXIF := appendTestPNGChunk(appendTestPNGChunk(signature, "IHDR", ihdr), "eXIf", testPhotoTIFF(binary.LittleEndian))
+	withXMP := appendTestPNGChunk(appendTestPNGChunk(signature, "IHDR", ihdr), "iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"+testXMPPacket))
+	oversized := appendTestPNGChunk(signature, "IHDR", ihdr)
+	oversized = append(oversized, 0xFF, 0xFF, 0xFF, 0xFF, 'i', 'T', 'X', 't', 0, 0)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		camera string
+		size   string
+	}{
+		{"EXIF", appendTestPNGChunk(withEXIF, "IEND", nil), "Canon EOS R6", "800x600"},
+		{"XMP", appendTestPNGChunk(withXMP, "IEND", nil), "Fujifilm X-T4", "800x600"},
+		{"other text", appendTestPNGChunk(appendTestPNGChunk(signature, "iTXt", []byte("Comment\x00\x00\x00\x00\x00hello")), "IEND", nil), "", ""},
+		{"no IEND", withEXIF, "Canon EOS R6", "800x600"},
+		{"truncated EXIF", withEXIF[:len(withEXIF)-len(testPhotoTIFF(binary.LittleEndian))+20], "", "800x600"},
+		{"oversized length", oversized, "", "800x600"},
+		{"truncated IHDR", appendTestPNGChunk(signature, "IHDR", ihdr)[:20], "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				r, err := readPNG(bytes.NewReader(tt.data))
+				assert.NoError(t, err)
+				m := r.decode()
+				assert.Equal(t, tt.camera, m.Camera())
+				assert.Equal(t, tt.size, m.Dimensions())
+			})
+		})
+	}
+}
+
+// Appends a RIFF chunk, padded to an even size
+func appendTestRIFFChunk(file []byte, id string, data []byte) []byte {
+	file = append(file, id...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(data)))
+	file = append(file, data...)
+	if len(data)%2 == 1 {
+		file = append(file, 0)
+	}
+	return file
+}
+
+func TestReadWebP(t *testing.T) {
+	header := []byte("RIFF\x00\x00\x00\x00WEBP")
+	// canvas of 1920x1080, stored minus one
+	vp8x := []byte{0x08, 0, 0, 0, 0x7F, 0x07, 0, 0x37, 0x04, 0}
+	extended := appendTestRIFFChunk(bytes.Clone(header), "VP8X", vp8x)
+	// lossless 300x200 bitstream header
+	bits := uint32(299) | uint32(199)<<14
+	lossless := appendTestRIFFChunk(bytes.Clone(header), "VP8L", binary.LittleEndian.AppendUint32([]byte{0x2F}, bits))
+	// lossy 320x240 frame header
+	lossy := appendTestRIFFChunk(bytes.Clone(header), "VP8 ", []byte{0, 0, 0, 0x9D, 0x01, 0x2A, 0x40, 0x01, 0xF0, 0x00})
+	oversized := append(bytes.Clone(extended), "XMP \xff\xff\xff\xff<x:"...)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		camera string
+		size   string
+	}{
+		{"EXIF", appendTestRIFFChunk(bytes.Clone(extended), "EXIF", testPhotoTIFF(binary.LittleEndian)), "Canon EOS R6", "1920x1080"},
+		{"EXIF with prefix", appendTestRIFFChunk(bytes.Clone(extended), "EXIF", append([]byte("Exif\x00\x00"), testPhotoTIFF(binary.BigEndian)...)), "Canon EOS R6", "1920x1080"},
+		{"XMP", appendTestRIFFChunk(bytes.Clone(extended), "XMP ", []byte(testXMPPacket)), "Fujifilm X-T4", "1920x1080"},
+		{"lossless", lossless, "", "300x200"},
+		{"lossy", lossy, "", "320x240"},
+		{"oversized XMP", oversized, "", "1920x1080"},
+		{"truncated VP8X", extended[:len(extended)-4], "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				r, err := readWebP(bytes.NewReader(tt.data))
+				assert.NoError(t, err)
+				m := r.decode()
+				assert.Equal(t, tt.camera, m.Camera())
+				assert.Equal(t, tt.size, m.Dimensions())
+			})
+		})
+	}
+}
+
+// Builds an ISO base media box
+func testISOBox(kind string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, kind...), body...)
+}
+
+// Builds a version 2 item info entry
+func testISOInfe(id uint16, itemType string, extra string) []byte {
+	d := []byte{2, 0, 0, 0}
+	d = binary.BigEndian.AppendUint16(d, id)
+	d = append(d, 0, 0)
+	d = append(d, itemType...)
+	return testISOBox("infe", append(append(d, 0), extra...))
+}
+
+// Builds a HEIF file with a 4032x3024 image and the Exif and XMP items stored
+// after the meta box. xmpLength replaces the length of the XMP item when not 0
+func testISOBMFF(xmpLength uint32) []byte {
+	ftyp := testISOBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	ispe := testISOBox("ispe", []byte{0, 0, 0, 0, 0, 0, 0x0F, 0xC0, 0, 0, 0x0B, 0xD0})
+	iprp := testISOBox("iprp", testISOBox("ipco", ispe))
+	iinf := testISOBox("iinf", []byte{0, 0, 0, 0, 0, 2},
+		testISOInfe(1, "Exif", ""),
+		testISOInfe(2, "mime", "application/rdf+xml\x00"))
+
+	exif := append([]byte{0, 0, 0, 0}, testPhotoTIFF(binary.BigEndian)...)
+	xmp := []byte(testXMPPacket)
+	if xmpLength == 0 {
+		xmpLength = uint32(len(xmp))
+	}
+	iloc := func(dataStart uint32) []byte {
+		d := []byte{0, 0, 0, 0, 0x44, 0x00, 0, 2}
+		for i, item := range []struct{ offset, length uint32 }{{dataStart, uint32(len(exif))}, {dataStart + uint32(len(exif)), xmpLength}} {
+			d = binary.BigEndian.AppendUint16(d, uint16(i+1))
+			d = append(d, 0, 0, 0, 1)
+			d = binary.BigEndian.AppendUint32(d, item.offset)
+			d = binary.BigEndian.AppendUint32(d, item.length)
+		}
+		return testISOBox("iloc", d)
+	}
+	metaSize := len(testISOBox("meta", []byte{0, 0, 0, 0}, iprp, iinf, iloc(0)))
+	meta := testISOBox("meta", []byte{0, 0, 0, 0}, iprp, iinf, iloc(uint32(len(ftyp)+metaSize+8)))
+	mdat := testISOBox("mdat", exif, xmp)
+	return bytes.Join([][]byte{ftyp, meta, mdat}, nil)
+}
+
+func TestReadISOBMFF(t *testing.T) {
+	valid := testISOBMFF(0)
+	tests := []struct {
+		name   string
+		data   []byte
+		camera string
+		make   string
+		size   string
+	}{
+		{"valid", valid, "Canon EOS R6", "Canon", "4032x3024"},
+		{"truncated item data", valid[:len(valid)-len(testXMPPacket)-len(testPhotoTIFF(binary.BigEndian))+20], "", "", "4032x3024"},
+		{"truncated meta", valid[:60], "", "", ""},
+		{"XMP length past the end", testISOBMFF(0xFFFFFF), "Canon EOS R6", "Canon", "4032x3024"},
+		// the length is negative once read as an int64
+		{"XMP length overflows", testISOBMFF(0xFFFFFFFF), "Canon EOS R6", "Canon", "4032x3024"},
+		{"no meta", testISOBox("ftyp", []byte("heic")), "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				r, err := readISOBMFF(bytes.NewReader(tt.data), int64(len(tt.data)))
+				assert.NoError(t, err)
+				m := r.decode()
+				assert.Equal(t, tt.camera, m.Camera())
+				assert.Equal(t, tt.make, m.Make)
+				assert.Equal(t, tt.size, m.Dimensions())
+			})
+		})
+	}
+}
+
+func TestReadBoxes(t *testing.T) {
+	largeBox := func(size uint64, kind string) []byte {
+		b := append([]byte{0, 0, 0, 1}, kind...)
+		return binary.BigEndian.AppendUint64(b, size)
+	}
+	tests := []struct {
+		name  string
+		data  []byte
+		kinds []string
+	}{
+		{"empty", nil, nil},
+		{"two boxes", append(testISOBox("ftyp", []byte("heic")), testISOBox("meta")...), []string{"ftyp", "meta"}},
+		{"size 0 runs to the end", append(testISOBox("ftyp"), 0, 0, 0, 0, 'm', 'd', 'a', 't', 1, 2), []string{"ftyp", "mdat"}},
+		{"64 bit size", append(largeBox(20, "mdat"), 1, 2, 3, 4), []string{"mdat"}},
+		{"truncated header", append(testISOBox("ftyp"), 0, 0, 0), []string{"ftyp"}},
+		{"truncated 64 bit size", append(testISOBox("ftyp"), 0, 0, 0, 1, 'm', 'd', 'a', 't', 0), []string{"ftyp"}},
+		{"size below the header", append(testISOBox("ftyp"), 0, 0, 0, 4, 'm', 'e', 't', 'a'), []string{"ftyp"}},
+		{"size past the end", append(testISOBox("ftyp"), 0, 0, 1, 0, 'm', 'e', 't', 'a'), []string{"ftyp"}},
+		{"64 bit size overflows", append(testISOBox("ftyp"), largeBox(0x7FFFFFFFFFFFFFFF, "meta")...), []string{"ftyp"}},
+		{"negative 64 bit size", append(testISOBox("ftyp"), largeBox(0xFFFFFFFFFFFFFFFF, "meta")...), []string{"ftyp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds []string
+			for _, b := range readBoxes(bytes.NewReader(tt.data), 0, int64(len(tt.data))) {
+				kinds = append(kinds, b.kind)
+				assert.LessOrEqual(t, b.offset+b.size, int64(len(tt.data)))
+			}
+			assert.Equal(t, tt.kinds, kinds)
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IFD entry value types from the TIFF 6.0 specification
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeSByte     = 6
+	typeUndefined = 7
+	typeSShort    = 8
+	typeSLong     = 9
+	typeSRational = 10
+	typeFloat     = 11
+	typeDouble    = 12
+)
+
+var typeSizes = map[uint16]int{
+	typeByte:      1,
+	typeASCII:     1,
+	typeShort:     2,
+	typeLong:      4,
+	typeRational:  8,
+	typeSByte:     1,
+	typeUndefined: 1,
+	typeSShort:    2,
+	typeSLong:     4,
+	typeSRational: 8,
+	typeFloat:     4,
+	typeDouble:    8,
+}
+
+// Tags that point to the Exif and GPS IFDs instead of holding a value
+const (
+	tagExifIFD = 0x8769
+	tagGPSIFD  = 0x8825
+	tagSubIFDs = 0x014A // images of raw camera files next to IFD0
+)
+
+// Names of the EXIF tags that get stored, the names are the ones used by the EXIF specification
+var tagNames = map[uint16]string{
+	// IFD0
+	0x0100: "ImageWidth",
+	0x0101: "ImageLength",
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x011A: "XResolution",
+	0x011B: "YResolution",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x8298: "Copyright",
+	// Exif IFD
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8822: "ExposureProgram",
+	0x8827: "ISOSpeedRatings",
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x9010: "OffsetTime",
+	0x9011: "OffsetTimeOriginal",
+	0x9201: "ShutterSpeedValue",
+	0x9202: "ApertureValue",
+	0x9204: "ExposureBiasValue",
+	0x9207: "MeteringMode",
+	0x9209: "Flash",
+	0x920A: "FocalLength",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA405: "FocalLengthIn35mmFilm",
+	0xA420: "ImageUniqueID",
+	0xA431: "BodySerialNumber",
+	0xA433: "LensMake",
+	0xA434: "LensModel",
+	// GPS IFD, its ids overlap with nothing in IFD0 or the Exif IFD
+	0x0001: "GPSLatitudeRef",
+	0x0002: "GPSLatitude",
+	0x0003: "GPSLongitudeRef",
+	0x0004: "GPSLongitude",
+	0x0006: "GPSAltitude",
+}
+
+// Values bigger than this are skipped, no tag we read comes close
+const maxTagSize = 1 << 20
+
+// tag is a single IFD entry with its raw value bytes
+type tag struct {
+	id    uint16
+	typ   uint16
+	count uint32
+	data  []byte
+	order binary.ByteOrder
+}
+
+// Returns the i-th value of an integer tag
+func (t tag) uint(i int) uint32 {
+	switch t.typ {
+	case typeByte, typeUndefined, typeSByte:
+		if i < len(t.data) {
+			return uint32(t.data[i])
+		}
+	case typeShort, typeSShort:
+		if 2*i+2 <= len(t.data) {
+			return uint32(t.order.Uint16(t.data[2*i:]))
+		}
+	case typeLong, typeSLong:
+		if 4*i+4 <= len(t.data) {
+			return t.order.Uint32(t.data[4*i:])
+		}
+	}
+	return 0
+}
+
+// Returns the i-th value of a rational tag as numerator and denominator
+func (t tag) rational(i int) (int64, int64) {
+	if 8*i+8 > len(t.data) {
+		return 0, 0
+	}
+	if t.typ == typeSRational {
+		return int64(int32(t.order.Uint32(t.data[8*i:]))), int64(int32(t.order.Uint32(t.data[8*i+4:])))
+	}
+	return int64(t.order.Uint32(t.data[8*i:])), int64(t.order.Uint32(t.data[8*i+4:]))
+}
+
+// Returns the i-th value of a numeric tag as a float
+func (t tag) float(i int) float64 {
+	switch t.typ {
+	case typeRational, typeSRational:
+		num, den := t.rational(i)
+		if den == 0 {
+			return 0
+		}
+		return float64(num) / float64(den)
+	case typeSShort:
+		return float64(int16(t.uint(i)))
+	case typeSLong:
+		return float64(int32(t.uint(i)))
+	case typeFloat:
+		if 4*i+4 <= len(t.data) {
+			return float64(math.Float32frombits(t.order.Uint32(t.data[4*i:])))
+		}
+	case typeDouble:
+		if 8*i+8 <= len(t.data) {
+			return math.Float64frombits(t.order.Uint64(t.data[8*i:]))
+		}
+	default:
+		return float64(t.uint(i))
+	}
+	return 0
+}
+
+// Formats the value of the tag as text, lists are separated by spaces
+func (t tag) String() string {
+	switch t.typ {
+	case typeASCII:
+		return strings.TrimSpace(strings.TrimRight(string(t.data), "\x00"))
+	case typeUndefined:
+		// mostly versions like 0230 or binary blobs
+		if isPrintable(t.data) {
+			return strings.TrimSpace(strings.TrimRight(string(t.data), "\x00"))
+		}
+		return fmt.Sprintf("%d bytes", len(t.data))
+	}
+
+	values := make([]string, 0, min(t.count, 16))
+	for i := 0; i < int(t.count) && i < 16; i++ {
+		switch t.typ {
+		case typeRational, typeSRational:
+			num, den := t.rational(i)
+			// exposure times read better as fractions
+			if t.id == 0x829A && num > 0 && num < den {
+				values = append(values, fmt.Sprintf("1/%d", int64(math.Round(float64(den)/float64(num)))))
+				continue
+			}
+			// coordinates need every digit, a hundredth of a degree is about a kilometer
+			if t.id == 0x0002 || t.id == 0x0004 {
+				values = append(values, strconv.FormatFloat(t.float(i), 'f', -1, 64))
+				continue
+			}
+			values = append(values, formatFloat(t.float(i)))
+		case typeFloat, typeDouble:
+			values = append(values, formatFloat(t.float(i)))
+		case typeSShort, typeSLong:
+			values = append(values, strconv.Itoa(int(t.float(i))))
+		default:
+			values = append(values, strconv.FormatUint(uint64(t.uint(i)), 10))
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+func isPrintable(data []byte) bool {
+	for _, b := range data {
+		if b != 0 && (b < 0x20 || b > 0x7e) {
+			return false
+		}
+	}
+	return true
+}
+
+// tiffReader reads the IFDs of a TIFF structure, either a whole TIFF file
+// or the EXIF block embedded in another container
+type tiffReader struct {
+	r     io.ReaderAt
+	base  int64 // offset of the TIFF header, IFD offsets are relative to it
+	order binary.ByteOrder
+}
+
+// Reads the TIFF header at base and returns the reader with the offset of the first IFD
+func newTIFFReader(r io.ReaderAt, base int64) (*tiffReader, int64, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, base); err != nil {
+		return nil, 0, fmt.Errorf("error reading TIFF header: %w", err)
+	}
+
+	t := &tiffReader{r: r, base: base}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, fmt.Errorf("invalid TIFF byte order %q", header[:2])
+	}
+	// Panasonic RW2 raw files use their own magic number
+	if magic := t.order.Uint16(header[2:]); magic != 42 && magic != 0x55 {
+		return nil, 0, fmt.Errorf("invalid TIFF magic number")
+	}
+
+	return t, int64(t.order.Uint32(header[4:])), nil
+}
+
+// Reads the IFD at offset, returns its tags and the offset of the next IFD (0 if last)
+func (t *tiffReader) readIFD(offset int64) (map[uint16]tag, int64, error) {
+	countBytes := make([]byte, 2)
+	if _, err := t.r.ReadAt(countBytes, t.base+offset); err != nil {
+		return nil, 0, fmt.Errorf("error reading IFD: %w", err)
+	}
+	count := int(t.order.Uint16(countBytes))
+	if count > 1000 {
+		return nil, 0, fmt.Errorf("IFD has too many entries: %d", count)
+	}
+
+	entries := make([]byte, count*12+4)
+	n, err := t.r.ReadAt(entries, t.base+offset+2)
+	if err != nil && err != io.EOF {
+		return nil, 0, fmt.Errorf("error reading IFD entries: %w", err)
+	}
+	// entries cut off by the end of the file are left out
+	if n < len(entries) {
+		count = n / 12
+		entries = append(entries[:count*12], 0, 0, 0, 0)
+	}
+
+	tags := make(map[uint16]tag, count)
+	for i := 0; i < count; i++ {
+		entry := entries[i*12 : i*12+12]
+		tg := tag{
+			id:    t.order.Uint16(entry[0:]),
+			typ:   t.order.Uint16(entry[2:]),
+			count: t.order.Uint32(entry[4:]),
+			order: t.order,
+		}
+		size, ok := typeSizes[tg.typ]
+		if !ok || int64(tg.count)*int64(size) > maxTagSize {
+			continue
+		}
+
+		length := int(tg.count) * size
+		if length <= 4 {
+			// small values are stored in the entry itself
+			tg.data = entry[8 : 8+length]
+		} else {
+			tg.data = make([]byte, length)
+			if _, err := t.r.ReadAt(tg.data, t.base+int64(t.order.Uint32(entry[8:]))); err != nil {
+				continue
+			}
+		}
+		tags[tg.id] = tg
+	}
+
+	next := int64(t.order.Uint32(entries[count*12:]))
+	return tags, next, nil
+}
+
+// Reads IFD0 and the Exif and GPS IFDs it points to into one map
+func (t *tiffReader) readEXIF(offset int64) (map[uint16]tag, error) {
+	tags, _, err := t.readIFD(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pointerId := range []uint16{tagExifIFD, tagGPSIFD} {
+		pointer, ok := tags[pointerId]
+		if !ok {
+			continue
+		}
+		subTags, _, err := t.readIFD(int64(pointer.uint(0)))
+		if err != nil {
+			continue
+		}
+		for id, tg := range subTags {
+			if _, exists := tags[id]; !exists {
+				tags[id] = tg
+			}
+		}
+	}
+
+	return tags, nil
+}
+
+// Returns the size of the biggest image in IFD0 and its sub IFDs, raw camera files
+// keep a small preview in IFD0 and the sensor image in a sub IFD
+func (t *tiffReader) readLargestSize(offset int64) (int, int) {
+	tags, _, err := t.readIFD(offset)
+	if err != nil {
+		return 0, 0
+	}
+	ifds := []map[uint16]tag{tags}
+	if subIFDs, ok := tags[tagSubIFDs]; ok {
+		for i := 0; i < int(subIFDs.count) && i < 8; i++ {
+			if sub, _, err := t.readIFD(int64(subIFDs.uint(i))); err == nil {
+				ifds = append(ifds, sub)
+			}
+		}
+	}
+
+	var width, height int
+	for _, ifd := range ifds {
+		w, h := int(ifd[0x0100].uint(0)), int(ifd[0x0101].uint(0))
+		if w*h > width*height {
+			width, height = w, h
+		}
+	}
+	return width, height
+}
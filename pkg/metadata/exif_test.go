@@ -0,0 +1,197 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Byte orders the test files are written in
+type testByteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+type testEntry struct {
+	id    uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func testASCII(id uint16, value string) testEntry {
+	return testEntry{id, typeASCII, uint32(len(value) + 1), append([]byte(value), 0)}
+}
+
+func testShort(order testByteOrder, id uint16, value uint16) testEntry {
+	return testEntry{id, typeShort, 1, order.AppendUint16(nil, value)}
+}
+
+func testRationals(order testByteOrder, id uint16, values ...uint32) testEntry {
+	var data []byte
+	for _, v := range values {
+		data = order.AppendUint32(data, v)
+	}
+	return testEntry{id, typeRational, uint32(len(values) / 2), data}
+}
+
+// Returns the size of an IFD with the values that don't fit in its entries after it
+func testIFDSize(entries []testEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, e := range entries {
+		if len(e.value) > 4 {
+			size += len(e.value) + len(e.value)%2
+		}
+	}
+	return size
+}
+
+// Appends an IFD at the end of file, which starts at the TIFF header
+func appendTestIFD(file []byte, order testByteOrder, entries []testEntry, next uint32) []byte {
+	data := len(file) + 2 + 12*len(entries) + 4
+	var values []byte
+	file = order.AppendUint16(file, uint16(len(entries)))
+	for _, e := range entries {
+		file = order.AppendUint16(file, e.id)
+		file = order.AppendUint16(file, e.typ)
+		file = order.AppendUint32(file, e.count)
+		if len(e.value) <= 4 {
+			file = append(file, e.value...)
+			file = append(file, make([]byte, 4-len(e.value))...)
+			continue
+		}
+		file = order.AppendUint32(file, uint32(data+len(values)))
+		values = append(values, e.value...)
+		if len(e.value)%2 == 1 {
+			values = append(values, 0)
+		}
+	}
+	file = order.AppendUint32(file, next)
+	return append(file, values...)
+}
+
+// Builds a TIFF structure with IFD0 and an Exif IFD when it has entries
+func testTIFF(order testByteOrder, ifd0 []testEntry, exif []testEntry) []byte {
+	file := []byte("II*\x00")
+	if order == binary.BigEndian {
+		file = []byte("MM\x00*")
+	}
+	file = order.AppendUint32(file, 8)
+	if len(exif) > 0 {
+		ifd0 = append(ifd0, testEntry{tagExifIFD, typeLong, 1, nil})
+		ifd0[len(ifd0)-1].value = order.AppendUint32(nil, uint32(8+testIFDSize(ifd0)))
+	}
+	file = appendTestIFD(file, order, ifd0, 0)
+	if len(exif) > 0 {
+		file = appendTestIFD(file, order, exif, 0)
+	}
+	return file
+}
+
+// Builds the TIFF structure of a photo with a camera, exposure and GPS position
+func testPhotoTIFF(order testByteOrder) []byte {
+	ifd0 := []testEntry{
+		testASCII(0x010F, "Canon"),
+		testASCII(0x0110, "Canon EOS R6"),
+		testShort(order, 0x0112, 6),
+		testASCII(0x0132, "2023:05:01 10:00:00"),
+	}
+	exif := []testEntry{
+		testRationals(order, 0x829A, 1, 250),
+		testRationals(order, 0x829D, 28, 10),
+		testShort(order, 0x8827, 400),
+		testASCII(0x9003, "2023:04:30 18:15:42"),
+		testShort(order, 0xA002, 6000),
+		testShort(order, 0xA003, 4000),
+	}
+	return testTIFF(order, ifd0, exif)
+}
+
+func TestReadTIFF(t *testing.T) {
+	for _, order := range []testByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			r := &raw{}
+			assert.NoError(t, r.readTIFF(bytes.NewReader(testPhotoTIFF(order)), 0))
+			m := r.decode()
+			assert.Equal(t, "Canon EOS R6", m.Camera())
+			assert.Equal(t, "1/250 s f/2.8 ISO 400", m.Exposure())
+			assert.Equal(t, 6, m.Orientation)
+			assert.Equal(t, "6000x4000", m.Dimensions())
+			assert.Equal(t, "2023-04-30 18:15:42", m.CaptureDate.Format("2006-01-02 15:04:05"))
+		})
+	}
+}
+
+func TestReadTIFFInvalid(t *testing.T) {
+	order := binary.LittleEndian
+	valid := testPhotoTIFF(order)
+	tooMany := testTIFF(order, nil, nil)
+	order.PutUint16(tooMany[8:], 1001)
+	// the Make value points past the end of the file
+	pastEnd := testTIFF(order, []testEntry{testASCII(0x010F, "Canon"), testShort(order, 0x0112, 3)}, nil)
+	order.PutUint32(pastEnd[8+2+8:], 0xFFFFFFF0)
+	// the Make value claims 4 billion characters
+	oversized := testTIFF(order, []testEntry{testASCII(0x010F, "Canon"), testShort(order, 0x0112, 3)}, nil)
+	order.PutUint32(oversized[8+2+4:], 0xFFFFFFFF)
+	unknownType := testTIFF(order, []testEntry{{0x010F, 99, 1, []byte{1}}, testShort(order, 0x0112, 3)}, nil)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		hasErr      bool
+		orientation int
+		make        string
+	}{
+		{"empty", nil, true, 0, ""},
+		{"bad byte order", []byte("XX*\x00\x08\x00\x00\x00"), true, 0, ""},
+		{"bad magic number", []byte("II\x00\x00\x08\x00\x00\x00"), true, 0, ""},
+		{"IFD past the end", []byte("II*\x00\xff\xff\xff\x00"), true, 0, ""},
+		{"too many entries", tooMany, true, 0, ""},
+		{"truncated entries", valid[:30], false, 0, ""},
+		{"truncated values", valid[:len(valid)-20], false, 6, "Canon"},
+		{"value past the end", pastEnd, false, 3, ""},
+		{"oversized count", oversized, false, 3, ""},
+		{"unknown type", unknownType, false, 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				r := &raw{}
+				err := r.readTIFF(bytes.NewReader(tt.data), 0)
+				assert.Equal(t, tt.hasErr, err != nil, err)
+				m := r.decode()
+				assert.Equal(t, tt.orientation, m.Orientation)
+				assert.Equal(t, tt.make, m.Make)
+			})
+		})
+	}
+}
+
+func TestTagString(t *testing.T) {
+	le := binary.LittleEndian
+	tests := []struct {
+		name string
+		tag  tag
+		want string
+	}{
+		{"ASCII", tag{typ: typeASCII, data: []byte(" Nikon \x00\x00")}, "Nikon"},
+		{"printable undefined", tag{typ: typeUndefined, data: []byte("0230")}, "0230"},
+		{"binary undefined", tag{typ: typeUndefined, data: []byte{1, 2, 0xff}}, "3 bytes"},
+		{"short list", tag{typ: typeShort, count: 2, data: []byte{100, 0, 200, 0}, order: le}, "100 200"},
+		{"negative short", tag{typ: typeSShort, count: 1, data: []byte{0xfe, 0xff}, order: le}, "-2"},
+		{"exposure fraction", tag{id: 0x829A, typ: typeRational, count: 1, data: le.AppendUint32(le.AppendUint32(nil, 10), 300000), order: le}, "1/30000"},
+		{"negative rational", tag{typ: typeSRational, count: 1, data: le.AppendUint32(le.AppendUint32(nil, 0xFFFFFFFD), 3), order: le}, "-1"},
+		{"zero denominator", tag{typ: typeRational, count: 1, data: make([]byte, 8), order: le}, "0"},
+		{"coordinate keeps every digit", tag{id: 0x0002, typ: typeRational, count: 1, data: le.AppendUint32(le.AppendUint32(nil, 123456), 1000), order: le}, "123.456"},
+		// the count says more values than there is data for
+		{"count past the data", tag{typ: typeLong, count: 3, data: le.AppendUint32(nil, 7), order: le}, "7 0 0"},
+		{"huge count", tag{typ: typeByte, count: 0xFFFFFFFF, data: []byte{1}, order: le}, "1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.tag.String())
+		})
+	}
+}
@@ -0,0 +1,313 @@
+// Package metadata reads EXIF and XMP metadata from JPEG, TIFF, raw camera,
+// PNG, WebP and HEIC/HEIF files without decoding the image, and the container
+// metadata of videos
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"main/pkg/video"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date format used by the EXIF DateTime tags
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// Date format dates are shown in, the same as the date added in the sidebar
+const displayDateLayout = "15:04 02-01-2006"
+
+var ErrUnsupportedFormat = errors.New("unsupported file format for metadata")
+
+// Metadata of an image file
+type Metadata struct {
+	Fields       map[string]string // every read EXIF tag by its EXIF name, formatted as text
+	CaptureDate  time.Time         // when the photo was taken, zero if unknown
+	Make         string
+	Model        string
+	Lens         string
+	ExposureTime string // in seconds, fractions are written like 1/250
+	FNumber      float64
+	ISO          int
+	FocalLength  float64 // in mm
+	Width        int
+	Height       int
+	Orientation  int           // EXIF orientation 1-8, 0 if unknown
+	HasGPS       bool          // true if the file has GPS coordinates
+	Latitude     float64       // in degrees, south is negative
+	Longitude    float64       // in degrees, west is negative
+	Duration     time.Duration // of videos, 0 for images
+	VideoCodec   string
+	AudioCodec   string
+}
+
+// Reads the metadata of the file, the container format is detected from its content.
+// Returns ErrUnsupportedFormat for files that can't hold EXIF or XMP.
+func Read(path string) (*Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file info: %w", err)
+	}
+
+	header := make([]byte, 64)
+	n, _ := file.ReadAt(header, 0)
+	if video.Detect(header[:n]) != "" {
+		return readVideo(path)
+	}
+
+	var r *raw
+	switch detectFormat(header[:n]) {
+	case formatJPEG:
+		r, err = readJPEG(file)
+	case formatPNG:
+		r, err = readPNG(file)
+	case formatWebP:
+		r, err = readWebP(file)
+	case formatTIFF:
+		r = &raw{}
+		err = r.readTIFF(file, 0)
+		if t, first, tiffErr := newTIFFReader(file, 0); tiffErr == nil {
+			r.width, r.height = t.readLargestSize(first)
+		}
+	case formatISOBMFF:
+		r, err = readISOBMFF(file, info.Size())
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if r == nil {
+		return nil, err
+	}
+
+	// a broken block still leaves whatever was read before it
+	return r.decode(), err
+}
+
+// EXIF orientations of videos shown rotated clockwise by 90, 180 and 270 degrees
+var videoOrientations = map[int]int{90: 6, 180: 3, 270: 8}
+
+// Reads the metadata of a video from its container
+func readVideo(path string) (*Metadata, error) {
+	info, err := video.Probe(path)
+	if info == nil {
+		return nil, err
+	}
+	m := &Metadata{
+		Fields:      map[string]string{},
+		CaptureDate: info.CreationDate,
+		Width:       info.Width,
+		Height:      info.Height,
+		Orientation: videoOrientations[info.Rotation],
+		Duration:    info.Duration,
+		VideoCodec:  info.VideoCodec,
+		AudioCodec:  info.AudioCodec,
+	}
+	return m, err
+}
+
+// Builds the metadata from the EXIF tags, falling back to XMP and the container
+func (r *raw) decode() *Metadata {
+	m := &Metadata{Fields: map[string]string{}}
+
+	for id, tg := range r.exif {
+		if name, ok := tagNames[id]; ok {
+			if value := tg.String(); value != "" {
+				m.Fields[name] = value
+			}
+		}
+	}
+	if r.xmp != nil {
+		for name, value := range parseXMP(r.xmp) {
+			if _, exists := m.Fields[name]; !exists {
+				m.Fields[name] = value
+			}
+		}
+	}
+
+	for _, name := range []string{"DateTimeOriginal", "DateTimeDigitized", "DateTime"} {
+		if date, err := time.Parse(exifDateLayout, m.Fields[name]); err == nil && date.Year() > 1 {
+			m.CaptureDate = date
+			break
+		}
+	}
+
+	m.Make = m.Fields["Make"]
+	m.Model = m.Fields["Model"]
+	m.Lens = m.Fields["LensModel"]
+	m.ExposureTime = m.Fields["ExposureTime"]
+	m.FNumber, _ = strconv.ParseFloat(m.Fields["FNumber"], 64)
+	m.FocalLength, _ = strconv.ParseFloat(m.Fields["FocalLength"], 64)
+	// several ISO values can be listed, the first one is the one used
+	m.ISO, _ = strconv.Atoi(strings.Fields(m.Fields["ISOSpeedRatings"] + " 0")[0])
+	m.Orientation, _ = strconv.Atoi(m.Fields["Orientation"])
+	latitude, latOk := parseCoordinate(m.Fields["GPSLatitude"], m.Fields["GPSLatitudeRef"])
+	longitude, lonOk := parseCoordinate(m.Fields["GPSLongitude"], m.Fields["GPSLongitudeRef"])
+	// cameras without a fix often write 0, 0
+	if latOk && lonOk && math.Abs(latitude) <= 90 && math.Abs(longitude) <= 180 && (latitude != 0 || longitude != 0) {
+		m.HasGPS = true
+		m.Latitude, m.Longitude = latitude, longitude
+	}
+
+	// the container knows the real dimensions, EXIF may describe the thumbnail or be stale
+	m.Width, m.Height = r.width, r.height
+	for _, names := range [][2]string{{"PixelXDimension", "PixelYDimension"}, {"ImageWidth", "ImageLength"}} {
+		if m.Width != 0 && m.Height != 0 {
+			break
+		}
+		m.Width, _ = strconv.Atoi(m.Fields[names[0]])
+		m.Height, _ = strconv.Atoi(m.Fields[names[1]])
+	}
+
+	return m
+}
+
+// Returns the camera make and model, the make is left out when the model already names it
+func (m *Metadata) Camera() string {
+	if m.Make == "" || strings.HasPrefix(strings.ToLower(m.Model), strings.ToLower(strings.Fields(m.Make + " ")[0])) {
+		return m.Model
+	}
+	if m.Model == "" {
+		return m.Make
+	}
+	return m.Make + " " + m.Model
+}
+
+// Returns the exposure settings like "1/250 s f/2.8 ISO 100 35 mm"
+func (m *Metadata) Exposure() string {
+	var parts []string
+	if m.ExposureTime != "" {
+		parts = append(parts, m.ExposureTime+" s")
+	}
+	if m.FNumber > 0 {
+		parts = append(parts, "f/"+formatFloat(m.FNumber))
+	}
+	if m.ISO > 0 {
+		parts = append(parts, "ISO "+strconv.Itoa(m.ISO))
+	}
+	if m.FocalLength > 0 {
+		parts = append(parts, formatFloat(m.FocalLength)+" mm")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Returns the width and height the image is shown with after applying the EXIF orientation
+func (m *Metadata) DisplaySize() (int, int) {
+	// orientations 5-8 are rotated by 90 degrees
+	if m.Orientation >= 5 && m.Orientation <= 8 {
+		return m.Height, m.Width
+	}
+	return m.Width, m.Height
+}
+
+// Returns the dimensions like "4000x3000", empty if unknown
+func (m *Metadata) Dimensions() string {
+	if m.Width == 0 || m.Height == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", m.Width, m.Height)
+}
+
+// Returns the video and audio codecs like "H.264, AAC", empty for images
+func (m *Metadata) Codecs() string {
+	var codecs []string
+	for _, codec := range []string{m.VideoCodec, m.AudioCodec} {
+		if codec != "" {
+			codecs = append(codecs, codec)
+		}
+	}
+	return strings.Join(codecs, ", ")
+}
+
+// Returns the value of a field for display, empty if the file doesn't have it. Besides the EXIF
+// tag names it knows Camera, Lens, Exposure, Dimensions, GPS, CaptureDate, Duration and Codecs.
+func (m *Metadata) Field(name string) string {
+	switch name {
+	case "Duration":
+		if m.Duration <= 0 {
+			return ""
+		}
+		return video.FormatDuration(m.Duration)
+	case "Codecs":
+		return m.Codecs()
+	case "Camera":
+		return m.Camera()
+	case "Lens":
+		return m.Lens
+	case "Exposure":
+		return m.Exposure()
+	case "Dimensions":
+		return m.Dimensions()
+	case "GPS":
+		if !m.HasGPS {
+			return ""
+		}
+		return fmt.Sprintf("%.5f, %.5f", m.Latitude, m.Longitude)
+	case "CaptureDate":
+		if m.CaptureDate.IsZero() {
+			return ""
+		}
+		return m.CaptureDate.Format(displayDateLayout)
+	case "DateTime", "DateTimeOriginal", "DateTimeDigitized":
+		if date, err := time.Parse(exifDateLayout, m.Fields[name]); err == nil {
+			return date.Format(displayDateLayout)
+		}
+	}
+	return m.Fields[name]
+}
+
+// Parses a GPS coordinate, either the EXIF degrees, minutes and seconds like "56 57 1.2"
+// with the reference N, S, E or W or the XMP format like "56,57.02N"
+func parseCoordinate(value string, ref string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	// XMP puts the reference at the end
+	if last := value[len(value)-1]; strings.ContainsRune("NSEW", rune(last)) {
+		ref = string(last)
+		value = value[:len(value)-1]
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, false
+	}
+	coordinate := 0.0
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		coordinate += number / math.Pow(60, float64(i))
+	}
+
+	if ref == "S" || ref == "W" {
+		coordinate = -coordinate
+	}
+	return coordinate, true
+}
+
+// Parses a value like 28/10 or 2.8
+func parseFraction(value string) (float64, float64, bool) {
+	num, den, found := strings.Cut(value, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !found {
+		return n, 1, true
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return n, d, true
+}
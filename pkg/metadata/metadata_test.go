@@ -0,0 +1,115 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		ref   string
+		want  float64
+		ok    bool
+	}{
+		{"degrees minutes seconds", "56 57 36", "N", 56.96, true},
+		{"south", "33 51 54", "S", -(33 + 51.0/60 + 54.0/3600), true},
+		{"decimal degrees", "24.1052", "E", 24.1052, true},
+		{"XMP", "56,57.6N", "", 56.96, true},
+		{"XMP west", "3,42.3W", "", -3.705, true},
+		{"empty", "", "N", 0, false},
+		{"reference only", "N", "", 0, false},
+		{"too many parts", "1 2 3 4", "N", 0, false},
+		{"not a number", "56 x 36", "N", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCoordinate(tt.value, tt.ref)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestParseFraction(t *testing.T) {
+	tests := []struct {
+		value    string
+		num, den float64
+		ok       bool
+	}{
+		{"28/10", 28, 10, true},
+		{"2.8", 2.8, 1, true},
+		{"1/0", 1, 0, true},
+		{"", 0, 0, false},
+		{"1/", 0, 0, false},
+		{"a/2", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			num, den, ok := parseFraction(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.num, num)
+			assert.Equal(t, tt.den, den)
+		})
+	}
+}
+
+func TestDecodeGPS(t *testing.T) {
+	order := binary.LittleEndian
+	gps := func(lat, lon []uint32, latRef, lonRef string) *raw {
+		tags := map[uint16]tag{}
+		for id, e := range map[uint16]testEntry{
+			0x0001: testASCII(0x0001, latRef),
+			0x0002: testRationals(order, 0x0002, lat...),
+			0x0003: testASCII(0x0003, lonRef),
+			0x0004: testRationals(order, 0x0004, lon...),
+		} {
+			tags[id] = tag{id: e.id, typ: e.typ, count: e.count, data: e.value, order: order}
+		}
+		return &raw{exif: tags}
+	}
+	riga := gps([]uint32{56, 1, 57, 1, 36, 1}, []uint32{24, 1, 6, 1, 18, 1}, "N", "E")
+	m := riga.decode()
+	assert.True(t, m.HasGPS)
+	assert.InDelta(t, 56.96, m.Latitude, 1e-9)
+	assert.InDelta(t, 24.105, m.Longitude, 1e-9)
+
+	// cameras without a fix write 0, 0
+	assert.False(t, gps([]uint32{0, 1, 0, 1, 0, 1}, []uint32{0, 1, 0, 1, 0, 1}, "N", "E").decode().HasGPS)
+	assert.False(t, gps([]uint32{91, 1, 0, 1, 0, 1}, []uint32{0, 1, 0, 1, 0, 1}, "N", "E").decode().HasGPS)
+	// truncated rationals read as zero
+	assert.False(t, gps([]uint32{56}, []uint32{24}, "N", "E").decode().HasGPS)
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		data   []byte
+		err    error
+		camera string
+	}{
+		{"JPEG", testJPEG(), nil, "Canon EOS R6"},
+		{"TIFF", testPhotoTIFF(binary.LittleEndian), nil, "Canon EOS R6"},
+		{"HEIF", testISOBMFF(0), nil, "Canon EOS R6"},
+		{"text", []byte("hello"), ErrUnsupportedFormat, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m, err := Read(path)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil && assert.NotNil(t, m) {
+				assert.Equal(t, tt.camera, m.Camera())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package metadata
+
+import (
+	"regexp"
+	"time"
+)
+
+// XMP properties that are read, mapped to the EXIF field they fill in when
+// the EXIF block doesn't have it
+var xmpProperties = map[string]string{
+	"tiff:Make":               "Make",
+	"tiff:Model":              "Model",
+	"tiff:Orientation":        "Orientation",
+	"tiff:ImageWidth":         "ImageWidth",
+	"tiff:ImageLength":        "ImageLength",
+	"tiff:Artist":             "Artist",
+	"xmp:CreatorTool":         "Software",
+	"xmp:ModifyDate":          "DateTime",
+	"xmp:CreateDate":          "DateTimeDigitized",
+	"exif:DateTimeOriginal":   "DateTimeOriginal",
+	"photoshop:DateCreated":   "DateTimeOriginal",
+	"exif:ExposureTime":       "ExposureTime",
+	"exif:FNumber":            "FNumber",
+	"exif:FocalLength":        "FocalLength",
+	"exif:PixelXDimension":    "PixelXDimension",
+	"exif:PixelYDimension":    "PixelYDimension",
+	"exifEX:LensMake":         "LensMake",
+	"exifEX:LensModel":        "LensModel",
+	"aux:Lens":                "LensModel",
+	"exifEX:BodySerialNumber": "BodySerialNumber",
+	"aux:SerialNumber":        "BodySerialNumber",
+	"exif:GPSLatitude":        "GPSLatitude",
+	"exif:GPSLongitude":       "GPSLongitude",
+}
+
+// Matches simple properties written as attributes (prefix:Name="value")
+// or as elements (<prefix:Name>value</prefix:Name>)
+var (
+	xmpAttribute = regexp.MustCompile(`([A-Za-z]+:[A-Za-z]+)\s*=\s*"([^"]*)"`)
+	xmpElement   = regexp.MustCompile(`<([A-Za-z]+:[A-Za-z]+)>([^<]*)</([A-Za-z]+:[A-Za-z]+)>`)
+	xmpISOSpeed  = regexp.MustCompile(`<exif:ISOSpeedRatings>\s*<rdf:Seq>\s*<rdf:li>(\d+)</rdf:li>`)
+)
+
+// Reads the known properties of an XMP packet, keyed by EXIF field name
+func parseXMP(packet []byte) map[string]string {
+	fields := map[string]string{}
+	set := func(property string, value string) {
+		name, ok := xmpProperties[property]
+		if !ok || value == "" {
+			return
+		}
+		if _, exists := fields[name]; exists {
+			return
+		}
+		fields[name] = xmpValue(name, value)
+	}
+
+	for _, match := range xmpAttribute.FindAllSubmatch(packet, -1) {
+		set(string(match[1]), string(match[2]))
+	}
+	for _, match := range xmpElement.FindAllSubmatch(packet, -1) {
+		if string(match[1]) == string(match[3]) {
+			set(string(match[1]), string(match[2]))
+		}
+	}
+	if match := xmpISOSpeed.FindSubmatch(packet); match != nil {
+		fields["ISOSpeedRatings"] = string(match[1])
+	}
+	return fields
+}
+
+// XMP dates are ISO 8601, they are converted to the EXIF date format
+var xmpDateLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Converts XMP values to the format the same EXIF tag is shown in
+func xmpValue(name string, value string) string {
+	switch name {
+	case "DateTime", "DateTimeOriginal", "DateTimeDigitized":
+		for _, layout := range xmpDateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.Format(exifDateLayout)
+			}
+		}
+	case "FNumber", "FocalLength":
+		// rationals are written as numerator/denominator
+		if num, den, ok := parseFraction(value); ok && den != 0 {
+			return formatFloat(num / den)
+		}
+	case "ExposureTime":
+		if num, den, ok := parseFraction(value); ok && den != 0 && num > 0 && num < den {
+			return "1/" + formatFloat(den/num)
+		} else if ok && den != 0 {
+			return formatFloat(num / den)
+		}
+	}
+	return value
+}
@@ -0,0 +1,48 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseXMP(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet string
+		want   map[string]string
+	}{
+		{"empty", "", map[string]string{}},
+		{
+			"attributes",
+			`<rdf:Description tiff:Make="Sony" tiff:Model = "ILCE-7M3" exif:FNumber="18/10" exif:ExposureTime="1/125"/>`,
+			map[string]string{"Make": "Sony", "Model": "ILCE-7M3", "FNumber": "1.8", "ExposureTime": "1/125"},
+		},
+		{
+			"elements",
+			`<exif:FocalLength>350/10</exif:FocalLength><xmp:CreateDate>2021-07-04T12:30:00+02:00</xmp:CreateDate>`,
+			map[string]string{"FocalLength": "35", "DateTimeDigitized": "2021:07:04 12:30:00"},
+		},
+		{
+			"ISO list",
+			`<exif:ISOSpeedRatings><rdf:Seq><rdf:li>800</rdf:li></rdf:Seq></exif:ISOSpeedRatings>`,
+			map[string]string{"ISOSpeedRatings": "800"},
+		},
+		{
+			"first value wins",
+			`<rdf:Description exifEX:LensModel="FE 35mm"/><aux:Lens>Other</aux:Lens>`,
+			map[string]string{"LensModel": "FE 35mm"},
+		},
+		{"mismatched element", `<tiff:Make>Sony</tiff:Model>`, map[string]string{}},
+		{"unknown and empty properties", `<rdf:Description dc:format="image/jpeg" tiff:Make=""/>`, map[string]string{}},
+		{"unterminated", `<rdf:Description tiff:Make="Sony`, map[string]string{}},
+		{"long exposure", `<rdf:Description exif:ExposureTime="30/1"/>`, map[string]string{"ExposureTime": "30"}},
+		{"bad date kept as is", `<xmp:ModifyDate>yesterday</xmp:ModifyDate>`, map[string]string{"DateTime": "yesterday"}},
+		{"zero denominator kept as is", `<rdf:Description exif:FNumber="28/0"/>`, map[string]string{"FNumber": "28/0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseXMP([]byte(tt.packet)))
+		})
+	}
+}
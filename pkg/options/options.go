@@ -4,23 +4,68 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"main/pkg/fileutils"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 )
 
 type Options struct {
-	DatabasePath  string
-	ExcludedDirs  map[string]int
-	Profiling     bool
-	Timezone      int // Timezone like UTC+3 or UTC-3
-	SortDesc      bool
-	UseRGB        bool
-	ExifFields    []string // exif fields to display in the sidebar
-	ImageNumber   uint
-	ThumbnailSize int
-	FirstBoot     bool
+	DatabasePath        string
+	ExcludedDirs        map[string]int
+	Profiling           bool
+	Timezone            int // Timezone like UTC+3 or UTC-3
+	SortDesc            bool
+	UseRGB              bool
+	ExifFields          []string // exif fields to display in the sidebar
+	ImageNumber         uint
+	ThumbnailSize       int
+	FirstBoot           bool
+	ThumbnailCacheSize  int      // most MB the thumbnails cached on disk take up
+	MediaTypes          []string // media types added to the library, see fileutils.MediaTypes
+	GridLayout          string   // how thumbnails are laid out, one of GridLayouts
+	AnimationPlayback   string   // when animated thumbnails play, one of AnimationPlaybacks
+	AnimationFrameRate  int      // frames per second of animated thumbnails, 0 keeps the timing of the file
+	SlideshowInterval   int      // seconds each slide is shown
+	SlideshowShuffle    bool
+	SlideshowLoop       bool   // starts again after the last slide
+	SlideshowTransition string // how slides change, one of SlideshowTransitions
+	SlideshowInfo       bool   // shows the tags and notes of the slides
+}
+
+// Layouts of the thumbnail grid
+const (
+	SquareLayout    = "square"    // columns of square thumbnails cropped from the center
+	FitLayout       = "fit"       // columns of whole images fitted in their box
+	JustifiedLayout = "justified" // rows of whole images, the widths follow their aspect ratios
+)
+
+var GridLayouts = []string{SquareLayout, FitLayout, JustifiedLayout}
+
+// When animated GIF, WebP and PNG thumbnails play
+const (
+	PlayOnHover = "hover"
+	PlayAlways  = "always"
+	PlayNever   = "never" // until play is pressed on the thumbnail
+)
+
+var AnimationPlaybacks = []string{PlayOnHover, PlayAlways, PlayNever}
+
+// How one slide of a slideshow changes to the next
+const (
+	NoTransition    = "none"
+	FadeTransition  = "fade"
+	SlideTransition = "slide"
+)
+
+var SlideshowTransitions = []string{NoTransition, FadeTransition, SlideTransition}
+
+// Returns true if the thumbnails show the whole image instead of a square crop, the
+// justified layout always gets them as its tiles take the aspect of their thumbnails
+func (opts Options) FitThumbnails() bool {
+	return opts.GridLayout == FitLayout || opts.GridLayout == JustifiedLayout
 }
 
 // Checks if the directory is blacklisted
@@ -51,14 +96,24 @@ func (opts Options) InitDefault() *Options {
 			cwd:            1,
 			// filepath.Dir(os.Args[0]): 1,
 		},
-		Profiling:     false,
-		Timezone:      3,
-		SortDesc:      true,
-		UseRGB:        false,
-		ExifFields:    []string{"DateTime"},
-		ImageNumber:   20,
-		ThumbnailSize: 256,
-		FirstBoot:     true,
+		Profiling:           false,
+		Timezone:            3,
+		SortDesc:            true,
+		UseRGB:              false,
+		ExifFields:          []string{"DateTime"},
+		ImageNumber:         20,
+		ThumbnailSize:       256,
+		FirstBoot:           true,
+		ThumbnailCacheSize:  512,
+		MediaTypes:          slices.Clone(fileutils.DefaultMediaTypes),
+		GridLayout:          SquareLayout,
+		AnimationPlayback:   PlayOnHover,
+		AnimationFrameRate:  0,
+		SlideshowInterval:   5,
+		SlideshowShuffle:    false,
+		SlideshowLoop:       true,
+		SlideshowTransition: FadeTransition,
+		SlideshowInfo:       true,
 	}
 }
 
@@ -85,6 +140,11 @@ func SaveOptionsToDB(db *sql.DB, options *Options) error {
 		return fmt.Errorf("error marshaling ExifFields: %v", err)
 	}
 
+	mediaTypesJSON, err := json.Marshal(options.MediaTypes)
+	if err != nil {
+		return fmt.Errorf("error marshaling MediaTypes: %v", err)
+	}
+
 	var numOptionsDb int64
 	err = db.QueryRow("SELECT COUNT(*) FROM Options").Scan(&numOptionsDb)
 	if err != nil {
@@ -98,8 +158,10 @@ func SaveOptionsToDB(db *sql.DB, options *Options) error {
 		query = `
 		INSERT INTO Options (
 			DatabasePath, ExcludedDirs, Profiling, Timezone, SortDesc, 
-			UseRGB, ExifFields, ImageNumber, ThumbnailSize, FirstBoot
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+			UseRGB, ExifFields, ImageNumber, ThumbnailSize, FirstBoot,
+			ThumbnailCacheSize, MediaTypes, GridLayout, AnimationPlayback, AnimationFrameRate,
+			SlideshowInterval, SlideshowShuffle, SlideshowLoop, SlideshowTransition, SlideshowInfo
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 	case 1:
 		options.FirstBoot = false
 		query = `
@@ -113,7 +175,17 @@ func SaveOptionsToDB(db *sql.DB, options *Options) error {
 		ExifFields = ?,
 		ImageNumber = ?,
 		ThumbnailSize = ?,
-		FirstBoot = ?
+		FirstBoot = ?,
+		ThumbnailCacheSize = ?,
+		MediaTypes = ?,
+		GridLayout = ?,
+		AnimationPlayback = ?,
+		AnimationFrameRate = ?,
+		SlideshowInterval = ?,
+		SlideshowShuffle = ?,
+		SlideshowLoop = ?,
+		SlideshowTransition = ?,
+		SlideshowInfo = ?
 		WHERE id = 1;
 		`
 	default:
@@ -139,6 +211,16 @@ func SaveOptionsToDB(db *sql.DB, options *Options) error {
 		options.ImageNumber,
 		options.ThumbnailSize,
 		options.FirstBoot,
+		options.ThumbnailCacheSize,
+		string(mediaTypesJSON),
+		options.GridLayout,
+		options.AnimationPlayback,
+		options.AnimationFrameRate,
+		options.SlideshowInterval,
+		options.SlideshowShuffle,
+		options.SlideshowLoop,
+		options.SlideshowTransition,
+		options.SlideshowInfo,
 	)
 	if err != nil {
 		return fmt.Errorf("error executing statement: %v", err)
@@ -152,11 +234,13 @@ func LoadOptionsFromDB(db *sql.DB) (*Options, error) {
 
 	row := db.QueryRow(`
 		SELECT DatabasePath, ExcludedDirs, Profiling, Timezone, SortDesc, 
-			   UseRGB, ExifFields, ImageNumber, ThumbnailSize, FirstBoot
+			   UseRGB, ExifFields, ImageNumber, ThumbnailSize, FirstBoot,
+			   ThumbnailCacheSize, MediaTypes, GridLayout, AnimationPlayback, AnimationFrameRate,
+			   SlideshowInterval, SlideshowShuffle, SlideshowLoop, SlideshowTransition, SlideshowInfo
 		FROM options WHERE id = 1 LIMIT 1
 	`)
 
-	var excludedDirsJSON, exifFieldsJSON string
+	var excludedDirsJSON, exifFieldsJSON, mediaTypesJSON string
 
 	err := row.Scan(
 		&options.DatabasePath,
@@ -169,6 +253,16 @@ func LoadOptionsFromDB(db *sql.DB) (*Options, error) {
 		&options.ImageNumber,
 		&options.ThumbnailSize,
 		&options.FirstBoot,
+		&options.ThumbnailCacheSize,
+		&mediaTypesJSON,
+		&options.GridLayout,
+		&options.AnimationPlayback,
+		&options.AnimationFrameRate,
+		&options.SlideshowInterval,
+		&options.SlideshowShuffle,
+		&options.SlideshowLoop,
+		&options.SlideshowTransition,
+		&options.SlideshowInfo,
 	)
 	options.FirstBoot = false
 	if err != nil {
@@ -189,5 +283,10 @@ func LoadOptionsFromDB(db *sql.DB) (*Options, error) {
 		return nil, fmt.Errorf("error unmarshaling ExifFields: %v", err)
 	}
 
+	err = json.Unmarshal([]byte(mediaTypesJSON), &options.MediaTypes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling MediaTypes: %v", err)
+	}
+
 	return options, nil
 }
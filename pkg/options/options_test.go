@@ -0,0 +1,25 @@
+package options
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The justified layout sizes its tiles by the aspect of their thumbnails, so it
+// needs fitted ones
+func TestFitThumbnails(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   bool
+	}{
+		{SquareLayout, false},
+		{FitLayout, true},
+		{JustifiedLayout, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			assert.Equal(t, tt.want, Options{GridLayout: tt.layout}.FitThumbnails())
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Package resourcecache keeps decoded image resources in memory up to a size in
+// bytes, the resources used longest ago are evicted first
+package resourcecache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// Kind of resource made from an image
+type Variant int
+
+const (
+	Thumbnail Variant = iota // square crop from the center
+	Scaled                   // whole image scaled down, keeping its aspect ratio
+)
+
+func (v Variant) String() string {
+	switch v {
+	case Thumbnail:
+		return "thumbnail"
+	case Scaled:
+		return "scaled"
+	default:
+		return fmt.Sprintf("variant %d", int(v))
+	}
+}
+
+// Key of a cached resource, the same image has one resource per variant and size
+type Key struct {
+	Path    string
+	Variant Variant
+	Size    int
+}
+
+type entry struct {
+	key      Key
+	resource fyne.Resource
+	bytes    int64
+}
+
+// Cache is a least recently used cache of resources bounded by their size in bytes,
+// it is safe to use from several goroutines
+type Cache struct {
+	mu        sync.Mutex
+	maxBytes  int64
+	bytes     int64
+	entries   map[Key]*list.Element
+	order     *list.List // most recently used at the front
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// Returns an empty cache that holds at most maxBytes of resource content
+func New(maxBytes int64) *Cache {
+	return &Cache{
+		maxBytes: maxBytes,
+		entries:  map[Key]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Returns the cached resource and marks it as used
+func (c *Cache) Get(key Key) (fyne.Resource, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*entry).resource, true
+}
+
+// Adds a resource, evicting the ones used longest ago until it fits. Resources
+// bigger than the whole cache aren't kept
+func (c *Cache) Put(key Key, resource fyne.Resource) {
+	size := int64(len(resource.Content()))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+	if size > c.maxBytes {
+		return
+	}
+	for c.bytes+size > c.maxBytes {
+		c.removeElement(c.order.Back())
+		c.evictions++
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, resource: resource, bytes: size})
+	c.bytes += size
+}
+
+// Removes every resource of an image, e.g. after the file changed
+func (c *Cache) RemovePath(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if key.Path == path {
+			c.removeElement(element)
+		}
+	}
+}
+
+// the caller holds the lock
+func (c *Cache) removeElement(element *list.Element) {
+	e := c.order.Remove(element).(*entry)
+	delete(c.entries, e.key)
+	c.bytes -= e.bytes
+}
+
+// Usage of a cache since it was made
+type Stats struct {
+	Entries   int
+	Bytes     int64
+	MaxBytes  int64
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// Returns the share of lookups that found their resource
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d resources, %.1f of %.1f MB, %d hits, %d misses (%.0f%% hit rate), %d evictions",
+		s.Entries, float64(s.Bytes)/(1<<20), float64(s.MaxBytes)/(1<<20), s.Hits, s.Misses, s.HitRate()*100, s.Evictions)
+}
+
+// Returns the current usage of the cache
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Entries:   len(c.entries),
+		Bytes:     c.bytes,
+		MaxBytes:  c.maxBytes,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
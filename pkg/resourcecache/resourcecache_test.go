@@ -0,0 +1,136 @@
+package resourcecache
+
+import (
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a resource with size bytes of content
+func testResource(name string, size int) fyne.Resource {
+	return fyne.NewStaticResource(name, []byte(strings.Repeat("x", size)))
+}
+
+func testKey(path string) Key {
+	return Key{Path: path, Variant: Thumbnail, Size: 256}
+}
+
+// Cached paths from the most recently used to the least
+func cachedPaths(c *Cache) []string {
+	var paths []string
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		paths = append(paths, element.Value.(*entry).key.Path)
+	}
+	return paths
+}
+
+func TestCachePut(t *testing.T) {
+	type put struct {
+		path string
+		size int
+	}
+	tests := []struct {
+		name      string
+		maxBytes  int64
+		puts      []put
+		gets      []string // looked up after the puts, marking them as used
+		then      []put    // put after the lookups
+		want      []string
+		bytes     int64
+		evictions uint64
+	}{
+		{"fits", 100, []put{{"a", 10}, {"b", 20}}, nil, nil, []string{"b", "a"}, 30, 0},
+		{"evicts the oldest", 30, []put{{"a", 10}, {"b", 10}, {"c", 10}}, nil, []put{{"d", 10}}, []string{"d", "c", "b"}, 30, 1},
+		{"a lookup keeps it", 30, []put{{"a", 10}, {"b", 10}, {"c", 10}}, []string{"a"}, []put{{"d", 10}}, []string{"d", "a", "c"}, 30, 1},
+		{"evicts until it fits", 30, []put{{"a", 10}, {"b", 10}, {"c", 10}}, nil, []put{{"d", 25}}, []string{"d"}, 25, 3},
+		{"bigger than the cache", 30, []put{{"a", 10}}, nil, []put{{"huge", 31}}, []string{"a"}, 10, 0},
+		{"exactly the cache", 30, []put{{"a", 10}}, nil, []put{{"full", 30}}, []string{"full"}, 30, 1},
+		// replacing counts the new size only and doesn't evict the entry it replaces
+		{"replace with bigger", 30, []put{{"a", 10}, {"b", 10}}, nil, []put{{"a", 20}}, []string{"a", "b"}, 30, 0},
+		{"replace with smaller", 30, []put{{"a", 20}, {"b", 10}}, nil, []put{{"a", 5}}, []string{"a", "b"}, 15, 0},
+		{"replace with too big", 30, []put{{"a", 10}, {"b", 10}}, nil, []put{{"a", 40}}, []string{"b"}, 10, 0},
+		{"empty resource", 10, []put{{"a", 0}, {"b", 10}}, nil, nil, []string{"b", "a"}, 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.maxBytes)
+			for _, p := range tt.puts {
+				c.Put(testKey(p.path), testResource(p.path, p.size))
+			}
+			for _, path := range tt.gets {
+				_, ok := c.Get(testKey(path))
+				assert.True(t, ok)
+			}
+			for _, p := range tt.then {
+				c.Put(testKey(p.path), testResource(p.path, p.size))
+			}
+
+			assert.Equal(t, tt.want, cachedPaths(c))
+			stats := c.Stats()
+			assert.Equal(t, tt.bytes, stats.Bytes)
+			assert.Equal(t, len(tt.want), stats.Entries)
+			assert.Equal(t, tt.evictions, stats.Evictions)
+		})
+	}
+}
+
+func TestCacheGet(t *testing.T) {
+	c := New(100)
+	resource := testResource("a", 10)
+	c.Put(testKey("a"), resource)
+
+	tests := []struct {
+		name string
+		key  Key
+		want fyne.Resource
+	}{
+		{"cached", testKey("a"), resource},
+		{"other path", testKey("b"), nil},
+		{"other variant", Key{Path: "a", Variant: Scaled, Size: 256}, nil},
+		{"other size", Key{Path: "a", Variant: Thumbnail, Size: 128}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := c.Get(tt.key)
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.InDelta(t, 0.25, stats.HitRate(), 1e-9)
+}
+
+func TestCacheRemovePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove string
+		want   []string
+		bytes  int64
+	}{
+		{"every variant of the path", "a", []string{"b"}, 10},
+		{"not cached", "c", []string{"b", "a", "a", "a"}, 40},
+		{"empty path", "", []string{"b", "a", "a", "a"}, 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(100)
+			c.Put(Key{Path: "a", Variant: Thumbnail, Size: 256}, testResource("a", 10))
+			c.Put(Key{Path: "a", Variant: Scaled, Size: 256}, testResource("a", 10))
+			c.Put(Key{Path: "a", Variant: Thumbnail, Size: 128}, testResource("a", 10))
+			c.Put(testKey("b"), testResource("b", 10))
+
+			c.RemovePath(tt.remove)
+			assert.Equal(t, tt.want, cachedPaths(c))
+			assert.Equal(t, tt.bytes, c.Stats().Bytes)
+
+			// the freed bytes can be used again without evicting
+			c.Put(testKey("new"), testResource("new", int(100-tt.bytes)))
+			assert.Zero(t, c.Stats().Evictions)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package thumbcache keeps generated thumbnails on disk so they aren't made again
+// on every start. Thumbnails are keyed by the MD5 of the image content, the
+// thumbnail size and their variant, so moved files keep their thumbnails and
+// changed files get new ones
+package thumbcache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Returns the directory the thumbnails are kept in, inside the user cache directory
+// ($XDG_CACHE_HOME or ~/.cache on Linux)
+func Dir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "TagVault", "thumbnails")
+}
+
+// Changes when thumbnails are made differently, older thumbnails are made again
+// and the old files are pruned once they are the least used
+const version = 2 // 2: turned upright by the EXIF orientation
+
+// Returns the path of a thumbnail, they are spread over folders by the first two
+// characters of the hash so no folder gets too big. The variant tells apart
+// thumbnails of the same image made differently, like fitted or edited ones
+func thumbnailPath(hash string, size int, variant string) string {
+	folder := "00"
+	if len(hash) >= 2 {
+		folder = hash[:2]
+	}
+	if variant != "" {
+		return filepath.Join(Dir(), folder, fmt.Sprintf("%s_%d_%s_v%d", hash, size, variant, version))
+	}
+	return filepath.Join(Dir(), folder, fmt.Sprintf("%s_%d_v%d", hash, size, version))
+}
+
+// Returns the encoded thumbnail of an image, false if it isn't cached
+func Load(hash string, size int, variant string) ([]byte, bool) {
+	if hash == "" {
+		return nil, false
+	}
+	path := thumbnailPath(hash, size, variant)
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	// the modification time tells Prune which thumbnails were used last
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Saves the encoded thumbnail of an image
+func Store(hash string, size int, variant string, data []byte) error {
+	if hash == "" {
+		return fmt.Errorf("thumbnail has no content hash")
+	}
+	path := thumbnailPath(hash, size, variant)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// written next to it first so a half written thumbnail is never read
+	temp, err := os.CreateTemp(filepath.Dir(path), ".thumbnail-*")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Returns every thumbnail in the cache
+func cachedFiles() ([]cachedFile, error) {
+	var files []cachedFile
+	err := filepath.WalkDir(Dir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, cachedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// Returns the number of bytes the cached thumbnails take up
+func Size() (int64, error) {
+	files, err := cachedFiles()
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	return total, err
+}
+
+// Removes the thumbnails used longest ago until the cache is at most maxBytes,
+// returns the number of removed thumbnails
+func Prune(maxBytes int64) (int, error) {
+	files, err := cachedFiles()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	if total <= maxBytes {
+		return 0, nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	removed := 0
+	for _, f := range files {
+		if total <= maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		total -= f.size
+		removed++
+	}
+	return removed, nil
+}
+
+// Removes every cached thumbnail
+func Clear() error {
+	return os.RemoveAll(Dir())
+}
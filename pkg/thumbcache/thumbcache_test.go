@@ -0,0 +1,50 @@
+package thumbcache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		kept     []string
+	}{
+		{"under the limit", 40, []string{"aa", "bb", "cc", "dd"}},
+		{"oldest removed first", 25, []string{"aa", "dd"}},
+		// loaded thumbnails count as used, so aa outlives the ones stored after it
+		{"loaded kept", 10, []string{"aa"}},
+		{"everything removed", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			t.Setenv("HOME", t.TempDir())
+			base := time.Now().Add(-time.Hour)
+			for i, hash := range []string{"aa", "bb", "cc", "dd"} {
+				assert.NoError(t, Store(hash, 100, "", make([]byte, 10)))
+				modTime := base.Add(time.Duration(i) * time.Minute)
+				os.Chtimes(thumbnailPath(hash, 100, ""), modTime, modTime)
+			}
+			// cc is older than bb
+			os.Chtimes(thumbnailPath("cc", 100, ""), base.Add(-time.Minute), base.Add(-time.Minute))
+			_, ok := Load("aa", 100, "")
+			assert.True(t, ok)
+
+			removed, err := Prune(tt.maxBytes)
+			assert.NoError(t, err)
+			assert.Equal(t, 4-len(tt.kept), removed)
+			var kept []string
+			for _, hash := range []string{"aa", "bb", "cc", "dd"} {
+				if _, err := os.Stat(thumbnailPath(hash, 100, "")); err == nil {
+					kept = append(kept, hash)
+				}
+			}
+			assert.Equal(t, tt.kept, kept)
+		})
+	}
+}
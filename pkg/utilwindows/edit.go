@@ -0,0 +1,280 @@
+package utilwindows
+
+import (
+	"database/sql"
+	"fmt"
+	"image"
+	"main/pkg/database"
+	"main/pkg/imagecodec"
+	"main/pkg/imageconv"
+	"main/pkg/imagetransform"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Longest side of the copy of the image the edits are previewed on
+const editPreviewSize = 640
+
+// Aspect ratios the crop can be locked to, width / height. Original keeps the
+// aspect of the rotated image
+var cropAspects = []struct {
+	name   string
+	aspect float64
+}{
+	{"No Crop", -1},
+	{"Original", 0},
+	{"1:1", 1},
+	{"4:3", 4.0 / 3},
+	{"3:2", 3.0 / 2},
+	{"16:9", 16.0 / 9},
+	{"3:4", 3.0 / 4},
+	{"2:3", 2.0 / 3},
+	{"9:16", 9.0 / 16},
+}
+
+// Shows the window with the basic edits of an image. The edits are saved as a recipe
+// applied when the image is shown, onSaved is called after they are saved and onBaked
+// after they were written to the new file at the path
+func ShowEditWindow(a fyne.App, w fyne.Window, db *sql.DB, path string, onSaved func(), onBaked func(newPath string)) {
+	img, format, err := imagecodec.DecodeFile(path)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	imageId := database.GetImageId(db, path)
+	edit, err := database.GetFileEdit(db, imageId)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	editWindow := a.NewWindow("Edit " + filepath.Base(path))
+	size := img.Bounds().Size()
+	small := img
+	if longest := max(size.X, size.Y); longest > editPreviewSize {
+		small = imagetransform.Resize(img, max(1, size.X*editPreviewSize/longest), max(1, size.Y*editPreviewSize/longest))
+	}
+
+	preview := canvas.NewImageFromImage(small)
+	preview.FillMode = canvas.ImageFillContain
+	preview.SetMinSize(fyne.NewSize(480, 360))
+	sizeLabel := widget.NewLabel("")
+
+	// the crop is kept as an aspect, a zoom and a center so it still fits when the image is rotated
+	cropAspect, zoom, centerX, centerY := -1.0, 1.0, 0.5, 0.5
+	rotatedSize := func() image.Point {
+		if edit.Rotation%180 != 0 {
+			return image.Pt(size.Y, size.X)
+		}
+		return size
+	}
+	if edit.Crop.W > 0 && edit.Crop.H > 0 {
+		rotated := rotatedSize()
+		cropAspect = edit.Crop.W * float64(rotated.X) / (edit.Crop.H * float64(rotated.Y))
+		full := imagetransform.AspectCrop(rotated, cropAspect, 1, 0.5, 0.5)
+		zoom = edit.Crop.W / full.W
+		centerX, centerY = edit.Crop.X+edit.Crop.W/2, edit.Crop.Y+edit.Crop.H/2
+	}
+
+	update := func() {
+		if cropAspect < 0 {
+			edit.Crop = imagetransform.Rect{}
+		} else {
+			edit.Crop = imagetransform.AspectCrop(rotatedSize(), cropAspect, zoom, centerX, centerY)
+		}
+		// the resize is left out of the preview, it is drawn fitted anyway
+		previewEdit := edit
+		previewEdit.Width, previewEdit.Height = 0, 0
+		preview.Image = imagetransform.Apply(small, previewEdit)
+		preview.Refresh()
+
+		result := rotatedSize()
+		if edit.Crop.W > 0 && edit.Crop.H > 0 {
+			result = edit.Crop.Bounds(image.Rectangle{Max: result}).Size()
+		}
+		switch {
+		case edit.Width > 0 && edit.Height > 0:
+			result = image.Pt(edit.Width, edit.Height)
+		case edit.Width > 0:
+			result = image.Pt(edit.Width, max(1, int(math.Round(float64(edit.Width)*float64(result.Y)/float64(result.X)))))
+		case edit.Height > 0:
+			result = image.Pt(max(1, int(math.Round(float64(edit.Height)*float64(result.X)/float64(result.Y)))), edit.Height)
+		}
+		sizeLabel.SetText(fmt.Sprintf("%d x %d → %d x %d", size.X, size.Y, result.X, result.Y))
+	}
+
+	rotateLeftButton := widget.NewButtonWithIcon("", theme.MediaReplayIcon(), func() {
+		edit.Rotation = (edit.Rotation + 270) % 360
+		update()
+	})
+	rotateRightButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() {
+		edit.Rotation = (edit.Rotation + 90) % 360
+		update()
+	})
+	flipHButton := widget.NewButton("Flip ↔", func() {
+		edit.FlipH = !edit.FlipH
+		update()
+	})
+	flipVButton := widget.NewButton("Flip ↕", func() {
+		edit.FlipV = !edit.FlipV
+		update()
+	})
+
+	zoomSlider := widget.NewSlider(0.1, 1)
+	zoomSlider.Step = 0.01
+	xSlider, ySlider := widget.NewSlider(0, 1), widget.NewSlider(0, 1)
+	xSlider.Step, ySlider.Step = 0.01, 0.01
+	setSliders := func() {
+		zoomSlider.SetValue(zoom)
+		xSlider.SetValue(centerX)
+		ySlider.SetValue(centerY)
+		for _, slider := range []*widget.Slider{zoomSlider, xSlider, ySlider} {
+			if cropAspect < 0 {
+				slider.Disable()
+			} else {
+				slider.Enable()
+			}
+		}
+	}
+	zoomSlider.OnChanged = func(value float64) { zoom = value; update() }
+	xSlider.OnChanged = func(value float64) { centerX = value; update() }
+	ySlider.OnChanged = func(value float64) { centerY = value; update() }
+
+	aspectNames := make([]string, len(cropAspects))
+	for i, c := range cropAspects {
+		aspectNames[i] = c.name
+	}
+	aspectSelect := widget.NewSelect(aspectNames, func(selected string) {
+		for _, c := range cropAspects {
+			if c.name == selected {
+				cropAspect = c.aspect
+			}
+		}
+		setSliders()
+		update()
+	})
+	// saved crops that don't match a preset keep their aspect until another one is picked
+	aspectSelect.PlaceHolder = "Custom"
+	selectAspect := func() {
+		for _, c := range cropAspects {
+			if c.aspect == cropAspect || (c.aspect > 0 && math.Abs(c.aspect-cropAspect) < 0.01) {
+				aspectSelect.SetSelected(c.name)
+				return
+			}
+		}
+		aspectSelect.ClearSelected()
+	}
+
+	widthEntry, heightEntry := widget.NewEntry(), widget.NewEntry()
+	widthEntry.SetPlaceHolder("Width")
+	heightEntry.SetPlaceHolder("Height")
+	setSizeEntries := func() {
+		widthEntry.SetText("")
+		heightEntry.SetText("")
+		if edit.Width > 0 {
+			widthEntry.SetText(strconv.Itoa(edit.Width))
+		}
+		if edit.Height > 0 {
+			heightEntry.SetText(strconv.Itoa(edit.Height))
+		}
+	}
+	// empty or invalid sizes follow the aspect of the other side
+	widthEntry.OnChanged = func(text string) {
+		edit.Width, _ = strconv.Atoi(strings.TrimSpace(text))
+		edit.Width = max(edit.Width, 0)
+		update()
+	}
+	heightEntry.OnChanged = func(text string) {
+		edit.Height, _ = strconv.Atoi(strings.TrimSpace(text))
+		edit.Height = max(edit.Height, 0)
+		update()
+	}
+
+	// the edited image is written in the format of the original when it can be,
+	// formats like HEIC can only be read
+	var bakeTypes []string
+	for _, file := range imageconv.ImageTypes {
+		if f, ok := imagecodec.ByName(file); ok && f.CanEncode() && !f.Video {
+			bakeTypes = append(bakeTypes, file)
+		}
+	}
+	bakeSelect := widget.NewSelect(bakeTypes, nil)
+	bakeSelect.SetSelected("JPG")
+	if format != nil && format.CanEncode() {
+		bakeSelect.SetSelected(format.Name)
+	}
+
+	resetButton := widget.NewButtonWithIcon("Reset", theme.ContentUndoIcon(), func() {
+		edit = imagetransform.Edit{}
+		cropAspect, zoom, centerX, centerY = -1, 1, 0.5, 0.5
+		selectAspect()
+		setSliders()
+		setSizeEntries()
+		update()
+	})
+	saveButton := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		if err := database.SetFileEdit(db, imageId, edit); err != nil {
+			dialog.ShowError(err, editWindow)
+			return
+		}
+		onSaved()
+		editWindow.Close()
+	})
+	saveButton.Importance = widget.HighImportance
+	bakeButton := widget.NewButtonWithIcon("Bake to New File", theme.FileImageIcon(), func() {
+		newPath, err := imageconv.BakeImage(path, edit, bakeSelect.Selected)
+		if err != nil {
+			dialog.ShowError(err, editWindow)
+			return
+		}
+		// the new file is added to the library with the tags and notes of the original
+		if err := database.AddFile(db, newPath); err != nil {
+			dialog.ShowError(err, editWindow)
+			return
+		}
+		if newId := database.GetImageId(db, newPath); newId != 0 {
+			if err := database.CopyManualTags(db, imageId, newId); err != nil {
+				dialog.ShowError(err, editWindow)
+			}
+			if notes, err := database.GetFileNotes(db, imageId); err == nil && !notes.IsEmpty() {
+				database.SetFileNotes(db, newId, notes)
+			}
+		}
+		onBaked(newPath)
+		dialog.ShowInformation("Success", fmt.Sprintf("Saved the edited image as %s", filepath.Base(newPath)), editWindow)
+	})
+
+	controls := container.NewVBox(
+		widget.NewLabel("Rotate and flip:"),
+		container.NewGridWithColumns(4, rotateLeftButton, rotateRightButton, flipHButton, flipVButton),
+		widget.NewLabel("Crop:"),
+		aspectSelect,
+		widget.NewLabel("Zoom:"), zoomSlider,
+		widget.NewLabel("Horizontal position:"), xSlider,
+		widget.NewLabel("Vertical position:"), ySlider,
+		widget.NewLabel("Resize:"),
+		container.NewGridWithColumns(2, widthEntry, heightEntry),
+		sizeLabel,
+		container.NewGridWithColumns(2, resetButton, saveButton),
+		widget.NewSeparator(),
+		container.NewBorder(nil, nil, nil, bakeSelect, bakeButton),
+	)
+
+	selectAspect()
+	setSliders()
+	setSizeEntries()
+	update()
+
+	editWindow.SetContent(container.NewBorder(nil, nil, nil, container.NewVScroll(controls), preview))
+	editWindow.Resize(fyne.NewSize(900, 600))
+	editWindow.Show()
+}
@@ -0,0 +1,550 @@
+package utilwindows
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"main/pkg/database"
+	"main/pkg/options"
+	"math/rand/v2"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How long changing from one slide to the next takes
+const slideTransitionTime = 600 * time.Millisecond
+
+// Longest line of the notes shown on a slide and the most lines of them
+const (
+	slideNotesWidth = 120
+	slideNotesLines = 6
+)
+
+// Shows the window a slideshow is started from. It goes through the images of the
+// current results, paths, or through the results of a saved search. query is the
+// text of the search bar, it can be saved as a new search. decode returns the image
+// of a file as it is shown
+func ShowSlideshowWindow(a fyne.App, db *sql.DB, opts *options.Options, query string, paths []string, decode func(path string) (image.Image, error)) {
+	setupWindow := a.NewWindow("Slideshow")
+
+	currentResults := fmt.Sprintf("Current results (%d)", len(paths))
+	var searches []database.SavedSearch
+	sourceSelect := widget.NewSelect(nil, nil)
+	loadSearches := func(selected string) {
+		var err error
+		searches, err = database.GetSavedSearches(db)
+		if err != nil {
+			dialog.ShowError(err, setupWindow)
+		}
+		names := []string{currentResults}
+		for _, s := range searches {
+			names = append(names, s.Name)
+		}
+		sourceSelect.Options = names
+		sourceSelect.SetSelected(selected)
+	}
+	loadSearches(currentResults)
+
+	// the query in the search bar can be saved to be shown again later
+	searchName := widget.NewEntry()
+	searchName.SetPlaceHolder("Name")
+	saveSearchButton := widget.NewButton("Save", func() {
+		name := strings.TrimSpace(searchName.Text)
+		if name == "" {
+			dialog.ShowError(errors.New("the saved search needs a name"), setupWindow)
+			return
+		}
+		if err := database.SaveSearch(db, name, query); err != nil {
+			dialog.ShowError(err, setupWindow)
+			return
+		}
+		searchName.SetText("")
+		loadSearches(name)
+	})
+	saveSearchRow := container.NewBorder(nil, nil, nil, saveSearchButton, searchName)
+	if strings.TrimSpace(query) == "" {
+		searchName.SetPlaceHolder("Search for images first")
+		searchName.Disable()
+		saveSearchButton.Disable()
+	}
+	deleteSearchButton := widget.NewButton("Delete Saved Search", nil)
+	deleteSearchButton.Disable()
+	selectedSearch := func() (database.SavedSearch, bool) {
+		for _, s := range searches {
+			if s.Name == sourceSelect.Selected {
+				return s, true
+			}
+		}
+		return database.SavedSearch{}, false
+	}
+	deleteSearchButton.OnTapped = func() {
+		s, ok := selectedSearch()
+		if !ok {
+			return
+		}
+		dialog.ShowConfirm("Delete Saved Search", fmt.Sprintf("Delete the saved search %q?", s.Name), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := database.DeleteSavedSearch(db, s.Id); err != nil {
+				dialog.ShowError(err, setupWindow)
+				return
+			}
+			loadSearches(currentResults)
+		}, setupWindow)
+	}
+	sourceSelect.OnChanged = func(string) {
+		if _, ok := selectedSearch(); ok {
+			deleteSearchButton.Enable()
+		} else {
+			deleteSearchButton.Disable()
+		}
+	}
+
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText(strconv.Itoa(max(opts.SlideshowInterval, 1)))
+	shuffleCheck := widget.NewCheck("Shuffle", nil)
+	shuffleCheck.SetChecked(opts.SlideshowShuffle)
+	loopCheck := widget.NewCheck("Loop", nil)
+	loopCheck.SetChecked(opts.SlideshowLoop)
+	infoCheck := widget.NewCheck("Show tags and notes", nil)
+	infoCheck.SetChecked(opts.SlideshowInfo)
+	transitionSelect := widget.NewSelect(options.SlideshowTransitions, nil)
+	transitionSelect.SetSelected(opts.SlideshowTransition)
+	if transitionSelect.Selected == "" {
+		transitionSelect.SetSelected(options.FadeTransition)
+	}
+
+	startButton := widget.NewButton("Start", func() {
+		interval, err := strconv.Atoi(strings.TrimSpace(intervalEntry.Text))
+		if err != nil || interval < 1 || interval > 3600 {
+			dialog.ShowError(errors.New("the interval must be between 1 and 3600 seconds"), setupWindow)
+			return
+		}
+		slides := paths
+		if s, ok := selectedSearch(); ok {
+			slides, err = database.SearchImages(db, s.Query)
+			if err != nil {
+				dialog.ShowError(err, setupWindow)
+				return
+			}
+		}
+		if len(slides) == 0 {
+			dialog.ShowInformation("Slideshow", "There are no images to show", setupWindow)
+			return
+		}
+
+		opts.SlideshowInterval = interval
+		opts.SlideshowShuffle = shuffleCheck.Checked
+		opts.SlideshowLoop = loopCheck.Checked
+		opts.SlideshowTransition = transitionSelect.Selected
+		opts.SlideshowInfo = infoCheck.Checked
+		if err := options.SaveOptionsToDB(db, opts); err != nil {
+			dialog.ShowError(err, setupWindow)
+			return
+		}
+		setupWindow.Close()
+		startSlideshow(a, db, slides, *opts, decode)
+	})
+	startButton.Importance = widget.HighImportance
+
+	form := widget.NewForm(
+		widget.NewFormItem("Images", sourceSelect),
+		widget.NewFormItem("Save search", saveSearchRow),
+		widget.NewFormItem("Seconds per slide", intervalEntry),
+		widget.NewFormItem("Transition", transitionSelect),
+		widget.NewFormItem("", container.NewHBox(shuffleCheck, loopCheck)),
+		widget.NewFormItem("", infoCheck),
+	)
+	setupWindow.SetContent(container.NewVBox(form, container.NewHBox(deleteSearchButton, layout.NewSpacer(), startButton)))
+	setupWindow.Resize(fyne.NewSize(450, 300))
+	setupWindow.CenterOnScreen()
+	setupWindow.Show()
+}
+
+// Full screen window showing one image after another
+type slideshow struct {
+	window     fyne.Window
+	db         *sql.DB
+	paths      []string
+	decode     func(path string) (image.Image, error)
+	interval   time.Duration
+	shuffle    bool
+	loop       bool
+	transition string
+
+	bottom *canvas.Image // the slide shown
+	top    *canvas.Image // the next slide while it comes in
+	slide  *slideLayout
+	layer  *fyne.Container
+	info   *fyne.Container
+	name   *canvas.Text
+	tags   *canvas.Text
+	notes  *fyne.Container
+	status *canvas.Text
+
+	mu       sync.Mutex
+	order    []int // indexes of the paths in the order they are shown
+	position int   // of the slide shown in order
+	paused   bool
+	skip     chan int // steps through the slides by the number sent
+	stop     chan struct{}
+	next     preloadedSlide
+}
+
+// Image of the slide after the one shown, decoded while the slide is shown
+type preloadedSlide struct {
+	path string
+	img  image.Image
+	err  error
+	done chan struct{}
+}
+
+// Lays the slides over each other, the top one is moved by offset times the width
+type slideLayout struct {
+	offset float32
+}
+
+func (l *slideLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	for i, o := range objects {
+		o.Resize(size)
+		if i == len(objects)-1 {
+			o.Move(fyne.NewPos(l.offset*size.Width, 0))
+		} else {
+			o.Move(fyne.NewPos(0, 0))
+		}
+	}
+}
+
+func (l *slideLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+// Opens the slideshow full screen. The arrow keys step through the slides, space
+// pauses, I shows or hides the tags and notes, F11 leaves full screen and Escape
+// closes it
+func startSlideshow(a fyne.App, db *sql.DB, paths []string, opts options.Options, decode func(path string) (image.Image, error)) {
+	s := &slideshow{
+		window:     a.NewWindow("Slideshow"),
+		db:         db,
+		paths:      paths,
+		decode:     decode,
+		interval:   time.Duration(max(opts.SlideshowInterval, 1)) * time.Second,
+		shuffle:    opts.SlideshowShuffle,
+		loop:       opts.SlideshowLoop,
+		transition: opts.SlideshowTransition,
+		skip:       make(chan int, 1),
+		stop:       make(chan struct{}),
+	}
+	s.order = s.newOrder()
+
+	s.bottom = canvas.NewImageFromImage(nil)
+	s.bottom.FillMode = canvas.ImageFillContain
+	s.top = canvas.NewImageFromImage(nil)
+	s.top.FillMode = canvas.ImageFillContain
+	s.top.Hide()
+	s.slide = &slideLayout{}
+	s.layer = container.New(s.slide, s.bottom, s.top)
+
+	white := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	s.name = canvas.NewText("", white)
+	s.name.TextStyle.Bold = true
+	s.name.TextSize = 18
+	s.tags = canvas.NewText("", white)
+	s.notes = container.NewVBox()
+	infoBackground := canvas.NewRectangle(color.NRGBA{A: 0xaa})
+	infoBackground.CornerRadius = 6
+	s.info = container.NewVBox(layout.NewSpacer(), container.NewHBox(
+		container.NewStack(infoBackground, container.NewPadded(container.NewVBox(s.name, s.tags, s.notes))),
+		layout.NewSpacer(),
+	))
+	if !opts.SlideshowInfo {
+		s.info.Hide()
+	}
+	s.status = canvas.NewText("", white)
+	s.status.Alignment = fyne.TextAlignTrailing
+
+	background := canvas.NewRectangle(color.Black)
+	statusBar := container.NewVBox(container.NewPadded(s.status), layout.NewSpacer())
+	s.window.SetContent(container.NewStack(background, s.layer, container.NewPadded(s.info), statusBar))
+	s.window.SetPadded(false)
+
+	s.window.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		switch ev.Name {
+		case fyne.KeyLeft, fyne.KeyPageUp:
+			s.step(-1)
+		case fyne.KeyRight, fyne.KeyPageDown:
+			s.step(1)
+		case fyne.KeySpace:
+			s.togglePause()
+		case fyne.KeyI:
+			if s.info.Visible() {
+				s.info.Hide()
+			} else {
+				s.info.Show()
+			}
+		case fyne.KeyF11, fyne.KeyF:
+			s.window.SetFullScreen(!s.window.FullScreen())
+		case fyne.KeyEscape:
+			s.window.Close()
+		}
+	})
+	s.window.SetOnClosed(func() {
+		close(s.stop)
+	})
+
+	s.window.Resize(fyne.NewSize(1000, 700))
+	s.window.SetFullScreen(true)
+	s.window.Show()
+	go s.run()
+}
+
+// Returns the order the paths are shown in
+func (s *slideshow) newOrder() []int {
+	order := make([]int, len(s.paths))
+	for i := range order {
+		order[i] = i
+	}
+	if s.shuffle {
+		rand.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+	}
+	return order
+}
+
+// Shows the slides until the window is closed, the next slide comes after the
+// interval unless the slideshow is paused or stepped through by hand
+func (s *slideshow) run() {
+	s.show(false)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case by := <-s.skip:
+			s.advance(by)
+		case <-time.After(s.interval):
+			s.mu.Lock()
+			paused := s.paused
+			s.mu.Unlock()
+			if !paused {
+				s.advance(1)
+			}
+		}
+	}
+}
+
+// Steps by a number of slides, e.g. -1 for the previous one
+func (s *slideshow) step(by int) {
+	select {
+	case s.skip <- by:
+	default:
+	}
+}
+
+func (s *slideshow) togglePause() {
+	s.mu.Lock()
+	s.paused = !s.paused
+	s.mu.Unlock()
+	s.updateStatus()
+}
+
+// Moves on by a number of slides, past the last slide the slideshow starts again
+// when it loops and stops on the last one when it doesn't
+func (s *slideshow) advance(by int) {
+	s.mu.Lock()
+	position := s.position + by
+	switch {
+	case position >= len(s.order) && !s.loop:
+		s.paused = true
+		s.mu.Unlock()
+		s.updateStatus()
+		return
+	case position >= len(s.order):
+		// every loop of a shuffled slideshow has an order of its own
+		if s.shuffle {
+			s.order = s.newOrder()
+		}
+		position = 0
+	case position < 0 && !s.loop:
+		position = 0
+	case position < 0:
+		position = len(s.order) - 1
+	}
+	if position == s.position {
+		s.mu.Unlock()
+		return
+	}
+	s.position = position
+	s.mu.Unlock()
+	s.show(by > 0)
+}
+
+// Shows the slide at the position, forward slides come in with the transition
+func (s *slideshow) show(forward bool) {
+	s.mu.Lock()
+	path := s.paths[s.order[s.position]]
+	next := s.next
+	s.mu.Unlock()
+
+	var img image.Image
+	var err error
+	if next.done != nil && next.path == path {
+		<-next.done
+		img, err = next.img, next.err
+	} else {
+		img, err = s.decode(path)
+	}
+	if err != nil {
+		// slides that can't be decoded are shown as their name only
+		img = nil
+	}
+	s.showImage(img, forward)
+	s.showInfo(path)
+	s.updateStatus()
+	s.preload()
+}
+
+// Decodes the slide after the one shown in the background
+func (s *slideshow) preload() {
+	s.mu.Lock()
+	position := s.position + 1
+	if position >= len(s.order) {
+		if !s.loop || s.shuffle {
+			s.mu.Unlock()
+			return
+		}
+		position = 0
+	}
+	next := preloadedSlide{path: s.paths[s.order[position]], done: make(chan struct{})}
+	s.next = next
+	s.mu.Unlock()
+
+	go func() {
+		img, err := s.decode(next.path)
+		s.mu.Lock()
+		if s.next.done == next.done {
+			s.next.img, s.next.err = img, err
+		}
+		s.mu.Unlock()
+		close(next.done)
+	}()
+}
+
+// Changes to the image with the transition of the slideshow
+func (s *slideshow) showImage(img image.Image, forward bool) {
+	if s.transition == options.NoTransition || !forward || s.bottom.Image == nil {
+		s.bottom.Image = img
+		s.bottom.Refresh()
+		return
+	}
+
+	s.top.Image = img
+	s.top.Translucency = 0
+	s.slide.offset = 0
+	s.top.Show()
+	done := make(chan struct{})
+	animation := fyne.NewAnimation(slideTransitionTime, func(progress float32) {
+		switch s.transition {
+		case options.SlideTransition:
+			s.slide.offset = 1 - progress
+			s.layer.Refresh()
+		default:
+			s.top.Translucency = float64(1 - progress)
+			s.top.Refresh()
+		}
+		if progress == 1 {
+			close(done)
+		}
+	})
+	animation.Curve = fyne.AnimationEaseInOut
+	if s.transition == options.SlideTransition {
+		s.slide.offset = 1
+		s.layer.Refresh()
+	} else {
+		s.top.Translucency = 1
+	}
+	animation.Start()
+
+	select {
+	case <-done:
+	case <-s.stop:
+		animation.Stop()
+		return
+	case <-time.After(2 * slideTransitionTime):
+		animation.Stop()
+	}
+	s.bottom.Image = img
+	s.bottom.Refresh()
+	s.top.Hide()
+	s.top.Image = nil
+	s.slide.offset = 0
+	s.layer.Refresh()
+}
+
+// Shows the name, title, tags and notes of the file of the slide
+func (s *slideshow) showInfo(path string) {
+	fileId := database.GetImageId(s.db, path)
+	notes, _ := database.GetFileNotes(s.db, fileId)
+	tags, _ := database.GetFileTagNames(s.db, fileId)
+
+	s.name.Text = filepath.Base(path)
+	if notes.Title != "" {
+		s.name.Text = notes.Title
+	}
+	s.name.Refresh()
+	s.tags.Text = strings.Join(tags, ", ")
+	s.tags.Refresh()
+
+	var lines []string
+	for _, text := range []string{notes.Description, notes.Notes} {
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, truncateLine(line, slideNotesWidth))
+			}
+		}
+	}
+	if len(lines) > slideNotesLines {
+		lines = append(lines[:slideNotesLines-1], "…")
+	}
+	s.notes.RemoveAll()
+	for _, line := range lines {
+		text := canvas.NewText(line, s.name.Color)
+		s.notes.Add(text)
+	}
+	s.notes.Refresh()
+	s.info.Refresh()
+}
+
+// Shows the number of the slide and if the slideshow is paused
+func (s *slideshow) updateStatus() {
+	s.mu.Lock()
+	text := fmt.Sprintf("%d / %d", s.position+1, len(s.order))
+	if s.paused {
+		text = "Paused  " + text
+	}
+	s.mu.Unlock()
+	s.status.Text = text
+	s.status.Refresh()
+}
+
+// Cuts a line to the number of characters, ending it with an ellipsis
+func truncateLine(line string, length int) string {
+	runes := []rune(line)
+	if len(runes) <= length {
+		return line
+	}
+	return string(runes[:length-1]) + "…"
+}
@@ -0,0 +1,27 @@
+package utilwindows
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		length int
+		want   string
+	}{
+		{"short", "Beach", 10, "Beach"},
+		{"exact", "Beach day", 9, "Beach day"},
+		{"long", "Beach day with friends", 10, "Beach day…"},
+		// counted in characters, not bytes
+		{"multibyte", "Été à la plage", 6, "Été à…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, truncateLine(tt.line, tt.length))
+		})
+	}
+}
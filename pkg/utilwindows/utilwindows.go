@@ -2,6 +2,7 @@ package utilwindows
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"image/color"
 	"io"
@@ -10,12 +11,17 @@ import (
 	"main/pkg/archives"
 	"main/pkg/colorutils"
 	"main/pkg/database"
+	"main/pkg/fileutils"
+	"main/pkg/imagecodec"
 	"main/pkg/imageconv"
 	"main/pkg/options"
 	"main/pkg/tagwindow"
+	"main/pkg/thumbcache"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -158,6 +164,70 @@ func ShowColorPickerWindow(propertyName string, colorPreview *canvas.Rectangle,
 	updateColor() // Initial color update
 }
 
+// Shows the color sliders to pick a color to search images by, onPicked gets it as a hex string
+func ShowColorSearchWindow(a fyne.App, opts *options.Options, onPicked func(hex string)) {
+	colorSearchWindow := a.NewWindow("Search by Color")
+
+	colorPreviewRect := canvas.NewRectangle(color.NRGBA{0, 0, 130, 255})
+	colorPreviewRect.SetMinSize(fyne.NewSize(64, 128))
+	colorPreviewRect.CornerRadius = 5
+
+	var content *fyne.Container
+	var updateColor func()
+	var getHexColor func() string
+
+	if opts.UseRGB {
+		r, g, b := widget.NewSlider(0, 255), widget.NewSlider(0, 255), widget.NewSlider(0, 255)
+		getHexColor = func() string {
+			return fmt.Sprintf("#%02X%02X%02X", int(r.Value), int(g.Value), int(b.Value))
+		}
+		for _, slider := range []*widget.Slider{r, g, b} {
+			slider.OnChanged = func(_ float64) { updateColor() }
+		}
+		content = container.NewVBox(
+			widget.NewLabel("Color preview:"),
+			colorPreviewRect,
+			widget.NewLabel("Red:"), r,
+			widget.NewLabel("Green:"), g,
+			widget.NewLabel("Blue:"), b,
+		)
+	} else {
+		h, s, v := widget.NewSlider(0, 359), widget.NewSlider(0, 1), widget.NewSlider(0, 1)
+		h.Value, s.Value, v.Value = 200, 0.5, 1
+		h.Step, s.Step, v.Step = 1, 0.01, 0.01
+		getHexColor = func() string {
+			return colorutils.HSVToHex(h.Value, s.Value, v.Value)
+		}
+		for _, slider := range []*widget.Slider{h, s, v} {
+			slider.OnChanged = func(_ float64) { updateColor() }
+		}
+		content = container.NewVBox(
+			widget.NewLabel("Color preview:"),
+			colorPreviewRect,
+			widget.NewLabel("Hue:"), h,
+			widget.NewLabel("Saturation:"), s,
+			widget.NewLabel("Value:"), v,
+		)
+	}
+	updateColor = func() {
+		if newColor, err := colorutils.HexToColor(getHexColor()); err == nil {
+			colorPreviewRect.FillColor = newColor
+			colorPreviewRect.Refresh()
+		}
+	}
+
+	searchButton := widget.NewButtonWithIcon("Search", theme.SearchIcon(), func() {
+		onPicked(getHexColor())
+		colorSearchWindow.Close()
+	})
+	content.Add(searchButton)
+
+	colorSearchWindow.SetContent(content)
+	colorSearchWindow.Resize(fyne.NewSize(300, 400))
+	colorSearchWindow.Show()
+	updateColor() // Initial color update
+}
+
 func ShowAllTagWindow(a fyne.App, parent fyne.Window, db *sql.DB, opts *options.Options) {
 	tagEditWindow := a.NewWindow("Show Tags")
 
@@ -191,8 +261,9 @@ func ShowAllTagWindow(a fyne.App, parent fyne.Window, db *sql.DB, opts *options.
 	tagEditWindow.Show()
 }
 
-// Add a settings window
-func ShowSettingsWindow(a fyne.App, parent fyne.Window, db *sql.DB, opts *options.Options) {
+// Add a settings window, reloadImages loads the grid again after files were renamed
+// or the thumbnails are shown differently
+func ShowSettingsWindow(a fyne.App, parent fyne.Window, db *sql.DB, opts *options.Options, reloadImages func()) {
 	settingsWindow := a.NewWindow("Settings")
 
 	// Create a form for database path
@@ -260,9 +331,182 @@ func ShowSettingsWindow(a fyne.App, parent fyne.Window, db *sql.DB, opts *option
 		timeZone = widget.NewLabel("Timezone in UTC: UTC" + strconv.Itoa(opts.Timezone))
 	}
 
+	// Comma separated EXIF fields shown in the sidebar
+	exifFieldsEntry := widget.NewEntry()
+	exifFieldsEntry.SetPlaceHolder("DateTime, Camera, Lens, Exposure, Dimensions")
+	exifFieldsEntry.SetText(strings.Join(opts.ExifFields, ", "))
+	exifFieldsEntry.OnChanged = func(s string) {
+		opts.ExifFields = opts.ExifFields[:0]
+		for _, field := range strings.Split(s, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				opts.ExifFields = append(opts.ExifFields, field)
+			}
+		}
+	}
+
+	// Media types added to the library, the home directory is scanned for them on startup
+	mediaTypesCheck := widget.NewCheckGroup(fileutils.MediaTypes, func(selected []string) {
+		opts.MediaTypes = selected
+	})
+	mediaTypesCheck.Horizontal = true
+	mediaTypesCheck.Selected = slices.Clone(opts.MediaTypes)
+
+	// Layout of the thumbnail grid, square crops or whole images in columns or justified rows
+	gridLayoutNames := map[string]string{
+		options.SquareLayout:    "Square thumbnails",
+		options.FitLayout:       "Whole images",
+		options.JustifiedLayout: "Justified rows",
+	}
+	gridLayoutOptions := make([]string, 0, len(options.GridLayouts))
+	for _, layout := range options.GridLayouts {
+		gridLayoutOptions = append(gridLayoutOptions, gridLayoutNames[layout])
+	}
+	savedGridLayout := opts.GridLayout
+	gridLayoutSelect := widget.NewSelect(gridLayoutOptions, func(selected string) {
+		for layout, name := range gridLayoutNames {
+			if name == selected {
+				opts.GridLayout = layout
+			}
+		}
+	})
+	if name, ok := gridLayoutNames[opts.GridLayout]; ok {
+		gridLayoutSelect.SetSelected(name)
+	} else {
+		gridLayoutSelect.SetSelected(gridLayoutNames[options.SquareLayout])
+	}
+
+	// When animated thumbnails play and how fast, 0 frames per second keeps the timing of the file
+	playbackNames := map[string]string{
+		options.PlayOnHover: "Play on hover",
+		options.PlayAlways:  "Always play",
+		options.PlayNever:   "Play when pressed",
+	}
+	playbackOptions := make([]string, 0, len(options.AnimationPlaybacks))
+	for _, playback := range options.AnimationPlaybacks {
+		playbackOptions = append(playbackOptions, playbackNames[playback])
+	}
+	playbackSelect := widget.NewSelect(playbackOptions, func(selected string) {
+		for playback, name := range playbackNames {
+			if name == selected {
+				opts.AnimationPlayback = playback
+			}
+		}
+	})
+	if name, ok := playbackNames[opts.AnimationPlayback]; ok {
+		playbackSelect.SetSelected(name)
+	} else {
+		playbackSelect.SetSelected(playbackNames[options.PlayOnHover])
+	}
+	frameRateEntry := widget.NewEntry()
+	frameRateEntry.SetPlaceHolder("0 keeps the timing of the file")
+	frameRateEntry.SetText(strconv.Itoa(opts.AnimationFrameRate))
+	frameRateEntry.Validator = func(s string) error {
+		if rate, err := strconv.Atoi(s); err != nil || rate < 0 || rate > 60 {
+			return fmt.Errorf("the frame rate has to be a number from 0 to 60")
+		}
+		return nil
+	}
+	frameRateEntry.OnChanged = func(s string) {
+		if rate, err := strconv.Atoi(s); err == nil && rate >= 0 && rate <= 60 {
+			opts.AnimationFrameRate = rate
+		}
+	}
+	savedPlayback, savedFrameRate := opts.AnimationPlayback, opts.AnimationFrameRate
+
+	// Reads the metadata of every file again and replaces their auto tags
+	var retagButton *widget.Button
+	retagButton = widget.NewButton("Re-run Auto Tagging", func() {
+		retagButton.Disable()
+		go func() {
+			defer retagButton.Enable()
+			tagged, err := database.RetagLibrary(db)
+			if err != nil {
+				dialog.ShowError(err, settingsWindow)
+				return
+			}
+			dialog.ShowInformation("Auto Tagging", fmt.Sprintf("Tagged %d files", tagged), settingsWindow)
+		}()
+	})
+
+	// Renames the images whose extension doesn't match their content, e.g. PNGs saved as .jpg
+	var fixExtensionsButton *widget.Button
+	fixExtensionsButton = widget.NewButton("Fix File Extensions", func() {
+		fixExtensionsButton.Disable()
+		go func() {
+			defer fixExtensionsButton.Enable()
+			mismatches, err := database.GetExtensionMismatches(db)
+			if err != nil {
+				dialog.ShowError(err, settingsWindow)
+				return
+			}
+			if len(mismatches) == 0 {
+				dialog.ShowInformation("File Extensions", "Every image has the right extension", settingsWindow)
+				return
+			}
+
+			renames := make([]string, 0, len(mismatches))
+			for _, mismatch := range mismatches {
+				renames = append(renames, filepath.Base(mismatch.Path)+" → "+filepath.Base(mismatch.FixedPath()))
+			}
+			renameList := widget.NewLabel(strings.Join(renames, "\n"))
+			renameScroll := container.NewVScroll(renameList)
+			renameScroll.SetMinSize(fyne.NewSize(350, 200))
+			message := widget.NewLabel(fmt.Sprintf("Rename %d images to the extension of their format?", len(mismatches)))
+			dialog.ShowCustomConfirm("File Extensions", "Rename", "Cancel", container.NewBorder(message, nil, nil, nil, renameScroll), func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				fixed, err := database.FixExtensions(db, mismatches)
+				if fixed > 0 {
+					reloadImages()
+				}
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("renamed %d of %d images: %w", fixed, len(mismatches), err), settingsWindow)
+					return
+				}
+				dialog.ShowInformation("File Extensions", fmt.Sprintf("Renamed %d images", fixed), settingsWindow)
+			}, settingsWindow)
+		}()
+	})
+
+	// Most MB the thumbnails cached on disk can take up, the least used go first
+	cacheSizeEntry := widget.NewEntry()
+	cacheSizeEntry.SetText(strconv.Itoa(opts.ThumbnailCacheSize))
+	cacheSizeEntry.Validator = func(s string) error {
+		if size, err := strconv.Atoi(s); err != nil || size < 0 {
+			return fmt.Errorf("the cache size has to be a number of MB")
+		}
+		return nil
+	}
+	cacheSizeEntry.OnChanged = func(s string) {
+		if size, err := strconv.Atoi(s); err == nil && size >= 0 {
+			opts.ThumbnailCacheSize = size
+		}
+	}
+	cacheUsage := widget.NewLabel("")
+	updateCacheUsage := func() {
+		used, _ := thumbcache.Size()
+		cacheUsage.SetText("Thumbnail cache: " + fileutils.FormatSize(used) + " in " + thumbcache.Dir())
+	}
+	updateCacheUsage()
+	clearCacheButton := widget.NewButton("Clear Thumbnail Cache", func() {
+		if err := thumbcache.Clear(); err != nil {
+			dialog.ShowError(err, settingsWindow)
+		}
+		updateCacheUsage()
+	})
+
 	saveOptionsButton := widget.NewButton("Save Options", func() {
 		err := options.SaveOptionsToDB(db, opts)
 		if err == nil {
+			if _, err := thumbcache.Prune(int64(opts.ThumbnailCacheSize) << 20); err != nil {
+				dialog.ShowError(err, settingsWindow)
+			}
+			updateCacheUsage()
+			if opts.GridLayout != savedGridLayout || opts.AnimationPlayback != savedPlayback || opts.AnimationFrameRate != savedFrameRate {
+				savedGridLayout, savedPlayback, savedFrameRate = opts.GridLayout, opts.AnimationPlayback, opts.AnimationFrameRate
+				reloadImages()
+			}
 			dialog.ShowInformation("Success", "Options saved successfully", settingsWindow)
 		} else {
 			dialog.ShowError(err, settingsWindow)
@@ -285,6 +529,22 @@ func ShowSettingsWindow(a fyne.App, parent fyne.Window, db *sql.DB, opts *option
 		}),
 		tagList,
 		timeZone,
+		widget.NewLabel("EXIF fields shown in the sidebar"),
+		exifFieldsEntry,
+		widget.NewLabel("File types added to the library on startup"),
+		mediaTypesCheck,
+		widget.NewLabel("Thumbnail grid layout"),
+		gridLayoutSelect,
+		widget.NewLabel("Animated thumbnails"),
+		playbackSelect,
+		widget.NewLabel("Animation frames per second"),
+		frameRateEntry,
+		retagButton,
+		fixExtensionsButton,
+		widget.NewLabel("Thumbnail cache size in MB"),
+		cacheSizeEntry,
+		cacheUsage,
+		clearCacheButton,
 		// themeEditorButton,
 		widget.NewLabel("Default sorting: Date Added, Descending"),
 		saveOptionsButton,
@@ -351,7 +611,48 @@ func mapToStringSlice(m map[string]bool) []string {
 	return slice
 }
 
-func ShowRightClickMenu(w fyne.Window, fileList map[string]bool, a fyne.App) {
+// Writes the notes of the listed files to a notes.json in dir that gets archived with them,
+// returns the file list with notes.json added if any of the files have notes
+func addNotesFile(db *sql.DB, fileList []string, dir string) []string {
+	notes, err := database.GetNotesByPaths(db, fileList)
+	if err != nil || len(notes) == 0 {
+		return fileList
+	}
+
+	// archives store files by their base name so key the notes the same way
+	notesByName := make(map[string]database.FileNotes, len(notes))
+	for path, n := range notes {
+		notesByName[filepath.Base(path)] = n
+	}
+
+	notesJSON, err := json.MarshalIndent(notesByName, "", "  ")
+	if err != nil {
+		log.Println("Failed to marshal notes: ", err)
+		return fileList
+	}
+
+	notesPath := filepath.Join(dir, "notes.json")
+	if err := os.WriteFile(notesPath, notesJSON, 0644); err != nil {
+		log.Println("Failed to write notes: ", err)
+		return fileList
+	}
+
+	return append(fileList, notesPath)
+}
+
+// Makes an archive of the files and their notes. notes.json goes in a directory of
+// its own so exports running at the same time don't share it, and is removed after
+func archiveWithNotes(db *sql.DB, fileList []string, create func(fileList []string) error) error {
+	notesDir, err := os.MkdirTemp("", "tagvault-export-")
+	if err != nil {
+		log.Println("Failed to create notes directory: ", err)
+		return create(fileList)
+	}
+	defer os.RemoveAll(notesDir)
+	return create(addNotesFile(db, fileList, notesDir))
+}
+
+func ShowRightClickMenu(w fyne.Window, db *sql.DB, fileList map[string]bool, a fyne.App) {
 	home, _ := os.UserHomeDir()
 	now := time.Now()
 	formattedDate := now.Format("02-01-2006")
@@ -360,7 +661,9 @@ func ShowRightClickMenu(w fyne.Window, fileList map[string]bool, a fyne.App) {
 
 	gzipButton := widget.NewButton("Create Gzip Archive", func() {
 		archivePath := filepath.Join(home, "Desktop", formattedDate+".tar.gz")
-		err := archives.CreateTarGzipArchive(archivePath, listedFiles, w)
+		err := archiveWithNotes(db, listedFiles, func(files []string) error {
+			return archives.CreateTarGzipArchive(archivePath, files, w)
+		})
 		if err != nil {
 			dialog.ShowError(err, w)
 		} else {
@@ -370,7 +673,9 @@ func ShowRightClickMenu(w fyne.Window, fileList map[string]bool, a fyne.App) {
 
 	bzip2Button := widget.NewButton("Create Bzip2 Archive", func() {
 		archivePath := filepath.Join(home, "Desktop", formattedDate+".tar.bz2")
-		err := archives.CreateTarBzip2Archive(archivePath, listedFiles, w)
+		err := archiveWithNotes(db, listedFiles, func(files []string) error {
+			return archives.CreateTarBzip2Archive(archivePath, files, w)
+		})
 		if err != nil {
 			dialog.ShowError(err, w)
 		} else {
@@ -380,7 +685,9 @@ func ShowRightClickMenu(w fyne.Window, fileList map[string]bool, a fyne.App) {
 
 	zipButton := widget.NewButton("Create Zip Archive", func() {
 		archivePath := filepath.Join(home, "Desktop", formattedDate+".zip")
-		err := archives.CreateZipArchive(archivePath, listedFiles, w)
+		err := archiveWithNotes(db, listedFiles, func(files []string) error {
+			return archives.CreateZipArchive(archivePath, files, w)
+		})
 		if err != nil {
 			dialog.ShowError(err, w)
 		} else {
@@ -389,7 +696,7 @@ func ShowRightClickMenu(w fyne.Window, fileList map[string]bool, a fyne.App) {
 	})
 
 	encryptedButton := widget.NewButton("Create Encrypted Archive", func() {
-		showPasswordWindow(a, formattedDate, listedFiles, w)
+		showPasswordWindow(a, db, formattedDate, listedFiles, w)
 	})
 
 	convertButton := widget.NewButton("Convert Files", func() {
@@ -428,6 +735,10 @@ func showChooseConvertType(a fyne.App, w fyne.Window, fileList []string, resPath
 	// var resType string
 
 	for _, file := range imageconv.ImageTypes {
+		// formats like HEIC can only be read
+		if format, ok := imagecodec.ByName(file); !ok || !format.CanEncode() {
+			continue
+		}
 		button := widget.NewButton(file, func() {
 			// resType = file
 			_, err := imageconv.ConvertImage(fileList, file, resPath)
@@ -447,13 +758,13 @@ func showChooseConvertType(a fyne.App, w fyne.Window, fileList []string, resPath
 	convertWindow.Show()
 }
 
-func showPasswordWindow(a fyne.App, fmtDate string, fileList []string, tagVaultWindow fyne.Window) {
+func showPasswordWindow(a fyne.App, db *sql.DB, fmtDate string, fileList []string, tagVaultWindow fyne.Window) {
 	passwordWindow := a.NewWindow("Enter Password")
 	label := widget.NewLabel("Enter Password:")
 	password := widget.NewEntry()
 	password.OnSubmitted = func(password string) {
 		archives.ArchivePassword = password
-		showChooseArchiveType(tagVaultWindow, fmtDate, fileList)
+		showChooseArchiveType(tagVaultWindow, db, fmtDate, fileList)
 		passwordWindow.Close()
 	}
 	container := container.NewVBox(label, password)
@@ -496,19 +807,25 @@ func ShowSelectWindow(a fyne.App, options []string, title string, onSelected fun
 	selectWindow.Show()
 }
 
-func showChooseArchiveType(w fyne.Window, formattedDate string, fileList []string) {
+func showChooseArchiveType(w fyne.Window, db *sql.DB, formattedDate string, fileList []string) {
 	home, _ := os.UserHomeDir()
 	gzipButton := widget.NewButton("Gzip Archive", func() {
 		archivePath := filepath.Join(home, "Desktop", formattedDate+".tar.gz")
-		archives.CreateEncryptedTarGzipArchive(archivePath, fileList, w)
+		archiveWithNotes(db, fileList, func(files []string) error {
+			return archives.CreateEncryptedTarGzipArchive(archivePath, files, w)
+		})
 	})
 	bzip2Button := widget.NewButton("Bzip2 Archive", func() {
 		archivePath := filepath.Join(home, "Desktop", formattedDate+".tar.bz2")
-		archives.CreateEncryptedTarBzip2Archive(archivePath, fileList, w)
+		archiveWithNotes(db, fileList, func(files []string) error {
+			return archives.CreateEncryptedTarBzip2Archive(archivePath, files, w)
+		})
 	})
 	zipButton := widget.NewButton("Zip Archive", func() {
 		archivePath := filepath.Join(home, "Desktop", formattedDate+".zip")
-		archives.CreateEncryptedZipArchive(archivePath, fileList, w)
+		archiveWithNotes(db, fileList, func(files []string) error {
+			return archives.CreateEncryptedZipArchive(archivePath, files, w)
+		})
 	})
 
 	content := container.NewVBox(
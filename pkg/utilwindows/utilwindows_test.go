@@ -0,0 +1,61 @@
+package utilwindows
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"main/pkg/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveWithNotes(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	db.Exec("CREATE TABLE File (path TEXT, title TEXT, description TEXT, notes TEXT)")
+	db.Exec("INSERT INTO File VALUES ('/photos/cat.jpg', 'Cat', '', 'on the sofa'), ('/photos/dog.jpg', '', '', '')")
+
+	tests := []struct {
+		name  string
+		files []string
+		notes map[string]database.FileNotes
+	}{
+		{"with notes", []string{"/photos/cat.jpg", "/photos/dog.jpg"}, map[string]database.FileNotes{"cat.jpg": {Title: "Cat", Notes: "on the sofa"}}},
+		{"without notes", []string{"/photos/dog.jpg", "/photos/bird.jpg"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var archived []string
+			var notesPath string
+			err := archiveWithNotes(db, tt.files, func(files []string) error {
+				archived = files
+				if len(files) > len(tt.files) {
+					notesPath = files[len(files)-1]
+					data, err := os.ReadFile(notesPath)
+					if assert.NoError(t, err) {
+						var notes map[string]database.FileNotes
+						assert.NoError(t, json.Unmarshal(data, &notes))
+						assert.Equal(t, tt.notes, notes)
+					}
+				}
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.files, archived[:len(tt.files)])
+			if tt.notes == nil {
+				assert.Len(t, archived, len(tt.files))
+				return
+			}
+			assert.Equal(t, "notes.json", filepath.Base(notesPath))
+			// the notes only exist while the archive is made
+			assert.NoDirExists(t, filepath.Dir(notesPath))
+		})
+	}
+}
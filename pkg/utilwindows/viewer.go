@@ -0,0 +1,191 @@
+package utilwindows
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"main/pkg/components/viewer"
+	"path/filepath"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Shows the files at paths one at a time in a window of their own, starting at
+// index. decode returns the image of a file, frame is its index in files like
+// HEIF bursts that hold several images, and count returns how many they hold.
+// The mouse wheel zooms, dragging pans and the arrow keys go through the files
+func ShowViewerWindow(a fyne.App, paths []string, index int, decode func(path string, frame int) (image.Image, error), count func(path string) int) {
+	if len(paths) == 0 {
+		return
+	}
+	index = min(max(index, 0), len(paths)-1)
+	viewerWindow := a.NewWindow("View Image")
+
+	imageViewer := viewer.NewImageViewer(nil)
+	positionLabel := widget.NewLabel("")
+	nameLabel := widget.NewLabel("")
+	nameLabel.Truncation = fyne.TextTruncateEllipsis
+	zoomLabel := widget.NewLabel("100%")
+	sizeLabel := widget.NewLabel("")
+	pixelLabel := widget.NewLabel("")
+	frameLabel := widget.NewLabel("")
+	errorLabel := widget.NewLabel("")
+	errorLabel.Hide()
+
+	imageViewer.OnZoomChanged = func(zoom float64) {
+		zoomLabel.SetText(fmt.Sprintf("%.0f%%", zoom*100))
+	}
+	imageViewer.OnPixelHovered = func(x, y int, c color.Color, onImage bool) {
+		if !onImage {
+			pixelLabel.SetText("")
+			return
+		}
+		pixel := color.NRGBAModel.Convert(c).(color.NRGBA)
+		pixelLabel.SetText(fmt.Sprintf("x %d, y %d  #%02X%02X%02X  alpha %d", x, y, pixel.R, pixel.G, pixel.B, pixel.A))
+	}
+
+	// frames of the file shown, stepped through below the image when there are several
+	var mu sync.Mutex
+	frame, frames := 0, 1
+	// number of the last image asked for, images decoded after another was asked for are dropped
+	loading := 0
+	previousFrameButton := widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil)
+	nextFrameButton := widget.NewButtonWithIcon("", theme.MoveDownIcon(), nil)
+	frameControls := container.NewHBox(previousFrameButton, frameLabel, nextFrameButton)
+	frameControls.Hide()
+
+	showImage := func(next int, nextFrame int) {
+		mu.Lock()
+		index, frame = next, nextFrame
+		loading++
+		current := loading
+		mu.Unlock()
+
+		path := paths[next]
+		positionLabel.SetText(fmt.Sprintf("%d / %d", next+1, len(paths)))
+		nameLabel.SetText(filepath.Base(path))
+		viewerWindow.SetTitle(filepath.Base(path))
+		go func() {
+			img, err := decode(path, nextFrame)
+			fileFrames := 0
+			if nextFrame == 0 {
+				fileFrames = max(count(path), 1)
+			}
+
+			mu.Lock()
+			if current != loading {
+				mu.Unlock()
+				return
+			}
+			if fileFrames > 0 {
+				frames = fileFrames
+			}
+			shownFrames := frames
+			mu.Unlock()
+
+			if shownFrames > 1 {
+				frameLabel.SetText(fmt.Sprintf("Image %d / %d", nextFrame+1, shownFrames))
+				frameControls.Show()
+			} else {
+				frameControls.Hide()
+			}
+			if err != nil {
+				imageViewer.SetImage(nil)
+				sizeLabel.SetText("")
+				errorLabel.SetText(err.Error())
+				errorLabel.Show()
+				return
+			}
+			errorLabel.Hide()
+			imageViewer.SetImage(img)
+			sizeLabel.SetText(fmt.Sprintf("%d × %d", img.Bounds().Dx(), img.Bounds().Dy()))
+		}()
+	}
+	step := func(by int) {
+		mu.Lock()
+		next := (index + by + len(paths)) % len(paths)
+		mu.Unlock()
+		showImage(next, 0)
+	}
+	stepFrame := func(by int) {
+		mu.Lock()
+		current, next := index, (frame+by+frames)%frames
+		many := frames > 1
+		mu.Unlock()
+		if many {
+			showImage(current, next)
+		}
+	}
+	previousFrameButton.OnTapped = func() { stepFrame(-1) }
+	nextFrameButton.OnTapped = func() { stepFrame(1) }
+
+	previousButton := widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { step(-1) })
+	nextButton := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { step(1) })
+	if len(paths) == 1 {
+		previousButton.Disable()
+		nextButton.Disable()
+	}
+	zoomOutButton := widget.NewButtonWithIcon("", theme.ZoomOutIcon(), imageViewer.ZoomOut)
+	zoomInButton := widget.NewButtonWithIcon("", theme.ZoomInIcon(), imageViewer.ZoomIn)
+	fitButton := widget.NewButtonWithIcon("Fit", theme.ZoomFitIcon(), imageViewer.ZoomFit)
+	actualButton := widget.NewButton("1:1", imageViewer.ZoomActual)
+	fullScreenButton := widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), func() {
+		viewerWindow.SetFullScreen(!viewerWindow.FullScreen())
+	})
+
+	toolbar := container.NewBorder(nil, nil,
+		container.NewHBox(previousButton, positionLabel, nextButton),
+		container.NewHBox(zoomOutButton, zoomLabel, zoomInButton, fitButton, actualButton, fullScreenButton),
+		nameLabel,
+	)
+	statusBar := container.NewHBox(sizeLabel, pixelLabel, layout.NewSpacer(), frameControls)
+
+	viewerWindow.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		switch ev.Name {
+		case fyne.KeyLeft, fyne.KeyPageUp:
+			step(-1)
+		case fyne.KeyRight, fyne.KeyPageDown, fyne.KeySpace:
+			step(1)
+		case fyne.KeyUp:
+			stepFrame(-1)
+		case fyne.KeyDown:
+			stepFrame(1)
+		case fyne.KeyHome:
+			showImage(0, 0)
+		case fyne.KeyEnd:
+			showImage(len(paths)-1, 0)
+		case fyne.KeyF11:
+			viewerWindow.SetFullScreen(!viewerWindow.FullScreen())
+		case fyne.KeyEscape:
+			if viewerWindow.FullScreen() {
+				viewerWindow.SetFullScreen(false)
+			} else {
+				viewerWindow.Close()
+			}
+		}
+	})
+	viewerWindow.Canvas().SetOnTypedRune(func(r rune) {
+		switch r {
+		case '+', '=':
+			imageViewer.ZoomIn()
+		case '-':
+			imageViewer.ZoomOut()
+		case '0':
+			imageViewer.ZoomFit()
+		case '1':
+			imageViewer.ZoomActual()
+		case 'f':
+			viewerWindow.SetFullScreen(!viewerWindow.FullScreen())
+		}
+	})
+
+	viewerWindow.SetContent(container.NewBorder(toolbar, statusBar, nil, nil, container.NewStack(imageViewer, container.NewCenter(errorLabel))))
+	viewerWindow.Resize(fyne.NewSize(1000, 700))
+	showImage(index, 0)
+	viewerWindow.Show()
+}
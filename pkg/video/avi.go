@@ -0,0 +1,196 @@
+package video
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AVI files are RIFF files, the headers of the file and of every stream are in
+// the hdrl list and the frames are chunks of the movi list
+
+var errNoAVIHeader = errors.New("AVI file has no header")
+
+// Most chunks looked through for the first frame, index and junk chunks can come before it
+const maxAVIFrameChunks = 64
+
+// Codec names of the compression four character codes, upper case
+var aviCodecs = map[string]string{
+	"H264": "H.264", "X264": "H.264", "AVC1": "H.264", "HEVC": "HEVC", "H265": "HEVC", "X265": "HEVC",
+	"XVID": "MPEG-4", "DIVX": "MPEG-4", "DX50": "MPEG-4", "FMP4": "MPEG-4", "MP4V": "MPEG-4", "MP42": "MPEG-4 v2",
+	"MP43": "MPEG-4 v3", "DIV3": "MPEG-4 v3", "MJPG": "MJPEG", "AVRN": "MJPEG", "DVSD": "DV", "CVID": "Cinepak",
+	"VP80": "VP8", "VP90": "VP9", "WMV3": "WMV",
+}
+
+// Codec names of the WAVE format tags
+var aviAudioCodecs = map[uint16]string{
+	0x0001: "PCM", 0x0002: "ADPCM", 0x0011: "ADPCM", 0x0050: "MP2", 0x0055: "MP3", 0x00FF: "AAC", 0x1610: "AAC",
+	0x2000: "AC-3", 0x2001: "DTS", 0x0161: "WMA", 0xFFFE: "PCM",
+}
+
+type riffChunk struct {
+	id       string
+	listType string // the type of LIST chunks, their children follow it
+	offset   int64  // offset of the chunk data, after the list type
+	size     int64
+}
+
+// Lists the chunks between start and end, at most limit of them when limit isn't 0
+func readRIFFChunks(r file, start int64, end int64, limit int) []riffChunk {
+	var chunks []riffChunk
+	header := make([]byte, 12)
+	for offset := start; offset+8 <= end && (limit == 0 || len(chunks) < limit); {
+		n, _ := r.ReadAt(header, offset)
+		if n < 8 {
+			break
+		}
+		chunk := riffChunk{id: string(header[:4]), offset: offset + 8, size: int64(binary.LittleEndian.Uint32(header[4:]))}
+		if chunk.offset+chunk.size > end {
+			chunk.size = end - chunk.offset
+		}
+		next := chunk.offset + chunk.size + chunk.size%2 // chunks are padded to an even size
+		if chunk.id == "LIST" && n == 12 && chunk.size >= 4 {
+			chunk.listType = string(header[8:12])
+			chunk.offset += 4
+			chunk.size -= 4
+		}
+		chunks = append(chunks, chunk)
+		offset = next
+	}
+	return chunks
+}
+
+func findRIFFList(chunks []riffChunk, listType string) (riffChunk, bool) {
+	for _, c := range chunks {
+		if c.id == "LIST" && c.listType == listType {
+			return c, true
+		}
+	}
+	return riffChunk{}, false
+}
+
+func readRIFFChunk(r file, c riffChunk, minSize int) ([]byte, bool) {
+	if c.size < int64(minSize) || c.size > 1<<20 {
+		return nil, false
+	}
+	data := make([]byte, c.size)
+	if _, err := r.ReadAt(data, c.offset); err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Reads the main and stream headers of an AVI file
+func probeAVI(r file) (*Info, error) {
+	riff := readRIFFChunks(r, 12, r.Size(), 0)
+	hdrl, ok := findRIFFList(riff, "hdrl")
+	if !ok {
+		return nil, errNoAVIHeader
+	}
+	info := &Info{Container: AVI}
+	headers := readRIFFChunks(r, hdrl.offset, hdrl.offset+hdrl.size, 0)
+
+	var frames uint32
+	var frameDuration time.Duration
+	for _, c := range headers {
+		if c.id != "avih" {
+			continue
+		}
+		if data, ok := readRIFFChunk(r, c, 40); ok {
+			frameDuration = time.Duration(binary.LittleEndian.Uint32(data)) * time.Microsecond
+			frames = binary.LittleEndian.Uint32(data[16:])
+			info.Width = int(binary.LittleEndian.Uint32(data[32:]))
+			info.Height = int(binary.LittleEndian.Uint32(data[36:]))
+		}
+	}
+	// OpenDML files bigger than 1 GB count every frame in their extended header
+	if odml, ok := findRIFFList(headers, "odml"); ok {
+		for _, c := range readRIFFChunks(r, odml.offset, odml.offset+odml.size, 0) {
+			if data, ok := readRIFFChunk(r, c, 4); ok && c.id == "dmlh" {
+				frames = max(frames, binary.LittleEndian.Uint32(data))
+			}
+		}
+	}
+	info.Duration = time.Duration(frames) * frameDuration
+
+	videoStream := -1
+	var videoCodec string
+	stream := 0
+	for _, strl := range headers {
+		if strl.id != "LIST" || strl.listType != "strl" {
+			continue
+		}
+		var kind string
+		var header, format []byte
+		for _, c := range readRIFFChunks(r, strl.offset, strl.offset+strl.size, 0) {
+			switch c.id {
+			case "strh":
+				header, _ = readRIFFChunk(r, c, 36)
+			case "strf":
+				format, _ = readRIFFChunk(r, c, 2)
+			}
+		}
+		if len(header) >= 36 {
+			kind = string(header[:4])
+		}
+
+		switch {
+		case kind == "vids" && videoStream < 0:
+			videoStream = stream
+			// the compression of the bitmap header is more reliable than the handler of the stream header
+			codec := strings.ToUpper(string(header[4:8]))
+			if len(format) >= 20 {
+				if compression := strings.ToUpper(string(format[16:20])); strings.Trim(compression, "\x00 ") != "" {
+					codec = compression
+				}
+				width, height := int32(binary.LittleEndian.Uint32(format[4:])), int32(binary.LittleEndian.Uint32(format[8:]))
+				// bottom up bitmaps have a negative height
+				info.Width, info.Height = int(width), int(max(height, -height))
+			}
+			videoCodec = codec
+			info.VideoCodec = aviCodecs[codec]
+			if info.VideoCodec == "" {
+				info.VideoCodec = strings.Trim(codec, "\x00 ")
+			}
+			// the length of the stream in units of scale / rate seconds
+			scale, rate, length := binary.LittleEndian.Uint32(header[20:]), binary.LittleEndian.Uint32(header[24:]), binary.LittleEndian.Uint32(header[32:])
+			if info.Duration == 0 && rate > 0 {
+				info.Duration = time.Duration(float64(length) * float64(scale) / float64(rate) * float64(time.Second))
+			}
+		case kind == "auds" && info.AudioCodec == "" && len(format) >= 2:
+			tag := binary.LittleEndian.Uint16(format)
+			info.AudioCodec = aviAudioCodecs[tag]
+			if info.AudioCodec == "" {
+				info.AudioCodec = fmt.Sprintf("0x%04X", tag)
+			}
+		}
+		stream++
+	}
+
+	if videoCodec == "MJPG" || videoCodec == "AVRN" {
+		if movi, ok := findRIFFList(riff, "movi"); ok {
+			info.frame = firstAVIFrame(r, movi, videoStream)
+		}
+	}
+	return info, nil
+}
+
+// Locates the first frame of a stream in the movi list, the frames of stream 0
+// are chunks named 00dc or 00db, they can be grouped in rec lists
+func firstAVIFrame(r file, movi riffChunk, stream int) posterSource {
+	compressed, uncompressed := fmt.Sprintf("%02ddc", stream), fmt.Sprintf("%02ddb", stream)
+	for _, c := range readRIFFChunks(r, movi.offset, movi.offset+movi.size, maxAVIFrameChunks) {
+		if c.id == "LIST" && c.listType == "rec " {
+			if frame := firstAVIFrame(r, c, stream); frame.length > 0 {
+				return frame
+			}
+			continue
+		}
+		if (c.id == compressed || c.id == uncompressed) && c.size > 0 {
+			return posterSource{offset: c.offset, length: c.size}
+		}
+	}
+	return posterSource{}
+}
@@ -0,0 +1,156 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a RIFF chunk, padded to an even size
+func testRIFFChunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	c := binary.LittleEndian.AppendUint32([]byte(id), uint32(len(body)))
+	c = append(c, body...)
+	if len(body)%2 == 1 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+func testRIFFList(listType string, chunks ...[]byte) []byte {
+	return testRIFFChunk("LIST", append([][]byte{[]byte(listType)}, chunks...)...)
+}
+
+// Builds the main AVI header
+func testAvih(microsecondsPerFrame, frames, width, height uint32) []byte {
+	d := make([]byte, 56)
+	binary.LittleEndian.PutUint32(d, microsecondsPerFrame)
+	binary.LittleEndian.PutUint32(d[16:], frames)
+	binary.LittleEndian.PutUint32(d[32:], width)
+	binary.LittleEndian.PutUint32(d[36:], height)
+	return testRIFFChunk("avih", d)
+}
+
+// Builds a video stream list with a stream header and a bitmap header
+func testVideoStream(handler string, compression string, width, height int32) []byte {
+	strh := make([]byte, 56)
+	copy(strh, "vids")
+	copy(strh[4:], handler)
+	binary.LittleEndian.PutUint32(strh[20:], 1)
+	binary.LittleEndian.PutUint32(strh[24:], 25)
+	binary.LittleEndian.PutUint32(strh[32:], 250)
+	strf := make([]byte, 40)
+	binary.LittleEndian.PutUint32(strf[4:], uint32(width))
+	binary.LittleEndian.PutUint32(strf[8:], uint32(height))
+	copy(strf[16:], compression)
+	return testRIFFList("strl", testRIFFChunk("strh", strh), testRIFFChunk("strf", strf))
+}
+
+func testAudioStream(tag uint16) []byte {
+	strh := make([]byte, 56)
+	copy(strh, "auds")
+	return testRIFFList("strl", testRIFFChunk("strh", strh), testRIFFChunk("strf", binary.LittleEndian.AppendUint16(nil, tag), make([]byte, 16)))
+}
+
+func testAVI(chunks ...[]byte) []byte {
+	body := append([]byte("AVI "), bytes.Join(chunks, nil)...)
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+func TestProbeAVI(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		video    string
+		audio    string
+		width    int
+		height   int
+		duration time.Duration
+	}{
+		{
+			"XviD with MP3",
+			testAVI(testRIFFList("hdrl", testAvih(40000, 250, 640, 480), testVideoStream("xvid", "XVID", 640, 480), testAudioStream(0x55))),
+			"MPEG-4", "MP3", 640, 480, 10 * time.Second,
+		},
+		{
+			"bottom up bitmap",
+			testAVI(testRIFFList("hdrl", testAvih(40000, 25, 320, 240), testVideoStream("DIB ", "\x00\x00\x00\x00", 320, -240))),
+			"DIB", "", 320, 240, time.Second,
+		},
+		{
+			"duration from the stream",
+			testAVI(testRIFFList("hdrl", testAvih(0, 0, 0, 0), testVideoStream("mjpg", "MJPG", 8, 8), testAudioStream(0x1234))),
+			"MJPEG", "0x1234", 8, 8, 10 * time.Second,
+		},
+		{
+			"OpenDML frame count",
+			testAVI(testRIFFList("hdrl", testAvih(40000, 25, 8, 8), testRIFFList("odml", testRIFFChunk("dmlh", binary.LittleEndian.AppendUint32(nil, 500))))),
+			"", "", 8, 8, 20 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := probe(bytes.NewReader(tt.data))
+			if assert.NoError(t, err) {
+				assert.Equal(t, AVI, info.Container)
+				assert.Equal(t, tt.video, info.VideoCodec)
+				assert.Equal(t, tt.audio, info.AudioCodec)
+				assert.Equal(t, tt.width, info.Width)
+				assert.Equal(t, tt.height, info.Height)
+				assert.Equal(t, tt.duration, info.Duration)
+			}
+		})
+	}
+}
+
+func TestProbeAVIInvalid(t *testing.T) {
+	valid := testAVI(testRIFFList("hdrl", testAvih(40000, 250, 640, 480), testVideoStream("xvid", "XVID", 640, 480)))
+	oversized := testAVI(testRIFFList("hdrl", testAvih(40000, 250, 640, 480)), []byte("LIST\xff\xff\xff\xffmovi00dc"))
+	shortHeaders := testAVI(testRIFFList("hdrl", testRIFFChunk("avih", make([]byte, 10)), testRIFFList("strl", testRIFFChunk("strh", []byte("vids")))))
+
+	tests := []struct {
+		name   string
+		data   []byte
+		hasErr bool
+		codec  string
+		width  int
+	}{
+		{"no header list", testAVI(testRIFFList("movi")), true, "", 0},
+		{"truncated RIFF header", valid[:14], true, "", 0},
+		// the codec falls back to the handler of the stream header
+		{"truncated bitmap header", valid[:len(valid)-30], false, "MPEG-4", 640},
+		{"oversized chunk", oversized, false, "", 640},
+		{"short headers", shortHeaders, false, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				info, err := probe(bytes.NewReader(tt.data))
+				assert.Equal(t, tt.hasErr, err != nil, err)
+				if info != nil {
+					assert.Equal(t, tt.codec, info.VideoCodec)
+					assert.Equal(t, tt.width, info.Width)
+				}
+			})
+		})
+	}
+}
+
+func TestDecodePosterAVI(t *testing.T) {
+	frame := testJPEGFrame(t)
+	hdrl := testRIFFList("hdrl", testAvih(40000, 1, 4, 2), testAudioStream(0x55), testVideoStream("MJPG", "MJPG", 4, 2))
+	// the frames of the second stream, the first ones are grouped in a rec list
+	movi := testRIFFList("movi", testRIFFChunk("00wb", []byte{1, 2}), testRIFFList("rec ", testRIFFChunk("JUNK"), testRIFFChunk("01dc", frame)))
+	img, err := DecodePoster(bytes.NewReader(testAVI(hdrl, movi)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, img.Bounds().Dx())
+	}
+
+	// no frame of the video stream
+	movi = testRIFFList("movi", testRIFFChunk("00wb", []byte{1, 2}))
+	_, err = DecodePoster(bytes.NewReader(testAVI(hdrl, movi)))
+	assert.ErrorIs(t, err, ErrNoPoster)
+}
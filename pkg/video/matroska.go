@@ -0,0 +1,376 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+// Matroska and WebM files are EBML, a binary XML where every element has a
+// variable length id and size. Only the elements with metadata are read, the
+// clusters holding the frames are skipped apart from the first ones
+
+var errNoSegment = errors.New("Matroska file has no segment")
+
+const ebmlMagic = "\x1a\x45\xdf\xa3"
+
+// Element ids
+const (
+	ebmlDocType         = 0x4282
+	mkvSegment          = 0x18538067
+	mkvInfo             = 0x1549A966
+	mkvTimecodeScale    = 0x2AD7B1
+	mkvDuration         = 0x4489
+	mkvDateUTC          = 0x4461
+	mkvTracks           = 0x1654AE6B
+	mkvTrackEntry       = 0xAE
+	mkvTrackNumber      = 0xD7
+	mkvTrackType        = 0x83
+	mkvCodecID          = 0x86
+	mkvVideo            = 0xE0
+	mkvPixelWidth       = 0xB0
+	mkvPixelHeight      = 0xBA
+	mkvAttachments      = 0x1941A469
+	mkvAttachedFile     = 0x61A7
+	mkvFileName         = 0x466E
+	mkvFileMimeType     = 0x4660
+	mkvFileData         = 0x465C
+	mkvCluster          = 0x1F43B675
+	mkvSimpleBlock      = 0xA3
+	mkvBlockGroup       = 0xA0
+	mkvBlock            = 0xA1
+	mkvVideoTrack       = 1
+	mkvAudioTrack       = 2
+	mkvDefaultTimescale = 1000000 // nanoseconds per tick
+)
+
+// Most elements listed in one parent and clusters looked into for the first frame
+const (
+	maxEBMLElements   = 100000
+	maxFrameClusters  = 4
+	maxEBMLStringSize = 1024
+)
+
+// Matroska dates count from 2001
+var mkvEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Codec names of the codec ids, ids not listed keep their name
+var mkvCodecs = map[string]string{
+	"V_MPEG4/ISO/AVC": "H.264", "V_MPEGH/ISO/HEVC": "HEVC", "V_VP8": "VP8", "V_VP9": "VP9", "V_AV1": "AV1",
+	"V_MPEG4/ISO/ASP": "MPEG-4", "V_MPEG4/ISO/SP": "MPEG-4", "V_MPEG2": "MPEG-2", "V_MJPEG": "MJPEG", "V_THEORA": "Theora",
+	"A_OPUS": "Opus", "A_VORBIS": "Vorbis", "A_FLAC": "FLAC", "A_AC3": "AC-3", "A_EAC3": "E-AC-3", "A_DTS": "DTS",
+	"A_MPEG/L3": "MP3", "A_MPEG/L2": "MP2", "A_TRUEHD": "TrueHD",
+}
+
+type ebmlElement struct {
+	id     uint32
+	offset int64 // offset of the element data
+	size   int64
+}
+
+// Reads a variable length integer, ids keep their length marker and sizes don't.
+// A size with every bit set is unknown, it is returned as math.MaxUint64
+func readVint(data []byte, keepMarker bool) (uint64, int) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0
+	}
+	length := 1
+	for data[0]&(0x80>>(length-1)) == 0 {
+		length++
+	}
+	if length > len(data) {
+		return 0, 0
+	}
+	value := uint64(data[0])
+	if !keepMarker {
+		value &= 0xFF >> length
+	}
+	allOnes := value == 0xFF>>length
+	for _, b := range data[1:length] {
+		value = value<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+	if !keepMarker && allOnes {
+		return math.MaxUint64, length
+	}
+	return value, length
+}
+
+// Lists the elements between start and end, an element of unknown size runs to the end
+func readEBMLElements(r file, start int64, end int64) []ebmlElement {
+	var elements []ebmlElement
+	header := make([]byte, 12)
+	for offset := start; offset < end && len(elements) < maxEBMLElements; {
+		n, _ := r.ReadAt(header, offset)
+		id, idLength := readVint(header[:n], true)
+		if idLength == 0 || idLength > 4 {
+			break
+		}
+		size, sizeLength := readVint(header[idLength:n], false)
+		if sizeLength == 0 {
+			break
+		}
+		dataOffset := offset + int64(idLength+sizeLength)
+		// the header runs past the end of the parent
+		if dataOffset > end {
+			break
+		}
+		if size == math.MaxUint64 || int64(size) > end-dataOffset {
+			size = uint64(end - dataOffset)
+		}
+		elements = append(elements, ebmlElement{id: uint32(id), offset: dataOffset, size: int64(size)})
+		offset = dataOffset + int64(size)
+	}
+	return elements
+}
+
+func ebmlChildren(r file, e ebmlElement) []ebmlElement {
+	return readEBMLElements(r, e.offset, e.offset+e.size)
+}
+
+func findEBMLElement(elements []ebmlElement, id uint32) (ebmlElement, bool) {
+	for _, e := range elements {
+		if e.id == id {
+			return e, true
+		}
+	}
+	return ebmlElement{}, false
+}
+
+func readEBMLData(r file, e ebmlElement, maxSize int64) ([]byte, bool) {
+	if e.size > maxSize {
+		return nil, false
+	}
+	data := make([]byte, e.size)
+	if _, err := r.ReadAt(data, e.offset); err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func readEBMLUint(r file, e ebmlElement) uint64 {
+	data, _ := readEBMLData(r, e, 8)
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func readEBMLFloat(r file, e ebmlElement) float64 {
+	data, _ := readEBMLData(r, e, 8)
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+	return 0
+}
+
+func readEBMLString(r file, e ebmlElement) string {
+	data, _ := readEBMLData(r, e, maxEBMLStringSize)
+	return string(bytes.TrimRight(data, "\x00"))
+}
+
+// Tells WebM files from other Matroska files by the document type in the EBML header
+func matroskaKind(header []byte) string {
+	r := bytes.NewReader(header)
+	elements := readEBMLElements(r, 0, r.Size())
+	if len(elements) == 0 {
+		return ""
+	}
+	docType, ok := findEBMLElement(ebmlChildren(r, elements[0]), ebmlDocType)
+	if !ok {
+		// the header may be cut off, Matroska is the default document type
+		return MKV
+	}
+	if readEBMLString(r, docType) == "webm" {
+		return WEBM
+	}
+	return MKV
+}
+
+// Reads the segment info, the tracks and the attachments of a Matroska file
+func probeMatroska(r file) (*Info, error) {
+	top := readEBMLElements(r, 0, r.Size())
+	info := &Info{Container: MKV}
+	if len(top) > 0 {
+		if docType, ok := findEBMLElement(ebmlChildren(r, top[0]), ebmlDocType); ok && readEBMLString(r, docType) == "webm" {
+			info.Container = WEBM
+		}
+	}
+	segment, ok := findEBMLElement(top, mkvSegment)
+	if !ok {
+		return info, errNoSegment
+	}
+
+	var videoTrack uint64
+	var videoCodec string
+	var clusters []ebmlElement
+	for _, e := range ebmlChildren(r, segment) {
+		switch e.id {
+		case mkvInfo:
+			readMatroskaInfo(r, e, info)
+		case mkvTracks:
+			videoTrack, videoCodec = readMatroskaTracks(r, e, info)
+		case mkvAttachments:
+			info.cover = findMatroskaCover(r, e)
+		case mkvCluster:
+			if len(clusters) < maxFrameClusters {
+				clusters = append(clusters, e)
+			}
+		}
+	}
+
+	if videoCodec == "V_MJPEG" {
+		info.frame = firstMatroskaFrame(r, clusters, videoTrack)
+	}
+	return info, nil
+}
+
+func readMatroskaInfo(r file, e ebmlElement, info *Info) {
+	scale := uint64(mkvDefaultTimescale)
+	var duration float64
+	for _, child := range ebmlChildren(r, e) {
+		switch child.id {
+		case mkvTimecodeScale:
+			if value := readEBMLUint(r, child); value > 0 {
+				scale = value
+			}
+		case mkvDuration:
+			duration = readEBMLFloat(r, child)
+		case mkvDateUTC:
+			data, _ := readEBMLData(r, child, 8)
+			if len(data) == 8 {
+				info.CreationDate = mkvEpoch.Add(time.Duration(int64(binary.BigEndian.Uint64(data))))
+			}
+		}
+	}
+	info.Duration = time.Duration(duration * float64(scale))
+}
+
+// Reads the codecs of the first video and audio tracks, returns the number and codec id of the video track
+func readMatroskaTracks(r file, e ebmlElement, info *Info) (uint64, string) {
+	var videoTrack uint64
+	var videoCodec string
+	for _, entry := range ebmlChildren(r, e) {
+		if entry.id != mkvTrackEntry {
+			continue
+		}
+		var number, kind uint64
+		var codec string
+		var width, height int
+		for _, child := range ebmlChildren(r, entry) {
+			switch child.id {
+			case mkvTrackNumber:
+				number = readEBMLUint(r, child)
+			case mkvTrackType:
+				kind = readEBMLUint(r, child)
+			case mkvCodecID:
+				codec = readEBMLString(r, child)
+			case mkvVideo:
+				for _, setting := range ebmlChildren(r, child) {
+					switch setting.id {
+					case mkvPixelWidth:
+						width = int(readEBMLUint(r, setting))
+					case mkvPixelHeight:
+						height = int(readEBMLUint(r, setting))
+					}
+				}
+			}
+		}
+
+		name := mkvCodecs[codec]
+		switch {
+		case name != "":
+		case strings.HasPrefix(codec, "A_AAC"):
+			name = "AAC"
+		case strings.HasPrefix(codec, "A_PCM"):
+			name = "PCM"
+		default:
+			// V_VP9 style ids without their prefix
+			name = codec
+			if len(codec) > 2 && codec[1] == '_' {
+				name = codec[2:]
+			}
+		}
+
+		switch {
+		case kind == mkvVideoTrack && info.VideoCodec == "":
+			info.VideoCodec = name
+			info.Width, info.Height = width, height
+			videoTrack, videoCodec = number, codec
+		case kind == mkvAudioTrack && info.AudioCodec == "":
+			info.AudioCodec = name
+		}
+	}
+	return videoTrack, videoCodec
+}
+
+// Locates the attached image meant as the cover, files named cover come first
+func findMatroskaCover(r file, e ebmlElement) posterSource {
+	var cover posterSource
+	for _, attachment := range ebmlChildren(r, e) {
+		if attachment.id != mkvAttachedFile {
+			continue
+		}
+		var name, mimeType string
+		var data ebmlElement
+		for _, child := range ebmlChildren(r, attachment) {
+			switch child.id {
+			case mkvFileName:
+				name = strings.ToLower(readEBMLString(r, child))
+			case mkvFileMimeType:
+				mimeType = readEBMLString(r, child)
+			case mkvFileData:
+				data = child
+			}
+		}
+		if (mimeType != "image/jpeg" && mimeType != "image/png") || data.size == 0 {
+			continue
+		}
+		if strings.HasPrefix(name, "cover") {
+			return posterSource{offset: data.offset, length: data.size}
+		}
+		if cover.length == 0 {
+			cover = posterSource{offset: data.offset, length: data.size}
+		}
+	}
+	return cover
+}
+
+// Locates the first frame of the video track in the first clusters
+func firstMatroskaFrame(r file, clusters []ebmlElement, track uint64) posterSource {
+	for _, cluster := range clusters {
+		for _, e := range ebmlChildren(r, cluster) {
+			block := e
+			if e.id == mkvBlockGroup {
+				var ok bool
+				if block, ok = findEBMLElement(ebmlChildren(r, e), mkvBlock); !ok {
+					continue
+				}
+			} else if e.id != mkvSimpleBlock {
+				continue
+			}
+
+			// the track number, a 16 bit timecode and the flags come before the frame
+			header := make([]byte, 12)
+			n, _ := r.ReadAt(header, block.offset)
+			number, length := readVint(header[:n], false)
+			if length == 0 || number != track || n < length+3 {
+				continue
+			}
+			// laced blocks hold several frames
+			if header[length+2]&0x06 != 0 {
+				return posterSource{}
+			}
+			skip := int64(length + 3)
+			return posterSource{offset: block.offset + skip, length: block.size - skip}
+		}
+	}
+	return posterSource{}
+}
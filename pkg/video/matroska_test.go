@@ -0,0 +1,171 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds an EBML element, the size is always written in 8 bytes
+func testEBML(id uint32, data ...[]byte) []byte {
+	var e []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(e) > 0 {
+			e = append(e, b)
+		}
+	}
+	body := bytes.Join(data, nil)
+	e = append(e, 0x01)
+	e = append(e, binary.BigEndian.AppendUint64(nil, uint64(len(body)))[1:]...)
+	return append(e, body...)
+}
+
+func testEBMLUint(id uint32, value uint64) []byte {
+	return testEBML(id, binary.BigEndian.AppendUint64(nil, value))
+}
+
+// Builds a WebM header and segment, the segment has an unknown size when unknown is set
+func testMatroska(docType string, unknown bool, elements ...[]byte) []byte {
+	header := testEBML(0x1A45DFA3, testEBML(ebmlDocType, []byte(docType)))
+	segment := testEBML(mkvSegment, elements...)
+	if unknown {
+		// the id, then a size with every bit set
+		segment = append([]byte{0x18, 0x53, 0x80, 0x67, 0xFF}, bytes.Join(elements, nil)...)
+	}
+	return append(header, segment...)
+}
+
+func testMatroskaTrack(number uint64, kind uint64, codec string, width, height uint64) []byte {
+	children := [][]byte{
+		testEBMLUint(mkvTrackNumber, number),
+		testEBMLUint(mkvTrackType, kind),
+		testEBML(mkvCodecID, []byte(codec)),
+	}
+	if width > 0 {
+		children = append(children, testEBML(mkvVideo, testEBMLUint(mkvPixelWidth, width), testEBMLUint(mkvPixelHeight, height)))
+	}
+	return testEBML(mkvTrackEntry, children...)
+}
+
+func testMatroskaInfo() []byte {
+	return testEBML(mkvInfo,
+		testEBMLUint(mkvTimecodeScale, 1000000),
+		testEBML(mkvDuration, binary.BigEndian.AppendUint64(nil, math.Float64bits(12500))),
+		testEBMLUint(mkvDateUTC, uint64(365*24*time.Hour)),
+	)
+}
+
+func TestProbeMatroska(t *testing.T) {
+	tracks := testEBML(mkvTracks,
+		testMatroskaTrack(1, mkvAudioTrack, "A_AAC/MPEG4/LC", 0, 0),
+		testMatroskaTrack(2, mkvVideoTrack, "V_VP9", 1280, 720),
+		testMatroskaTrack(3, mkvVideoTrack, "V_MPEG4/ISO/AVC", 640, 480),
+	)
+	for _, unknown := range []bool{false, true} {
+		info, err := probe(bytes.NewReader(testMatroska("webm", unknown, testMatroskaInfo(), tracks)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, WEBM, info.Container)
+			assert.Equal(t, 12500*time.Millisecond, info.Duration)
+			assert.Equal(t, time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC), info.CreationDate)
+			assert.Equal(t, "VP9", info.VideoCodec)
+			assert.Equal(t, "AAC", info.AudioCodec)
+			assert.Equal(t, 1280, info.Width)
+			assert.Equal(t, 720, info.Height)
+		}
+	}
+}
+
+func TestProbeMatroskaInvalid(t *testing.T) {
+	valid := testMatroska("matroska", false, testMatroskaInfo())
+	// the info says it is 3 bytes long, the header of its only child runs past that
+	straddling := testMatroska("matroska", false, []byte{0x15, 0x49, 0xA9, 0x66, 0x83, 0x2A, 0xD7, 0xB1, 0x81, 0x01})
+	huge := testMatroska("matroska", false, testEBML(mkvInfo, testEBML(mkvDuration, make([]byte, 8))))
+	// the duration claims 2^55 bytes
+	binary.BigEndian.PutUint64(huge[len(huge)-16:], 0x0080000000000000)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		hasErr   bool
+		duration time.Duration
+	}{
+		{"no segment", testMatroska("matroska", false)[:20], true, 0},
+		{"truncated info", valid[:len(valid)-10], false, 12500 * time.Millisecond},
+		{"child past the end of its parent", straddling, false, 0},
+		{"oversized element", huge, false, 0},
+		{"bad id length", append([]byte(ebmlMagic), 0x80, 0x00, 0x00, 0x00, 0x00), true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				info, err := probe(bytes.NewReader(tt.data))
+				assert.Equal(t, tt.hasErr, err != nil, err)
+				if info != nil {
+					assert.Equal(t, tt.duration, info.Duration)
+				}
+			})
+		})
+	}
+}
+
+func TestReadVint(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		keepMarker bool
+		value      uint64
+		length     int
+	}{
+		{"one byte size", []byte{0x81}, false, 1, 1},
+		{"two byte size", []byte{0x40, 0x02}, false, 2, 2},
+		{"id keeps its marker", []byte{0x1A, 0x45, 0xDF, 0xA3}, true, 0x1A45DFA3, 4},
+		{"unknown size", []byte{0xFF}, false, math.MaxUint64, 1},
+		{"unknown 8 byte size", []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, false, math.MaxUint64, 8},
+		{"truncated", []byte{0x40}, false, 0, 0},
+		{"zero byte", []byte{0x00, 0x01}, false, 0, 0},
+		{"empty", nil, false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, length := readVint(tt.data, tt.keepMarker)
+			assert.Equal(t, tt.value, value)
+			assert.Equal(t, tt.length, length)
+		})
+	}
+}
+
+func TestDecodePosterMatroska(t *testing.T) {
+	frame := testJPEGFrame(t)
+	tracks := testEBML(mkvTracks, testMatroskaTrack(1, mkvVideoTrack, "V_MJPEG", 4, 2))
+	block := append([]byte{0x81, 0, 0, 0x80}, frame...)
+	cluster := testEBML(mkvCluster, testEBMLUint(0xE7, 0), testEBML(mkvSimpleBlock, block))
+	data := testMatroska("matroska", false, tracks, cluster)
+
+	img, err := DecodePoster(bytes.NewReader(data))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, img.Bounds().Dx())
+	}
+
+	attachments := testEBML(mkvAttachments,
+		testEBML(mkvAttachedFile, testEBML(mkvFileName, []byte("small_cover.png")), testEBML(mkvFileMimeType, []byte("image/png")), testEBML(mkvFileData, []byte("png"))),
+		testEBML(mkvAttachedFile, testEBML(mkvFileName, []byte("Cover.jpg")), testEBML(mkvFileMimeType, []byte("image/jpeg")), testEBML(mkvFileData, frame)),
+	)
+	info, err := probe(bytes.NewReader(testMatroska("matroska", false, attachments)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(len(frame)), info.cover.length)
+	}
+
+	// laced blocks hold several frames
+	laced := testEBML(mkvCluster, testEBML(mkvSimpleBlock, append([]byte{0x81, 0, 0, 0x82}, frame...)))
+	_, err = DecodePoster(bytes.NewReader(testMatroska("matroska", false, tracks, laced)))
+	assert.ErrorIs(t, err, ErrNoPoster)
+
+	// a block header cut off by the end of the file
+	truncated := testMatroska("matroska", false, tracks, testEBML(mkvCluster, []byte{0xA3, 0x82, 0x81, 0x00}))
+	_, err = DecodePoster(bytes.NewReader(truncated))
+	assert.ErrorIs(t, err, ErrNoPoster)
+}
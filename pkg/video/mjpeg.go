@@ -0,0 +1,55 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"sync"
+)
+
+// Motion JPEG frames usually leave out their Huffman tables and use the standard
+// ones of the JPEG specification. The image/jpeg encoder writes the same tables,
+// so they are taken from a tiny encoded image
+var standardHuffmanTables = sync.OnceValue(func() []byte {
+	var buf bytes.Buffer
+	// a color image so the chrominance tables are written too
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		return nil
+	}
+	data := buf.Bytes()
+	var tables []byte
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF && data[pos+1] != 0xDA; {
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			break
+		}
+		if data[pos+1] == 0xC4 {
+			tables = append(tables, data[pos:end]...)
+		}
+		pos = end
+	}
+	return tables
+})
+
+// Returns the frame with the standard Huffman tables added before its scan when
+// it has none of its own
+func addHuffmanTables(frame []byte) []byte {
+	for pos := 2; pos+4 <= len(frame) && frame[pos] == 0xFF; {
+		switch frame[pos+1] {
+		case 0xC4:
+			return frame
+		case 0xDA:
+			fixed := make([]byte, 0, len(frame)+len(standardHuffmanTables()))
+			fixed = append(fixed, frame[:pos]...)
+			fixed = append(fixed, standardHuffmanTables()...)
+			return append(fixed, frame[pos:]...)
+		case 0xFF:
+			// fill byte
+			pos++
+			continue
+		}
+		pos += 2 + int(binary.BigEndian.Uint16(frame[pos+2:]))
+	}
+	return frame
+}
@@ -0,0 +1,359 @@
+package video
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MP4 and MOV files are ISO base media files, the metadata is in the moov box
+// and the frames of a track are located through its sample tables
+
+var errNoMoov = errors.New("MP4 file has no moov box")
+
+// Biggest box read into memory, sample tables of long videos are the biggest ones
+const maxMP4BoxSize = 64 << 20
+
+// Brands of ISO base media files that are images or audio, not video
+var mp4OtherBrands = map[string]bool{
+	"avif": true, "avis": true, "heic": true, "heix": true, "heim": true, "heis": true, "hevc": true, "hevx": true,
+	"mif1": true, "msf1": true, "crx ": true, "M4A ": true, "M4B ": true, "M4P ": true,
+}
+
+// Brands of MP4 video files
+var mp4Brands = map[string]bool{
+	"isom": true, "iso2": true, "iso4": true, "iso5": true, "iso6": true, "mp41": true, "mp42": true, "avc1": true,
+	"M4V ": true, "M4VH": true, "M4VP": true, "3gp4": true, "3gp5": true, "3gp6": true, "3g2a": true, "3g2b": true,
+	"dash": true, "mmp4": true, "XAVC": true, "f4v ": true, "MSNV": true, "NDAS": true, "CAEP": true,
+}
+
+// Codec names of the sample entry types
+var mp4Codecs = map[string]string{
+	"avc1": "H.264", "avc3": "H.264", "hvc1": "HEVC", "hev1": "HEVC", "vp08": "VP8", "vp09": "VP9", "av01": "AV1",
+	"mp4v": "MPEG-4", "s263": "H.263", "h263": "H.263", "jpeg": "MJPEG", "mjpa": "MJPEG", "png ": "PNG",
+	"apch": "ProRes", "apcn": "ProRes", "apcs": "ProRes", "apco": "ProRes", "ap4h": "ProRes", "ap4x": "ProRes",
+	"mp4a": "AAC", "ac-3": "AC-3", "ec-3": "E-AC-3", "Opus": "Opus", "fLaC": "FLAC", "alac": "ALAC", ".mp3": "MP3",
+	"samr": "AMR", "sowt": "PCM", "twos": "PCM", "lpcm": "PCM", "ipcm": "PCM",
+}
+
+// Sample entry types whose frames are whole JPEG or PNG images
+var mp4ImageCodecs = map[string]bool{"jpeg": true, "mjpa": true, "png ": true}
+
+// QuickTime and MP4 count seconds from 1904
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Tells MP4 and MOV files apart by the brands of their ftyp box. QuickTime files
+// made before the ftyp box existed start with their movie or media data, boxes
+// any binary file can start with, so they are only told by their extension
+func mp4Kind(header []byte) string {
+	if string(header[4:8]) != "ftyp" {
+		return ""
+	}
+	if len(header) < 12 || mp4OtherBrands[string(header[8:12])] {
+		return ""
+	}
+	if string(header[8:12]) == "qt  " {
+		return MOV
+	}
+	size := int(binary.BigEndian.Uint32(header))
+	end := min(max(size, 12), len(header))
+	// the major brand, then the minor version and the compatible brands
+	for offset := 8; offset+4 <= end; offset += 4 {
+		if offset != 12 && mp4Brands[string(header[offset:offset+4])] {
+			return MP4
+		}
+	}
+	return ""
+}
+
+// Returns true for QuickTime files without an ftyp box, they start with one of
+// the top level boxes of a movie
+func isLegacyQuickTime(header []byte) bool {
+	if len(header) < 8 {
+		return false
+	}
+	switch string(header[4:8]) {
+	case "moov", "mdat", "wide", "free", "skip":
+		return true
+	}
+	return false
+}
+
+type mp4Box struct {
+	kind   string
+	offset int64 // offset of the box contents after the header
+	size   int64 // size of the box contents
+}
+
+// Lists the boxes between start and end
+func readMP4Boxes(r file, start int64, end int64) []mp4Box {
+	var boxes []mp4Box
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			break
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// box runs to the end of the file
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:], offset+8); err != nil {
+				return boxes
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		// 64 bit sizes can overflow offset+size, so they are compared with what is left
+		if size < headerSize || size > end-offset {
+			break
+		}
+		boxes = append(boxes, mp4Box{kind: string(header[4:8]), offset: offset + headerSize, size: size - headerSize})
+		offset += size
+	}
+	return boxes
+}
+
+func findMP4Box(boxes []mp4Box, kind string) (mp4Box, bool) {
+	for _, b := range boxes {
+		if b.kind == kind {
+			return b, true
+		}
+	}
+	return mp4Box{}, false
+}
+
+// Returns the children of a box
+func mp4Children(r file, b mp4Box) []mp4Box {
+	return readMP4Boxes(r, b.offset, b.offset+b.size)
+}
+
+// Follows a path of boxes like mdia/minf/stbl down from the given boxes
+func findMP4Path(r file, boxes []mp4Box, path string) (mp4Box, bool) {
+	var b mp4Box
+	for i, kind := range strings.Split(path, "/") {
+		if i > 0 {
+			boxes = mp4Children(r, b)
+		}
+		var ok bool
+		if b, ok = findMP4Box(boxes, kind); !ok {
+			return b, false
+		}
+	}
+	return b, true
+}
+
+// Reads the contents of a box
+func readMP4Box(r file, b mp4Box) ([]byte, bool) {
+	if b.size > maxMP4BoxSize {
+		return nil, false
+	}
+	data := make([]byte, b.size)
+	if _, err := r.ReadAt(data, b.offset); err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Reads the movie header and the tracks of an MP4 or MOV file
+func probeMP4(r file) (*Info, error) {
+	top := readMP4Boxes(r, 0, r.Size())
+	info := &Info{Container: MP4}
+	ftyp, ok := findMP4Box(top, "ftyp")
+	if data, read := readMP4Box(r, ftyp); !ok || (read && len(data) >= 4 && string(data[:4]) == "qt  ") {
+		info.Container = MOV
+	}
+
+	moov, ok := findMP4Box(top, "moov")
+	if !ok {
+		return info, errNoMoov
+	}
+	children := mp4Children(r, moov)
+
+	if mvhd, ok := findMP4Box(children, "mvhd"); ok {
+		if data, ok := readMP4Box(r, mvhd); ok && len(data) >= 20 {
+			var created, timescale, duration uint64
+			if data[0] == 1 && len(data) >= 32 {
+				created = binary.BigEndian.Uint64(data[4:])
+				timescale = uint64(binary.BigEndian.Uint32(data[20:]))
+				duration = binary.BigEndian.Uint64(data[24:])
+			} else {
+				created = uint64(binary.BigEndian.Uint32(data[4:]))
+				timescale = uint64(binary.BigEndian.Uint32(data[12:]))
+				duration = uint64(binary.BigEndian.Uint32(data[16:]))
+			}
+			// fragmented files keep the duration in the movie extends header
+			if mehd, ok := findMP4Path(r, children, "mvex/mehd"); ok && duration == 0 {
+				if data, ok := readMP4Box(r, mehd); ok && len(data) >= 8 {
+					if data[0] == 1 && len(data) >= 12 {
+						duration = binary.BigEndian.Uint64(data[4:])
+					} else {
+						duration = uint64(binary.BigEndian.Uint32(data[4:]))
+					}
+				}
+			}
+			if timescale > 0 {
+				info.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+			}
+			// cameras without a clock write 0
+			if date := mp4Epoch.Add(time.Duration(created) * time.Second); created > 0 && date.Year() >= 1971 {
+				info.CreationDate = date
+			}
+		}
+	}
+
+	for _, trak := range children {
+		if trak.kind == "trak" {
+			readMP4Track(r, trak, info)
+		}
+	}
+
+	info.cover = findMP4Cover(r, children)
+	return info, nil
+}
+
+// Reads the codec of a track, and of the first video track its size, rotation and first frame
+func readMP4Track(r file, trak mp4Box, info *Info) {
+	children := mp4Children(r, trak)
+	hdlr, ok := findMP4Path(r, children, "mdia/hdlr")
+	if !ok {
+		return
+	}
+	handler, ok := readMP4Box(r, hdlr)
+	if !ok || len(handler) < 12 {
+		return
+	}
+	kind := string(handler[8:12])
+	if (kind != "vide" || info.VideoCodec != "") && (kind != "soun" || info.AudioCodec != "") {
+		return
+	}
+
+	stbl, ok := findMP4Path(r, children, "mdia/minf/stbl")
+	if !ok {
+		return
+	}
+	tables := mp4Children(r, stbl)
+	stsd, ok := findMP4Box(tables, "stsd")
+	if !ok {
+		return
+	}
+	entries, ok := readMP4Box(r, stsd)
+	// version and flags, the entry count, then the size and type of the first entry
+	if !ok || len(entries) < 16 {
+		return
+	}
+	codec := string(entries[12:16])
+	name := mp4Codecs[codec]
+	if name == "" {
+		name = strings.ToUpper(strings.TrimSpace(codec))
+	}
+
+	if kind == "soun" {
+		info.AudioCodec = name
+		return
+	}
+	info.VideoCodec = name
+
+	// visual sample entries have the size of the frames after 24 bytes
+	if len(entries) >= 36 {
+		info.Width = int(binary.BigEndian.Uint16(entries[32:]))
+		info.Height = int(binary.BigEndian.Uint16(entries[34:]))
+	}
+	if tkhd, ok := findMP4Box(children, "tkhd"); ok {
+		if data, ok := readMP4Box(r, tkhd); ok {
+			readMP4TrackHeader(data, info)
+		}
+	}
+
+	if mp4ImageCodecs[codec] {
+		info.frame = firstMP4Sample(r, tables)
+	}
+}
+
+// Reads the size and the rotation of the video from its track header
+func readMP4TrackHeader(data []byte, info *Info) {
+	matrix := 40
+	if len(data) > 0 && data[0] == 1 {
+		matrix = 52
+	}
+	if len(data) < matrix+44 {
+		return
+	}
+	// the size is 16.16 fixed point, audio tracks have none
+	if width, height := int(binary.BigEndian.Uint32(data[matrix+36:])>>16), int(binary.BigEndian.Uint32(data[matrix+40:])>>16); width > 0 && height > 0 {
+		info.Width, info.Height = width, height
+	}
+
+	// the a, b, c and d of the transformation matrix are 16.16 fixed point, phones
+	// store portrait videos as landscape frames rotated by the matrix
+	a := int32(binary.BigEndian.Uint32(data[matrix:])) >> 16
+	b := int32(binary.BigEndian.Uint32(data[matrix+4:])) >> 16
+	c := int32(binary.BigEndian.Uint32(data[matrix+12:])) >> 16
+	d := int32(binary.BigEndian.Uint32(data[matrix+16:])) >> 16
+	switch {
+	case a == 0 && b == 1 && c == -1 && d == 0:
+		info.Rotation = 90
+	case a == -1 && b == 0 && c == 0 && d == -1:
+		info.Rotation = 180
+	case a == 0 && b == -1 && c == 1 && d == 0:
+		info.Rotation = 270
+	}
+}
+
+// Locates the first sample of a track, it starts its first chunk
+func firstMP4Sample(r file, tables []mp4Box) posterSource {
+	var source posterSource
+	if stco, ok := findMP4Box(tables, "stco"); ok {
+		data := make([]byte, 12)
+		if _, err := r.ReadAt(data, stco.offset); err == nil && binary.BigEndian.Uint32(data[4:]) > 0 {
+			source.offset = int64(binary.BigEndian.Uint32(data[8:]))
+		}
+	} else if co64, ok := findMP4Box(tables, "co64"); ok {
+		data := make([]byte, 16)
+		if _, err := r.ReadAt(data, co64.offset); err == nil && binary.BigEndian.Uint32(data[4:]) > 0 {
+			source.offset = int64(binary.BigEndian.Uint64(data[8:]))
+		}
+	}
+
+	stsz, ok := findMP4Box(tables, "stsz")
+	if !ok || source.offset == 0 {
+		return posterSource{}
+	}
+	data := make([]byte, 16)
+	if _, err := r.ReadAt(data, stsz.offset); err != nil || binary.BigEndian.Uint32(data[8:]) == 0 {
+		return posterSource{}
+	}
+	// every sample has the same size, or the sizes are listed after the count
+	source.length = int64(binary.BigEndian.Uint32(data[4:]))
+	if source.length == 0 {
+		source.length = int64(binary.BigEndian.Uint32(data[12:]))
+	}
+	return source
+}
+
+// Locates the cover art iTunes style metadata keeps in moov/udta/meta/ilst/covr
+func findMP4Cover(r file, moov []mp4Box) posterSource {
+	meta, ok := findMP4Path(r, moov, "udta/meta")
+	if !ok {
+		return posterSource{}
+	}
+	// meta is a full box in MP4 files but not in QuickTime files
+	start := meta.offset
+	kind := make([]byte, 8)
+	if _, err := r.ReadAt(kind, start); err == nil && string(kind[4:]) != "hdlr" {
+		start += 4
+	}
+	ilst, ok := findMP4Box(readMP4Boxes(r, start, meta.offset+meta.size), "ilst")
+	if !ok {
+		return posterSource{}
+	}
+	covr, ok := findMP4Path(r, mp4Children(r, ilst), "covr/data")
+	// the data box starts with the type of the value and a locale
+	if !ok || covr.size <= 8 {
+		return posterSource{}
+	}
+	return posterSource{offset: covr.offset + 8, length: covr.size - 8}
+}
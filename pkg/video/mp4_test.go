@@ -0,0 +1,167 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds an MP4 box
+func testMP4Box(kind string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, kind...), body...)
+}
+
+// Builds a version 0 movie header
+func testMvhd(created, timescale, duration uint32) []byte {
+	d := make([]byte, 4)
+	for _, v := range []uint32{created, created, timescale, duration} {
+		d = binary.BigEndian.AppendUint32(d, v)
+	}
+	return testMP4Box("mvhd", d, make([]byte, 80))
+}
+
+// Builds a version 0 track header with the rotation matrix a, b, c, d and the size
+func testTkhd(a, b, c, d int32, width, height uint32) []byte {
+	data := make([]byte, 40)
+	matrix := []int32{a, b, 0, c, d, 0, 0, 0, 1}
+	for _, v := range matrix {
+		data = binary.BigEndian.AppendUint32(data, uint32(v<<16))
+	}
+	data = binary.BigEndian.AppendUint32(data, width<<16)
+	return testMP4Box("tkhd", binary.BigEndian.AppendUint32(data, height<<16))
+}
+
+// Builds a track with a handler and the first sample entry, tables are added to its stbl
+func testTrak(handler string, codec string, width, height uint16, tkhd []byte, tables ...[]byte) []byte {
+	hdlr := testMP4Box("hdlr", make([]byte, 8), []byte(handler), make([]byte, 12))
+	entry := append(make([]byte, 16), byte(width>>8), byte(width), byte(height>>8), byte(height))
+	entry = append(binary.BigEndian.AppendUint32(nil, uint32(8+len(entry))), append([]byte(codec), entry...)...)
+	stsd := testMP4Box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, entry)
+	stbl := testMP4Box("stbl", append([][]byte{stsd}, tables...)...)
+	return testMP4Box("trak", tkhd, testMP4Box("mdia", hdlr, testMP4Box("minf", stbl)))
+}
+
+// Builds an MP4 with an H.264 video turned 90 degrees and an AAC track
+func testMP4() []byte {
+	moov := testMP4Box("moov",
+		testMvhd(3786912000, 1000, 12500),
+		testTrak("vide", "avc1", 1920, 1080, testTkhd(0, 1, -1, 0, 1920, 1080)),
+		testTrak("soun", "mp4a", 0, 0, testTkhd(1, 0, 0, 1, 0, 0)),
+	)
+	return append(testMP4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2")), moov...)
+}
+
+// Builds a QuickTime MJPEG video with its frame and a cover in the media data
+func testMJPEGMP4(frame []byte, cover []byte) []byte {
+	ftyp := testMP4Box("ftyp", []byte("qt  \x00\x00\x00\x00qt  "))
+	moov := func(frameOffset uint32) []byte {
+		stco := testMP4Box("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1}, binary.BigEndian.AppendUint32(nil, frameOffset))
+		stsz := testMP4Box("stsz", make([]byte, 4), binary.BigEndian.AppendUint32(nil, uint32(len(frame))), []byte{0, 0, 0, 1})
+		trak := testTrak("vide", "jpeg", 4, 2, testTkhd(-1, 0, 0, -1, 4, 2), stco, stsz)
+		covr := testMP4Box("covr", testMP4Box("data", []byte{0, 0, 0, 13, 0, 0, 0, 0}, cover))
+		udta := testMP4Box("udta", testMP4Box("meta", make([]byte, 4), testMP4Box("ilst", covr)))
+		return testMP4Box("moov", testMvhd(0, 600, 0), trak, udta)
+	}
+	frameOffset := len(ftyp) + len(moov(0)) + 8
+	return bytes.Join([][]byte{ftyp, moov(uint32(frameOffset)), testMP4Box("mdat", frame)}, nil)
+}
+
+func TestProbeMP4(t *testing.T) {
+	info, err := probe(bytes.NewReader(testMP4()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, MP4, info.Container)
+		assert.Equal(t, 12500*time.Millisecond, info.Duration)
+		assert.Equal(t, "H.264", info.VideoCodec)
+		assert.Equal(t, "AAC", info.AudioCodec)
+		assert.Equal(t, 1920, info.Width)
+		assert.Equal(t, 1080, info.Height)
+		assert.Equal(t, 90, info.Rotation)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), info.CreationDate)
+		assert.False(t, info.HasPoster())
+	}
+}
+
+func TestProbeLegacyQuickTime(t *testing.T) {
+	// old QuickTime files have no ftyp box, they are still read when the extension says they are videos
+	data := testMP4()
+	info, err := probe(bytes.NewReader(data[len(testMP4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2"))):]))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 12500*time.Millisecond, info.Duration)
+		assert.Equal(t, "H.264", info.VideoCodec)
+	}
+}
+
+func TestProbeMP4Invalid(t *testing.T) {
+	valid := testMP4()
+	ftyp := testMP4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2"))
+	largeMoov := append(bytes.Clone(ftyp), 0, 0, 0, 1, 'm', 'o', 'o', 'v')
+	noHandler := testMP4Box("trak", testMP4Box("mdia", testMP4Box("hdlr", make([]byte, 8), []byte("vi"))))
+	// the stsd box ends in the middle of the codec of its entry
+	stsd := testMP4Box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 36, 'a', 'v'})
+	hdlr := testMP4Box("hdlr", make([]byte, 8), []byte("vide"), make([]byte, 12))
+	shortEntry := testMP4Box("trak", testMP4Box("mdia", hdlr, testMP4Box("minf", testMP4Box("stbl", stsd))))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		hasErr   bool
+		codec    string
+		duration time.Duration
+	}{
+		{"no moov", ftyp, true, "", 0},
+		{"truncated moov", valid[:len(valid)-10], true, "", 0},
+		{"moov past the end", append(bytes.Clone(ftyp), 0, 0, 0xff, 0xff, 'm', 'o', 'o', 'v'), true, "", 0},
+		{"64 bit size overflows", binary.BigEndian.AppendUint64(bytes.Clone(largeMoov), 0x7FFFFFFFFFFFFFFF), true, "", 0},
+		{"negative 64 bit size", binary.BigEndian.AppendUint64(bytes.Clone(largeMoov), 0xFFFFFFFFFFFFFFFF), true, "", 0},
+		{"truncated mvhd", append(bytes.Clone(ftyp), testMP4Box("moov", testMP4Box("mvhd", make([]byte, 10)))...), false, "", 0},
+		{"truncated handler", append(bytes.Clone(ftyp), testMP4Box("moov", testMvhd(0, 1000, 2000), noHandler)...), false, "", 2 * time.Second},
+		{"truncated sample entry", append(bytes.Clone(ftyp), testMP4Box("moov", testMvhd(0, 1000, 2000), shortEntry)...), false, "", 2 * time.Second},
+		{"zero timescale", append(bytes.Clone(ftyp), testMP4Box("moov", testMvhd(0, 0, 2000))...), false, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				info, err := probe(bytes.NewReader(tt.data))
+				assert.Equal(t, tt.hasErr, err != nil, err)
+				if assert.NotNil(t, info) {
+					assert.Equal(t, tt.codec, info.VideoCodec)
+					assert.Equal(t, tt.duration, info.Duration)
+				}
+			})
+		})
+	}
+}
+
+func TestDecodePosterMP4(t *testing.T) {
+	frame := testJPEGFrame(t)
+	cover := testJPEGFrame(t)
+
+	// the cover comes first and is upright
+	img, err := DecodePoster(bytes.NewReader(testMJPEGMP4(frame, cover)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, img.Bounds().Dx())
+	}
+
+	// the frame is turned by the track matrix, 180 degrees keeps its size
+	data := testMJPEGMP4(frame, nil)
+	info, err := probe(bytes.NewReader(data))
+	if assert.NoError(t, err) {
+		assert.Equal(t, MOV, info.Container)
+		assert.Equal(t, "MJPEG", info.VideoCodec)
+		assert.Equal(t, 180, info.Rotation)
+		assert.Equal(t, int64(len(frame)), info.frame.length)
+	}
+	img, err = DecodePoster(bytes.NewReader(data))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, img.Bounds().Dx())
+	}
+
+	// a frame that isn't all there
+	_, err = DecodePoster(bytes.NewReader(data[:len(data)-len(frame)/2]))
+	assert.ErrorIs(t, err, ErrNoPoster)
+}
@@ -0,0 +1,171 @@
+// Package video reads the container metadata of MP4, MOV, Matroska, WebM and AVI
+// files and finds the poster image of a video, its embedded cover art or its first
+// frame when that is stored as a JPEG or PNG. Other codecs can't be decoded in pure Go
+package video
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"main/pkg/imagetransform"
+	"os"
+	"time"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported video format")
+	ErrNoPoster          = errors.New("video has no cover art or decodable frame")
+)
+
+// Container names, the same as the file type tags
+const (
+	MP4  = "MP4"
+	MOV  = "MOV"
+	MKV  = "MKV"
+	WEBM = "WEBM"
+	AVI  = "AVI"
+)
+
+// Biggest poster read, cover art and single frames are far smaller
+const maxPosterSize = 32 << 20
+
+// Info is what the container of a video tells without decoding it
+type Info struct {
+	Container    string // one of the container names
+	Duration     time.Duration
+	Width        int // of the stored frames, before the rotation
+	Height       int
+	Rotation     int       // degrees clockwise the video is shown rotated by, 0, 90, 180 or 270
+	VideoCodec   string    // e.g. H.264 or VP9, empty without a video track
+	AudioCodec   string    // e.g. AAC or Opus, empty without an audio track
+	CreationDate time.Time // when the video was recorded, zero if unknown
+
+	cover posterSource // embedded cover art
+	frame posterSource // the first frame of the video track, if it is a JPEG or PNG
+}
+
+// Location of a poster image in the file, a zero length when there is none
+type posterSource struct {
+	offset int64
+	length int64
+}
+
+// Returns true if the video has an image that can be shown as its poster
+func (info *Info) HasPoster() bool {
+	return info.cover.length > 0 || info.frame.length > 0
+}
+
+// Tells the container of a video from the start of the file, empty for other files
+func Detect(header []byte) string {
+	switch {
+	case len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "AVI ":
+		return AVI
+	case bytes.HasPrefix(header, []byte(ebmlMagic)):
+		return matroskaKind(header)
+	case len(header) >= 8:
+		return mp4Kind(header)
+	}
+	return ""
+}
+
+// file is a video file that can be read at any offset
+type file interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// Reads the metadata of a video file
+func Probe(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return probe(io.NewSectionReader(f, 0, stat.Size()))
+}
+
+func probe(r file) (*Info, error) {
+	header := make([]byte, 64)
+	n, _ := r.ReadAt(header, 0)
+
+	switch Detect(header[:n]) {
+	case MP4, MOV:
+		return probeMP4(r)
+	case MKV, WEBM:
+		return probeMatroska(r)
+	case AVI:
+		return probeAVI(r)
+	}
+	// the file is already known to be a video by its extension here
+	if isLegacyQuickTime(header[:n]) {
+		return probeMP4(r)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// Decodes the poster of a video, the cover art if it has one, otherwise the
+// first frame turned the way the video is shown. r has to be read at any offset,
+// other readers are read into memory
+func DecodePoster(r io.Reader) (image.Image, error) {
+	f, ok := r.(file)
+	if !ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		f = bytes.NewReader(data)
+	}
+
+	info, err := probe(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range []posterSource{info.cover, info.frame} {
+		if source.length <= 0 || source.length > maxPosterSize {
+			continue
+		}
+		data := make([]byte, source.length)
+		if _, err := f.ReadAt(data, source.offset); err != nil && err != io.EOF {
+			continue
+		}
+		img, err := decodeFrame(data)
+		if err != nil {
+			continue
+		}
+		// frames are stored before the rotation of the track, cover art is upright
+		if source == info.frame {
+			img = imagetransform.Rotate(img, info.Rotation)
+		}
+		return img, nil
+	}
+	return nil, ErrNoPoster
+}
+
+// Decodes a cover or a frame stored as a JPEG or PNG
+func decodeFrame(data []byte) (image.Image, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8")):
+		return jpeg.Decode(bytes.NewReader(addHuffmanTables(data)))
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return png.Decode(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("%w: the poster isn't a JPEG or PNG", ErrNoPoster)
+}
+
+// Formats a duration like 0:42, 3:07 or 1:02:09
+func FormatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,73 @@
+package video
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Encodes a 4x2 JPEG, the poster tests check its size to see if it was turned
+func testJPEGFrame(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"MP4", "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2", MP4},
+		{"MP4 compatible brand", "\x00\x00\x00\x14ftypXXXX\x00\x00\x00\x00mp42", MP4},
+		{"QuickTime brand", "\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00qt  ", MOV},
+		// without an ftyp box any file could start like this, they are told by their extension
+		{"old QuickTime", "\x00\x00\x00\x08wide\x00\x00\x00\x10mdat", ""},
+		{"moov first", "\x00\x00\x00\x10moov\x00\x00\x00\x08mvhd", ""},
+		{"HEIC", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic", ""},
+		{"truncated ftyp", "\x00\x00\x00\x18ftyp", ""},
+		{"ftyp size past the header", "\x00\x00\xff\xffftypXXXX\x00\x00\x00\x00isom", MP4},
+		{"AVI", "RIFF\x00\x00\x00\x00AVI LIST", AVI},
+		{"WAVE", "RIFF\x00\x00\x00\x00WAVEfmt ", ""},
+		{"Matroska", ebmlMagic + "\x9f\x42\x82\x88matroska", MKV},
+		{"WebM", ebmlMagic + "\x9f\x42\x82\x84webm", WEBM},
+		// the EBML header is cut off before its document type
+		{"truncated EBML", ebmlMagic + "\x9f", MKV},
+		{"empty", "", ""},
+		{"text", "hello world", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Detect([]byte(tt.header)))
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		want     string
+	}{
+		{0, "0:00"},
+		{42 * time.Second, "0:42"},
+		{3*time.Minute + 7*time.Second + 600*time.Millisecond, "3:08"},
+		{time.Hour + 2*time.Minute + 9*time.Second, "1:02:09"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatDuration(tt.duration))
+		})
+	}
+}
+
+func TestDecodePosterUnsupported(t *testing.T) {
+	_, err := DecodePoster(bytes.NewReader([]byte("not a video")))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -1,2 +1,3 @@
 #!/usr/bin/env bash
-go test -v -race -timeout 10s main_test.go
+go test -tags sqlite_fts5 ./pkg/...
+go test -tags sqlite_fts5 -v -race -timeout 10s main_test.go